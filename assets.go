@@ -0,0 +1,111 @@
+package cfgx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// Asset is a file referenced with file: by the input, as listed by Assets.
+type Asset struct {
+	// Keys are the dotted key paths of the values referencing the file.
+	Keys []string
+
+	// Ref is the reference as written, without the file: prefix.
+	Ref string
+
+	// Path is the path of the file, resolved relative to the input directory,
+	// or slash-separated within GenerateOptions.FS.
+	Path string
+
+	// Content is the content of the file.
+	Content []byte
+
+	// ContentType is the media type of the content, as cfgx:type=asset
+	// values report it.
+	ContentType string
+
+	// SHA256 is the hex-encoded SHA-256 hash of the content.
+	SHA256 string
+}
+
+// Assets returns the files referenced with file: by the input of opts, in
+// key order, once each however many values reference them, for tools that
+// ship them alongside the binary, such as cfgx bundle. The files are read
+// the way GenerateFromFile reads them, honoring MaxFileSize, and Assets
+// fails on the first reference that is missing or too large. Only the input
+// options are used; OutputFile may be left empty.
+func Assets(opts *GenerateOptions) ([]Asset, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("options cannot be nil")
+	}
+	inputs, err := inputFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	o := *opts
+	o.InputFile = inputs[0]
+	opts = &o
+
+	source, err := readInput(opts, opts.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file %s: %w", opts.InputFile, err)
+	}
+	data, src, err := decodeInput(opts, opts.InputFile, source)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, err)
+	}
+
+	inputDir := inputDirOf(opts)
+	for _, file := range inputs[1:] {
+		if err := mergeInput(opts, data, file, inputDir); err != nil {
+			return nil, err
+		}
+	}
+	typeHints, err := loadTypeHints(opts, inputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	maxFileSize := opts.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	gen := generator.New(
+		generator.WithInputDir(inputDir),
+		generator.WithMaxFileSize(maxFileSize),
+		generator.WithFS(opts.FS),
+		generator.WithSource(src),
+		generator.WithTypeHints(typeHints),
+	)
+	refs, err := gen.FileReferences(data)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, err)
+	}
+
+	var assets []Asset
+	index := make(map[string]int)
+	for _, ref := range refs {
+		if i, ok := index[ref.Path]; ok {
+			if !slices.Contains(assets[i].Keys, ref.Key) {
+				assets[i].Keys = append(assets[i].Keys, ref.Key)
+			}
+			continue
+		}
+		sum := sha256.Sum256(ref.Content)
+		index[ref.Path] = len(assets)
+		assets = append(assets, Asset{
+			Keys:        []string{ref.Key},
+			Ref:         ref.Ref,
+			Path:        ref.Path,
+			Content:     ref.Content,
+			ContentType: snippets.AssetContentType(ref.Path, ref.Content),
+			SHA256:      hex.EncodeToString(sum[:]),
+		})
+	}
+	return assets, nil
+}
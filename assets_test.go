@@ -0,0 +1,42 @@
+package cfgx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssets(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "certs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "certs", "server.pem"), []byte("cert"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "logo.png"), []byte("\x89PNG\r\n\x1a\n"), 0644))
+
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte(`[tls]
+cert = "file:certs/server.pem"
+backup = "file:certs/server.pem"
+
+[ui]
+logo = "file:logo.png"
+`), 0644))
+
+	assets, err := Assets(&GenerateOptions{InputFile: inputFile})
+	require.NoError(t, err)
+	require.Len(t, assets, 2)
+
+	require.Equal(t, []string{"tls.backup", "tls.cert"}, assets[0].Keys, "files referenced twice should be listed once")
+	require.Equal(t, "certs/server.pem", assets[0].Ref)
+	require.Equal(t, filepath.Join(tmpDir, "certs", "server.pem"), assets[0].Path)
+	require.Equal(t, []byte("cert"), assets[0].Content)
+	require.Equal(t, "06298432e8066b29e2223bcc23aa9504b56ae508fabf3435508869b9c3190e22", assets[0].SHA256)
+
+	require.Equal(t, []string{"ui.logo"}, assets[1].Keys)
+	require.Equal(t, "image/png", assets[1].ContentType)
+
+	require.NoError(t, os.WriteFile(inputFile, []byte("[tls]\ncert = \"file:missing.pem\"\n"), 0644))
+	_, err = Assets(&GenerateOptions{InputFile: inputFile})
+	require.ErrorContains(t, err, "file not found")
+}
@@ -0,0 +1,225 @@
+package cfgx
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gomantics/cfgx/internal/generator"
+)
+
+// DefaultCacheSize is the number of results kept by CachedGenerate.
+const DefaultCacheSize = 128
+
+// errGenerationPanicked is reported to callers waiting on a generation that panicked.
+var errGenerationPanicked = errors.New("code generation panicked")
+
+// generateCache holds the results of CachedGenerate.
+var generateCache = newCache(DefaultCacheSize)
+
+// CachedGenerate is GenerateWithOptions for servers that generate code on
+// request. Results are cached by a hash of the input and options, and
+// concurrent calls with the same input share a single generation. Failed
+// generations are not cached. The cache keeps the DefaultCacheSize most
+// recently used results.
+//
+// Files referenced with file: are read when a result is first generated;
+// later changes to them are not seen until the entry is evicted.
+func CachedGenerate(tomlData []byte, packageName string, enableEnv bool, inputDir string, maxFileSize int64, mode string) ([]byte, error) {
+	h := sha256.New()
+	for _, s := range []string{packageName, inputDir, mode} {
+		binary.Write(h, binary.LittleEndian, int64(len(s)))
+		h.Write([]byte(s))
+	}
+	binary.Write(h, binary.LittleEndian, enableEnv)
+	binary.Write(h, binary.LittleEndian, maxFileSize)
+	h.Write(tomlData)
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+
+	code, err := generateCache.do(key, func() ([]byte, error) {
+		return GenerateWithOptions(tomlData, packageName, enableEnv, inputDir, maxFileSize, mode)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers own the returned slice
+	return bytes.Clone(code), nil
+}
+
+// cache is a size-bounded LRU cache of generation results that also
+// deduplicates concurrent generations of the same key.
+type cache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[[sha256.Size]byte]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+}
+
+// cacheEntry is a generation result, complete once done is closed.
+type cacheEntry struct {
+	key  [sha256.Size]byte
+	done chan struct{}
+	code []byte
+	err  error
+}
+
+func newCache(max int) *cache {
+	return &cache{
+		max:     max,
+		entries: make(map[[sha256.Size]byte]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+// do returns the cached result for key, waiting for a generation in flight,
+// or runs generate and caches its result.
+func (c *cache) do(key [sha256.Size]byte, generate func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+
+		e := elem.Value.(*cacheEntry)
+		<-e.done
+		return e.code, e.err
+	}
+
+	e := &cacheEntry{key: key, done: make(chan struct{})}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.max {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	c.mu.Unlock()
+
+	c.run(e, generate)
+	return e.code, e.err
+}
+
+// run fills in e with the result of generate. Waiters are released even if
+// generate panics, and failed entries are dropped so the next call retries.
+func (c *cache) run(e *cacheEntry, generate func() ([]byte, error)) {
+	defer func() {
+		close(e.done)
+		if e.err == nil {
+			return
+		}
+		c.mu.Lock()
+		if elem, ok := c.entries[e.key]; ok && elem.Value == e {
+			c.lru.Remove(elem)
+			delete(c.entries, e.key)
+		}
+		c.mu.Unlock()
+	}()
+
+	e.err = errGenerationPanicked
+	e.code, e.err = generate()
+}
+
+// FileCache keeps, across the runs of a session such as watch mode, the
+// contents of the files read for file: references and what was written to
+// each output file. Files are read again only once their size or
+// modification time changes, and outputs are not rewritten when unchanged,
+// which keeps regenerations of configurations embedding many assets fast.
+// Pass the same FileCache, from NewFileCache, as GenerateOptions.FileCache
+// to every run. It is safe for concurrent use.
+type FileCache struct {
+	files *generator.FileCache
+
+	mu      sync.Mutex
+	written map[string]writtenFile
+	refs    []string
+}
+
+// writtenFile is what was written to an output file, as of its size and
+// modification time after writing.
+type writtenFile struct {
+	sum     [sha256.Size]byte
+	size    int64
+	modTime time.Time
+}
+
+// NewFileCache returns an empty FileCache.
+func NewFileCache() *FileCache {
+	return &FileCache{
+		files:   generator.NewFileCache(),
+		written: make(map[string]writtenFile),
+	}
+}
+
+// generatorCache returns the cache of file contents of c, or nil.
+func (c *FileCache) generatorCache() *generator.FileCache {
+	if c == nil {
+		return nil
+	}
+	return c.files
+}
+
+// unchanged reports whether the output file at path already holds data, as
+// written by this cache and left untouched since.
+func (c *FileCache) unchanged(path string, data []byte) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	w, ok := c.written[path]
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() != w.size || !info.ModTime().Equal(w.modTime) {
+		return false
+	}
+	return sha256.Sum256(data) == w.sum
+}
+
+// wrote records that data was written to the output file at path.
+func (c *FileCache) wrote(path string, data []byte) {
+	if c == nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written[path] = writtenFile{sum: sha256.Sum256(data), size: info.Size(), modTime: info.ModTime()}
+}
+
+// ReferencedFiles returns the absolute paths of the files read for file:
+// references by the last successful run, sorted, for watchers to regenerate
+// when they change.
+func (c *FileCache) ReferencedFiles() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.refs)
+}
+
+// referenced records the files read for file: references by a successful run.
+func (c *FileCache) referenced(paths []string) {
+	if c == nil {
+		return
+	}
+	refs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if abs, err := filepath.Abs(path); err == nil {
+			refs = append(refs, abs)
+		}
+	}
+	slices.Sort(refs)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refs = refs
+}
@@ -0,0 +1,139 @@
+package cfgx
+
+import (
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedGenerate(t *testing.T) {
+	data := []byte("[server]\naddr = \":8080\"\n")
+
+	want, err := GenerateWithOptions(data, "config", true, "", 0, "getter")
+	require.NoError(t, err)
+
+	got, err := CachedGenerate(data, "config", true, "", 0, "getter")
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	// Returned slices are not shared with the cache
+	got[0] = 'x'
+	again, err := CachedGenerate(data, "config", true, "", 0, "getter")
+	require.NoError(t, err)
+	require.Equal(t, want, again)
+
+	// Options are part of the key
+	static, err := CachedGenerate(data, "config", true, "", 0, "static")
+	require.NoError(t, err)
+	require.NotEqual(t, want, static)
+
+	_, err = CachedGenerate([]byte("[server"), "config", true, "", 0, "static")
+	require.Error(t, err)
+}
+
+func TestCache_SharesConcurrentGenerations(t *testing.T) {
+	c := newCache(2)
+	key := sha256.Sum256([]byte("a"))
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code, err := c.do(key, func() ([]byte, error) {
+				calls.Add(1)
+				<-release
+				return []byte("code"), nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, "code", string(code))
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	require.Equal(t, int32(1), calls.Load(), "identical requests should share one generation")
+}
+
+func TestCache_EvictsAndRetries(t *testing.T) {
+	c := newCache(2)
+	calls := 0
+	generate := func() ([]byte, error) {
+		calls++
+		return []byte("code"), nil
+	}
+
+	for _, s := range []string{"a", "b", "a", "c", "a", "b"} {
+		_, err := c.do(sha256.Sum256([]byte(s)), generate)
+		require.NoError(t, err)
+	}
+	// a, b, (a hit), c evicts b, (a hit), b again
+	require.Equal(t, 4, calls)
+
+	failing := sha256.Sum256([]byte("fail"))
+	_, err := c.do(failing, func() ([]byte, error) { return nil, errors.New("boom") })
+	require.Error(t, err)
+	_, err = c.do(failing, generate)
+	require.NoError(t, err, "failed generations should not be cached")
+
+	panicking := sha256.Sum256([]byte("panic"))
+	require.Panics(t, func() {
+		c.do(panicking, func() ([]byte, error) { panic("boom") })
+	})
+	_, err = c.do(panicking, generate)
+	require.NoError(t, err, "panicked generations should not be cached")
+}
+
+func TestFileCache(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(cert, []byte("cert-1"), 0644))
+	info, err := os.Stat(cert)
+	require.NoError(t, err)
+
+	inputFile := filepath.Join(dir, "config.toml")
+	outputFile := filepath.Join(dir, "config", "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[tls]\ncert = \"file:cert.pem\"\n"), 0644))
+	opts := &GenerateOptions{
+		InputFile:   inputFile,
+		OutputFile:  outputFile,
+		PackageName: "config",
+		FileCache:   NewFileCache(),
+	}
+	require.NoError(t, GenerateFromFile(opts))
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.True(t, opts.FileCache.unchanged(outputFile, output))
+	require.False(t, opts.FileCache.unchanged(outputFile, append(output, '\n')))
+	require.Equal(t, []string{cert}, opts.FileCache.ReferencedFiles())
+
+	// A file of the same size and modification time is not read again
+	require.NoError(t, os.WriteFile(cert, []byte("cert-2"), 0644))
+	require.NoError(t, os.Chtimes(cert, info.ModTime(), info.ModTime()))
+	require.NoError(t, GenerateFromFile(opts))
+	cached, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Equal(t, output, cached)
+
+	// Until it is modified
+	later := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(cert, later, later))
+	require.NoError(t, GenerateFromFile(opts))
+	updated, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.NotEqual(t, output, updated)
+	require.True(t, opts.FileCache.unchanged(outputFile, updated))
+
+	// Outputs modified by others are written again
+	require.NoError(t, os.Chtimes(outputFile, later, later))
+	require.False(t, opts.FileCache.unchanged(outputFile, updated))
+}
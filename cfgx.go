@@ -42,26 +42,75 @@
 package cfgx
 
 import (
-	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 
 	"github.com/gomantics/cfgx/internal/envoverride"
 	"github.com/gomantics/cfgx/internal/generator"
 	"github.com/gomantics/cfgx/internal/pkgutil"
+	"github.com/gomantics/cfgx/internal/policy"
+	"github.com/gomantics/cfgx/internal/record"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
 )
 
 // DefaultMaxFileSize is the default maximum file size (1 MB) for files referenced with "file:" prefix.
 const DefaultMaxFileSize = 1024 * 1024 // 1 MB
 
+// TypesFile is the name of the optional sidecar file, next to the input file,
+// giving the cfgx:type of keys without annotating them in the input:
+//
+//	[billing]
+//	sku = "string" # keep "1h" a string rather than a time.Duration
+const TypesFile = "cfgx.types.toml"
+
 // GenerateOptions contains all options for generating configuration code.
 type GenerateOptions struct {
 	// InputFile is the path to the input TOML file
 	InputFile string
 
+	// InputFiles, if set, lists several input files that are deep-merged in
+	// order, e.g. a base configuration followed by per-environment overrides.
+	// Later files win: tables are merged key by key and any other value
+	// replaces the earlier one. InputFile may be left empty, and otherwise
+	// must be the first entry. Relative file: references resolve from the
+	// directory of the file they appear in, but cfgx annotations and the
+	// source positions of generation errors come from the first file only.
+	InputFiles []string
+
+	// Source, if non-nil, holds the contents of the first input file, which
+	// is then not read, such as the unsaved contents of an editor buffer.
+	// File: references still resolve from the directory of the file.
+	Source []byte
+
+	// AppendArrays makes arrays in later InputFiles append to the arrays they
+	// override instead of replacing them.
+	AppendArrays bool
+
+	// Format is the input format, "toml" or "json". If empty, it is detected
+	// from the input file extension: ".json" is JSON, anything else TOML.
+	// JSON input carries no comments, so cfgx annotations are not available.
+	Format string
+
+	// MergeDuplicates makes TOML input tolerate repeated [table] headers and
+	// keys, as found in streams of concatenated TOML fragments. The stream is
+	// split into documents that are merged in order: with "last", later
+	// values win; with "error", repeated tables are merged but a key defined
+	// in more than one document is an error. If empty, duplicates are
+	// rejected like any other TOML syntax error.
+	MergeDuplicates string
+
 	// OutputFile is the path where the generated Go code will be written
 	OutputFile string
 
@@ -69,9 +118,25 @@ type GenerateOptions struct {
 	// If empty, it will be inferred from the output file path.
 	PackageName string
 
-	// EnableEnv enables environment variable override support
+	// EnableEnv enables environment variable override support. In static and
+	// hybrid mode, the variables are read at generation time, and the values
+	// they override carry a trailing comment naming the variable.
 	EnableEnv bool
 
+	// EnvPrefix is the prefix of environment variable override names, which
+	// are <EnvPrefix>_<SECTION>_<KEY>. If empty, defaults to "CONFIG".
+	EnvPrefix string
+
+	// EnvOverrideStrictness tells what to do with environment variables
+	// applied at generation time that do not parse as the type of the value
+	// they override: "error" (the default) fails generation, and "warn" keeps
+	// the value from the input and logs a warning to Logger.
+	EnvOverrideStrictness string
+
+	// Logger receives warnings, such as ignored environment variable
+	// overrides. If nil, slog.Default() is used.
+	Logger *slog.Logger
+
 	// MaxFileSize is the maximum size in bytes for files referenced with "file:" prefix.
 	// If zero, defaults to DefaultMaxFileSize (1 MB).
 	MaxFileSize int64
@@ -79,25 +144,273 @@ type GenerateOptions struct {
 	// Mode specifies the generation mode:
 	//   "static" - values baked at build time (default)
 	//   "getter" - generate getter methods with runtime env var overrides
+	//   "hybrid" - values baked at build time, plus a LoadOverrides function
+	//              applying env var overrides to them at runtime
+	//   "loader" - values baked at build time as defaults, plus a Config type
+	//              and Load functions decoding a TOML file over them at runtime,
+	//              then env var overrides unless EnableEnv is false
 	// If empty, defaults to "static".
 	Mode string
+
+	// Style sets how static mode exposes the values:
+	//   "vars"    - a package variable for each top-level key (default)
+	//   "compact" - a Config type and a single Default function returning
+	//               the values as one literal, with no package variables;
+	//               Validate, PrintSummary and the other generated
+	//               functions reading the values are methods of Config
+	// If empty, defaults to "vars".
+	Style string
+
+	// CRLF makes the generated file use CRLF line endings. By default output
+	// always uses LF, regardless of the platform or the input's line endings.
+	CRLF bool
+
+	// DynamicValues enables values generated at generation time, for test
+	// fixtures and seeds: "uuid:v4", "random:hex:<bytes>", "random:int:<max>",
+	// "now:rfc3339", "now:date" and "now:unix". Files containing such values
+	// are marked dynamic in their record, since regenerating them changes them.
+	// If false, these strings are generated as-is.
+	DynamicValues bool
+
+	// TrackUsage makes getters count how often each key is read, reported by
+	// a generated Usage function. It requires getter mode.
+	TrackUsage bool
+
+	// EnvAtInit makes getters read their environment variables once, when the
+	// package is initialized, and return the values stored then. Getters are
+	// then as cheap as static mode fields and never allocate, but changes to
+	// the environment after startup are not seen. It requires getter mode.
+	EnvAtInit bool
+
+	// Summary generates a PrintSummary function that writes the effective
+	// configuration to an io.Writer as an aligned table, with secrets
+	// redacted, for logging it at startup. Keys annotated cfgx:secret and keys
+	// named like credentials, such as "password" or "api_key", are secrets.
+	Summary bool
+
+	// EmitEnvDocs generates a comment block listing every environment
+	// variable the generated code reads, with the type and default value of
+	// the key it overrides. Static mode reads none.
+	EmitEnvDocs bool
+
+	// PreserveOrder generates struct fields, and the variables and values
+	// initializing them, in the order their keys are defined in the input
+	// file rather than alphabetically, keeping the grouping of the TOML. Keys
+	// only defined by overlays or extends follow the others, alphabetically.
+	PreserveOrder bool
+
+	// EmbedThreshold, if positive, makes file: references to files larger
+	// than this many bytes generate a //go:embed variable instead of a []byte
+	// literal. The files are copied into a cfgx_embed directory next to the
+	// output file, which GenerateFiles includes in its result.
+	EmbedThreshold int64
+
+	// StructTags lists struct tag keys, such as "json", "yaml", "toml" or
+	// "mapstructure", written on every generated struct field with the
+	// original TOML key as the name, e.g. `json:"max_conns" yaml:"max_conns"`.
+	StructTags []string
+
+	// IntType is the Go type integers are generated as: "int64" (the default)
+	// or "int", for code that would otherwise convert them everywhere, as in
+	// int(config.Server.MaxConns). Keys can be given the other type with a
+	// cfgx:type=int or cfgx:type=int64 annotation.
+	IntType string
+
+	// Initialisms lists the initialisms, such as "ID" or "URL", that words of
+	// keys are written as in generated identifiers, matching them regardless
+	// of case: with "API" and "URL", api_url becomes APIURL rather than
+	// ApiUrl. The item "default" stands for DefaultInitialisms. Keys are
+	// named word by word, capitalized, if empty.
+	Initialisms []string
+
+	// NameOverrides sets the Go names of the fields, variables and getters
+	// generated for keys, by dotted key path, e.g. "cluster.k8s_namespace" to
+	// "K8sNamespace", where the derived name is awkward or collides with
+	// another. Keys can also be named with a cfgx:name=<Name> annotation,
+	// which NameOverrides takes precedence over. Generated type names are
+	// still derived from the keys.
+	NameOverrides map[string]string
+
+	// Precedence lists, in loader mode, the layers the generated Resolve
+	// applies over the defaults, lowest precedence first: "file" (the TOML
+	// file loaded at runtime), "env" (CONFIG_* environment variables) and
+	// "flag" (command-line flags defined with DefineFlags). The file layer is
+	// required. Defaults to "file" then "env", or just "file" if EnableEnv is
+	// false.
+	Precedence []string
+
+	// Region, if set, makes OutputFile an existing Go file that the generated
+	// code is injected into, replacing the lines between "// cfgx:begin <Region>"
+	// and "// cfgx:end". The file keeps its package clause, and the imports the
+	// generated code needs are added to it.
+	Region string
+
+	// Policies lists policy files whose rules the effective configuration must
+	// satisfy, or generation fails. Each is a TOML file of [[rule]] tables with
+	// a name, a CEL expression in expr and an optional message, e.g.
+	// expr = 'env != "prod" || database.pool.max_size >= 10'. Rules see the
+	// configuration after overlays, references, inheritance and computed values
+	// are resolved, and after environment overrides in static mode.
+	Policies []string
+
+	// FS, if set, is the file system InputFile and file: references are read
+	// from, with slash-separated paths. Generation then needs no OS file access,
+	// as in browsers under js/wasm; GenerateFromFile still writes to the OS.
+	FS fs.FS
+
+	// FileCache, if non-nil, keeps the contents of files read for file:
+	// references and of the files written by GenerateFromFile across the runs
+	// it is passed to, so that regenerations, as in watch mode, only read the
+	// referenced files that changed and only write the outputs that changed.
+	FileCache *FileCache
+
+	// Context, if non-nil, cancels the run: generation stops with its error
+	// between stages, and ciphers decrypting cfgx:encrypted values at
+	// generation time are passed it.
+	Context context.Context
+
+	// Stats, if non-nil, is filled in with timing and size statistics for the run.
+	Stats *Stats
+
+	// Profiling, if non-nil, collects CPU, memory and trace profiles around the run.
+	Profiling *Profiling
 }
 
+// Stats reports where time and output size went during a generation run.
+type Stats = generator.Stats
+
+// KeyWeight is the size of the Go literal emitted for a single key.
+type KeyWeight = generator.KeyWeight
+
 // GenerateFromFile generates Go code from a TOML file and writes it to the output file.
 // This is the main entry point for file-based generation.
 func GenerateFromFile(opts *GenerateOptions) error {
+	files, err := GenerateFiles(opts)
+	if err != nil {
+		return err
+	}
+
+	inputs, _ := inputFiles(opts)
+	for path := range files {
+		for _, input := range inputs {
+			if SamePath(input, path) {
+				return fmt.Errorf("output file %s is the input file", path)
+			}
+		}
+	}
+
+	// Write output files, creating their directories as needed
+	for path, generated := range files {
+		if opts.FileCache.unchanged(path, generated) {
+			continue
+		}
+		if outputDir := filepath.Dir(path); outputDir != "." && outputDir != "" {
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+		}
+		if err := os.WriteFile(path, generated, 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		opts.FileCache.wrote(path, generated)
+	}
+
+	return nil
+}
+
+// GenerateBytes runs the same pipeline as GenerateFromFile but returns the generated
+// code instead of writing it. This is useful for checking whether an existing output
+// file is up to date. Companion files are not included; see GenerateFiles.
+func GenerateBytes(opts *GenerateOptions) ([]byte, error) {
+	files, err := GenerateFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	return files[opts.OutputFile], nil
+}
+
+// GenerateFiles runs the same pipeline as GenerateFromFile and returns every file
+// it would write, keyed by path: the output file, plus a companion file such as
+// config_redis.go for each build-tagged helper requested with cfgx:helper or
+// config_loader.go in loader mode, and a file in its own package directory for each table annotated with cfgx:package.
+func GenerateFiles(opts *GenerateOptions) (map[string][]byte, error) {
 	if opts == nil {
-		return fmt.Errorf("options cannot be nil")
+		return nil, fmt.Errorf("options cannot be nil")
 	}
 
+	return withProfiling(opts.Profiling, func() (map[string][]byte, error) {
+		return generateFiles(opts)
+	})
+}
+
+// GenerateFromFS is GenerateFiles with the inputs read from fsys, for tools
+// generating from an embed.FS, an in-memory file system in tests or a remote
+// one: the input files, the TypesFile, policies and file: references are all
+// resolved in it, with slash-separated paths, and opts.FS is ignored. Nothing
+// is written; the returned files are keyed by their paths on the OS, as
+// derived from OutputFile.
+func GenerateFromFS(fsys fs.FS, opts *GenerateOptions) (map[string][]byte, error) {
+	if fsys == nil {
+		return nil, fmt.Errorf("file system cannot be nil")
+	}
+	if opts == nil {
+		return nil, fmt.Errorf("options cannot be nil")
+	}
+	o := *opts
+	o.FS = fsys
+	return GenerateFiles(&o)
+}
+
+// PartPath returns the path of the companion file for the named part of a
+// generated file, e.g. config/config_redis.go for config/config.go, or
+// config_fixtures_redis_test.go for config_fixtures_test.go. Parts of
+// the form "<package>/<part>" belong to a table split off with cfgx:package
+// and live in a subdirectory, e.g. config/dbconfig/config.go for "dbconfig/".
+func PartPath(outputFile, part string) string {
+	if pkg, rest, ok := strings.Cut(part, "/"); ok {
+		return PartPath(filepath.Join(filepath.Dir(outputFile), pkg, filepath.Base(outputFile)), rest)
+	}
+	if part == "" {
+		return outputFile
+	}
+	if base, ok := strings.CutSuffix(outputFile, "_test.go"); ok {
+		return base + "_" + part + "_test.go"
+	}
+	return strings.TrimSuffix(outputFile, ".go") + "_" + part + ".go"
+}
+
+// MainPath returns the path of the generated file a companion file belongs
+// to. It is the inverse of PartPath for parts without a package.
+func MainPath(partFile, part string) string {
+	if part == "" {
+		return partFile
+	}
+	if base, ok := strings.CutSuffix(partFile, "_"+part+"_test.go"); ok {
+		return base + "_test.go"
+	}
+	return strings.TrimSuffix(partFile, "_"+part+".go") + ".go"
+}
+
+// generateFiles implements GenerateFiles.
+func generateFiles(opts *GenerateOptions) (map[string][]byte, error) {
 	if opts.OutputFile == "" {
-		return fmt.Errorf("output file is required")
+		return nil, fmt.Errorf("output file is required")
+	}
+
+	inputs, err := inputFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.InputFile == "" {
+		o := *opts
+		o.InputFile = inputs[0]
+		opts = &o
 	}
 
 	// Read input file
-	data, err := os.ReadFile(opts.InputFile)
+	source, err := readInput(opts, opts.InputFile)
 	if err != nil {
-		return fmt.Errorf("failed to read input file %s: %w", opts.InputFile, err)
+		return nil, fmt.Errorf("failed to read input file %s: %w", opts.InputFile, err)
 	}
 
 	// Set default mode if not specified
@@ -106,67 +419,501 @@ func GenerateFromFile(opts *GenerateOptions) error {
 		mode = "static"
 	}
 
-	// Parse TOML to apply environment variable overrides if enabled.
-	// In getter mode, env vars are resolved at runtime via os.Getenv() calls
-	// in the generated code, so applying them at generation time would
-	// incorrectly bake runtime values (e.g. secrets) into the source as defaults.
-	var configData map[string]any
-	if err := toml.Unmarshal(data, &configData); err != nil {
-		return fmt.Errorf("failed to parse TOML: %w", err)
+	// Parse the input once; the generator consumes the decoded data directly.
+	start := time.Now()
+	configData, src, err := decodeInput(opts, opts.InputFile, source)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, err)
+	}
+
+	// Extract input directory for resolving file: references
+	inputDir := inputDirOf(opts)
+
+	typeHints, err := loadTypeHints(opts, inputDir)
+	if err != nil {
+		return nil, err
 	}
 
-	if opts.EnableEnv && mode != "getter" {
-		if err := envoverride.Apply(configData); err != nil {
-			return fmt.Errorf("failed to apply environment overrides: %w", err)
+	// Merge the remaining input files over the first
+	for _, file := range inputs[1:] {
+		if err := mergeInput(opts, configData, file, inputDir); err != nil {
+			return nil, err
 		}
+	}
+	parseTime := time.Since(start)
+
+	if err := validateEnvOverrideStrictness(opts.EnvOverrideStrictness); err != nil {
+		return nil, err
+	}
 
-		// Re-marshal to TOML for generation
-		// This ensures the overridden values are used
-		var buf bytes.Buffer
-		enc := toml.NewEncoder(&buf)
-		if err := enc.Encode(configData); err != nil {
-			return fmt.Errorf("failed to re-encode TOML: %w", err)
+	// Apply environment variable overrides if enabled.
+	// In getter mode, env vars are resolved at runtime via os.Getenv() calls
+	// in the generated code, and in loader mode by Load, so applying them at
+	// generation time would incorrectly bake runtime values (e.g. secrets)
+	// into the source as defaults.
+	var overridden map[string]string
+	if opts.EnableEnv && mode != "getter" && mode != "loader" {
+		// Resolve references and inheritance first so that the keys they
+		// produce can be overridden too
+		if err := generator.ResolveDefines(configData); err != nil {
+			return nil, locateError(opts.InputFile, source, fmt.Errorf("failed to generate code: %w", err))
+		}
+		if err := generator.ResolveExtends(configData); err != nil {
+			return nil, locateError(opts.InputFile, source, fmt.Errorf("failed to generate code: %w", err))
+		}
+		overridden = envoverride.Overrides(configData, envPrefixOf(opts))
+		if opts.EnvOverrideStrictness == "warn" {
+			for _, err := range envoverride.ApplyValid(configData, envPrefixOf(opts)) {
+				warnEnvOverride(opts, err)
+			}
+		} else if err := envoverride.Apply(configData, envPrefixOf(opts)); err != nil {
+			return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
 		}
-		data = buf.Bytes()
 	}
 
 	// Infer package name if not provided
+	// Injected code keeps the package clause of the file it is injected into
 	packageName := opts.PackageName
-	if packageName == "" {
+	if opts.Region != "" {
+		packageName = "config"
+	} else if packageName == "" {
 		packageName = pkgutil.InferName(opts.OutputFile)
 	}
 
-	// Extract input directory for resolving file: references
-	inputDir := filepath.Dir(opts.InputFile)
-
 	// Set default max file size if not specified
 	maxFileSize := opts.MaxFileSize
 	if maxFileSize == 0 {
 		maxFileSize = DefaultMaxFileSize
 	}
 
-	// Generate code
-	generated, err := GenerateWithOptions(data, packageName, opts.EnableEnv, inputDir, maxFileSize, mode)
+	rules, err := loadPolicies(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateStructTags(opts.StructTags); err != nil {
+		return nil, err
+	}
+	if err := validateEnvPrefix(opts.EnvPrefix); err != nil {
+		return nil, err
+	}
+	if err := validatePrecedence(opts.Precedence, mode); err != nil {
+		return nil, err
+	}
+	initialisms, err := expandInitialisms(opts.Initialisms)
 	if err != nil {
-		return fmt.Errorf("failed to generate code: %w", err)
+		return nil, err
+	}
+	if err := validateIntType(opts.IntType); err != nil {
+		return nil, err
+	}
+	if err := validateStyle(opts.Style, mode); err != nil {
+		return nil, err
+	}
+
+	// The record keeps the default prefix and integer type implicit
+	recordedEnvPrefix := opts.EnvPrefix
+	if recordedEnvPrefix == "CONFIG" {
+		recordedEnvPrefix = ""
+	}
+	recordedIntType := opts.IntType
+	if recordedIntType == "int64" {
+		recordedIntType = ""
+	}
+	recordedStyle := opts.Style
+	if recordedStyle == "vars" {
+		recordedStyle = ""
+	}
+
+	gen := generator.New(
+		generator.WithPackageName(packageName),
+		generator.WithEnvOverride(opts.EnableEnv),
+		generator.WithEnvPrefix(opts.EnvPrefix),
+		generator.WithInputDir(inputDir),
+		generator.WithMaxFileSize(maxFileSize),
+		generator.WithMode(mode),
+		generator.WithRecord(&record.Record{
+			Input:          recordInputPath(opts, opts.InputFile),
+			Overlays:       recordOverlays(opts, inputs[1:]),
+			Append:         opts.AppendArrays,
+			Mode:           mode,
+			EnableEnv:      opts.EnableEnv,
+			EnvPrefix:      recordedEnvPrefix,
+			MaxFileSize:    maxFileSize,
+			CRLF:           opts.CRLF,
+			Usage:          opts.TrackUsage,
+			EnvAtInit:      opts.EnvAtInit,
+			Summary:        opts.Summary,
+			EnvDocs:        opts.EmitEnvDocs,
+			PreserveOrder:  opts.PreserveOrder,
+			EmbedThreshold: opts.EmbedThreshold,
+			Duplicates:     opts.MergeDuplicates,
+			StructTags:     opts.StructTags,
+			IntType:        recordedIntType,
+			Style:          recordedStyle,
+			Initialisms:    opts.Initialisms,
+			Names:          opts.NameOverrides,
+			Precedence:     opts.Precedence,
+		}),
+		generator.WithCRLF(opts.CRLF),
+		generator.WithDynamicValues(opts.DynamicValues),
+		generator.WithUsageTracking(opts.TrackUsage),
+		generator.WithEnvAtInit(opts.EnvAtInit),
+		generator.WithSummary(opts.Summary),
+		generator.WithEnvDocs(opts.EmitEnvDocs),
+		generator.WithPreserveOrder(opts.PreserveOrder),
+		generator.WithOverridden(overridden),
+		generator.WithEmbedThreshold(opts.EmbedThreshold),
+		generator.WithStructTags(opts.StructTags),
+		generator.WithIntType(cmp.Or(opts.IntType, "int64")),
+		generator.WithStyle(opts.Style),
+		generator.WithInitialisms(initialisms),
+		generator.WithNameOverrides(opts.NameOverrides),
+		generator.WithPrecedence(opts.Precedence),
+		generator.WithStats(opts.Stats),
+		generator.WithFS(opts.FS),
+		generator.WithFileCache(opts.FileCache.generatorCache()),
+		generator.WithContext(opts.Context),
+		generator.WithSource(src),
+		generator.WithTypeHints(typeHints),
+		generator.WithPolicy(rules),
+	)
+
+	parts, err := gen.GenerateFilesFromMap(configData)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, fmt.Errorf("failed to generate code: %w", err))
+	}
+	if opts.Stats != nil {
+		opts.Stats.ParseTime += parseTime
 	}
+	opts.FileCache.referenced(gen.ReferencedFiles())
 
-	// Ensure output directory exists
-	outputDir := filepath.Dir(opts.OutputFile)
-	if outputDir != "." && outputDir != "" {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+	embedded := gen.EmbeddedFiles()
+	if opts.Region != "" {
+		return injectFiles(opts, parts, embedded)
+	}
+
+	files := make(map[string][]byte, len(parts)+len(embedded))
+	for part, generated := range parts {
+		files[PartPath(opts.OutputFile, part)] = generated
+	}
+	for name, content := range embedded {
+		files[filepath.Join(filepath.Dir(opts.OutputFile), filepath.FromSlash(name))] = content
+	}
+	return files, nil
+}
+
+// DefaultInitialisms are the initialisms Go code review conventions write in
+// upper case, for GenerateOptions.Initialisms.
+var DefaultInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "DSN", "EOF", "GUID", "HTML",
+	"HTTP", "HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC",
+	"SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID",
+	"UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// initialismPattern matches the values allowed in GenerateOptions.Initialisms.
+var initialismPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// expandInitialisms checks GenerateOptions.Initialisms and returns them with
+// "default" replaced by DefaultInitialisms.
+func expandInitialisms(initialisms []string) ([]string, error) {
+	var expanded []string
+	for _, initialism := range initialisms {
+		switch {
+		case initialism == "default":
+			expanded = append(expanded, DefaultInitialisms...)
+		case !initialismPattern.MatchString(initialism):
+			return nil, fmt.Errorf("invalid initialism %q", initialism)
+		default:
+			expanded = append(expanded, initialism)
+		}
+	}
+	return expanded, nil
+}
+
+// structTagKey matches the keys allowed in GenerateOptions.StructTags.
+var structTagKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateStructTags checks the keys of GenerateOptions.StructTags.
+func validateStructTags(tags []string) error {
+	for _, tag := range tags {
+		if !structTagKey.MatchString(tag) {
+			return fmt.Errorf("invalid struct tag %q", tag)
 		}
 	}
+	return nil
+}
+
+// validateIntType checks GenerateOptions.IntType.
+func validateIntType(intType string) error {
+	if intType != "" && intType != "int64" && intType != "int" {
+		return fmt.Errorf("invalid int type %q: must be 'int64' or 'int'", intType)
+	}
+	return nil
+}
+
+// validateStyle checks GenerateOptions.Style for the mode.
+func validateStyle(style, mode string) error {
+	switch style {
+	case "", "vars":
+	case "compact":
+		if mode != "static" {
+			return fmt.Errorf("compact style requires static mode, not %s mode", mode)
+		}
+	default:
+		return fmt.Errorf("invalid style %q: must be 'vars' or 'compact'", style)
+	}
+	return nil
+}
+
+// envPrefixPattern matches the values allowed in GenerateOptions.EnvPrefix.
+var envPrefixPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnvPrefix checks GenerateOptions.EnvPrefix.
+func validateEnvPrefix(prefix string) error {
+	if prefix != "" && !envPrefixPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid env prefix %q", prefix)
+	}
+	return nil
+}
+
+// validateEnvOverrideStrictness checks GenerateOptions.EnvOverrideStrictness.
+func validateEnvOverrideStrictness(strictness string) error {
+	if strictness != "" && strictness != "error" && strictness != "warn" {
+		return fmt.Errorf("invalid env override strictness %q: must be 'error' or 'warn'", strictness)
+	}
+	return nil
+}
+
+// warnEnvOverride logs an environment variable override ignored with
+// EnvOverrideStrictness "warn".
+func warnEnvOverride(opts *GenerateOptions, err error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	var envErr *envoverride.Error
+	if errors.As(err, &envErr) {
+		logger.Warn("ignoring invalid environment variable override", "env", envErr.EnvVar, "key", envErr.Key, "err", envErr.Err)
+		return
+	}
+	logger.Warn("ignoring invalid environment variable override", "err", err)
+}
+
+// envPrefixOf returns the environment variable prefix of opts.
+func envPrefixOf(opts *GenerateOptions) string {
+	if opts.EnvPrefix == "" {
+		return "CONFIG"
+	}
+	return opts.EnvPrefix
+}
+
+// validatePrecedence checks GenerateOptions.Precedence for the mode.
+func validatePrecedence(layers []string, mode string) error {
+	if len(layers) == 0 {
+		return nil
+	}
+	if mode != "loader" {
+		return fmt.Errorf("precedence requires loader mode, not %s mode", mode)
+	}
+	seen := make(map[string]bool)
+	for _, layer := range layers {
+		if layer != "file" && layer != "env" && layer != "flag" {
+			return fmt.Errorf("invalid precedence layer %q: must be 'file', 'env' or 'flag'", layer)
+		}
+		if seen[layer] {
+			return fmt.Errorf("precedence layer %q is listed twice", layer)
+		}
+		seen[layer] = true
+	}
+	if !seen["file"] {
+		return fmt.Errorf("precedence must include the file layer")
+	}
+	return nil
+}
+
+// inputDirOf returns the directory of the input file of opts, from which
+// file: references resolve.
+func inputDirOf(opts *GenerateOptions) string {
+	if opts.FS != nil {
+		return path.Dir(opts.InputFile)
+	}
+	return filepath.Dir(opts.InputFile)
+}
+
+// mergeInput reads and decodes the input file named file and merges it into
+// data, rebasing its file: references onto baseDir, the first file's directory.
+func mergeInput(opts *GenerateOptions, data map[string]any, file, baseDir string) error {
+	source, err := readInput(opts, file)
+	if err != nil {
+		return fmt.Errorf("failed to read input file %s: %w", file, err)
+	}
+	overlay, _, err := decodeInput(opts, file, source)
+	if err != nil {
+		return locateError(file, source, err)
+	}
 
-	// Write output file
-	if err := os.WriteFile(opts.OutputFile, generated, 0644); err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
+	dir := filepath.Dir(file)
+	if opts.FS != nil {
+		dir = path.Dir(file)
+	}
+	if err := rebaseFileRefs(overlay, dir, baseDir); err != nil {
+		return err
 	}
 
+	mergeTables(data, overlay, opts.AppendArrays)
 	return nil
 }
 
+// decodeInput parses the input file named file in the format of opts and
+// returns the decoded data along with the scanned source for annotations
+// and positions. An empty format is detected from the file extension.
+func decodeInput(opts *GenerateOptions, file string, source []byte) (map[string]any, *tomlsrc.Source, error) {
+	format := opts.Format
+	if format == "" {
+		format = "toml"
+		if strings.EqualFold(filepath.Ext(file), ".json") {
+			format = "json"
+		}
+	}
+
+	switch format {
+	case "toml":
+		if opts.MergeDuplicates != "" {
+			data, err := decodeTOMLStream(source, opts.MergeDuplicates)
+			if err != nil {
+				return nil, nil, err
+			}
+			return data, tomlsrc.Scan(source), nil
+		}
+		var data map[string]any
+		if err := toml.Unmarshal(source, &data); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse TOML: %w", err)
+		}
+		return data, tomlsrc.Scan(source), nil
+	case "json":
+		data, err := decodeJSON(source)
+		if err != nil {
+			return nil, nil, err
+		}
+		return data, tomlsrc.Scan(nil), nil
+	}
+	return nil, nil, fmt.Errorf("unknown input format %q: must be 'toml' or 'json'", format)
+}
+
+// decodeTOMLStream parses a stream of concatenated TOML documents and merges
+// them in order, as described for GenerateOptions.MergeDuplicates.
+func decodeTOMLStream(source []byte, duplicates string) (map[string]any, error) {
+	if duplicates != "last" && duplicates != "error" {
+		return nil, fmt.Errorf("invalid duplicates mode %q: must be 'last' or 'error'", duplicates)
+	}
+
+	data := make(map[string]any)
+	for _, fragment := range tomlsrc.Split(source) {
+		var doc map[string]any
+		if err := toml.Unmarshal(fragment.Source, &doc); err != nil {
+			// Report positions in the whole stream
+			var parseErr toml.ParseError
+			if errors.As(err, &parseErr) {
+				parseErr.Position.Line += fragment.Line - 1
+				err = parseErr
+			}
+			return nil, fmt.Errorf("failed to parse TOML: %w", err)
+		}
+		if err := mergeDocument(data, doc, "", duplicates == "error"); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// readInput reads the named input file from opts.Source, opts.FS or the OS
+// file system.
+func readInput(opts *GenerateOptions, file string) ([]byte, error) {
+	if opts.Source != nil && file == opts.InputFile {
+		return opts.Source, nil
+	}
+	if opts.FS != nil {
+		return fs.ReadFile(opts.FS, file)
+	}
+	return os.ReadFile(file)
+}
+
+// loadTypeHints reads the TypesFile in dir, if there is one.
+func loadTypeHints(opts *GenerateOptions, dir string) (map[string]string, error) {
+	file := filepath.Join(dir, TypesFile)
+	if opts.FS != nil {
+		file = path.Join(dir, TypesFile)
+	}
+	data, err := readInput(opts, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read type hints %s: %w", file, err)
+	}
+	hints, err := generator.ParseTypeHints(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid type hints %s: %w", file, err)
+	}
+	return hints, nil
+}
+
+// loadPolicies reads and compiles the policy files of opts. It returns nil if there are none.
+func loadPolicies(opts *GenerateOptions) (*policy.Policy, error) {
+	var rules *policy.Policy
+	for _, file := range opts.Policies {
+		data, err := readInput(opts, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy file %s: %w", file, err)
+		}
+		p, err := policy.Parse(file, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy: %w", err)
+		}
+		if rules == nil {
+			rules = p
+		} else {
+			rules.Merge(p)
+		}
+	}
+	return rules, nil
+}
+
+// recordOverlays returns the record paths of the input files merged over the first.
+func recordOverlays(opts *GenerateOptions, files []string) []string {
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, recordInputPath(opts, file))
+	}
+	return paths
+}
+
+// recordInputPath returns an input path relative to the output file's directory,
+// so the record stays valid when the repository is checked out elsewhere. Inputs
+// read from an fs.FS are recorded as they are.
+func recordInputPath(opts *GenerateOptions, inputFile string) string {
+	if opts.FS != nil {
+		return inputFile
+	}
+	outputFile := opts.OutputFile
+
+	absInput, err := filepath.Abs(inputFile)
+	if err != nil {
+		return filepath.ToSlash(inputFile)
+	}
+	absOutputDir, err := filepath.Abs(filepath.Dir(outputFile))
+	if err != nil {
+		return filepath.ToSlash(inputFile)
+	}
+	rel, err := filepath.Rel(absOutputDir, absInput)
+	if err != nil {
+		return filepath.ToSlash(absInput)
+	}
+	return filepath.ToSlash(rel)
+}
+
 // Generate generates Go code from TOML data with the specified package name.
 // This is useful for programmatic usage where you have the TOML data in memory.
 //
@@ -193,7 +940,7 @@ func Generate(tomlData []byte, packageName string, enableEnv bool) ([]byte, erro
 //   - enableEnv: Whether to enable environment variable override markers in generated code
 //   - inputDir: Directory to resolve file: references from (empty string to disable)
 //   - maxFileSize: Maximum file size in bytes for file: references (0 for default 1MB)
-//   - mode: Generation mode ("static" or "getter")
+//   - mode: Generation mode ("static", "getter", "hybrid" or "loader")
 //
 // Returns the generated Go code as bytes, or an error if generation fails.
 func GenerateWithOptions(tomlData []byte, packageName string, enableEnv bool, inputDir string, maxFileSize int64, mode string) ([]byte, error) {
@@ -219,3 +966,20 @@ func GenerateWithOptions(tomlData []byte, packageName string, enableEnv bool, in
 
 	return gen.Generate(tomlData)
 }
+
+// SamePath reports whether two paths refer to the same file, following
+// symlinks and hard links. Paths that do not exist yet are compared by
+// their cleaned absolute form.
+func SamePath(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	ia, errA := os.Stat(a)
+	ib, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(ia, ib)
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
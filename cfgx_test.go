@@ -2,13 +2,19 @@ package cfgx
 
 import (
 	"bytes"
+	"context"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/BurntSushi/toml"
 	"github.com/gomantics/cfgx/internal/envoverride"
+	"github.com/gomantics/cfgx/internal/record"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,7 +59,7 @@ max_conns = 10
 	err := toml.Unmarshal(tomlData, &data)
 	require.NoError(t, err)
 
-	err = envoverride.Apply(data)
+	err = envoverride.Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	var buf bytes.Buffer
@@ -72,6 +78,34 @@ max_conns = 10
 	require.NotContains(t, outputStr, `":8080"`, "original server.addr should have been overridden")
 }
 
+func TestGenerateBytes_EnvOverridesWithoutReencoding(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.toml")
+	require.NoError(t, err)
+
+	for _, fixture := range fixtures {
+		opts := &GenerateOptions{InputFile: fixture, OutputFile: filepath.Join(t.TempDir(), "config.go"), PackageName: "config"}
+		want, err := GenerateBytes(opts)
+		require.NoError(t, err, fixture)
+
+		// With no variables set, applying overrides must not change the output
+		opts.EnableEnv = true
+		got, err := GenerateBytes(opts)
+		require.NoError(t, err, fixture)
+		require.Equal(t,
+			strings.Replace(string(want), " env=false ", " env=true ", 1), string(got), fixture)
+	}
+
+	t.Setenv("CONFIG_SERVER_B_PORT", "9090")
+	output, err := GenerateBytes(&GenerateOptions{
+		InputFile:   "testdata/extends.toml",
+		OutputFile:  filepath.Join(t.TempDir(), "config.go"),
+		PackageName: "config",
+		EnableEnv:   true,
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port:        9090,", "inherited keys should be overridable")
+}
+
 func TestGenerateFromFile_GetterModeIgnoresEnvOverrides(t *testing.T) {
 	tmpDir := t.TempDir()
 	inputFile := filepath.Join(tmpDir, "config.toml")
@@ -119,6 +153,144 @@ api_key = "set-from-env"
 		"getter mode should still generate runtime env var lookups")
 }
 
+func TestGenerateFromFile_LoaderModeIgnoresEnvOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[secrets]\napi_key = \"set-from-env\"\n"), 0644))
+	t.Setenv("CONFIG_SECRETS_API_KEY", "sk-real-secret-key-12345")
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, PackageName: "config", EnableEnv: true, Mode: "loader"})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `"set-from-env"`)
+	require.NotContains(t, string(output), "sk-real-secret-key-12345")
+
+	// Load applies the variable at runtime instead
+	loader, err := os.ReadFile(filepath.Join(tmpDir, "config_loader.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(loader), `applyConfigLayer(c, envConfigLayer("CONFIG")`)
+}
+
+func TestGenerateBytes_EnvOverrideStrictness(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\nport = 8080\nhost = \"localhost\"\n"), 0644))
+	t.Setenv("CONFIG_SERVER_PORT", "eighty")
+	t.Setenv("CONFIG_SERVER_HOST", "example.com")
+
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", EnableEnv: true}
+	_, err := GenerateBytes(opts)
+	require.ErrorContains(t, err, "invalid value for CONFIG_SERVER_PORT (server.port)")
+
+	var logs bytes.Buffer
+	opts.EnvOverrideStrictness = "warn"
+	opts.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port: 8080,")
+	require.Contains(t, string(output), `Host: "example.com",`)
+	require.Contains(t, logs.String(), `level=WARN msg="ignoring invalid environment variable override" env=CONFIG_SERVER_PORT key=server.port`)
+
+	opts.EnvOverrideStrictness = "lenient"
+	_, err = GenerateBytes(opts)
+	require.EqualError(t, err, `invalid env override strictness "lenient": must be 'error' or 'warn'`)
+}
+
+func TestGenerateBytes_EnvOverrideNotes(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("name = \"api\"\n\n[server]\naddr = \":8080\"\nhosts = [\"a\"]\nport = 8080\n"), 0644))
+	t.Setenv("CONFIG_NAME", "web")
+	t.Setenv("CONFIG_SERVER_ADDR", ":9090")
+	t.Setenv("CONFIG_SERVER_HOSTS", "b,c")
+
+	output, err := GenerateBytes(&GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", EnableEnv: true})
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Name   string = \"web\" // overridden by CONFIG_NAME at generation\n")
+	require.Contains(t, outputStr, "\t\tAddr:  \":9090\",            // overridden by CONFIG_SERVER_ADDR at generation\n")
+	require.Contains(t, outputStr, "\t\tHosts: []string{\"b\", \"c\"}, // overridden by CONFIG_SERVER_HOSTS at generation\n")
+	require.Contains(t, outputStr, "\t\tPort:  8080,\n")
+
+	output, err = GenerateBytes(&GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config"})
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "overridden by")
+}
+
+func TestGenerateBytes_Initialisms(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[database]\ndsn = \"postgres://\"\nsku_id = 1\n"), 0644))
+
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Initialisms: []string{"default", "SKU"}}
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "\tDSN   string\n\tSKUID int64\n")
+	require.Contains(t, string(output), " initialisms=default,SKU")
+
+	opts.Initialisms = []string{"S-K-U"}
+	_, err = GenerateBytes(opts)
+	require.EqualError(t, err, `invalid initialism "S-K-U"`)
+}
+
+func TestGenerateBytes_IntType(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\nmax_conns = 100\nports = [80, 443]\nmax_body = 1048576 # cfgx:type=int64\n"), 0644))
+
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", IntType: "int"}
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "\tMaxBody  int64\n\tMaxConns int\n\tPorts    []int\n")
+	require.Contains(t, string(output), " int-type=int")
+
+	opts.IntType = "int32"
+	_, err = GenerateBytes(opts)
+	require.EqualError(t, err, `invalid int type "int32": must be 'int64' or 'int'`)
+}
+
+func TestGenerateBytes_Style(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("name = \"api\"\n\n[server]\nport = 8080\n"), 0644))
+
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Style: "compact"}
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func Default() Config {")
+	require.NotContains(t, string(output), "var (")
+	require.Contains(t, string(output), " style=compact")
+
+	opts.Mode = "getter"
+	_, err = GenerateBytes(opts)
+	require.EqualError(t, err, "compact style requires static mode, not getter mode")
+
+	opts.Mode, opts.Style = "", "tiny"
+	_, err = GenerateBytes(opts)
+	require.EqualError(t, err, `invalid style "tiny": must be 'vars' or 'compact'`)
+}
+
+func TestGenerateBytes_SourceAndContext(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("port = 8080\n"), 0644))
+
+	// Unsaved contents are generated instead of the file
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Source: []byte("port = 9090\n")}
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port int64 = 9090")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts.Context = ctx
+	_, err = GenerateBytes(opts)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
 func TestGenerateFromFile(t *testing.T) {
 	// Create a temporary TOML file
 	tmpDir := t.TempDir()
@@ -263,6 +435,190 @@ addr = ":8080"
 	require.NoError(t, err, "generated code does not compile: %s", cmdOutput)
 }
 
+func TestGenerateFromFile_MergeDuplicates(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	tomlData := []byte(`[server]
+host = "a"
+port = 80
+
+[[workers]]
+id = 1
+
+[server]
+port = 81
+timeout = "5s"
+
+[[workers]]
+id = 2
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile})
+	require.Error(t, err, "duplicate tables are rejected by default")
+
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, MergeDuplicates: "last"})
+	require.NoError(t, err)
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Host:    \"a\",")
+	require.Contains(t, outputStr, "Port:    81,")
+	require.Contains(t, outputStr, "Id: 2,")
+	require.Contains(t, outputStr, " duplicates=last")
+
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, MergeDuplicates: "error"})
+	var cfgErr *Error
+	require.ErrorAs(t, err, &cfgErr)
+	require.Equal(t, "server.port", cfgErr.Key)
+	require.Equal(t, 3, cfgErr.Line, "the error points at the first definition")
+
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\nport = 80\n[server]\nport = \n"), 0644))
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, MergeDuplicates: "last"})
+	require.ErrorAs(t, err, &cfgErr)
+	require.Equal(t, 4, cfgErr.Line, "parse errors point into the whole stream")
+}
+
+func TestGenerateFromFile_StructTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\nmax_conns = 10\n"), 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, StructTags: []string{"json", "mapstructure"}})
+	require.NoError(t, err)
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "MaxConns int64 `json:\"max_conns\" mapstructure:\"max_conns\"`")
+	require.Contains(t, string(output), " tags=json,mapstructure")
+
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, StructTags: []string{"json:x"}})
+	require.ErrorContains(t, err, `invalid struct tag "json:x"`)
+}
+
+func TestGenerateFromFile_EnvPrefix(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\naddr = \":8080\"\nport = 80\n"), 0644))
+
+	t.Setenv("CONFIG_SERVER_PORT", "81")
+	t.Setenv("MYAPP_SERVER_PORT", "82")
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, EnableEnv: true, EnvPrefix: "MYAPP", Mode: "hybrid"})
+	require.NoError(t, err)
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port: 82,")
+	require.Contains(t, string(output), `os.Getenv("MYAPP_SERVER_ADDR")`)
+	require.Contains(t, string(output), " env-prefix=MYAPP")
+
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, EnvPrefix: "my-app"})
+	require.ErrorContains(t, err, `invalid env prefix "my-app"`)
+}
+
+func TestGenerateFromFile_Precedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\naddr = \":8080\"\n"), 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, Mode: "loader", Precedence: []string{"file", "env", "flag"}})
+	require.NoError(t, err)
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), " precedence=file,env,flag")
+	loader, err := os.ReadFile(filepath.Join(tmpDir, "config", "config_loader.go"))
+	require.NoError(t, err)
+	require.Contains(t, string(loader), "func DefineFlags(fs *flag.FlagSet) {")
+
+	tests := []struct {
+		mode   string
+		layers []string
+		want   string
+	}{
+		{"static", []string{"file", "env"}, "precedence requires loader mode, not static mode"},
+		{"loader", []string{"file", "remote"}, `invalid precedence layer "remote"`},
+		{"loader", []string{"file", "env", "file"}, `precedence layer "file" is listed twice`},
+		{"loader", []string{"env", "flag"}, "precedence must include the file layer"},
+	}
+	for _, tt := range tests {
+		err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, Mode: tt.mode, Precedence: tt.layers})
+		require.ErrorContains(t, err, tt.want, tt.layers)
+	}
+}
+
+func TestGenerateFromFile_TypeHints(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	tomlData := []byte(`
+[billing]
+sku = "1h"
+prefix = "file:"
+timeout = "30s"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, TypesFile), []byte("[billing]\nsku = \"string\"\nprefix = \"string\"\n"), 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Sku     string")
+	require.Contains(t, outputStr, "Prefix  string")
+	require.Contains(t, outputStr, "Timeout time.Duration")
+	require.Contains(t, outputStr, "Sku:     \"1h\",")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, TypesFile), []byte("[billing]\nsku = \"bogus\"\n"), 0644))
+	err = GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile})
+	require.ErrorContains(t, err, "invalid type hints")
+	require.ErrorContains(t, err, "billing.sku: unknown cfgx:type \"bogus\"")
+}
+
+func TestGenerateFromFile_EmbedThreshold(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "certs"), 0755))
+	bundle := bytes.Repeat([]byte("-----BEGIN CERTIFICATE-----\n"), 100)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "certs", "bundle.pem"), bundle, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "certs", "key.pem"), []byte("small"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module embedtest\n\ngo 1.22\n"), 0644))
+
+	tomlData := []byte(`
+[tls]
+bundle = "file:certs/bundle.pem"
+key = "file:certs/key.pem"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, EmbedThreshold: 1024})
+	require.NoError(t, err)
+
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Bundle: embeddedBundlePem,")
+	require.Contains(t, outputStr, "//go:embed cfgx_embed/bundle.pem\nvar embeddedBundlePem []byte")
+	require.Contains(t, outputStr, "Key: []byte{")
+	require.Contains(t, outputStr, "embed-threshold=1024")
+
+	copied, err := os.ReadFile(filepath.Join(tmpDir, "config", "cfgx_embed", "bundle.pem"))
+	require.NoError(t, err, "the embedded file should be copied next to the output")
+	require.Equal(t, bundle, copied)
+
+	cmd := exec.Command("go", "vet", "./config")
+	cmd.Dir = tmpDir
+	cmdOutput, err := cmd.CombinedOutput()
+	require.NoError(t, err, "generated code does not compile: %s", cmdOutput)
+}
+
 func TestGenerateFromFile_FileNotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	inputFile := filepath.Join(tmpDir, "config.toml")
@@ -315,3 +671,484 @@ content = "file:large.txt"
 	require.Error(t, err, "should error on file size exceeded")
 	require.Contains(t, err.Error(), "exceeds max size", "error should mention size limit")
 }
+
+func TestGenerateFromFile_OutputIsInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	linkFile := filepath.Join(tmpDir, "link.toml")
+
+	tomlData := []byte(`
+[app]
+name = "test"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+	require.NoError(t, os.Link(inputFile, linkFile))
+
+	for _, out := range []string{inputFile, linkFile} {
+		err := GenerateFromFile(&GenerateOptions{
+			InputFile:   inputFile,
+			OutputFile:  out,
+			PackageName: "config",
+		})
+		require.Error(t, err, "should refuse to overwrite the input via %s", out)
+
+		got, err := os.ReadFile(inputFile)
+		require.NoError(t, err)
+		require.Equal(t, tomlData, got, "input should be left untouched")
+	}
+}
+
+func TestGenerateFromFile_CompanionFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	tomlData := []byte(`
+# cfgx:helper=redis
+[cache]
+addr = "localhost:6379"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile})
+	require.NoError(t, err)
+
+	companion, err := os.ReadFile(filepath.Join(tmpDir, "config", "config_redis.go"))
+	require.NoError(t, err, "redis helper should be written next to the output")
+
+	rec, ok, err := record.Parse(companion)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "redis", rec.Part)
+	require.Equal(t, "../config.toml", rec.Input)
+}
+
+func TestGenerateFromFile_PackageSections(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	tomlData := []byte(`
+[app]
+name = "svc"
+
+# cfgx:package=dbconfig
+# cfgx:helper=redis
+[cache]
+addr = "localhost:6379"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+
+	err := GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile})
+	require.NoError(t, err)
+
+	section, err := os.ReadFile(filepath.Join(tmpDir, "config", "dbconfig", "config.go"))
+	require.NoError(t, err, "the section should be written to its package directory")
+	require.Contains(t, string(section), "package dbconfig")
+
+	companion, err := os.ReadFile(filepath.Join(tmpDir, "config", "dbconfig", "config_redis.go"))
+	require.NoError(t, err, "the section's companion files should be written next to it")
+
+	rec, ok, err := record.Parse(companion)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "dbconfig", rec.Section)
+	require.Equal(t, "redis", rec.Part)
+	require.Equal(t, "../../config.toml", rec.Input)
+}
+
+func TestGenerateBytes_RecordsInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config", "config.go")
+
+	err := os.WriteFile(inputFile, []byte("[server]\naddr = \":8080\"\n"), 0644)
+	require.NoError(t, err)
+
+	opts := &GenerateOptions{
+		InputFile:  inputFile,
+		OutputFile: outputFile,
+		Mode:       "getter",
+	}
+
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err, "GenerateBytes() should not error")
+
+	rec, ok, err := record.Parse(output)
+	require.NoError(t, err)
+	require.True(t, ok, "generated code should carry a source record")
+	require.Equal(t, "../config.toml", rec.Input)
+	require.Equal(t, "getter", rec.Mode)
+	require.Equal(t, int64(DefaultMaxFileSize), rec.MaxFileSize)
+
+	_, err = os.Stat(outputFile)
+	require.True(t, os.IsNotExist(err), "GenerateBytes() must not write the output file")
+}
+
+func TestGenerateBytes_DynamicValues(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "fixtures.toml")
+	err := os.WriteFile(inputFile, []byte("[seed]\nid = \"uuid:v4\"\n"), 0644)
+	require.NoError(t, err)
+
+	opts := &GenerateOptions{
+		InputFile:   inputFile,
+		OutputFile:  filepath.Join(tmpDir, "config.go"),
+		PackageName: "config",
+	}
+
+	// Without the option, the value is generated as-is
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `"uuid:v4"`)
+	rec, _, err := record.Parse(output)
+	require.NoError(t, err)
+	require.False(t, rec.Dynamic)
+
+	opts.DynamicValues = true
+	output, err = GenerateBytes(opts)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), `"uuid:v4"`)
+	rec, _, err = record.Parse(output)
+	require.NoError(t, err)
+	require.True(t, rec.Dynamic, "the record should flag generated values")
+}
+
+func TestGenerateFromFile_ErrorPosition(t *testing.T) {
+	tests := []struct {
+		name       string
+		toml       string
+		wantLine   int
+		wantColumn int
+		wantKey    string
+	}{
+		{
+			name:       "syntax error",
+			toml:       "[app]\nname = = \"x\"\n",
+			wantLine:   2,
+			wantColumn: 8,
+		},
+		{
+			name:       "missing file reference",
+			toml:       "[app]\nname = \"x\"\n  content = \"file:missing.txt\"\n",
+			wantLine:   3,
+			wantColumn: 3,
+			wantKey:    "app.content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			inputFile := filepath.Join(tmpDir, "config.toml")
+			require.NoError(t, os.WriteFile(inputFile, []byte(tt.toml), 0644))
+
+			err := GenerateFromFile(&GenerateOptions{
+				InputFile:  inputFile,
+				OutputFile: filepath.Join(tmpDir, "config.go"),
+			})
+			require.Error(t, err)
+
+			var cfgErr *Error
+			require.ErrorAs(t, err, &cfgErr)
+			require.Equal(t, inputFile, cfgErr.File)
+			require.Equal(t, tt.wantLine, cfgErr.Line)
+			require.Equal(t, tt.wantColumn, cfgErr.Column)
+			require.Equal(t, tt.wantKey, cfgErr.Key)
+		})
+	}
+}
+
+func TestGenerateBytes_Profiling(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\naddr = \":8080\"\n"), 0644))
+
+	var cpu, mem, trace bytes.Buffer
+	_, err := GenerateBytes(&GenerateOptions{
+		InputFile:   inputFile,
+		OutputFile:  filepath.Join(tmpDir, "config.go"),
+		PackageName: "config",
+		Profiling:   &Profiling{CPU: &cpu, Mem: &mem, Trace: &trace},
+	})
+	require.NoError(t, err, "GenerateBytes() should not error")
+
+	require.NotZero(t, cpu.Len(), "CPU profile should be written")
+	require.NotZero(t, mem.Len(), "memory profile should be written")
+	require.NotZero(t, trace.Len(), "trace should be written")
+}
+
+func TestPartPath(t *testing.T) {
+	tests := []struct {
+		output string
+		part   string
+		want   string
+	}{
+		{"config/config.go", "", "config/config.go"},
+		{"config/config.go", "redis", "config/config_redis.go"},
+		{"store/config_fixtures_test.go", "redis", "store/config_fixtures_redis_test.go"},
+		{"config/config.go", "dbconfig/", filepath.Join("config", "dbconfig", "config.go")},
+		{"config/config.go", "dbconfig/redis", filepath.Join("config", "dbconfig", "config_redis.go")},
+	}
+
+	for _, tt := range tests {
+		got := PartPath(tt.output, tt.part)
+		require.Equal(t, tt.want, got, "PartPath(%q, %q)", tt.output, tt.part)
+
+		if !strings.Contains(tt.part, "/") {
+			require.Equal(t, tt.output, MainPath(got, tt.part), "MainPath(%q, %q)", got, tt.part)
+		}
+	}
+}
+
+func TestGenerateFromFile_Region(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	mainFile := filepath.Join(tmpDir, "main.go")
+
+	require.NoError(t, os.WriteFile(inputFile, []byte("[server]\naddr = \":8080\"\ntimeout = \"5s\"\n"), 0644))
+	require.NoError(t, os.WriteFile(mainFile, []byte(`package main
+
+import "fmt"
+
+// cfgx:begin config
+type stale struct{}
+// cfgx:end
+
+func main() {
+	fmt.Println(Server.Addr, Server.Timeout)
+}
+`), 0644))
+
+	opts := &GenerateOptions{InputFile: inputFile, OutputFile: mainFile, Region: "config"}
+	require.NoError(t, GenerateFromFile(opts))
+
+	output, err := os.ReadFile(mainFile)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "package main\n\nimport (\n\t\"fmt\"\n\t\"time\"\n)")
+	require.Contains(t, outputStr, "// cfgx:begin config\n// Code in this region is generated by cfgx. DO NOT EDIT.\n\ntype ServerConfig struct {")
+	require.Contains(t, outputStr, "// cfgx:end\n\nfunc main() {")
+	require.NotContains(t, outputStr, "stale")
+	require.NotContains(t, outputStr, "Code generated", "the file should not be marked as generated")
+
+	// Regenerating is stable
+	require.NoError(t, GenerateFromFile(opts))
+	again, err := os.ReadFile(mainFile)
+	require.NoError(t, err)
+	require.Equal(t, string(output), string(again))
+}
+
+func TestInjectRegion_Errors(t *testing.T) {
+	generated := []byte("package config\n\nvar X = 1\n")
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"missing region", "package main\n", `region "config" not found`},
+		{"unterminated", "package main\n\n// cfgx:begin config\n", `region "config" has no "// cfgx:end" line`},
+		{"duplicate", "package main\n\n// cfgx:begin config\n// cfgx:end\n// cfgx:begin config\n// cfgx:end\n", "defined more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := injectRegion([]byte(tt.host), generated, "config")
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerateBytes_FS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"configs/app.toml":         {Data: []byte("[tls]\ncert = \"file:certs/server.crt\"\n")},
+		"configs/certs/server.crt": {Data: []byte("CERT")},
+	}
+
+	opts := &GenerateOptions{
+		InputFile:   "configs/app.toml",
+		OutputFile:  "config/config.go",
+		PackageName: "config",
+		FS:          fsys,
+	}
+	output, err := GenerateBytes(opts)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Cert []byte")
+
+	rec, ok, err := record.Parse(output)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "configs/app.toml", rec.Input)
+
+	fsys["configs/app.toml"] = &fstest.MapFile{Data: []byte("[tls]\ncert = \"file:../../etc/passwd\"\n")}
+	_, err = GenerateBytes(opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "outside the file system")
+}
+
+func TestGenerateFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.toml":       {Data: []byte("[server]\nport = 8080\nname = \"file:name.txt\"\nsku = \"1h\"\n")},
+		"prod.toml":       {Data: []byte("[server]\nport = 443\n")},
+		"name.txt":        {Data: []byte("api")},
+		"cfgx.types.toml": {Data: []byte("[server]\nsku = \"string\"\n")},
+		"policy.toml":     {Data: []byte("[[rule]]\nname = \"tls\"\nexpr = 'server.port == 443'\n")},
+	}
+
+	files, err := GenerateFromFS(fsys, &GenerateOptions{
+		InputFiles:  []string{"base.toml", "prod.toml"},
+		OutputFile:  "config/config.go",
+		PackageName: "config",
+		Policies:    []string{"policy.toml"},
+	})
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	output := string(files["config/config.go"])
+	require.Contains(t, output, "Name: []byte{")
+	require.Contains(t, output, "Port: 443")
+	require.Contains(t, output, "Sku:  \"1h\"")
+
+	_, err = GenerateFromFS(fsys, &GenerateOptions{InputFile: "missing.toml", OutputFile: "config.go"})
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestGenerateBytes_InputFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	base := filepath.Join(tmpDir, "base.toml")
+	prod := filepath.Join(tmpDir, "overrides", "prod.toml")
+	require.NoError(t, os.MkdirAll(filepath.Dir(prod), 0755))
+
+	require.NoError(t, os.WriteFile(base, []byte(`[server]
+addr = "localhost"
+port = 8080
+tags = ["a", "b"]
+`), 0644))
+	require.NoError(t, os.WriteFile(prod, []byte(`[server]
+port = 443
+tags = ["c"]
+cert = "file:prod.pem"
+
+[metrics]
+enabled = true
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "overrides", "prod.pem"), []byte("PROD"), 0644))
+
+	output := filepath.Join(tmpDir, "config.go")
+	generated, err := GenerateBytes(&GenerateOptions{InputFiles: []string{base, prod}, OutputFile: output, PackageName: "config", Mode: "getter"})
+	require.NoError(t, err)
+	got := string(generated)
+	require.Contains(t, got, ` overlay="overrides/prod.toml"`)
+	require.Contains(t, got, `return "localhost"`, "keys only in the base are kept")
+	require.Contains(t, got, `return 443`, "later files win")
+	require.Contains(t, got, `return []string{"c"}`, "arrays are replaced")
+	require.Contains(t, got, "0x50, 0x52, 0x4f, 0x44,", "file: references resolve from their own file")
+	require.Contains(t, got, "func (metricsConfig) Enabled() bool", "new tables are added")
+
+	generated, err = GenerateBytes(&GenerateOptions{InputFiles: []string{base, prod}, AppendArrays: true, OutputFile: output, PackageName: "config", Mode: "getter"})
+	require.NoError(t, err)
+	require.Contains(t, string(generated), `return []string{"a", "b", "c"}`)
+	require.Contains(t, string(generated), ` append=true`)
+
+	// InputFile must name the first input file when both are set
+	_, err = GenerateBytes(&GenerateOptions{InputFile: prod, InputFiles: []string{base, prod}, OutputFile: output, PackageName: "config"})
+	require.ErrorContains(t, err, "must be the first of the input files")
+
+	// Errors in later files point into them
+	require.NoError(t, os.WriteFile(prod, []byte("[server\n"), 0644))
+	_, err = GenerateBytes(&GenerateOptions{InputFiles: []string{base, prod}, OutputFile: output, PackageName: "config"})
+	var cfgErr *Error
+	require.ErrorAs(t, err, &cfgErr)
+	require.Equal(t, prod, cfgErr.File)
+}
+
+func TestGenerateBytes_Policies(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "config.toml")
+	policyFile := filepath.Join(tmpDir, "policy.toml")
+	require.NoError(t, os.WriteFile(input, []byte(`env = "prod"
+pool_size = 8
+workers = "expr: pool_size * 2"
+
+[server]
+addr = "0.0.0.0:8080"
+`), 0644))
+	require.NoError(t, os.WriteFile(policyFile, []byte(`[[rule]]
+name = "prod-pool"
+expr = 'env != "prod" || pool_size >= 10 && workers >= 20'
+message = "production pools need at least 10 connections"
+`), 0644))
+
+	opts := &GenerateOptions{InputFile: input, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Policies: []string{policyFile}}
+	_, err := GenerateBytes(opts)
+	require.ErrorContains(t, err, "policy prod-pool violated: production pools need at least 10 connections")
+
+	t.Setenv("CONFIG_POOL_SIZE", "12")
+	opts.EnableEnv = true
+	_, err = GenerateBytes(opts)
+	require.NoError(t, err, "rules should see environment overrides and computed values")
+
+	require.NoError(t, os.WriteFile(policyFile, []byte("[[rule]]\nexpr = 'pool_size >'\n"), 0644))
+	_, err = GenerateBytes(opts)
+	require.ErrorContains(t, err, "invalid policy: "+policyFile)
+}
+
+func TestGenerateBytes_JSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonFile := filepath.Join(tmpDir, "config.json")
+	tomlFile := filepath.Join(tmpDir, "config.toml")
+
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{
+  "name": "svc",
+  "server": {"port": 8080, "ratio": 1.5, "timeout": "5s", "tags": ["a", "b"]},
+  "endpoints": [{"path": "/v1"}, {"path": "/v2"}]
+}`), 0644))
+	require.NoError(t, os.WriteFile(tomlFile, []byte(`name = "svc"
+
+[server]
+port = 8080
+ratio = 1.5
+timeout = "5s"
+tags = ["a", "b"]
+
+[[endpoints]]
+path = "/v1"
+
+[[endpoints]]
+path = "/v2"
+`), 0644))
+
+	for _, mode := range []string{"static", "getter"} {
+		opts := &GenerateOptions{InputFile: tomlFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Mode: mode}
+		want, err := GenerateBytes(opts)
+		require.NoError(t, err)
+
+		opts.InputFile = jsonFile
+		got, err := GenerateBytes(opts)
+		require.NoError(t, err)
+		require.Equal(t, strings.Replace(string(want), `in="config.toml"`, `in="config.json"`, 1), string(got), mode)
+	}
+
+	// The format can be given explicitly
+	_, err := GenerateBytes(&GenerateOptions{InputFile: jsonFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Format: "toml"})
+	require.Error(t, err)
+	_, err = GenerateBytes(&GenerateOptions{InputFile: jsonFile, OutputFile: filepath.Join(tmpDir, "config.go"), PackageName: "config", Format: "yaml"})
+	require.ErrorContains(t, err, `unknown input format "yaml"`)
+}
+
+func TestGenerateBytes_JSONErrorPosition(t *testing.T) {
+	jsonFile := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(jsonFile, []byte("{\n  \"server\": {\n    \"port\": 80,,\n  }\n}\n"), 0644))
+
+	_, err := GenerateBytes(&GenerateOptions{InputFile: jsonFile, OutputFile: "config.go", PackageName: "config"})
+	require.Error(t, err)
+
+	var cfgErr *Error
+	require.ErrorAs(t, err, &cfgErr)
+	require.Equal(t, 3, cfgErr.Line)
+	require.Equal(t, 17, cfgErr.Column)
+
+	require.NoError(t, os.WriteFile(jsonFile, []byte(`{"a": null}`), 0644))
+	_, err = GenerateBytes(&GenerateOptions{InputFile: jsonFile, OutputFile: "config.go", PackageName: "config"})
+	require.ErrorContains(t, err, "a: null values are not supported")
+}
@@ -0,0 +1,81 @@
+// Package cfgxtest provides test helpers for projects that generate code with cfgx.
+//
+// Use it to assert in your own test suite that a configuration file keeps
+// producing valid Go code, without writing any files or running the go tool:
+//
+//	func TestConfigGenerates(t *testing.T) {
+//		cfgxtest.AssertGenerates(t, "config.toml", &cfgx.GenerateOptions{
+//			Mode: "getter",
+//		})
+//	}
+package cfgxtest
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"testing"
+
+	"github.com/gomantics/cfgx"
+)
+
+// AssertGenerates generates code for the TOML file at tomlPath in memory and
+// fails t unless the result is gofmt-clean and type-checks. InputFile in opts
+// is ignored; OutputFile, if empty, defaults to config.go next to the input
+// and only affects the generation record. opts may be nil.
+//
+// The generated source is returned for further assertions.
+func AssertGenerates(t testing.TB, tomlPath string, opts *cfgx.GenerateOptions) []byte {
+	t.Helper()
+
+	var o cfgx.GenerateOptions
+	if opts != nil {
+		o = *opts
+	}
+	o.InputFile = tomlPath
+	if o.OutputFile == "" {
+		o.OutputFile = filepath.Join(filepath.Dir(tomlPath), "config.go")
+	}
+	if o.PackageName == "" {
+		o.PackageName = "config"
+	}
+
+	code, err := cfgx.GenerateBytes(&o)
+	if err != nil {
+		t.Fatalf("cfgx: %s does not generate: %v", tomlPath, err)
+		return nil
+	}
+
+	formatted, err := format.Source(code)
+	if err != nil {
+		t.Fatalf("cfgx: code generated from %s does not parse: %v", tomlPath, err)
+		return nil
+	}
+	if !bytes.Equal(formatted, code) {
+		t.Errorf("cfgx: code generated from %s is not gofmt-clean", tomlPath)
+	}
+
+	if err := typeCheck(o.OutputFile, code); err != nil {
+		t.Fatalf("cfgx: code generated from %s does not type-check: %v", tomlPath, err)
+	}
+
+	return code
+}
+
+// typeCheck parses and type-checks a single generated file.
+func typeCheck(filename string, src []byte) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+
+	conf := types.Config{Importer: importer.Default()}
+	_, err = conf.Check(file.Name.Name, fset, []*ast.File{file}, nil)
+	return err
+}
@@ -0,0 +1,72 @@
+package cfgxtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gomantics/cfgx"
+)
+
+// TestAssertGenerates_Fixtures compiles every TOML fixture shipped with the
+// repository in every generation mode, with getter usage tracking, with the
+// summary printer, and with getters reading the environment at init.
+func TestAssertGenerates_Fixtures(t *testing.T) {
+	var fixtures []string
+	for _, pattern := range []string{"../testdata/*.toml", "../testdata/*.json", "../example/*/*.toml"} {
+		matches, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		fixtures = append(fixtures, matches...)
+	}
+	require.NotEmpty(t, fixtures)
+
+	for _, fixture := range fixtures {
+		for _, variant := range []struct {
+			name string
+			opts cfgx.GenerateOptions
+		}{
+			{"static", cfgx.GenerateOptions{Mode: "static", EnableEnv: true}},
+			{"getter", cfgx.GenerateOptions{Mode: "getter", EnableEnv: true}},
+			{"hybrid", cfgx.GenerateOptions{Mode: "hybrid", EnableEnv: true, Summary: true}},
+			{"loader", cfgx.GenerateOptions{Mode: "loader", EnableEnv: true, Summary: true}},
+			{"summary", cfgx.GenerateOptions{Mode: "static", EnableEnv: true, Summary: true}},
+			{"usage", cfgx.GenerateOptions{Mode: "getter", EnableEnv: true, TrackUsage: true, Summary: true}},
+			{"env-at-init", cfgx.GenerateOptions{Mode: "getter", EnableEnv: true, EnvAtInit: true, TrackUsage: true}},
+		} {
+			t.Run(fixture+"/"+variant.name, func(t *testing.T) {
+				code := AssertGenerates(t, fixture, &variant.opts)
+				require.True(t, strings.HasPrefix(string(code), "// Code generated by cfgx"))
+			})
+		}
+	}
+}
+
+func TestAssertGenerates_Fails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[app\nname = 1\n"), 0644))
+
+	rec := &recorder{TB: t}
+	func() {
+		defer func() { _ = recover() }()
+		AssertGenerates(rec, path, nil)
+	}()
+	require.True(t, rec.failed, "invalid TOML should fail the test")
+}
+
+// recorder captures failures instead of failing the enclosing test.
+type recorder struct {
+	testing.TB
+	failed bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) { r.failed = true }
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failed = true
+	panic("fatal")
+}
@@ -0,0 +1,46 @@
+package cfgx
+
+import (
+	"context"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// Cipher encrypts and decrypts the values of keys annotated cfgx:encrypted
+// with the key named by a key ID, such as the ARN of an AWS KMS key or the
+// resource name of a GCP KMS key. KMS-backed ciphers wrap the client of
+// their SDK:
+//
+//	type awsKMS struct{ client *kms.Client }
+//
+//	func (c awsKMS) Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+//		out, err := c.client.Decrypt(ctx, &kms.DecryptInput{KeyId: &keyID, CiphertextBlob: ciphertext})
+//		if err != nil {
+//			return nil, err
+//		}
+//		return out.Plaintext, nil
+//	}
+//
+// The generated package declares the same interface, for the ciphers that
+// getter and loader mode decrypt with at runtime.
+type Cipher interface {
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// RegisterCipher makes c available under name to the values annotated
+// cfgx:encrypted that static and hybrid mode decrypt at generation time, and
+// to Encrypt. It replaces any cipher registered under name before, including
+// the built-in "aes" cipher: AES-GCM with the base64-encoded key held in the
+// environment variable named by the key ID.
+func RegisterCipher(name string, c Cipher) {
+	snippets.RegisterCipher(name, c)
+}
+
+// Encrypt encrypts plaintext with the cipher registered under name and the
+// key keyID, and returns the value to write in the TOML file, annotated
+// cfgx:encrypted: "<cipher>:<key ID>:<base64 ciphertext>".
+func Encrypt(ctx context.Context, name, keyID string, plaintext []byte) (string, error) {
+	value, err := snippets.Encrypt(ctx, name, keyID, plaintext)
+	return string(value), err
+}
@@ -0,0 +1,42 @@
+package cfgx
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// suffixCipher stands in for a KMS-backed cipher.
+type suffixCipher struct{}
+
+func (suffixCipher) Encrypt(_ context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	return append(bytes.Clone(plaintext), keyID...), nil
+}
+
+func (suffixCipher) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	return bytes.TrimSuffix(ciphertext, []byte(keyID)), nil
+}
+
+func TestEncrypt(t *testing.T) {
+	RegisterCipher("test-kms", suffixCipher{})
+	value, err := Encrypt(context.Background(), "test-kms", "arn:aws:kms:us-east-1:111:key/abc", []byte("hunter2"))
+	require.NoError(t, err)
+	require.Regexp(t, `^test-kms:arn:aws:kms:us-east-1:111:key/abc:`, value)
+
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+	outputFile := filepath.Join(tmpDir, "config.go")
+	require.NoError(t, os.WriteFile(inputFile, []byte("[db]\npassword = \""+value+"\" # cfgx:encrypted\n"), 0644))
+
+	require.NoError(t, GenerateFromFile(&GenerateOptions{InputFile: inputFile, OutputFile: outputFile, PackageName: "config"}))
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `Password: "hunter2",`)
+
+	_, err = Encrypt(context.Background(), "missing", "key", []byte("x"))
+	require.EqualError(t, err, `unknown cipher "missing"`)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+	"github.com/gomantics/cfgx/internal/apidiff"
+)
+
+// Exit statuses of apidiff, from the most severe change found.
+const (
+	apidiffNone       = 0
+	apidiffCompatible = 1
+	apidiffBreaking   = 2
+	apidiffFailed     = 3
+)
+
+var apidiffMode string
+
+var apidiffCmd = &cobra.Command{
+	Use:   "apidiff [packages]",
+	Short: "Report changes regeneration would make to the API of generated files",
+	Long: `Find every file generated by cfgx, regenerate it in memory from the input
+recorded in its header, and compare the exported API of the result - types,
+fields, methods, functions, variables and constants - with the file on disk.
+
+Patterns follow the go tool convention, as for "cfgx check". The exit status
+tells the most severe change found, to gate releases on:
+
+  0  no API changes
+  1  compatible changes only (additions)
+  2  breaking changes
+  3  a file could not be regenerated`,
+	Example: `  # Gate a release on config-driven API breaks
+  cfgx apidiff ./...
+
+  # Preview the API changes of switching a package to getter mode
+  cfgx apidiff ./config --mode getter`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+		if apidiffMode != "" && apidiffMode != "static" && apidiffMode != "getter" && apidiffMode != "hybrid" && apidiffMode != "loader" {
+			return fmt.Errorf("invalid --mode value %q: must be 'static', 'getter', 'hybrid' or 'loader'", apidiffMode)
+		}
+
+		files, err := findGeneratedFiles(args)
+		if err != nil {
+			return err
+		}
+
+		status := apidiffNone
+		for _, file := range files {
+			changes, ok, err := regeneratedAPIChanges(file)
+			switch {
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "✗ %s: %v\n", file, err)
+				status = max(status, apidiffFailed)
+				continue
+			case !ok:
+				fmt.Printf("? %s has no source record (regenerate it to enable comparing)\n", file)
+				continue
+			}
+			printCompatReport(os.Stdout, file, changes)
+			switch {
+			case apidiff.Breaking(changes) > 0:
+				status = max(status, apidiffBreaking)
+			case len(changes) > 0:
+				status = max(status, apidiffCompatible)
+			}
+		}
+		os.Exit(status)
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	apidiffCmd.Flags().StringVar(&apidiffMode, "mode", "", "regenerate in this mode instead of the recorded one: 'static', 'getter', 'hybrid' or 'loader'")
+}
+
+// regeneratedAPIChanges regenerates a cfgx generated file in memory from its
+// recorded input and compares the exported API of the result with the file
+// on disk. It reports false if the file has no record.
+func regeneratedAPIChanges(path string) ([]apidiff.Change, bool, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	opts, ok, err := optionsFromRecord(path, src)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if apidiffMode != "" {
+		opts.Mode = apidiffMode
+	}
+
+	files, err := cfgx.GenerateFiles(opts)
+	if err != nil {
+		return nil, true, err
+	}
+	old, err := apidiff.Extract(src)
+	if err != nil {
+		return nil, true, err
+	}
+	// A companion file no longer generated loses all of its API
+	regenerated := apidiff.API{}
+	if generated, ok := files[path]; ok {
+		if regenerated, err = apidiff.Extract(generated); err != nil {
+			return nil, true, err
+		}
+	}
+	return apidiff.Compare(old, regenerated), true, nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+// manifestName is the name of the manifest in bundles.
+const manifestName = "manifest.json"
+
+// bundleTime is the modification time of bundle entries, fixed so that
+// bundles of the same files are identical.
+var bundleTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	bundleOut    string
+	bundleVerify bool
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Bundle the files referenced with file: into an archive",
+	Long: `Collect every file a TOML configuration references with file: into a tar,
+gzipped tar or zip archive, chosen by the extension of --out, for deployments
+that ship the raw assets alongside the binary, such as for external tools,
+while the configuration stays the one source of truth.
+
+Files are stored under their reference paths, which must stay within the
+input directory, next to a manifest.json listing for each file the keys
+referencing it, its size, content type and SHA-256 hash. Bundles of the same
+files are byte for byte identical.
+
+With --verify, the existing bundle is checked instead: every file must match
+the hash of the manifest, and the manifest must match the files the
+configuration references now. The command fails if anything differs.`,
+	Example: `  # Bundle the assets of a config
+  cfgx bundle --in config.toml --out assets.tar.gz
+
+  # Check in CI that the bundle is up to date
+  cfgx bundle --in config.toml --out assets.tar.gz --verify`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDuplicates(); err != nil {
+			return err
+		}
+		if bundleOut == "" {
+			return fmt.Errorf("--out flag is required")
+		}
+		format, err := bundleFormat(bundleOut)
+		if err != nil {
+			return err
+		}
+		maxFileSizeBytes, err := parseFileSize(maxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+
+		assets, err := cfgx.Assets(&cfgx.GenerateOptions{
+			InputFiles:      inputFiles,
+			AppendArrays:    appendArrays,
+			Format:          inputFormat,
+			MergeDuplicates: duplicates,
+			MaxFileSize:     maxFileSizeBytes,
+		})
+		if err != nil {
+			return err
+		}
+		want, err := newManifest(inputFiles, assets)
+		if err != nil {
+			return err
+		}
+
+		if bundleVerify {
+			problems, err := verifyBundle(bundleOut, format, want)
+			if err != nil {
+				return err
+			}
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "✗ %s\n", problem)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("bundle %s does not match %s", bundleOut, strings.Join(inputFiles, ", "))
+			}
+			fmt.Printf("✓ %s is up to date (%d files)\n", bundleOut, len(want.Files))
+			return nil
+		}
+
+		data, err := writeBundle(format, want, assets)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(bundleOut, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", bundleOut, err)
+		}
+		fmt.Printf("Bundled %d files into %s\n", len(want.Files), bundleOut)
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	bundleCmd.Flags().StringArrayVarP(&inputFiles, "in", "i", []string{"config.toml"}, "input TOML or JSON file; repeat to deep-merge later files over earlier ones")
+	bundleCmd.Flags().BoolVar(&appendArrays, "append-arrays", false, "when merging several --in files, append arrays instead of replacing them")
+	bundleCmd.Flags().StringVar(&inputFormat, "input-format", "", "input format: 'toml' or 'json' (default: detected from the file extension)")
+	bundleCmd.Flags().StringVar(&duplicates, "merge-duplicates", "", "accept concatenated TOML with repeated tables and keys: 'last' (later values win) or 'error' (fail on keys set twice)")
+	bundleCmd.Flags().StringVar(&maxFileSize, "max-file-size", "1MB", "maximum file size for file: references (e.g., 10MB, 1GB, 512KB)")
+	bundleCmd.Flags().StringVarP(&bundleOut, "out", "o", "", "output archive: .tar, .tar.gz, .tgz or .zip (required)")
+	bundleCmd.Flags().BoolVar(&bundleVerify, "verify", false, "check the existing bundle against the config instead of writing it")
+}
+
+// manifest is the manifest.json of a bundle.
+type manifest struct {
+	Input []string       `json:"input"`
+	Files []manifestFile `json:"files"`
+}
+
+// manifestFile describes a file of a bundle.
+type manifestFile struct {
+	Path        string   `json:"path"`
+	Keys        []string `json:"keys"`
+	Size        int64    `json:"size"`
+	ContentType string   `json:"content_type"`
+	SHA256      string   `json:"sha256"`
+}
+
+// newManifest returns the manifest of a bundle of assets, the files
+// referenced by the inputs.
+func newManifest(inputs []string, assets []cfgx.Asset) (*manifest, error) {
+	m := &manifest{Input: inputs, Files: make([]manifestFile, 0, len(assets))}
+	for _, asset := range assets {
+		name := path.Clean(filepath.ToSlash(asset.Ref))
+		if !filepath.IsLocal(filepath.FromSlash(name)) || name == manifestName {
+			return nil, fmt.Errorf("%s: cannot bundle file:%s: it must be a path within the input directory", asset.Keys[0], asset.Ref)
+		}
+		m.Files = append(m.Files, manifestFile{
+			Path:        name,
+			Keys:        asset.Keys,
+			Size:        int64(len(asset.Content)),
+			ContentType: asset.ContentType,
+			SHA256:      asset.SHA256,
+		})
+	}
+	return m, nil
+}
+
+// bundleFormat returns the archive format of a bundle from its extension:
+// "tar", "tar.gz" or "zip".
+func bundleFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return "tar.gz", nil
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar", nil
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip", nil
+	}
+	return "", fmt.Errorf("unsupported bundle %s: must end in .tar, .tar.gz, .tgz or .zip", name)
+}
+
+// writeBundle returns the archive of the manifest and the files of assets,
+// in the same order.
+func writeBundle(format string, m *manifest, assets []cfgx.Asset) ([]byte, error) {
+	manifestJSON, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestJSON = append(manifestJSON, '\n')
+
+	var buf bytes.Buffer
+	if format == "zip" {
+		zw := zip.NewWriter(&buf)
+		add := func(name string, content []byte) error {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: bundleTime})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(content)
+			return err
+		}
+		if err := add(manifestName, manifestJSON); err != nil {
+			return nil, err
+		}
+		for i, asset := range assets {
+			if err := add(m.Files[i].Path, asset.Content); err != nil {
+				return nil, err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if format == "tar.gz" {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	add := func(name string, content []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: bundleTime, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	if err := add(manifestName, manifestJSON); err != nil {
+		return nil, err
+	}
+	for i, asset := range assets {
+		if err := add(m.Files[i].Path, asset.Content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// readBundle returns the contents of the files of the bundle at name, by
+// path.
+func readBundle(name, format string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	if format == "zip" {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle %s: %w", name, err)
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from %s: %w", f.Name, name, err)
+			}
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from %s: %w", f.Name, name, err)
+			}
+			files[f.Name] = content
+		}
+		return files, nil
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", name, err)
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if format == "tar.gz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle %s: %w", name, err)
+		}
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle %s: %w", name, err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s from %s: %w", hdr.Name, name, err)
+		}
+		files[hdr.Name] = content
+	}
+}
+
+// verifyBundle checks the bundle at name against want, the manifest of the
+// files the configuration references now, and returns what differs: files
+// not matching the hashes of the bundle's manifest, and files added,
+// removed or changed since it was written.
+func verifyBundle(name, format string, want *manifest) ([]string, error) {
+	files, err := readBundle(name, format)
+	if err != nil {
+		return nil, err
+	}
+	manifestJSON, ok := files[manifestName]
+	if !ok {
+		return nil, fmt.Errorf("bundle %s has no %s", name, manifestName)
+	}
+	var got manifest
+	if err := json.Unmarshal(manifestJSON, &got); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", manifestName, name, err)
+	}
+
+	var problems []string
+	bundled := make(map[string]manifestFile, len(got.Files))
+	for _, f := range got.Files {
+		bundled[f.Path] = f
+		content, ok := files[f.Path]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing from the bundle", f.Path))
+			continue
+		}
+		sum := sha256.Sum256(content)
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: content does not match the manifest hash", f.Path))
+		}
+	}
+
+	current := make(map[string]bool, len(want.Files))
+	for _, f := range want.Files {
+		current[f.Path] = true
+		b, ok := bundled[f.Path]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s: referenced by %s but not bundled", f.Path, strings.Join(f.Keys, ", ")))
+		case b.SHA256 != f.SHA256:
+			problems = append(problems, fmt.Sprintf("%s: changed since it was bundled", f.Path))
+		}
+	}
+	for _, f := range got.Files {
+		if !current[f.Path] {
+			problems = append(problems, fmt.Sprintf("%s: bundled but no longer referenced", f.Path))
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,465 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/i18n"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// maxChangelogValue is the length beyond which values are shortened in changelog entries.
+const maxChangelogValue = 60
+
+// changelogTemplate renders one changelog entry for a regenerated file.
+var changelogTemplate = template.Must(template.New("changelog").Parse(`
+## {{.Date}} - {{.File}}
+
+{{range .Changes}}- ` + "`{{.Key}}`" + `: {{.Old}} -> {{.New}}{{if .Owner}} ({{.Owner}}){{end}}
+{{end}}`))
+
+// defaultChange is a key whose default value differs between two versions of a generated file.
+type defaultChange struct {
+	Key   string
+	Old   string
+	New   string
+	Owner string // formatted for the entry, if the key has one
+}
+
+// appendChangelog compares the defaults in the old and new versions of a generated
+// file and, if any changed, appends an entry listing them to the changelog file.
+// An empty old version means the file is new, which is not recorded. Secrets
+// of the input, as src finds them, are redacted, and keys are listed with
+// their cfgx:owner.
+func appendChangelog(changelog, file string, old, generated []byte, src *changelogSources) error {
+	if len(old) == 0 {
+		return nil
+	}
+	oldDefaults, err := generatedDefaults(old)
+	if err != nil {
+		// Not a file we can compare against, e.g. hand-edited into invalid Go
+		return nil
+	}
+	newDefaults, err := generatedDefaults(generated)
+	if err != nil {
+		return fmt.Errorf("failed to read defaults from %s: %w", file, err)
+	}
+
+	changes := diffDefaults(oldDefaults, newDefaults, src)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err = changelogTemplate.Execute(&buf, struct {
+		Date    string
+		File    string
+		Changes []defaultChange
+	}{time.Now().Format(time.DateOnly), file, changes})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(changelog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open changelog: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	return f.Close()
+}
+
+// diffDefaults lists the keys added, removed or changed between two sets of
+// defaults, sorted by key. Values are formatted for the changelog, with those
+// of secrets redacted, and owners are those of the input.
+func diffDefaults(old, new map[string]string, src *changelogSources) []defaultChange {
+	value := func(key, v string) string {
+		if src.isSecret(key) {
+			return generator.Redacted
+		}
+		return changelogValue(v)
+	}
+
+	var changes []defaultChange
+	for key, o := range old {
+		n, ok := new[key]
+		switch {
+		case !ok:
+			changes = append(changes, defaultChange{Key: key, Old: value(key, o), New: "(removed)"})
+		case n != o:
+			changes = append(changes, defaultChange{Key: key, Old: value(key, o), New: value(key, n)})
+		}
+	}
+	for key, n := range new {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, defaultChange{Key: key, Old: "(none)", New: value(key, n)})
+		}
+	}
+	for i := range changes {
+		if owner := src.owner(changes[i].Key); owner != "" {
+			changes[i].Owner = i18n.T(i18n.DiffOwner, owner)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// changelogValue formats a default as a Markdown code span, shortening long values.
+func changelogValue(v string) string {
+	if utf8.RuneCountInString(v) > maxChangelogValue {
+		v = string([]rune(v)[:maxChangelogValue]) + "..."
+	}
+	if strings.Contains(v, "`") {
+		return "`` " + v + " ``"
+	}
+	return "`" + v + "`"
+}
+
+// generatedDefaults returns the default of every key in a file generated by
+// cfgx, keyed by the Go selector that reads it, such as "Server.Port", and
+// rendered as Go source. Static mode files, in either style, and getter mode
+// files are understood.
+func generatedDefaults(src []byte) (map[string]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &defaultsReader{
+		fset:     fset,
+		methods:  make(map[string][]*ast.FuncDecl),
+		inits:    make(map[string]ast.Expr),
+		defaults: make(map[string]string),
+	}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				if ident, ok := decl.Recv.List[0].Type.(*ast.Ident); ok {
+					d.methods[ident.Name] = append(d.methods[ident.Name], decl)
+				}
+			}
+		case *ast.GenDecl:
+			// Getter mode with --env-at-init: getters return variables
+			// set by a function literal called at package init
+			if decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						continue
+					}
+					call, ok := vs.Values[i].(*ast.CallExpr)
+					if !ok || len(call.Args) != 0 {
+						continue
+					}
+					if fn, ok := call.Fun.(*ast.FuncLit); ok {
+						if result, ok := bodyResult(fn.Body); ok {
+							d.inits[name.Name] = result
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if _, ok := d.inits[name.Name]; ok {
+						continue
+					}
+					switch {
+					case i < len(vs.Values):
+						// Static mode: the value is a literal
+						d.value(name.Name, vs.Values[i])
+					case vs.Type != nil:
+						// Getter mode: an empty struct whose methods return the values
+						if ident, ok := vs.Type.(*ast.Ident); ok {
+							d.getters(name.Name, ident.Name)
+						}
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv != nil || !decl.Name.IsExported() {
+				continue
+			}
+			v, ok := d.getterResult(decl)
+			switch {
+			case !ok:
+			case decl.Name.Name == "Default":
+				// Compact style: the struct literal Default returns. The
+				// Default of loader mode returns the package variables
+				// read above.
+				if lit, ok := v.(*ast.CompositeLit); ok {
+					d.fields("", lit)
+				}
+			default:
+				// Getter mode: top-level keys that are not tables
+				d.leaf(decl.Name.Name, v)
+			}
+		}
+	}
+	return d.defaults, nil
+}
+
+// defaultsReader collects the defaults of a generated file.
+type defaultsReader struct {
+	fset     *token.FileSet
+	methods  map[string][]*ast.FuncDecl // by receiver type name
+	inits    map[string]ast.Expr        // defaults of --env-at-init variables
+	defaults map[string]string
+}
+
+// value records the defaults in a static mode value, descending into struct literals.
+func (d *defaultsReader) value(key string, expr ast.Expr) {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		d.leaf(key, expr)
+		return
+	}
+	if _, named := lit.Type.(*ast.Ident); !named {
+		d.leaf(key, expr)
+		return
+	}
+	d.fields(key+".", lit)
+}
+
+// fields records the defaults in the fields of a struct literal, keyed by
+// prefix followed by their name.
+func (d *defaultsReader) fields(prefix string, lit *ast.CompositeLit) {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			if ident, ok := kv.Key.(*ast.Ident); ok {
+				d.value(prefix+ident.Name, kv.Value)
+			}
+		}
+	}
+}
+
+// getters records the defaults returned by the getter methods of typ,
+// descending into the types returned for nested tables.
+func (d *defaultsReader) getters(key, typ string) {
+	for _, fn := range d.methods[typ] {
+		result, ok := d.getterResult(fn)
+		if !ok {
+			continue
+		}
+		name := key + "." + fn.Name.Name
+		if lit, ok := result.(*ast.CompositeLit); ok && len(lit.Elts) == 0 {
+			if ident, ok := lit.Type.(*ast.Ident); ok && len(d.methods[ident.Name]) > 0 {
+				d.getters(name, ident.Name)
+				continue
+			}
+		}
+		d.leaf(name, result)
+	}
+}
+
+// getterResult returns the default a generated getter returns: the result of
+// its final return statement, which may only be preceded by environment
+// variable lookups and, with --track-usage, the count of its reads. It
+// reports false for functions that are not getters. Getters returning a
+// variable read at package init return its default.
+func (d *defaultsReader) getterResult(fn *ast.FuncDecl) (ast.Expr, bool) {
+	if fn.Body == nil || fn.Type.Params.NumFields() != 0 || fn.Type.Results.NumFields() != 1 {
+		return nil, false
+	}
+	result, ok := bodyResult(fn.Body)
+	if !ok {
+		return nil, false
+	}
+	if ident, ok := result.(*ast.Ident); ok {
+		if init, ok := d.inits[ident.Name]; ok {
+			return init, true
+		}
+	}
+	return result, true
+}
+
+// bodyResult returns the result of the final return statement of a getter
+// body, if it is only preceded by environment variable lookups and counts of
+// reads.
+func bodyResult(body *ast.BlockStmt) (ast.Expr, bool) {
+	stmts := body.List
+	if len(stmts) == 0 {
+		return nil, false
+	}
+	ret, ok := stmts[len(stmts)-1].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return nil, false
+	}
+	for _, stmt := range stmts[:len(stmts)-1] {
+		if !isEnvLookup(stmt) && !isUsageCount(stmt) {
+			return nil, false
+		}
+	}
+	return ret.Results[0], true
+}
+
+// isEnvLookup reports whether stmt is an "if v := os.Getenv(...); ..." statement.
+func isEnvLookup(stmt ast.Stmt) bool {
+	ifStmt, ok := stmt.(*ast.IfStmt)
+	if !ok {
+		return false
+	}
+	assign, ok := ifStmt.Init.(*ast.AssignStmt)
+	if !ok || len(assign.Rhs) != 1 {
+		return false
+	}
+	call, ok := assign.Rhs[0].(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "os" && sel.Sel.Name == "Getenv"
+}
+
+// isUsageCount reports whether stmt is a "usageCounts[i].Add(1)" statement.
+func isUsageCount(stmt ast.Stmt) bool {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := expr.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Add" {
+		return false
+	}
+	index, ok := sel.X.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	counts, ok := index.X.(*ast.Ident)
+	return ok && counts.Name == "usageCounts"
+}
+
+// leaf records expr as the default of key, with its whitespace collapsed.
+func (d *defaultsReader) leaf(key string, expr ast.Expr) {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, d.fset, expr); err != nil {
+		return
+	}
+	d.defaults[key] = strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// changelogSources finds the TOML keys behind the Go selectors of changelog
+// entries, such as "Server.Port", to judge them as diff does.
+type changelogSources struct {
+	sources []*tomlsrc.Source // last input first
+	names   map[string]string // Go names given with --name, by key
+}
+
+// key returns the TOML key generated as selector: the key whose parts have
+// the Go names of the parts of selector, by --name, cfgx:name or else by
+// their letters and digits.
+func (s *changelogSources) key(selector string) (string, bool) {
+	parts := strings.Split(selector, ".")
+	for _, src := range s.sources {
+		for _, e := range src.Entries {
+			if s.generatedAs(e.Key, parts) {
+				return e.Key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// generatedAs reports whether key is generated as the selector of parts.
+func (s *changelogSources) generatedAs(key string, parts []string) bool {
+	keyParts := strings.Split(key, ".")
+	if len(keyParts) != len(parts) {
+		return false
+	}
+	for i, part := range keyParts {
+		if name, ok := s.name(strings.Join(keyParts[:i+1], ".")); ok {
+			if name != parts[i] {
+				return false
+			}
+		} else if identLetters(part) != identLetters(parts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// name returns the Go name given to key with --name or cfgx:name, if any.
+func (s *changelogSources) name(key string) (string, bool) {
+	if name, ok := s.names[key]; ok {
+		return name, true
+	}
+	for _, src := range s.sources {
+		if name, ok := src.Annotation(key, "name"); ok && name != "" {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// isSecret reports whether the key generated as selector is a secret, as
+// diff judges it. Selectors of keys not found are judged by their name.
+func (s *changelogSources) isSecret(selector string) bool {
+	if s == nil {
+		return false
+	}
+	key, ok := s.key(selector)
+	if !ok {
+		return generator.IsSecret(nil, selector)
+	}
+	return isSecret(key, s.sources)
+}
+
+// owner returns the owner of the key generated as selector, from cfgx:owner
+// annotations on it or its tables, as diff finds it.
+func (s *changelogSources) owner(selector string) string {
+	if s == nil {
+		return ""
+	}
+	key, ok := s.key(selector)
+	if !ok {
+		return ""
+	}
+	return keyOwner(key, s.sources)
+}
+
+// identLetters returns the lower-cased letters and digits of s, which a key
+// and the identifier generated from it share.
+func identLetters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+	"github.com/gomantics/cfgx/internal/record"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [packages]",
+	Short: "Report generated files that are out of date with their TOML input",
+	Long: `Find every file generated by cfgx, regenerate it in memory from the input
+recorded in its header, and report the ones that differ from what is on disk.
+
+Patterns follow the go tool convention: "./..." walks the current directory
+recursively, "dir" checks a single directory and "file.go" a single file.
+The command exits with status 1 if any generated file is stale.`,
+	Example: `  # Check every generated config in the repository
+  cfgx check ./...
+
+  # Check a single package
+  cfgx check ./config`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+
+		files, err := findGeneratedFiles(args)
+		if err != nil {
+			return err
+		}
+
+		if len(files) == 0 {
+			fmt.Println("No cfgx generated files found.")
+			return nil
+		}
+
+		stale := 0
+		for _, file := range files {
+			status, err := checkGeneratedFile(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %s: %v\n", file, err)
+				stale++
+				continue
+			}
+			switch status {
+			case checkFresh:
+				fmt.Printf("✓ %s\n", file)
+			case checkStale:
+				fmt.Printf("✗ %s is stale\n", file)
+				stale++
+			case checkNoRecord:
+				fmt.Printf("? %s has no source record (regenerate it to enable checking)\n", file)
+			case checkDynamic:
+				fmt.Printf("~ %s contains values generated with --dynamic-values and cannot be compared\n", file)
+			}
+		}
+
+		if stale > 0 {
+			return fmt.Errorf("%d generated file(s) out of date", stale)
+		}
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+type checkStatus int
+
+const (
+	checkFresh checkStatus = iota
+	checkStale
+	checkNoRecord
+	checkDynamic
+)
+
+// checkGeneratedFile regenerates a cfgx generated file in memory from its recorded
+// input and compares the result with the file on disk.
+func checkGeneratedFile(path string) (checkStatus, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return checkStale, err
+	}
+
+	opts, ok, err := optionsFromRecord(path, src)
+	if err != nil {
+		return checkStale, err
+	}
+	if !ok {
+		return checkNoRecord, nil
+	}
+	return checkGenerated(path, src, opts)
+}
+
+// checkGenerated regenerates the generated file at path, holding src, from
+// the options of its record and compares the result with src.
+func checkGenerated(path string, src []byte, opts *cfgx.GenerateOptions) (checkStatus, error) {
+	files, err := cfgx.GenerateFiles(opts)
+	if err != nil {
+		return checkStale, err
+	}
+	generated, ok := files[path]
+	if !ok {
+		// A companion file whose helper is no longer requested
+		return checkStale, nil
+	}
+	if opts.DynamicValues {
+		// Regenerating produces new values every time
+		return checkDynamic, nil
+	}
+
+	// Compare ignoring line endings, which git may rewrite on checkout (core.autocrlf)
+	if !bytes.Equal(normalizeNewlines(generated), normalizeNewlines(src)) {
+		return checkStale, nil
+	}
+	return checkFresh, nil
+}
+
+// optionsFromRecord rebuilds the generate options for a generated file from the
+// record in its header and its package clause.
+func optionsFromRecord(path string, src []byte) (*cfgx.GenerateOptions, bool, error) {
+	rec, ok, err := record.Parse(src)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	resolve := func(input string) string {
+		input = filepath.FromSlash(input)
+		if !filepath.IsAbs(input) {
+			input = filepath.Join(filepath.Dir(path), input)
+		}
+		return input
+	}
+	input := resolve(rec.Input)
+	var inputs []string
+	if len(rec.Overlays) > 0 {
+		inputs = []string{input}
+		for _, overlay := range rec.Overlays {
+			inputs = append(inputs, resolve(overlay))
+		}
+	}
+
+	// Companion files and sections are regenerated through the main file they belong to
+	output := path
+	if rec.Part != "" {
+		output = cfgx.MainPath(path, rec.Part)
+	}
+	if rec.Section != "" {
+		output = filepath.Join(filepath.Dir(filepath.Dir(output)), filepath.Base(output))
+
+		// The package name is that of the main file, not the section's
+		if src, err = os.ReadFile(output); err != nil {
+			return nil, false, fmt.Errorf("failed to read main output of section %s: %w", rec.Section, err)
+		}
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), output, src, parser.PackageClauseOnly)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse package clause: %w", err)
+	}
+
+	return &cfgx.GenerateOptions{
+		InputFile:       input,
+		InputFiles:      inputs,
+		AppendArrays:    rec.Append,
+		MergeDuplicates: rec.Duplicates,
+		OutputFile:      output,
+		PackageName:     file.Name.Name,
+		EnableEnv:       rec.EnableEnv,
+		MaxFileSize:     rec.MaxFileSize,
+		Mode:            rec.Mode,
+		CRLF:            rec.CRLF,
+		DynamicValues:   rec.Dynamic,
+		TrackUsage:      rec.Usage,
+		EnvAtInit:       rec.EnvAtInit,
+		Summary:         rec.Summary,
+		EmitEnvDocs:     rec.EnvDocs,
+		PreserveOrder:   rec.PreserveOrder,
+		EmbedThreshold:  rec.EmbedThreshold,
+		StructTags:      rec.StructTags,
+		IntType:         rec.IntType,
+		Style:           rec.Style,
+		Initialisms:     rec.Initialisms,
+		NameOverrides:   rec.Names,
+		Precedence:      rec.Precedence,
+		EnvPrefix:       rec.EnvPrefix,
+	}, true, nil
+}
+
+// normalizeNewlines converts CRLF line endings to LF.
+func normalizeNewlines(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+}
+
+// findGeneratedFiles expands go-style package patterns into the list of cfgx
+// generated files they contain.
+func findGeneratedFiles(patterns []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	add := func(path string) error {
+		if seen[path] || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		seen[path] = true
+
+		generated, err := isGeneratedFile(path)
+		if err != nil {
+			return err
+		}
+		if generated {
+			files = append(files, path)
+		}
+		return nil
+	}
+
+	for _, pattern := range patterns {
+		root, recursive := strings.CutSuffix(pattern, "...")
+		root = filepath.Clean(strings.TrimSuffix(root, "/"))
+		if root == "" {
+			root = "."
+		}
+
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			if err := add(root); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path == root {
+					return nil
+				}
+				name := d.Name()
+				if !recursive || name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			return add(path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+// isGeneratedFile reports whether the file at path starts with the cfgx header.
+func isGeneratedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(record.Header))
+	n, _ := f.Read(head)
+	return record.IsGenerated(head[:n]), nil
+}
@@ -1,61 +1,184 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"strconv"
+	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
+
+	"github.com/gomantics/cfgx"
+	"github.com/gomantics/cfgx/internal/present"
 )
 
 var (
-	inputFile   string
-	outputFile  string
-	packageName string
-	noEnv       bool
-	maxFileSize string
-	mode        string
+	inputFile     string
+	outputFile    string
+	packageName   string
+	noEnv         bool
+	envPrefix     string
+	envStrict     string
+	maxFileSize   string
+	embedSize     string
+	mode          string
+	errFormat     string
+	showStats     bool
+	crlf          bool
+	dynamic       bool
+	trackUsage    bool
+	envAtInit     bool
+	summary       bool
+	envDocs       bool
+	preserveOrder bool
+	inputFormat   string
+	duplicates    string
+	tags          string
+	intType       string
+	style         string
+	initialisms   string
+	names         []string
+	precedence    string
+	policies      []string
+	cpuProfile    string
+	memProfile    string
+	traceFile     string
 )
 
+// validateDuplicates checks the --merge-duplicates flag value.
+func validateDuplicates() error {
+	if duplicates != "" && duplicates != "last" && duplicates != "error" {
+		return fmt.Errorf("invalid --merge-duplicates value %q: must be 'last' or 'error'", duplicates)
+	}
+	return nil
+}
+
+// parseNames parses the key=Name values of the --name flag.
+func parseNames(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	names := make(map[string]string, len(values))
+	for _, value := range values {
+		key, name, ok := strings.Cut(value, "=")
+		if !ok || key == "" || name == "" {
+			return nil, fmt.Errorf("invalid --name value %q: must be key=Name", value)
+		}
+		names[key] = name
+	}
+	return names, nil
+}
+
+// parseList splits a comma-separated flag value, such as --tags.
+func parseList(s string) []string {
+	var list []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// validateErrFormat checks the --output-format flag value.
+func validateErrFormat() error {
+	if errFormat != "text" && errFormat != "gcc" {
+		return fmt.Errorf("invalid --output-format value %q: must be 'text' or 'gcc'", errFormat)
+	}
+	return nil
+}
+
+// formatError renders an error according to --output-format. In gcc format the
+// error is prefixed with file:line:col so editors and CI can annotate the input
+// file; errors without a known location point at the start of the file.
+func formatError(err error) string {
+	return formatErrorIn(err, inputFile)
+}
+
+// formatErrorIn is formatError for an error generating from file.
+func formatErrorIn(err error, file string) string {
+	if errFormat != "gcc" {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	line, col := 1, 1
+	var cfgErr *cfgx.Error
+	if errors.As(err, &cfgErr) {
+		file = cfgErr.File
+		line = max(cfgErr.Line, 1)
+		col = max(cfgErr.Column, 1)
+	}
+	return fmt.Sprintf("%s:%d:%d: error: %v", file, line, col, err)
+}
+
 // parseFileSize parses a human-readable file size string like "10MB", "1GB", "512KB"
-// into bytes. Returns 0 and error if parsing fails.
+// into bytes. An empty string is 0.
 func parseFileSize(sizeStr string) (int64, error) {
 	if sizeStr == "" {
 		return 0, nil
 	}
+	return present.ParseSize(sizeStr)
+}
 
-	sizeStr = strings.TrimSpace(strings.ToUpper(sizeStr))
+// printStats writes a generation statistics summary to w.
+func printStats(w io.Writer, stats *cfgx.Stats) {
+	fmt.Fprintln(w, "Generation stats:")
+	fmt.Fprintf(w, "  parse:     %s\n", stats.ParseTime)
+	fmt.Fprintf(w, "  analysis:  %s\n", stats.AnalysisTime)
+	fmt.Fprintf(w, "  emit:      %s\n", stats.EmitTime)
+	fmt.Fprintf(w, "  structs:   %d\n", stats.Structs)
+	fmt.Fprintf(w, "  fields:    %d\n", stats.Fields)
+	fmt.Fprintf(w, "  embedded:  %s\n", present.Size(stats.EmbeddedBytes))
+	fmt.Fprintf(w, "  output:    %s\n", present.Size(int64(stats.OutputBytes)))
 
-	// Define multipliers in order from longest to shortest to avoid prefix issues
-	multipliers := []struct {
-		suffix     string
-		multiplier int64
-	}{
-		{"TB", 1024 * 1024 * 1024 * 1024},
-		{"GB", 1024 * 1024 * 1024},
-		{"MB", 1024 * 1024},
-		{"KB", 1024},
-		{"B", 1},
+	if len(stats.HeaviestKeys) == 0 {
+		return
 	}
 
-	// Try to parse with suffix (check longest first)
-	for _, m := range multipliers {
-		if strings.HasSuffix(sizeStr, m.suffix) {
-			numStr := strings.TrimSuffix(sizeStr, m.suffix)
-			numStr = strings.TrimSpace(numStr)
+	fmt.Fprintln(w, "Heaviest keys:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, kw := range stats.HeaviestKeys {
+		fmt.Fprintf(tw, "  %s\t%s\n", kw.Key, present.Size(int64(kw.Bytes)))
+	}
+	tw.Flush()
+}
 
-			num, err := strconv.ParseInt(numStr, 10, 64)
-			if err != nil {
-				return 0, fmt.Errorf("invalid size format: %s", sizeStr)
-			}
+// openProfiling creates the profile output files requested via --cpuprofile,
+// --memprofile and --trace. The returned function closes them.
+func openProfiling() (*cfgx.Profiling, func(), error) {
+	if cpuProfile == "" && memProfile == "" && traceFile == "" {
+		return nil, func() {}, nil
+	}
 
-			return num * m.multiplier, nil
+	var (
+		profiling cfgx.Profiling
+		files     []*os.File
+	)
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
 		}
 	}
 
-	// Try to parse as plain number (bytes)
-	num, err := strconv.ParseInt(sizeStr, 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid size format: %s", sizeStr)
+	for _, p := range []struct {
+		path string
+		dst  *io.Writer
+	}{
+		{cpuProfile, &profiling.CPU},
+		{memProfile, &profiling.Mem},
+		{traceFile, &profiling.Trace},
+	} {
+		if p.path == "" {
+			continue
+		}
+		f, err := os.Create(p.path)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to create profile: %w", err)
+		}
+		files = append(files, f)
+		*p.dst = f
 	}
 
-	return num, nil
+	return &profiling, closeAll, nil
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gomantics/cfgx/internal/apidiff"
+)
+
+// apiChanges compares the exported API of the old and new versions of a
+// generated file.
+func apiChanges(file string, old, generated []byte) ([]apidiff.Change, error) {
+	oldAPI, err := apidiff.Extract(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the API of the previous %s: %w", file, err)
+	}
+	newAPI, err := apidiff.Extract(generated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the API of %s: %w", file, err)
+	}
+	return apidiff.Compare(oldAPI, newAPI), nil
+}
+
+// printCompatReport writes the changes to the exported API of file to w,
+// breaking changes first.
+func printCompatReport(w io.Writer, file string, changes []apidiff.Change) {
+	if len(changes) == 0 {
+		fmt.Fprintf(w, "No changes to the exported API of %s\n", file)
+		return
+	}
+	breaking := apidiff.Breaking(changes)
+	fmt.Fprintf(w, "Exported API of %s: %d breaking, %d compatible changes\n", file, breaking, len(changes)-breaking)
+	for _, wantBreaking := range []bool{true, false} {
+		for _, c := range changes {
+			if c.Breaking() != wantBreaking {
+				continue
+			}
+			mark := "+"
+			if c.Breaking() {
+				mark = "!"
+			}
+			fmt.Fprintf(w, "  %s %s\n", mark, c)
+		}
+	}
+}
+
+// reportCompat prints the changes to the exported API of the generated file
+// since its previous version, if there was one and it can be compared.
+func reportCompat(file string, previous []byte) error {
+	if len(previous) == 0 {
+		fmt.Printf("No previous %s to compare the exported API against\n", file)
+		return nil
+	}
+	generated, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	changes, err := apiChanges(file, previous, generated)
+	if err != nil {
+		// Not a file we can compare against, e.g. hand-edited into invalid Go
+		fmt.Printf("Cannot compare the exported API: %v\n", err)
+		return nil
+	}
+	printCompatReport(os.Stdout, file, changes)
+	return nil
+}
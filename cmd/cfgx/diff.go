@@ -4,16 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
-	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/i18n"
+	"github.com/gomantics/cfgx/internal/present"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
 )
 
 var (
-	keysOnly   bool
-	diffFormat string
+	keysOnly    bool
+	diffFormat  string
+	showSecrets bool
 )
 
 var diffCmd = &cobra.Command{
@@ -22,7 +28,14 @@ var diffCmd = &cobra.Command{
 	Long: `Compare two TOML configuration files and show what's different.
 
 This is useful for understanding changes between environments (dev vs prod) 
-or between base and override configurations.`,
+or between base and override configurations.
+
+Values of secrets are shown as [redacted], so that diffs can be printed in CI
+logs: keys annotated cfgx:secret in either file, and keys whose name suggests
+a credential, such as password or api_key, unless annotated cfgx:secret=false,
+as in the summary of generated code. Changed secrets are still listed. Pass
+--show-secrets to print them. Overlays leave changed secrets out, listing
+their keys in a comment, unless --show-secrets is given.`,
 	Example: `  # Compare two config files
   cfgx diff config.dev.toml config.prod.toml
 
@@ -30,14 +43,21 @@ or between base and override configurations.`,
   cfgx diff config.dev.toml config.prod.toml --keys-only
 
   # Output as JSON for scripting
-  cfgx diff base.toml override.toml --format json`,
+  cfgx diff base.toml override.toml --format json
+
+  # Print the values of secrets too
+  cfgx diff config.dev.toml config.prod.toml --show-secrets
+
+  # Derive an overlay that turns the first file into the second
+  cfgx diff config.base.toml config.prod.toml --format overlay > prod.overlay.toml`,
 	Args: cobra.ExactArgs(2),
 	Run:  runDiff,
 }
 
 func init() {
 	diffCmd.Flags().BoolVar(&keysOnly, "keys-only", false, "Show only the keys that differ, not their values")
-	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format: text or json")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format: text, json, or overlay")
+	diffCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Print the values of secrets instead of [redacted]")
 }
 
 func runDiff(cmd *cobra.Command, args []string) {
@@ -58,6 +78,11 @@ func runDiff(cmd *cobra.Command, args []string) {
 
 	// Compute differences
 	diffs := computeDiffs(data1, data2, "")
+	sources := scanSources(file1, file2)
+	annotateOwners(diffs, sources)
+	if !showSecrets && diffFormat != "overlay" {
+		maskSecrets(diffs, sources)
+	}
 
 	// Output based on format
 	switch diffFormat {
@@ -65,8 +90,16 @@ func runDiff(cmd *cobra.Command, args []string) {
 		outputJSON(diffs, file1, file2)
 	case "text":
 		outputText(diffs, file1, file2)
+	case "overlay":
+		// Overlays must stay valid to apply, so secrets are left out
+		// rather than redacted
+		var secretSources []*tomlsrc.Source
+		if !showSecrets {
+			secretSources = sources
+		}
+		outputOverlay(data1, data2, diffs, file1, file2, secretSources)
 	default:
-		fmt.Fprintf(os.Stderr, "Unknown format: %s (use 'text' or 'json')\n", diffFormat)
+		fmt.Fprintf(os.Stderr, "Unknown format: %s (use 'text', 'json', or 'overlay')\n", diffFormat)
 		os.Exit(1)
 	}
 
@@ -98,6 +131,106 @@ type Diff struct {
 	Type   DiffType `json:"type"`
 	Value1 any      `json:"value1,omitempty"`
 	Value2 any      `json:"value2,omitempty"`
+	Owner  string   `json:"owner,omitempty"`
+}
+
+// scanSources scans the annotations of files, last file first, skipping
+// those that cannot be read.
+func scanSources(files ...string) []*tomlsrc.Source {
+	sources := make([]*tomlsrc.Source, 0, len(files))
+	for i := len(files) - 1; i >= 0; i-- {
+		src, err := os.ReadFile(files[i])
+		if err != nil {
+			continue
+		}
+		sources = append(sources, tomlsrc.Scan(src))
+	}
+	return sources
+}
+
+// annotateOwners fills in the owner of each differing key from cfgx:owner
+// annotations, preferring the second file's annotations over the first's.
+func annotateOwners(diffs []Diff, sources []*tomlsrc.Source) {
+	for i := range diffs {
+		diffs[i].Owner = keyOwner(diffs[i].Key, sources)
+	}
+}
+
+// keyOwner returns the cfgx:owner of key or of its closest table having one,
+// from the first of sources to annotate it.
+func keyOwner(key string, sources []*tomlsrc.Source) string {
+	for _, src := range sources {
+		if owner, ok := src.Inherited(key, "owner"); ok {
+			return owner
+		}
+	}
+	return ""
+}
+
+// redactedValue is the value of a secret in diffs, printed unquoted.
+type redactedValue string
+
+// maskSecrets replaces the values of secrets in diffs, and in the tables
+// they hold, with generator.Redacted.
+func maskSecrets(diffs []Diff, sources []*tomlsrc.Source) {
+	for i := range diffs {
+		if diffs[i].Value1 != nil {
+			diffs[i].Value1 = maskValue(diffs[i].Key, diffs[i].Value1, sources)
+		}
+		if diffs[i].Value2 != nil {
+			diffs[i].Value2 = maskValue(diffs[i].Key, diffs[i].Value2, sources)
+		}
+	}
+}
+
+// maskValue returns v, the value of key, with the values of secrets masked.
+func maskValue(key string, v any, sources []*tomlsrc.Source) any {
+	if isSecret(key, sources) {
+		return redactedValue(generator.Redacted)
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		masked := make(map[string]any, len(val))
+		for k, item := range val {
+			masked[k] = maskValue(key+"."+k, item, sources)
+		}
+		return masked
+	case []map[string]any:
+		masked := make([]map[string]any, len(val))
+		for i, item := range val {
+			masked[i] = maskValue(key, item, sources).(map[string]any)
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(val))
+		for i, item := range val {
+			masked[i] = maskValue(key, item, sources)
+		}
+		return masked
+	}
+	return v
+}
+
+// isSecret reports whether key is a secret as generated code judges it,
+// following the cfgx:secret or cfgx:encrypted annotation of the first of
+// sources to have one.
+func isSecret(key string, sources []*tomlsrc.Source) bool {
+	for _, src := range sources {
+		_, secret := src.Annotation(key, "secret")
+		_, encrypted := src.Annotation(key, "encrypted")
+		if secret || encrypted {
+			return generator.IsSecret(src, key)
+		}
+	}
+	return generator.IsSecret(nil, key)
+}
+
+// ownerSuffix formats a diff's owner for text output.
+func ownerSuffix(diff Diff) string {
+	if diff.Owner == "" {
+		return ""
+	}
+	return fmt.Sprintf("     [%s]", i18n.T(i18n.DiffOwner, diff.Owner))
 }
 
 // computeDiffs recursively compares two maps and returns differences
@@ -184,58 +317,50 @@ func deepEqual(v1, v2 any) bool {
 // outputText outputs differences in human-readable text format
 func outputText(diffs []Diff, file1, file2 string) {
 	if len(diffs) == 0 {
-		fmt.Println("No differences found.")
+		fmt.Println(i18n.T(i18n.DiffNone))
 		return
 	}
 
-	fmt.Printf("Differences between %s and %s:\n\n", file1, file2)
+	fmt.Printf("%s\n\n", i18n.T(i18n.DiffHeader, file1, file2))
+	format1, format2 := fileFormatter(file1), fileFormatter(file2)
 
 	for _, diff := range diffs {
 		switch diff.Type {
 		case DiffChanged:
 			if keysOnly {
-				fmt.Printf("  ~ %s\n", diff.Key)
+				fmt.Printf("  ~ %s%s\n", diff.Key, ownerSuffix(diff))
 			} else {
-				fmt.Printf("  %s\n", diff.Key)
-				fmt.Printf("    - %s     (%s)\n", formatValue(diff.Value1), file1)
-				fmt.Printf("    + %s     (%s)\n", formatValue(diff.Value2), file2)
+				fmt.Printf("  %s%s\n", diff.Key, ownerSuffix(diff))
+				fmt.Printf("    - %s     (%s)\n", format1.Value(diff.Value1), file1)
+				fmt.Printf("    + %s     (%s)\n", format2.Value(diff.Value2), file2)
 				fmt.Println()
 			}
 		case DiffAdded:
 			if keysOnly {
-				fmt.Printf("  + %s\n", diff.Key)
+				fmt.Printf("  + %s%s\n", diff.Key, ownerSuffix(diff))
 			} else {
-				fmt.Printf("  + %s = %s     (only in %s)\n", diff.Key, formatValue(diff.Value2), file2)
+				fmt.Printf("  + %s = %s     (%s)%s\n", diff.Key, format2.Value(diff.Value2), i18n.T(i18n.DiffOnlyIn, file2), ownerSuffix(diff))
 			}
 		case DiffRemoved:
 			if keysOnly {
-				fmt.Printf("  - %s\n", diff.Key)
+				fmt.Printf("  - %s%s\n", diff.Key, ownerSuffix(diff))
 			} else {
-				fmt.Printf("  - %s = %s     (only in %s)\n", diff.Key, formatValue(diff.Value1), file1)
+				fmt.Printf("  - %s = %s     (%s)%s\n", diff.Key, format1.Value(diff.Value1), i18n.T(i18n.DiffOnlyIn, file1), ownerSuffix(diff))
 			}
 		}
 	}
 }
 
-// formatValue formats a value for display
-func formatValue(v any) string {
-	switch val := v.(type) {
-	case string:
-		// Quote strings
-		return fmt.Sprintf(`"%s"`, val)
-	case []any:
-		// Format arrays
-		parts := make([]string, len(val))
-		for i, item := range val {
-			parts[i] = formatValue(item)
+// fileFormatter returns the formatter of the values of a config file, which
+// reads file: references relative to the file.
+func fileFormatter(file string) present.Formatter {
+	dir := filepath.Dir(file)
+	return present.Formatter{ReadFile: func(name string) ([]byte, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
 		}
-		return "[" + strings.Join(parts, ", ") + "]"
-	case map[string]any:
-		// For nested maps, just show it's a table
-		return "{...}"
-	default:
-		return fmt.Sprintf("%v", val)
-	}
+		return os.ReadFile(name)
+	}}
 }
 
 // outputJSON outputs differences in JSON format
@@ -254,3 +379,132 @@ func outputJSON(diffs []Diff, file1, file2 string) {
 		os.Exit(1)
 	}
 }
+
+// computeOverlay returns the minimal set of keys from data2 that must be layered
+// on top of data1 to reproduce data2. Tables present in both files are merged
+// recursively, so only the differing leaves are kept.
+func computeOverlay(data1, data2 map[string]any) map[string]any {
+	overlay := make(map[string]any)
+
+	for key, val2 := range data2 {
+		val1, exists := data1[key]
+		if !exists {
+			overlay[key] = val2
+			continue
+		}
+
+		map1, isMap1 := val1.(map[string]any)
+		map2, isMap2 := val2.(map[string]any)
+		if isMap1 && isMap2 {
+			if nested := computeOverlay(map1, map2); len(nested) > 0 {
+				overlay[key] = nested
+			}
+			continue
+		}
+
+		if !deepEqual(val1, val2) {
+			overlay[key] = val2
+		}
+	}
+
+	return overlay
+}
+
+// omitSecrets deletes from overlay the values of secrets, and of arrays and
+// tables holding secrets, and returns their keys, sorted.
+func omitSecrets(overlay map[string]any, prefix string, sources []*tomlsrc.Source) []string {
+	keys := make([]string, 0, len(overlay))
+	for k := range overlay {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var secrets []string
+	for _, k := range keys {
+		key := prefix + k
+		if table, ok := overlay[k].(map[string]any); ok && !isSecret(key, sources) {
+			secrets = append(secrets, omitSecrets(table, key+".", sources)...)
+			if len(table) == 0 {
+				delete(overlay, k)
+			}
+			continue
+		}
+		if holdsSecret(key, overlay[k], sources) {
+			delete(overlay, k)
+			secrets = append(secrets, key)
+		}
+	}
+	return secrets
+}
+
+// holdsSecret reports whether v, the value of key, is or holds a secret, as
+// maskValue finds them.
+func holdsSecret(key string, v any, sources []*tomlsrc.Source) bool {
+	if isSecret(key, sources) {
+		return true
+	}
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if holdsSecret(key+"."+k, item, sources) {
+				return true
+			}
+		}
+	case []map[string]any:
+		for _, item := range val {
+			if holdsSecret(key, item, sources) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if holdsSecret(key, item, sources) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outputOverlay outputs a TOML overlay which, applied on top of file1, produces file2.
+// Keys that only exist in file1 cannot be expressed in an overlay, so they are
+// listed as comments for the reader to handle manually. Unless sources is nil,
+// the secrets it annotates, and those named like credentials, are left out
+// and listed the same way.
+func outputOverlay(data1, data2 map[string]any, diffs []Diff, file1, file2 string, sources []*tomlsrc.Source) {
+	fmt.Printf("# %s\n", i18n.T(i18n.DiffOverlayHeader, file1, file2))
+
+	var removed []string
+	for _, diff := range diffs {
+		if diff.Type == DiffRemoved {
+			removed = append(removed, diff.Key)
+		}
+	}
+	if len(removed) > 0 {
+		fmt.Printf("#\n# %s\n", i18n.T(i18n.DiffOverlayRemoved, file1))
+		for _, key := range removed {
+			fmt.Printf("#   %s\n", key)
+		}
+	}
+
+	overlay := computeOverlay(data1, data2)
+	if sources != nil {
+		if secrets := omitSecrets(overlay, "", sources); len(secrets) > 0 {
+			fmt.Printf("#\n# %s\n", i18n.T(i18n.DiffOverlaySecrets))
+			for _, key := range secrets {
+				fmt.Printf("#   %s\n", key)
+			}
+		}
+	}
+	if len(overlay) == 0 {
+		return
+	}
+
+	fmt.Println()
+	encoder := toml.NewEncoder(os.Stdout)
+	encoder.Indent = ""
+	if err := encoder.Encode(overlay); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding TOML: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+var (
+	encryptCipher string
+	encryptKey    string
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt a value for a cfgx:encrypted key",
+	Long: `Encrypt the value read from standard input, without its trailing newline,
+and print it in the form keys annotated cfgx:encrypted hold:
+
+  <cipher>:<key ID>:<base64 ciphertext>
+
+The command knows the built-in "aes" cipher only: AES-GCM with the
+base64-encoded 16, 24 or 32 byte key held in the environment variable named
+by --key. Values for KMS-backed ciphers are encrypted with cfgx.Encrypt by a
+program that registers them with cfgx.RegisterCipher.
+
+Static and hybrid mode decrypt the values at generation time, so the key must
+be available to cfgx generate; getter and loader mode decrypt them at runtime.`,
+	Example: `  # Encrypt a database password with the key in $APP_KEY
+  printf '%s' "$DB_PASSWORD" | cfgx encrypt --key APP_KEY
+
+  # Then, in config.toml:
+  #   password = "aes:APP_KEY:..." # cfgx:encrypted`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if encryptKey == "" {
+			return fmt.Errorf("--key flag is required")
+		}
+		plaintext, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read standard input: %w", err)
+		}
+		plaintext = bytes.TrimSuffix(bytes.TrimSuffix(plaintext, []byte("\n")), []byte("\r"))
+
+		value, err := cfgx.Encrypt(context.Background(), encryptCipher, encryptKey, plaintext)
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	encryptCmd.Flags().StringVar(&encryptCipher, "cipher", "aes", "name of the cipher to encrypt with")
+	encryptCmd.Flags().StringVar(&encryptKey, "key", "", "key ID: for the aes cipher, the environment variable holding the key (required)")
+}
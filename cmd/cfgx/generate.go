@@ -1,34 +1,175 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"github.com/gomantics/cfgx"
+	"github.com/gomantics/cfgx/internal/pkgutil"
+)
+
+// testOutputFile is the file name used for --test-output.
+const testOutputFile = "config_fixtures_test.go"
+
+var (
+	inputFiles   []string
+	appendArrays bool
+	testOutput   string
+	externalTest bool
+	outInject    string
+	region       string
+	changelog    string
+	compatReport bool
 )
 
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate type-safe Go code from TOML config",
-	Long:  `Generate type-safe Go code from TOML configuration files.`,
+	Long: `Generate type-safe Go code from TOML configuration files.
+
+Settings not given as flags are read from the [generate] table of a .cfgx.toml
+(or generate: in a cfgx.yaml) found in the current directory or a parent, up
+to the repository root: in, out, pkg, mode, env, env_prefix, env_strictness,
+tags, int_type, style, initialisms and precedence. Paths in it are relative to the file. Flags override it.`,
 	Example: `  # Generate config code
   cfgx generate --in config.toml --out config/config.go
 
+  # Generate with the settings of the project's .cfgx.toml
+  cfgx generate
+
+  # Generate from a JSON config exported by another tool
+  cfgx generate --in config.json --out config/config.go
+
+  # Merge production overrides over a base config
+  cfgx generate --in base.toml --in overrides/prod.toml --out config/config.go
+
+  # Merge TOML fragments concatenated by a deployment tool
+  cfgx generate --in rendered.toml --out config/config.go --merge-duplicates last
+
   # Custom package
   cfgx generate --in app.toml --out pkg/appcfg/config.go --pkg appcfg
 
   # Disable environment variable overrides
-  cfgx generate --in config.toml --out config.go --no-env`,
+  cfgx generate --in config.toml --out config.go --no-env
+
+  # Getters that read env vars once at startup, for hot paths
+  cfgx generate --in config.toml --out config.go --mode getter --env-at-init
+
+  # Baked values with runtime overrides applied by LoadOverrides
+  cfgx generate --in config.toml --out config.go --mode hybrid
+
+  # Baked defaults plus Load, LoadFS and LoadEmbedded reading TOML, then
+  # CONFIG_* env vars, at runtime
+  cfgx generate --in config.toml --out config.go --mode loader
+
+  # Loader mode with a Resolve layering env vars and flags over the file
+  cfgx generate --in config.toml --out config.go --mode loader --precedence file,env,flag
+
+  # Generate PrintSummary to log the effective config at startup
+  cfgx generate --in config.toml --out config.go --summary
+
+  # List the env vars getters read, for operators, in a comment
+  cfgx generate --in config.toml --out config.go --mode getter --env-docs
+
+  # Keep struct fields in the order keys are written in the TOML
+  cfgx generate --in config.toml --out config.go --preserve-order
+
+  # Tag fields to marshal the config back out as JSON or YAML
+  cfgx generate --in config.toml --out config.go --tags json,yaml
+
+  # Generate integers as int rather than int64
+  cfgx generate --in config.toml --out config.go --int-type int
+
+  # Generate a Config type and a Default function instead of package variables
+  cfgx generate --in config.toml --out config.go --style compact
+
+  # Name api_url APIURL rather than ApiUrl
+  cfgx generate --in config.toml --out config.go --initialisms default
+
+  # Rename the field of one key
+  cfgx generate --in config.toml --out config.go --name cluster.k8s_namespace=K8sNamespace
+
+  # Find out which keys make generation slow
+  cfgx generate --in config.toml --out config.go --stats
+
+  # Generate fixtures with fresh uuid:, random: and now: values
+  cfgx generate --in fixtures.toml --out fixtures/config.go --dynamic-values
+
+  # Generate test-only fixture config into ./store/config_fixtures_test.go
+  cfgx generate --in testdata/fixtures.toml --test-output ./store
+
+  # Same, for the external store_test package
+  cfgx generate --in testdata/fixtures.toml --test-output ./store --external-test
+
+  # Replace the "// cfgx:begin config" ... "// cfgx:end" region of main.go
+  cfgx generate --in config.toml --out-inject main.go --region config
+
+  # Enforce organization rules such as minimum pool sizes in production
+  cfgx generate --in config.toml --out config/config.go --policy org-policy.toml
+
+  # Record changed defaults in CHANGES.md
+  cfgx generate --in config.toml --out config/config.go --changelog CHANGES.md
+
+  # Report the API changes of switching to getter mode
+  cfgx generate --in config.toml --out config/config.go --mode getter --compat-report
+
+  # Report errors as file:line:col for editor problem matchers
+  cfgx generate --in config.toml --out config.go --output-format gcc`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyToolConfig(cmd); err != nil {
+			return err
+		}
+		if len(inputFiles) == 0 {
+			return fmt.Errorf("--in flag is required")
+		}
+		inputFile = inputFiles[0]
+
+		// Fixture configs go to a _test.go file in the target package
+		if testOutput != "" {
+			if outputFile != "" {
+				return fmt.Errorf("--out and --test-output cannot be used together")
+			}
+			outputFile = filepath.Join(testOutput, testOutputFile)
+		}
+		// Injected code replaces a region of an existing file
+		if outInject != "" {
+			if outputFile != "" || testOutput != "" {
+				return fmt.Errorf("--out-inject cannot be used with --out or --test-output")
+			}
+			outputFile = outInject
+		}
+
+		if externalTest {
+			if testOutput == "" {
+				return fmt.Errorf("--external-test requires --test-output")
+			}
+			if packageName == "" {
+				packageName = pkgutil.InferName(outputFile)
+			}
+			packageName += "_test"
+		}
+
 		// Require -out flag
 		if outputFile == "" {
-			return fmt.Errorf("--out flag is required")
+			return fmt.Errorf("--out, --test-output or --out-inject flag is required")
+		}
+
+		if err := validateErrFormat(); err != nil {
+			return err
+		}
+
+		if err := validateDuplicates(); err != nil {
+			return err
 		}
 
 		// Validate mode
-		if mode != "static" && mode != "getter" {
-			return fmt.Errorf("invalid --mode value %q: must be 'static' or 'getter'", mode)
+		if mode != "static" && mode != "getter" && mode != "hybrid" && mode != "loader" {
+			return fmt.Errorf("invalid --mode value %q: must be 'static', 'getter', 'hybrid' or 'loader'", mode)
 		}
 
 		// Parse max file size
@@ -36,22 +177,93 @@ var generateCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("invalid --max-file-size: %w", err)
 		}
+		embedThreshold, err := parseFileSize(embedSize)
+		if err != nil {
+			return fmt.Errorf("invalid --embed-threshold: %w", err)
+		}
+		nameOverrides, err := parseNames(names)
+		if err != nil {
+			return err
+		}
 
 		// Use the public API
 		opts := &cfgx.GenerateOptions{
-			InputFile:   inputFile,
-			OutputFile:  outputFile,
-			PackageName: packageName,
-			EnableEnv:   !noEnv,
-			MaxFileSize: maxFileSizeBytes,
-			Mode:        mode,
+			InputFiles:            inputFiles,
+			AppendArrays:          appendArrays,
+			Format:                inputFormat,
+			MergeDuplicates:       duplicates,
+			OutputFile:            outputFile,
+			PackageName:           packageName,
+			EnableEnv:             !noEnv,
+			EnvPrefix:             envPrefix,
+			EnvOverrideStrictness: envStrict,
+			MaxFileSize:           maxFileSizeBytes,
+			Mode:                  mode,
+			CRLF:                  crlf,
+			DynamicValues:         dynamic,
+			TrackUsage:            trackUsage,
+			EnvAtInit:             envAtInit,
+			Summary:               summary,
+			EmitEnvDocs:           envDocs,
+			PreserveOrder:         preserveOrder,
+			EmbedThreshold:        embedThreshold,
+			StructTags:            parseList(tags),
+			IntType:               intType,
+			Style:                 style,
+			Initialisms:           parseList(initialisms),
+			NameOverrides:         nameOverrides,
+			Precedence:            parseList(precedence),
+			Policies:              policies,
+		}
+		if outInject != "" {
+			opts.Region = region
+		}
+		if showStats {
+			opts.Stats = &cfgx.Stats{}
+		}
+
+		profiling, closeProfiles, err := openProfiling()
+		if err != nil {
+			return err
+		}
+		defer closeProfiles()
+		opts.Profiling = profiling
+
+		// Keep the previous output to compare its defaults and API against
+		var previous []byte
+		if changelog != "" || compatReport {
+			if previous, err = os.ReadFile(outputFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to read previous output: %w", err)
+			}
 		}
 
 		if err := cfgx.GenerateFromFile(opts); err != nil {
+			if errFormat == "gcc" {
+				fmt.Fprintln(os.Stderr, formatError(err))
+				os.Exit(1)
+			}
 			return err
 		}
 
 		fmt.Printf("Generated %s\n", outputFile)
+		if changelog != "" {
+			generated, err := os.ReadFile(outputFile)
+			if err != nil {
+				return err
+			}
+			src := &changelogSources{sources: scanSources(inputFiles...), names: nameOverrides}
+			if err := appendChangelog(changelog, outputFile, previous, generated, src); err != nil {
+				return err
+			}
+		}
+		if compatReport {
+			if err := reportCompat(outputFile, previous); err != nil {
+				return err
+			}
+		}
+		if showStats {
+			printStats(os.Stderr, opts.Stats)
+		}
 		return nil
 	},
 	SilenceUsage: true,
@@ -59,12 +271,45 @@ var generateCmd = &cobra.Command{
 
 func init() {
 	// Generate command flags
-	generateCmd.Flags().StringVarP(&inputFile, "in", "i", "config.toml", "input TOML file")
-	generateCmd.Flags().StringVarP(&outputFile, "out", "o", "", "output Go file (required)")
+	generateCmd.Flags().StringArrayVarP(&inputFiles, "in", "i", []string{"config.toml"}, "input TOML or JSON file; repeat to deep-merge later files over earlier ones")
+	generateCmd.Flags().BoolVar(&appendArrays, "append-arrays", false, "when merging several --in files, append arrays instead of replacing them")
+	generateCmd.Flags().StringVar(&inputFormat, "input-format", "", "input format: 'toml' or 'json' (default: detected from the file extension)")
+	generateCmd.Flags().StringVar(&duplicates, "merge-duplicates", "", "accept concatenated TOML with repeated tables and keys: 'last' (later values win) or 'error' (fail on keys set twice)")
+	generateCmd.Flags().StringVarP(&outputFile, "out", "o", "", "output Go file (required unless --test-output is set)")
+	generateCmd.Flags().StringVar(&testOutput, "test-output", "", "write the output to "+testOutputFile+" in package `dir`, so it is only compiled into tests")
+	generateCmd.Flags().BoolVar(&externalTest, "external-test", false, "with --test-output, use the external <pkg>_test package")
+	generateCmd.Flags().StringVar(&outInject, "out-inject", "", "inject the generated code into a region of an existing Go `file` instead of writing a whole file")
+	generateCmd.Flags().StringVar(&region, "region", "config", "with --out-inject, the region to replace, marked by '// cfgx:begin <region>' and '// cfgx:end' lines")
 	generateCmd.Flags().StringVarP(&packageName, "pkg", "p", "", "package name (default: inferred from output path or 'config')")
 	generateCmd.Flags().BoolVar(&noEnv, "no-env", false, "disable environment variable overrides")
+	generateCmd.Flags().StringVar(&envPrefix, "env-prefix", "CONFIG", "prefix of environment variable override names, as in CONFIG_SERVER_PORT")
+	generateCmd.Flags().StringVar(&envStrict, "env-strictness", "error", "what to do with env var overrides that do not parse: 'error' fails, 'warn' keeps the file value and logs a warning")
+	generateCmd.Flags().BoolVar(&noToolConfig, "no-tool-config", false, "ignore the .cfgx.toml or cfgx.yaml settings file of the project")
 	generateCmd.Flags().StringVar(&maxFileSize, "max-file-size", "1MB", "maximum file size for file: references (e.g., 10MB, 1GB, 512KB)")
-	generateCmd.Flags().StringVar(&mode, "mode", "static", "generation mode: 'static' (values baked at build time) or 'getter' (runtime env var overrides)")
+	generateCmd.Flags().StringVar(&embedSize, "embed-threshold", "", "embed file: references larger than this size with go:embed instead of byte literals (e.g., 64KB)")
+	generateCmd.Flags().StringVar(&mode, "mode", "static", "generation mode: 'static' (values baked at build time), 'getter' (runtime env var overrides) 'hybrid' (baked values with a LoadOverrides function) or 'loader' (baked defaults with Load functions reading TOML at runtime)")
+
+	generateCmd.Flags().BoolVar(&crlf, "crlf", false, "write the generated file with CRLF line endings (default: LF)")
+	generateCmd.Flags().BoolVar(&trackUsage, "track-usage", false, "in getter mode, count reads of each key and generate a Usage function")
+	generateCmd.Flags().BoolVar(&envAtInit, "env-at-init", false, "in getter mode, read env vars once at package init so getters never allocate")
+	generateCmd.Flags().StringVar(&tags, "tags", "", "comma-separated struct tags to write on generated fields with the TOML key names (e.g., json,yaml)")
+	generateCmd.Flags().StringVar(&intType, "int-type", "", "Go type of generated integers: 'int64' (default) or 'int'")
+	generateCmd.Flags().StringVar(&style, "style", "", "in static mode, 'vars' (default) for a package variable per top-level key or 'compact' for a single Default function")
+	generateCmd.Flags().StringVar(&initialisms, "initialisms", "", "comma-separated initialisms to write in upper case in identifiers, 'default' for the Go conventional ones (e.g., default,SKU)")
+	generateCmd.Flags().StringArrayVar(&names, "name", nil, "`key=Name` giving the Go name of the field, variable or getter of a dotted key path (repeatable)")
+	generateCmd.Flags().StringVar(&precedence, "precedence", "", "in loader mode, comma-separated runtime layers of the generated Resolve, lowest first: 'file', 'env' and 'flag' (default: file,env, or file with --no-env)")
+	generateCmd.Flags().BoolVar(&summary, "summary", false, "generate a PrintSummary function that prints the effective config with secrets redacted")
+	generateCmd.Flags().BoolVar(&envDocs, "env-docs", false, "list the environment variables the generated code reads, with their types and defaults, in a comment")
+	generateCmd.Flags().BoolVar(&preserveOrder, "preserve-order", false, "generate struct fields in the order keys are defined in the TOML rather than alphabetically")
+	generateCmd.Flags().BoolVar(&dynamic, "dynamic-values", false, "resolve uuid:, random: and now: values at generation time (for test fixtures)")
+	generateCmd.Flags().StringArrayVar(&policies, "policy", nil, "TOML policy `file` of CEL rules the effective config must satisfy (repeatable)")
+	generateCmd.Flags().StringVar(&changelog, "changelog", "", "append an entry listing changed defaults to this Markdown `file` when regeneration changes them")
+	generateCmd.Flags().BoolVar(&compatReport, "compat-report", false, "report the changes to the exported API of the output since the previous generation, such as fields turned into methods by a --mode switch")
+	generateCmd.Flags().StringVar(&errFormat, "output-format", "text", "error output format: 'text' or 'gcc' (file:line:col: message)")
+
+	generateCmd.Flags().BoolVar(&showStats, "stats", false, "print timing and size statistics for the run")
 
-	generateCmd.MarkFlagRequired("out")
+	generateCmd.Flags().StringVar(&cpuProfile, "cpuprofile", "", "write a CPU profile of the run to `file`")
+	generateCmd.Flags().StringVar(&memProfile, "memprofile", "", "write a memory profile after the run to `file`")
+	generateCmd.Flags().StringVar(&traceFile, "trace", "", "write an execution trace of the run to `file`")
 }
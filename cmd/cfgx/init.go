@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+var (
+	initPkg        string
+	initMode       string
+	initToolConfig bool
+	initForce      bool
+)
+
+// starterConfig is the config.toml written by init, formatted with the
+// application name, the package name and the application name again.
+const starterConfig = `# Configuration of %s.
+#
+# cfgx generates typed Go code from this file into the %s package: run
+# "go generate ./..." after editing it.
+
+name = %q
+
+[server]
+addr = ":8080"
+read_timeout = "5s"
+write_timeout = "10s"
+
+[log]
+level = "info"
+`
+
+var initCmd = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Scaffold a config file and a generated config package",
+	Long: `Create a config package in the project directory (default: the current
+directory) holding a starter config.toml and a gen.go that runs cfgx on it
+with //go:generate. The package is generated right away so the project builds.
+
+With --tool-config, a .cfgx.toml recording the generate settings is written
+too. Existing files are left alone unless --force is given.`,
+	Example: `  # Scaffold the current project
+  cfgx init
+
+  # Scaffold ./myapp with getter mode and a .cfgx.toml
+  cfgx init myapp --mode getter --tool-config`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		if initMode != "static" && initMode != "getter" && initMode != "hybrid" && initMode != "loader" {
+			return fmt.Errorf("invalid --mode value %q: must be 'static', 'getter', 'hybrid' or 'loader'", initMode)
+		}
+		if !token.IsIdentifier(initPkg) {
+			return fmt.Errorf("invalid --pkg value %q: must be a Go identifier", initPkg)
+		}
+
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		name := filepath.Base(abs)
+
+		pkgDir := filepath.Join(dir, initPkg)
+		configFile := filepath.Join(pkgDir, "config.toml")
+		outputFile := filepath.Join(pkgDir, "config.go")
+
+		generateArgs := fmt.Sprintf("generate --in config.toml --out config.go --pkg %s", initPkg)
+		if initMode != "static" {
+			generateArgs += " --mode " + initMode
+		}
+		files := []struct {
+			path    string
+			content string
+		}{
+			{configFile, fmt.Sprintf(starterConfig, name, initPkg, name)},
+			{filepath.Join(pkgDir, "gen.go"), fmt.Sprintf("// Package %s holds the configuration generated by cfgx from config.toml.\npackage %s\n\n//go:generate go run github.com/gomantics/cfgx/cmd/cfgx@%s %s\n",
+				initPkg, initPkg, cfgxVersion(), generateArgs)},
+		}
+		if initToolConfig {
+			files = append(files, struct {
+				path    string
+				content string
+			}{filepath.Join(dir, ".cfgx.toml"), fmt.Sprintf("# cfgx settings of this project.\n\n[generate]\nin = [%q]\nout = %q\npkg = %q\nmode = %q\n",
+				initPkg+"/config.toml", initPkg+"/config.go", initPkg, initMode)})
+		}
+
+		if !initForce {
+			for _, f := range files {
+				if _, err := os.Stat(f.path); err == nil {
+					return fmt.Errorf("%s already exists (use --force to overwrite)", f.path)
+				} else if !errors.Is(err, fs.ErrNotExist) {
+					return err
+				}
+			}
+		}
+
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", pkgDir, err)
+		}
+		for _, f := range files {
+			if err := os.WriteFile(f.path, []byte(f.content), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", f.path, err)
+			}
+			fmt.Printf("Created %s\n", f.path)
+		}
+
+		err = cfgx.GenerateFromFile(&cfgx.GenerateOptions{
+			InputFile:   configFile,
+			OutputFile:  outputFile,
+			PackageName: initPkg,
+			EnableEnv:   true,
+			Mode:        initMode,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Generated %s\n", outputFile)
+		if initMode == "loader" {
+			fmt.Println("Loader mode needs a TOML parser: run \"go get github.com/BurntSushi/toml\".")
+		}
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	initCmd.Flags().StringVarP(&initPkg, "pkg", "p", "config", "name of the generated config package and its directory")
+	initCmd.Flags().StringVar(&initMode, "mode", "static", "generation mode: 'static', 'getter', 'hybrid' or 'loader'")
+	initCmd.Flags().BoolVar(&initToolConfig, "tool-config", false, "also write a .cfgx.toml with the generate settings")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "overwrite existing files")
+}
+
+// cfgxVersion returns the version of cfgx to pin in go:generate directives.
+func cfgxVersion() string {
+	if version == "dev" || strings.Contains(version, "dirty") {
+		return "latest"
+	}
+	return version
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx/internal/i18n"
+	"github.com/gomantics/cfgx/internal/lint"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+var (
+	lintOverlays []string
+	lintVersion  string
+	lintFormat   string
+	lintDisable  []string
+	lintRules    bool
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check TOML config for suspicious settings",
+	Long: `Check a TOML configuration file, and optionally the overlays layered on top
+of it, for settings that are valid but likely mistakes.
+
+Issues are printed as file:line:col so editors and CI can annotate them.
+The command exits with status 1 if any issue is found.
+
+Rules are listed with --rules and can be turned off with --disable, or for
+a single key or table with a cfgx:nolint annotation naming them, as in
+cfgx:nolint=secret-literal. A bare cfgx:nolint turns off every rule.`,
+	Example: `  # Lint a config file
+  cfgx lint --in config.toml
+
+  # Warn about experimental keys set in the prod overlay and keys past removal
+  cfgx lint --in config.toml --overlay config.prod.toml --version 2.1.0
+
+  # Find overrides that repeat a value or match no key along the merge chain
+  cfgx lint --in config.toml --overlay config.staging.toml --overlay config.prod.toml
+
+  # Skip the rules flagging repeated values and literal secrets
+  cfgx lint --in config.toml --disable duplicate-value,secret-literal`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lintRules {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, rule := range lint.Rules {
+				fmt.Fprintf(w, "%s\t%s\n", rule.Name, rule.Description)
+			}
+			return w.Flush()
+		}
+		if lintFormat != "text" && lintFormat != "json" {
+			return fmt.Errorf("invalid --format value %q: must be 'text' or 'json'", lintFormat)
+		}
+
+		base, err := loadLintFile(inputFile)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range lintDisable {
+			if _, ok := lint.Lookup(name); !ok {
+				return fmt.Errorf("unknown lint rule %q: see cfgx lint --rules", name)
+			}
+		}
+
+		ctx := &lint.Context{Base: base, Version: lintVersion, Disable: lintDisable}
+		for _, path := range lintOverlays {
+			overlay, err := loadLintFile(path)
+			if err != nil {
+				return err
+			}
+			ctx.Overlays = append(ctx.Overlays, overlay)
+		}
+
+		issues := lint.Run(ctx)
+
+		if lintFormat == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(issues); err != nil {
+				return err
+			}
+		} else {
+			for _, issue := range issues {
+				fmt.Printf("%s:%d:%d: %s: %s (%s)\n", issue.File, max(issue.Pos.Line, 1), max(issue.Pos.Column, 1),
+					issue.Severity, issue.Message, issue.Rule)
+			}
+		}
+
+		if len(issues) > 0 {
+			return errors.New(i18n.T(i18n.LintSummary, len(issues)))
+		}
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	lintCmd.Flags().StringVarP(&inputFile, "in", "i", "config.toml", "input TOML file")
+	lintCmd.Flags().StringArrayVar(&lintOverlays, "overlay", nil, "overlay TOML file layered on top of the input (repeatable)")
+	lintCmd.Flags().StringVar(&lintVersion, "version", "", "current project version, for cfgx:removed-in checks")
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Output format: text or json")
+	lintCmd.Flags().StringSliceVar(&lintDisable, "disable", nil, "rules not to run, comma-separated (repeatable)")
+	lintCmd.Flags().BoolVar(&lintRules, "rules", false, "list the rules and exit")
+}
+
+// loadLintFile parses a TOML file and scans its source for annotations.
+func loadLintFile(path string) (*lint.File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var data map[string]any
+	if err := toml.Unmarshal(src, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return &lint.File{Path: path, Data: data, Source: tomlsrc.Scan(src)}, nil
+}
@@ -8,11 +8,16 @@ import (
 	"runtime/debug"
 
 	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx/internal/i18n"
 )
 
 var (
 	// version is set via ldflags at build time
 	version = "dev"
+
+	lang         string
+	messagesFile string
 )
 
 func main() {
@@ -27,6 +32,15 @@ var rootCmd = &cobra.Command{
 	Long: `cfgx generates type-safe Go code from TOML configuration files.
 
 It creates strongly-typed structs with values from the TOML file, with optional environment variable overrides.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if messagesFile != "" {
+			if err := i18n.LoadFile(messagesFile); err != nil {
+				return err
+			}
+		}
+		i18n.SetLocale(lang)
+		return nil
+	},
 }
 
 func init() {
@@ -41,12 +55,42 @@ func init() {
 		}
 	}
 
+	rootCmd.PersistentFlags().StringVar(&lang, "lang", "", "language for messages (default: from CFGX_LANG or LANG)")
+	rootCmd.PersistentFlags().StringVar(&messagesFile, "messages", "", "TOML message catalog to load (see 'cfgx messages')")
+
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(migrateGenCmd)
+	rootCmd.AddCommand(checkCmd)
+	rootCmd.AddCommand(apidiffCmd)
+	rootCmd.AddCommand(targetsCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(encryptCmd)
+	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(schemaCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(messagesCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
+var messagesCmd = &cobra.Command{
+	Use:   "messages",
+	Short: "Print the message catalog template for translators",
+	Long: `Print every translatable message key with its English text as a TOML message
+catalog. Translate the values, set the locale and load the file with --messages.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(`locale = "xx"`)
+		fmt.Println()
+		fmt.Println("[messages]")
+		for _, key := range i18n.Keys() {
+			fmt.Printf("%q = %q\n", key, i18n.English(key))
+		}
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
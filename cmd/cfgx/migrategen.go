@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx/internal/migrate"
+)
+
+var (
+	migrateOut     string
+	migratePkg     string
+	migrateFunc    string
+	migrateRenames []string
+)
+
+var migrateGenCmd = &cobra.Command{
+	Use:   "migrate-gen <old.toml> <new.toml>",
+	Short: "Generate a Go function migrating config files from an old format",
+	Long: `Compare two versions of a TOML configuration and generate a Go function that
+rewrites files in the old format to the new one, so that old files keep
+loading during a transition window.
+
+A key removed from the old file and a key added to the new file with the same
+value are taken to be the same key under a new path. Tables that moved as a
+whole become a single rename. Renames that cannot be detected, because the
+value changed too, are given with --rename.
+
+The function works on the map decoded from a config file, before it is loaded,
+and returns a deprecation warning for every old key found. Old keys with no
+counterpart are listed in a TODO comment to handle by hand.`,
+	Example: `  # Print a migration from v1 to v2 of the config
+  cfgx migrate-gen config.v1.toml config.v2.toml
+
+  # Write it to the config package, naming the moved keys the values changed for
+  cfgx migrate-gen config.v1.toml config.v2.toml --out config/migrate.go \
+    --rename server.timeout_ms=server.timeout`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		old, err := parseTomlFile(args[0])
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", args[0], err)
+		}
+		new, err := parseTomlFile(args[1])
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", args[1], err)
+		}
+
+		var explicit []migrate.Rename
+		for _, r := range migrateRenames {
+			from, to, ok := strings.Cut(r, "=")
+			if !ok || from == "" || to == "" {
+				return fmt.Errorf("invalid --rename value %q: expected old.key=new.key", r)
+			}
+			explicit = append(explicit, migrate.Rename{From: from, To: to})
+		}
+
+		src, err := migrate.Generate(migrate.Detect(old, new, explicit), migrate.Options{
+			Package:  migratePkg,
+			FuncName: migrateFunc,
+			Source:   args[0] + " and " + args[1],
+		})
+		if err != nil {
+			return err
+		}
+
+		if migrateOut == "" {
+			_, err = os.Stdout.Write(src)
+			return err
+		}
+		if err := os.WriteFile(migrateOut, src, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", migrateOut, err)
+		}
+		fmt.Printf("Generated %s\n", migrateOut)
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	migrateGenCmd.Flags().StringVarP(&migrateOut, "out", "o", "", "output Go file (default: stdout)")
+	migrateGenCmd.Flags().StringVarP(&migratePkg, "pkg", "p", "config", "package name of the generated file")
+	migrateGenCmd.Flags().StringVar(&migrateFunc, "func", "Migrate", "name of the generated migration function")
+	migrateGenCmd.Flags().StringArrayVar(&migrateRenames, "rename", nil, "old.key=new.key rename to include even if the value changed (repeatable)")
+}
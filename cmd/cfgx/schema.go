@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+var schemaOut string
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Export a JSON Schema of a config file",
+	Long: `Derive a JSON Schema from a TOML configuration, for editors to validate config
+files with and for services not written in Go to share the format with.
+
+Values get the types generate infers for them: durations and file: references
+are strings matching a pattern, datetimes are date-time strings, arrays of
+tables are arrays of objects and cfgx:map tables are objects of any keys.
+Constraint and format annotations carry over, current values become defaults
+(except secrets), and tables reject keys they do not have.`,
+	Example: `  # Print the schema of a config file
+  cfgx schema --in config.toml
+
+  # Write it next to the config for editors to pick up
+  cfgx schema --in config.toml --out config.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateDuplicates(); err != nil {
+			return err
+		}
+
+		schema, err := cfgx.Schema(&cfgx.GenerateOptions{
+			InputFiles:      inputFiles,
+			AppendArrays:    appendArrays,
+			Format:          inputFormat,
+			MergeDuplicates: duplicates,
+		})
+		if err != nil {
+			return err
+		}
+
+		if schemaOut == "" {
+			_, err = os.Stdout.Write(schema)
+			return err
+		}
+		if err := os.WriteFile(schemaOut, schema, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", schemaOut, err)
+		}
+		fmt.Printf("Generated %s\n", schemaOut)
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	schemaCmd.Flags().StringArrayVarP(&inputFiles, "in", "i", []string{"config.toml"}, "input TOML or JSON file; repeat to deep-merge later files over earlier ones")
+	schemaCmd.Flags().BoolVar(&appendArrays, "append-arrays", false, "when merging several --in files, append arrays instead of replacing them")
+	schemaCmd.Flags().StringVar(&inputFormat, "input-format", "", "input format: 'toml' or 'json' (default: detected from the file extension)")
+	schemaCmd.Flags().StringVar(&duplicates, "merge-duplicates", "", "accept concatenated TOML with repeated tables and keys: 'last' (later values win) or 'error' (fail on keys set twice)")
+	schemaCmd.Flags().StringVarP(&schemaOut, "out", "o", "", "output JSON file (default: stdout)")
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+var serveStdio bool
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a JSON-RPC server for editor and build tool integration",
+	Long: `Run a long-lived JSON-RPC 2.0 server so editor plugins and build daemons can
+reuse a warm process instead of invoking the CLI for every change.
+
+Messages are newline-delimited JSON objects. Supported methods:
+
+  generate        Generate code for a TOML file (optionally writing the output)
+  validate        Check that a TOML file generates without errors
+  diff            Compare two TOML files
+  docs            Describe the keys of a TOML file as a JSON Schema, as cfgx
+                  schema does, for hover documentation and completion
+  $/cancelRequest Cancel an in-flight request by id
+  shutdown        Stop the server after in-flight requests finish
+
+Generate, validate and docs accept an optional "content" parameter holding
+unsaved editor contents, which is used instead of reading the input file. Diff
+redacts the values of secrets as cfgx diff does, unless "showSecrets" is set.
+
+Files read for file: references are cached for the session, and read again
+only once they change. Cancelling a request stops its work at the next stage
+and keeps generate from writing its output. A request whose id is already in
+flight is rejected; notifications cannot be cancelled.`,
+	Example: `  # Serve over stdin/stdout
+  cfgx serve --stdio
+
+  # Example request
+  {"jsonrpc":"2.0","id":1,"method":"validate","params":{"input":"config.toml"}}`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !serveStdio {
+			return fmt.Errorf("--stdio is currently the only supported transport")
+		}
+		return newRPCServer(os.Stdout).serve(os.Stdin)
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveStdio, "stdio", false, "communicate over stdin/stdout")
+}
+
+// JSON-RPC 2.0 error codes.
+const (
+	rpcParseError       = -32700
+	rpcInvalidRequest   = -32600
+	rpcMethodNotFound   = -32601
+	rpcInvalidParams    = -32602
+	rpcInternalError    = -32603
+	rpcRequestCancelled = -32800
+)
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// errCancelled is the error of a request cancelled before it completed.
+var errCancelled = &rpcError{Code: rpcRequestCancelled, Message: "request cancelled"}
+
+// generateParams are the parameters of the generate, validate and docs
+// methods.
+type generateParams struct {
+	Input       string `json:"input"`
+	Content     string `json:"content,omitempty"`
+	Output      string `json:"output,omitempty"`
+	Package     string `json:"package,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	NoEnv       bool   `json:"noEnv,omitempty"`
+	MaxFileSize string `json:"maxFileSize,omitempty"`
+	Write       bool   `json:"write,omitempty"`
+}
+
+type diffParams struct {
+	File1       string `json:"file1"`
+	File2       string `json:"file2"`
+	ShowSecrets bool   `json:"showSecrets,omitempty"`
+}
+
+type cancelParams struct {
+	ID json.RawMessage `json:"id"`
+}
+
+// rpcServer dispatches JSON-RPC requests concurrently and serializes responses.
+type rpcServer struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	// inflight holds the cancel funcs of the requests in flight by id;
+	// notifications and requests with a null id cannot be cancelled
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc
+	wg       sync.WaitGroup
+
+	// files keeps the files read for file: references across requests
+	files *cfgx.FileCache
+
+	// dispatch executes a request, s.handle unless replaced by tests
+	dispatch func(ctx context.Context, req rpcRequest) (any, *rpcError)
+}
+
+func newRPCServer(out io.Writer) *rpcServer {
+	s := &rpcServer{
+		out:      out,
+		inflight: make(map[string]context.CancelFunc),
+		files:    cfgx.NewFileCache(),
+	}
+	s.dispatch = s.handle
+	return s
+}
+
+// serve reads requests from r until EOF or a shutdown request.
+func (s *rpcServer) serve(r io.Reader) error {
+	defer s.wg.Wait()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.reply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+			continue
+		}
+
+		switch req.Method {
+		case "shutdown":
+			s.reply(req.ID, "ok", nil)
+			return nil
+		case "$/cancelRequest":
+			var params cancelParams
+			if err := json.Unmarshal(req.Params, &params); err == nil {
+				s.cancel(params.ID)
+			}
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		if !s.track(req.ID, cancel) {
+			cancel()
+			s.reply(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: fmt.Sprintf("request id %s is already in flight", req.ID)})
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer cancel()
+			defer s.untrack(req.ID)
+
+			result, rpcErr := s.dispatch(ctx, req)
+			if req.ID != nil {
+				s.reply(req.ID, result, rpcErr)
+			}
+		}()
+	}
+
+	return scanner.Err()
+}
+
+// handle executes a single request and returns its result, stopping early
+// with a cancellation error once ctx is cancelled. Work that completed, such
+// as a written output file, is reported even if ctx is cancelled afterwards.
+func (s *rpcServer) handle(ctx context.Context, req rpcRequest) (any, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: `jsonrpc must be "2.0"`}
+	}
+
+	switch req.Method {
+	case "generate", "validate":
+		var params generateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+
+		code, err := s.generate(ctx, params)
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
+		if req.Method == "validate" {
+			if err != nil {
+				result := map[string]any{"valid": false, "error": err.Error()}
+				var cfgErr *cfgx.Error
+				if errors.As(err, &cfgErr) {
+					result["line"] = cfgErr.Line
+					result["column"] = cfgErr.Column
+					result["key"] = cfgErr.Key
+				}
+				return result, nil
+			}
+			return map[string]any{"valid": true}, nil
+		}
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+
+		if params.Write {
+			if params.Output == "" {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: "output is required when write is set"}
+			}
+			if err := os.MkdirAll(filepath.Dir(params.Output), 0755); err != nil {
+				return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			if ctx.Err() != nil {
+				return nil, errCancelled
+			}
+			if err := os.WriteFile(params.Output, code, 0644); err != nil {
+				return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+			}
+			return map[string]any{"output": params.Output}, nil
+		}
+		return map[string]any{"code": string(code)}, nil
+
+	case "diff":
+		var params diffParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+
+		data1, err := parseTomlFile(params.File1)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: fmt.Sprintf("parsing %s: %v", params.File1, err)}
+		}
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
+		data2, err := parseTomlFile(params.File2)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: fmt.Sprintf("parsing %s: %v", params.File2, err)}
+		}
+
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
+		diffs := computeDiffs(data1, data2, "")
+		sources := scanSources(params.File1, params.File2)
+		annotateOwners(diffs, sources)
+		if !params.ShowSecrets {
+			maskSecrets(diffs, sources)
+		}
+		return map[string]any{"differences": diffs, "count": len(diffs)}, nil
+
+	case "docs":
+		var params generateParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		if params.Input == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "input is required"}
+		}
+
+		schema, err := cfgx.Schema(&cfgx.GenerateOptions{
+			InputFile: params.Input,
+			Source:    source(params),
+			Context:   ctx,
+		})
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
+		if err != nil {
+			return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+		}
+		return map[string]any{"schema": json.RawMessage(schema)}, nil
+
+	default:
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+}
+
+// generate generates code for the given parameters, preferring unsaved
+// content over the file on disk when provided.
+func (s *rpcServer) generate(ctx context.Context, params generateParams) ([]byte, error) {
+	if params.Input == "" {
+		return nil, fmt.Errorf("input is required")
+	}
+
+	maxFileSizeBytes, err := parseFileSize(params.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+
+	output := params.Output
+	if output == "" {
+		output = filepath.Join(filepath.Dir(params.Input), "config.go")
+	}
+	return cfgx.GenerateBytes(&cfgx.GenerateOptions{
+		InputFile:   params.Input,
+		Source:      source(params),
+		OutputFile:  output,
+		PackageName: params.Package,
+		EnableEnv:   !params.NoEnv,
+		MaxFileSize: maxFileSizeBytes,
+		Mode:        params.Mode,
+		FileCache:   s.files,
+		Context:     ctx,
+	})
+}
+
+// source returns the unsaved content of params, or nil to read the input
+// file.
+func source(params generateParams) []byte {
+	if params.Content == "" {
+		return nil
+	}
+	return []byte(params.Content)
+}
+
+func (s *rpcServer) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := rpcResponse{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr}
+
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	if err := json.NewEncoder(s.out).Encode(resp); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing response: %v\n", err)
+	}
+}
+
+// cancellable reports whether a request with id can be cancelled: it has an
+// id, which is not null.
+func cancellable(id json.RawMessage) bool {
+	return id != nil && string(id) != "null"
+}
+
+// track records cancel as the cancel func of the request with id. It reports
+// false if a request with the same id is already in flight.
+func (s *rpcServer) track(id json.RawMessage, cancel context.CancelFunc) bool {
+	if !cancellable(id) {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.inflight[string(id)]; ok {
+		return false
+	}
+	s.inflight[string(id)] = cancel
+	return true
+}
+
+func (s *rpcServer) untrack(id json.RawMessage) {
+	if !cancellable(id) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, string(id))
+}
+
+func (s *rpcServer) cancel(id json.RawMessage) {
+	if !cancellable(id) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.inflight[string(id)]; ok {
+		cancel()
+	}
+}
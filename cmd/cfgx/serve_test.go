@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRPCServer_Serve(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{
+		{
+			name:  "request",
+			input: []string{`{"jsonrpc":"2.0","id":1,"method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`},
+			want:  []string{`{"jsonrpc":"2.0","id":1,"result":{"valid":true}}`},
+		},
+		{
+			name:  "notification",
+			input: []string{`{"jsonrpc":"2.0","method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`},
+		},
+		{
+			name: "cancellation",
+			input: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"wait"}`,
+				`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`,
+			},
+			want: []string{`{"jsonrpc":"2.0","id":1,"error":{"code":-32800,"message":"request cancelled"}}`},
+		},
+		{
+			name: "cancellation of an unknown id",
+			input: []string{
+				`{"jsonrpc":"2.0","id":"a","method":"wait"}`,
+				`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":"b"}}`,
+				`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":"a"}}`,
+			},
+			want: []string{`{"jsonrpc":"2.0","id":"a","error":{"code":-32800,"message":"request cancelled"}}`},
+		},
+		{
+			name: "duplicate id",
+			input: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"wait"}`,
+				`{"jsonrpc":"2.0","id":1,"method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`,
+				`{"jsonrpc":"2.0","method":"$/cancelRequest","params":{"id":1}}`,
+			},
+			want: []string{
+				`{"jsonrpc":"2.0","id":1,"error":{"code":-32600,"message":"request id 1 is already in flight"}}`,
+				`{"jsonrpc":"2.0","id":1,"error":{"code":-32800,"message":"request cancelled"}}`,
+			},
+		},
+		{
+			name: "null ids are not tracked",
+			input: []string{
+				`{"jsonrpc":"2.0","id":null,"method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`,
+				`{"jsonrpc":"2.0","id":null,"method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`,
+			},
+			want: []string{
+				`{"jsonrpc":"2.0","id":null,"result":{"valid":true}}`,
+				`{"jsonrpc":"2.0","id":null,"result":{"valid":true}}`,
+			},
+		},
+		{
+			name: "shutdown",
+			input: []string{
+				`{"jsonrpc":"2.0","id":1,"method":"shutdown"}`,
+				`{"jsonrpc":"2.0","id":2,"method":"validate","params":{"input":"config.toml","content":"name = \"svc\"\n"}}`,
+			},
+			want: []string{`{"jsonrpc":"2.0","id":1,"result":"ok"}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			s := newRPCServer(&out)
+			// wait blocks until the request is cancelled
+			s.dispatch = func(ctx context.Context, req rpcRequest) (any, *rpcError) {
+				if req.Method == "wait" {
+					<-ctx.Done()
+					return nil, errCancelled
+				}
+				return s.handle(ctx, req)
+			}
+
+			input := strings.Join(tt.input, "\n") + "\n"
+			require.NoError(t, s.serve(strings.NewReader(input)))
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+				if line != "" {
+					got = append(got, line)
+				}
+			}
+			require.Equal(t, tt.want, got)
+			require.Empty(t, s.inflight, "finished requests should be untracked")
+		})
+	}
+}
+
+func TestRPCServer_CancelledWrite(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "config.go")
+	params, err := json.Marshal(generateParams{Input: "config.toml", Content: "name = \"svc\"\n", Output: output, Package: "config", Write: true})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, rpcErr := newRPCServer(&bytes.Buffer{}).handle(ctx, rpcRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "generate", Params: params})
+	require.Nil(t, result)
+	require.Equal(t, errCancelled, rpcErr)
+	require.NoFileExists(t, output, "a cancelled generate should not write its output")
+
+	result, rpcErr = newRPCServer(&bytes.Buffer{}).handle(context.Background(), rpcRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "generate", Params: params})
+	require.Nil(t, rpcErr)
+	require.Equal(t, map[string]any{"output": output}, result)
+	require.FileExists(t, output)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+)
+
+var targetsFormat string
+
+var targetsCmd = &cobra.Command{
+	Use:   "targets [packages]",
+	Short: "List the generation targets found in a repository",
+	Long: `List every file generated by cfgx along with the input it was generated from,
+its generation mode, a hash of its current content and whether it is stale.
+
+Targets are discovered from the record cfgx writes into the header of each
+generated file, so no extra configuration is needed. The [[targets]] of the
+tool configuration file (.cfgx.toml or cfgx.yaml) are listed too, and marked
+missing until they are generated.`,
+	Example: `  # List all targets in the repository
+  cfgx targets ./...
+
+  # Output as JSON for scripting
+  cfgx targets ./... --format json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if targetsFormat != "table" && targetsFormat != "json" {
+			return fmt.Errorf("invalid --format value %q: must be 'table' or 'json'", targetsFormat)
+		}
+
+		if len(args) == 0 {
+			args = []string{"./..."}
+		}
+
+		files, err := findGeneratedFiles(args)
+		if err != nil {
+			return err
+		}
+
+		targets := make([]Target, 0, len(files))
+		for _, file := range files {
+			targets = append(targets, describeTarget(file))
+		}
+		configured, err := toolTargets()
+		if err != nil {
+			return err
+		}
+		for _, s := range configured {
+			if !slices.ContainsFunc(targets, func(t Target) bool { return cfgx.SamePath(t.Output, s.Out) }) {
+				targets = append(targets, describeConfiguredTarget(s))
+			}
+		}
+
+		if targetsFormat == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(targets)
+		}
+
+		if len(targets) == 0 {
+			fmt.Println("No cfgx generated files or configured targets found.")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "OUTPUT\tINPUT\tMODE\tCONTENT HASH\tSTATUS")
+		for _, t := range targets {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Output, t.Input, t.Mode, t.ContentHash, t.Status)
+		}
+		return w.Flush()
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	targetsCmd.Flags().StringVar(&targetsFormat, "format", "table", "Output format: table or json")
+}
+
+// Target describes a single generated file and the input it was produced from.
+type Target struct {
+	Output      string `json:"output"`
+	Input       string `json:"input,omitempty"`
+	Mode        string `json:"mode,omitempty"`
+	ContentHash string `json:"content_hash,omitempty"` // Of the file as it is now, stale or not
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// describeTarget reads a generated file and reports its recorded input and freshness.
+func describeTarget(path string) Target {
+	target := Target{Output: path}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		target.Status = "error"
+		target.Error = err.Error()
+		return target
+	}
+
+	sum := sha256.Sum256(src)
+	target.ContentHash = hex.EncodeToString(sum[:])[:12]
+
+	opts, ok, err := optionsFromRecord(path, src)
+	if err != nil {
+		target.Status = "error"
+		target.Error = err.Error()
+		return target
+	}
+	if !ok {
+		target.Status = "unknown"
+		return target
+	}
+	target.Input = opts.InputFile
+	target.Mode = opts.Mode
+
+	status, err := checkGenerated(path, src, opts)
+	switch {
+	case err != nil:
+		target.Status = "error"
+		target.Error = err.Error()
+	case status == checkStale:
+		target.Status = "stale"
+	case status == checkDynamic:
+		target.Status = "dynamic"
+	default:
+		target.Status = "fresh"
+	}
+
+	return target
+}
+
+// describeConfiguredTarget reports a target of the tool configuration file
+// that was not found among the generated files: missing if its output does
+// not exist yet.
+func describeConfiguredTarget(s generateSettings) Target {
+	if _, err := os.Stat(s.Out); !errors.Is(err, fs.ErrNotExist) {
+		return describeTarget(s.Out)
+	}
+	return Target{
+		Output: s.Out,
+		Input:  strings.Join(s.In, ","),
+		Mode:   cmp.Or(s.Mode, "static"),
+		Status: "missing",
+	}
+}
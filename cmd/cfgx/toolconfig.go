@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// toolConfigNames are the names of the tool configuration file, looked up
+// from the working directory up to the repository root.
+var toolConfigNames = []string{".cfgx.toml", "cfgx.yaml"}
+
+var noToolConfig bool
+
+// toolConfig holds project-wide cfgx settings, as written by "cfgx init
+// --tool-config". Paths are relative to the directory of the file.
+type toolConfig struct {
+	Generate generateSettings `toml:"generate" yaml:"generate"`
+
+	// Targets are further generation targets kept up to date by a single
+	// watch. Their settings default to those of Generate.
+	Targets []generateSettings `toml:"targets" yaml:"targets"`
+}
+
+// generateSettings are the defaults of the generate and watch flags of the
+// same names.
+type generateSettings struct {
+	In          stringList `toml:"in" yaml:"in"`
+	Out         string     `toml:"out" yaml:"out"`
+	Pkg         string     `toml:"pkg" yaml:"pkg"`
+	Mode        string     `toml:"mode" yaml:"mode"`
+	Env         *bool      `toml:"env" yaml:"env"`
+	EnvPrefix   string     `toml:"env_prefix" yaml:"env_prefix"`
+	EnvStrict   string     `toml:"env_strictness" yaml:"env_strictness"`
+	Tags        stringList `toml:"tags" yaml:"tags"`
+	IntType     string     `toml:"int_type" yaml:"int_type"`
+	Style       string     `toml:"style" yaml:"style"`
+	Initialisms stringList `toml:"initialisms" yaml:"initialisms"`
+	Precedence  stringList `toml:"precedence" yaml:"precedence"`
+}
+
+// stringList is a list setting, which may also be given as a single string.
+type stringList []string
+
+func (l *stringList) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case string:
+		*l = stringList{v}
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("expected a list of strings, got %T in it", item)
+			}
+			*l = append(*l, s)
+		}
+	default:
+		return fmt.Errorf("expected a string or a list of strings, got %T", v)
+	}
+	return nil
+}
+
+func (l *stringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = stringList{node.Value}
+		return nil
+	}
+	return node.Decode((*[]string)(l))
+}
+
+// findToolConfig returns the path of the tool configuration file, searching
+// the working directory and its parents up to the repository root, or "" if
+// there is none.
+func findToolConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		var found []string
+		for _, name := range toolConfigNames {
+			path := filepath.Join(dir, name)
+			if _, err := os.Stat(path); err == nil {
+				found = append(found, path)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		switch len(found) {
+		case 1:
+			return found[0], nil
+		case 2:
+			return "", fmt.Errorf("both %s and %s exist: keep one", found[0], found[1])
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// readToolConfig decodes the tool configuration file at path. Unknown
+// settings are an error, so that typos do not go unnoticed.
+func readToolConfig(path string) (*toolConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg toolConfig
+	if filepath.Ext(path) == ".yaml" {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &cfg, nil
+	}
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown setting %s", path, undecoded[0])
+	}
+	return &cfg, nil
+}
+
+// applyToolConfig sets the flags of cmd that were not given on the command
+// line from the tool configuration file, if any, so that flags override it.
+func applyToolConfig(cmd *cobra.Command) error {
+	if noToolConfig {
+		return nil
+	}
+	path, err := findToolConfig()
+	if err != nil || path == "" {
+		return err
+	}
+	cfg, err := readToolConfig(path)
+	if err != nil {
+		return err
+	}
+	settings := cfg.Generate
+	rel := func(p string) (string, error) {
+		return toolPath(path, p)
+	}
+
+	// Flags given on the command line win
+	given := make(map[string]bool)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		given[f.Name] = true
+	})
+	set := func(name, value string) error {
+		if cmd.Flags().Lookup(name) == nil || given[name] {
+			return nil
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid %s setting: %w", path, name, err)
+		}
+		return nil
+	}
+
+	for _, in := range settings.In {
+		p, err := rel(in)
+		if err != nil {
+			return err
+		}
+		if err := set("in", p); err != nil {
+			return err
+		}
+	}
+	if settings.Out != "" {
+		p, err := rel(settings.Out)
+		if err != nil {
+			return err
+		}
+		if err := set("out", p); err != nil {
+			return err
+		}
+	}
+	for name, value := range map[string]string{
+		"pkg":            settings.Pkg,
+		"mode":           settings.Mode,
+		"env-prefix":     settings.EnvPrefix,
+		"env-strictness": settings.EnvStrict,
+		"tags":           strings.Join(settings.Tags, ","),
+		"int-type":       settings.IntType,
+		"style":          settings.Style,
+		"initialisms":    strings.Join(settings.Initialisms, ","),
+		"precedence":     strings.Join(settings.Precedence, ","),
+	} {
+		if value == "" {
+			continue
+		}
+		if err := set(name, value); err != nil {
+			return err
+		}
+	}
+	if settings.Env != nil {
+		if err := set("no-env", strconv.FormatBool(!*settings.Env)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toolTargets returns the targets of the tool configuration file, if any,
+// with their paths relative to the working directory. Targets without
+// inputs or a mode take those of the generate section.
+func toolTargets() ([]generateSettings, error) {
+	if noToolConfig {
+		return nil, nil
+	}
+	path, err := findToolConfig()
+	if err != nil || path == "" {
+		return nil, err
+	}
+	cfg, err := readToolConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := cfg.Targets
+	for i := range targets {
+		t := &targets[i]
+		if len(t.In) == 0 {
+			t.In = slices.Clone(cfg.Generate.In)
+		}
+		if t.Mode == "" {
+			t.Mode = cfg.Generate.Mode
+		}
+		for j, in := range t.In {
+			if t.In[j], err = toolPath(path, in); err != nil {
+				return nil, err
+			}
+		}
+		if t.Out == "" {
+			return nil, fmt.Errorf("%s: target %d: out is required", path, i+1)
+		}
+		if t.Out, err = toolPath(path, t.Out); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+// toolPath returns p, a path of the tool configuration file at configPath,
+// relative to the working directory.
+func toolPath(configPath, p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return p, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(wd, filepath.Join(filepath.Dir(configPath), filepath.FromSlash(p)))
+}
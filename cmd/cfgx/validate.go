@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gomantics/cfgx"
+	"github.com/gomantics/cfgx/internal/i18n"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that a config generates without errors, writing nothing",
+	Long: `Check a configuration the way generate does, without writing any output.
+
+The input is parsed, file: references are resolved and environment variable
+overrides are applied, and every problem found is reported: syntax errors
+such as duplicate keys, missing or oversized files, and environment variables
+that do not parse as the type of the value they override. When there are none,
+the code is generated in memory to catch the remaining errors.
+
+The command exits with status 1 if any problem is found, making it usable as
+a CI gate before the generate step.`,
+	Example: `  # Validate a config file
+  cfgx validate --in config.toml
+
+  # Validate the production config with CI-annotated errors
+  cfgx validate --in base.toml --in overrides/prod.toml --output-format gcc
+
+  # Validate a getter-mode config against policies
+  cfgx validate --in config.toml --mode getter --policy policy.toml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := validateErrFormat(); err != nil {
+			return err
+		}
+		if err := validateDuplicates(); err != nil {
+			return err
+		}
+		if mode != "static" && mode != "getter" && mode != "hybrid" && mode != "loader" {
+			return fmt.Errorf("invalid --mode value %q: must be 'static', 'getter', 'hybrid' or 'loader'", mode)
+		}
+
+		maxFileSizeBytes, err := parseFileSize(maxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+
+		// Name the first input in gcc errors without a location
+		inputFile = inputFiles[0]
+
+		problems := cfgx.Validate(&cfgx.GenerateOptions{
+			InputFiles:            inputFiles,
+			AppendArrays:          appendArrays,
+			Format:                inputFormat,
+			MergeDuplicates:       duplicates,
+			EnableEnv:             !noEnv,
+			EnvOverrideStrictness: envStrict,
+			MaxFileSize:           maxFileSizeBytes,
+			Mode:                  mode,
+			Policies:              policies,
+		})
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, formatError(problem))
+		}
+		if len(problems) > 0 {
+			return errors.New(i18n.T(i18n.ValidateSummary, len(problems)))
+		}
+
+		fmt.Println(i18n.T(i18n.ValidateOK, inputFile))
+		return nil
+	},
+	SilenceUsage: true,
+}
+
+func init() {
+	validateCmd.Flags().StringArrayVarP(&inputFiles, "in", "i", []string{"config.toml"}, "input TOML or JSON file; repeat to deep-merge later files over earlier ones")
+	validateCmd.Flags().BoolVar(&appendArrays, "append-arrays", false, "when merging several --in files, append arrays instead of replacing them")
+	validateCmd.Flags().StringVar(&inputFormat, "input-format", "", "input format: 'toml' or 'json' (default: detected from the file extension)")
+	validateCmd.Flags().StringVar(&duplicates, "merge-duplicates", "", "accept concatenated TOML with repeated tables and keys: 'last' (later values win) or 'error' (fail on keys set twice)")
+	validateCmd.Flags().BoolVar(&noEnv, "no-env", false, "do not check environment variable overrides")
+	validateCmd.Flags().StringVar(&envStrict, "env-strictness", "error", "what to do with env var overrides that do not parse: 'error' fails, 'warn' keeps the file value and logs a warning")
+	validateCmd.Flags().StringVar(&maxFileSize, "max-file-size", "1MB", "maximum file size for file: references (e.g., 10MB, 1GB, 512KB)")
+	validateCmd.Flags().StringVar(&mode, "mode", "static", "generation mode to validate for: 'static', 'getter', 'hybrid' or 'loader'")
+	validateCmd.Flags().StringArrayVar(&policies, "policy", nil, "TOML policy `file` of CEL rules the effective config must satisfy (repeatable)")
+	validateCmd.Flags().StringVar(&errFormat, "output-format", "text", "error output format: 'text' or 'gcc' (file:line:col: message)")
+}
@@ -2,29 +2,78 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
-	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/gomantics/cfgx"
 )
 
+// Exit statuses of watch --once-on-change.
+const (
+	watchOnceGenerated = 0
+	watchOnceFailed    = 1
+	watchOnceStopped   = 2
+)
+
 var (
-	debounce int
+	debounce     int
+	onceOnChange bool
+	execCommand  string
+	watchTargets []string
 )
 
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch TOML file and auto-regenerate on changes",
-	Long:  `Watch a TOML configuration file and automatically regenerate Go code when it changes.`,
+	Long: `Watch a TOML configuration file and automatically regenerate Go code when it changes.
+
+Repeating --in deep-merges later files over earlier ones, as generate does,
+and watches them all. Repeat --target in=out to watch several targets in one
+process, with the inputs of each separated by commas. Without --in, --out or
+--target, the targets listed as [[targets]] in the .cfgx.toml or cfgx.yaml
+settings file are watched as well as the one of its [generate] section.
+Their settings, inputs included, default to those of [generate], and flags
+given on the command line apply to every target:
+
+  [[targets]]
+  in = "services/api/config.toml"
+  out = "services/api/config/config.go"
+
+  [[targets]]
+  in = ["services/worker/config.toml", "services/worker/config.prod.toml"]
+  out = "services/worker/config/config.go"
+  mode = "getter"
+
+Files referenced with file: are watched too, as of the last successful
+generation, and regenerate the code when they change. They are only read
+again once their size or modification time changes, and output files are only rewritten when their
+content changes, so regenerating configurations embedding many assets stays
+fast.
+
+With --once-on-change, watch skips the initial generation, waits for a single
+change, regenerates and exits, for file watchers such as Air, Tilt or
+Skaffold that run the loop themselves. The exit status tells what happened:
+
+  0  the file changed and was regenerated
+  1  the file changed and could not be regenerated
+  2  watch was stopped before the file changed
+
+With --exec, a shell command runs after every successful generation,
+including the initial one, with its output streamed, such as a build or a
+signal telling the running program to reload. Changes made while it runs
+are regenerated once it has finished.`,
 	Example: `  # Watch and auto-regenerate
   cfgx watch --in config.toml --out config/config.go
 
@@ -32,148 +81,325 @@ var watchCmd = &cobra.Command{
   cfgx watch --in config.toml --out config.go --debounce 200
 
   # Watch with custom mode
-  cfgx watch --in config.toml --out config.go --mode getter`,
+  cfgx watch --in config.toml --out config.go --mode getter
+
+  # Regenerate on the next change only, from an external watcher
+  cfgx watch --in config.toml --out config.go --once-on-change
+
+  # Watch a base config merged with its overrides
+  cfgx watch --in config.toml --in config.prod.toml --out config.go
+
+  # Watch two configs in one process
+  cfgx watch --target api.toml=api/config.go --target worker.toml,worker.prod.toml=worker/config.go
+
+  # Rebuild after every regeneration
+  cfgx watch --in config.toml --out config.go --exec "go build ./..."
+
+  # Tell the running program to reload
+  cfgx watch --in config.toml --out config.go --exec 'kill -HUP $(cat app.pid)'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if outputFile == "" {
-			return fmt.Errorf("--out flag is required")
+		// Flags given on the command line, before the tool configuration
+		// file sets the others
+		given := make(map[string]bool)
+		cmd.Flags().Visit(func(f *pflag.Flag) {
+			given[f.Name] = true
+		})
+		if err := applyToolConfig(cmd); err != nil {
+			return err
+		}
+
+		if err := validateErrFormat(); err != nil {
+			return err
 		}
 
-		if mode != "static" && mode != "getter" {
-			return fmt.Errorf("invalid --mode value %q: must be 'static' or 'getter'", mode)
+		if err := validateDuplicates(); err != nil {
+			return err
 		}
 
 		maxFileSizeBytes, err := parseFileSize(maxFileSize)
 		if err != nil {
 			return fmt.Errorf("invalid --max-file-size: %w", err)
 		}
-
-		absInputFile, err := filepath.Abs(inputFile)
+		embedThreshold, err := parseFileSize(embedSize)
 		if err != nil {
-			return fmt.Errorf("failed to get absolute path: %w", err)
+			return fmt.Errorf("invalid --embed-threshold: %w", err)
 		}
-
-		opts := &cfgx.GenerateOptions{
-			InputFile:   inputFile,
-			OutputFile:  outputFile,
-			PackageName: packageName,
-			EnableEnv:   !noEnv,
-			MaxFileSize: maxFileSizeBytes,
-			Mode:        mode,
+		nameOverrides, err := parseNames(names)
+		if err != nil {
+			return err
 		}
 
-		fmt.Printf("Generating %s...\n", outputFile)
-		if err := cfgx.GenerateFromFile(opts); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			fmt.Println("Continuing to watch for changes...")
-		} else {
-			fmt.Printf("✓ Generated %s\n", outputFile)
+		base := cfgx.GenerateOptions{
+			InputFiles:            inputFiles,
+			AppendArrays:          appendArrays,
+			Format:                inputFormat,
+			MergeDuplicates:       duplicates,
+			PackageName:           packageName,
+			EnableEnv:             !noEnv,
+			EnvPrefix:             envPrefix,
+			EnvOverrideStrictness: envStrict,
+			MaxFileSize:           maxFileSizeBytes,
+			Mode:                  mode,
+			CRLF:                  crlf,
+			DynamicValues:         dynamic,
+			TrackUsage:            trackUsage,
+			EnvAtInit:             envAtInit,
+			Summary:               summary,
+			EmitEnvDocs:           envDocs,
+			PreserveOrder:         preserveOrder,
+			EmbedThreshold:        embedThreshold,
+			StructTags:            parseList(tags),
+			IntType:               intType,
+			Style:                 style,
+			Initialisms:           parseList(initialisms),
+			NameOverrides:         nameOverrides,
+			Precedence:            parseList(precedence),
+			Policies:              policies,
 		}
 
-		watcher, err := fsnotify.NewWatcher()
-		if err != nil {
-			return fmt.Errorf("failed to create watcher: %w", err)
+		// Targets of the command line, or of the tool configuration file
+		var targets []*cfgx.GenerateOptions
+		for _, target := range watchTargets {
+			in, out, ok := strings.Cut(target, "=")
+			if !ok || in == "" || out == "" {
+				return fmt.Errorf("invalid --target %q: must be in=out", target)
+			}
+			opts := base
+			opts.InputFiles, opts.OutputFile = strings.Split(in, ","), out
+			targets = append(targets, &opts)
 		}
-		defer watcher.Close()
-
-		if err := watcher.Add(absInputFile); err != nil {
-			return fmt.Errorf("failed to watch %s: %w", absInputFile, err)
+		if outputFile != "" && (given["out"] || !given["target"]) {
+			opts := base
+			opts.OutputFile = outputFile
+			targets = append(targets, &opts)
+		}
+		if !given["in"] && !given["out"] && !given["target"] {
+			settings, err := toolTargets()
+			if err != nil {
+				return err
+			}
+			for _, s := range settings {
+				targets = append(targets, targetOptions(given, base, s))
+			}
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("--out flag is required")
 		}
 
-		fmt.Printf("\nWatching %s for changes (Ctrl+C to stop)...\n", inputFile)
-
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
+		for _, opts := range targets {
+			if opts.Mode != "static" && opts.Mode != "getter" && opts.Mode != "hybrid" && opts.Mode != "loader" {
+				return fmt.Errorf("invalid --mode value %q: must be 'static', 'getter', 'hybrid' or 'loader'", opts.Mode)
+			}
+			for _, other := range targets {
+				for _, in := range opts.InputFiles {
+					if cfgx.SamePath(in, other.OutputFile) {
+						return fmt.Errorf("--out %s is the watched input file", other.OutputFile)
+					}
+				}
+				if other != opts && cfgx.SamePath(opts.OutputFile, other.OutputFile) {
+					return fmt.Errorf("--out %s is the output of two targets", opts.OutputFile)
+				}
+			}
+		}
 
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-		defer signal.Stop(sigChan)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
 
 		var (
-			debounceTimer *time.Timer
-			timerMu       sync.Mutex
+			// Held while reporting a result, so that the outputs of the
+			// targets do not interleave and --exec runs one at a time
+			reportMu sync.Mutex
+			inputs   []string
 		)
-		debounceDuration := time.Duration(debounce) * time.Millisecond
-
-		var readdInProgress atomic.Bool
-
-		for {
-			select {
-			case event, ok := <-watcher.Events:
-				if !ok {
-					return nil
+		// Receive the initial generations, the errors Watch returns and,
+		// with --once-on-change, the exit status of the regeneration
+		ready := make(chan struct{}, len(targets))
+		errs := make(chan error, len(targets))
+		done := make(chan int, 1)
+		for _, opts := range targets {
+			for _, in := range opts.InputFiles {
+				if !slices.Contains(inputs, in) {
+					inputs = append(inputs, in)
 				}
+			}
+			// Errors are located in the first input, as generate reports them
+			input := opts.InputFiles[0]
+			watchOpts := &cfgx.WatchOptions{
+				GenerateOptions: *opts,
+				Debounce:        time.Duration(debounce) * time.Millisecond,
+				SkipInitial:     onceOnChange,
+			}
+			go func() {
+				errs <- cfgx.Watch(ctx, watchOpts, func(r cfgx.Result) {
+					reportMu.Lock()
+					defer reportMu.Unlock()
+					switch {
+					case errors.Is(r.Err, cfgx.ErrWatch):
+						fmt.Fprintf(os.Stderr, "Warning: %v\n", r.Err)
+						return
+					case r.Removed:
+						fmt.Printf("%s removed, waiting for recreation...\n", strings.Join(opts.InputFiles, " or "))
+						return
+					case r.Initial:
+						defer func() { ready <- struct{}{} }()
+						if r.Err != nil {
+							fmt.Fprintln(os.Stderr, formatErrorIn(r.Err, input))
+							fmt.Println("Continuing to watch for changes...")
+							return
+						}
+						fmt.Printf("✓ Generated %s\n", opts.OutputFile)
+						runExec(execCommand)
+						return
+					}
 
-				// Handle file events (Write, Create, Remove)
-				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-					// Debounce: reset timer on each event
-					timerMu.Lock()
-					if debounceTimer != nil {
-						debounceTimer.Stop()
+					fmt.Printf("\n[%s] Change detected, regenerating %s...\n", time.Now().Format("15:04:05"), opts.OutputFile)
+					status := watchOnceGenerated
+					if r.Err != nil {
+						fmt.Fprintf(os.Stderr, "✗ %s\n", formatErrorIn(r.Err, input))
+						status = watchOnceFailed
+					} else {
+						fmt.Printf("✓ Generated %s\n", opts.OutputFile)
+						runExec(execCommand)
 					}
-					debounceTimer = time.AfterFunc(debounceDuration, func() {
-						fmt.Printf("\n[%s] Change detected, regenerating...\n", time.Now().Format("15:04:05"))
-						if err := cfgx.GenerateFromFile(opts); err != nil {
-							fmt.Fprintf(os.Stderr, "✗ Error: %v\n", err)
-						} else {
-							fmt.Printf("✓ Generated %s\n", outputFile)
+					if onceOnChange {
+						select {
+						case done <- status:
+						default:
 						}
-					})
-					timerMu.Unlock()
-				} else if event.Has(fsnotify.Remove) {
-					fmt.Println("File removed, waiting for recreation...")
-					if err := watcher.Remove(absInputFile); err != nil {
-						fmt.Fprintf(os.Stderr, "Warning: failed to remove watcher: %v\n", err)
 					}
+				})
+			}()
+		}
 
-					if readdInProgress.CompareAndSwap(false, true) {
-						go func() {
-							defer readdInProgress.Store(false)
-
-							for range 10 {
-								select {
-								case <-ctx.Done():
-									return
-								case <-time.After(100 * time.Millisecond):
-									if err := watcher.Add(absInputFile); err == nil {
-										fmt.Println("File recreated, watching again...")
-										return
-									}
-								}
-							}
-							fmt.Fprintf(os.Stderr, "Warning: Could not re-watch file after removal\n")
-						}()
-					}
+		waiting := len(targets)
+		if onceOnChange {
+			waiting = 0
+			fmt.Printf("Waiting for a change to %s (Ctrl+C to stop)...\n", strings.Join(inputs, ", "))
+		}
+		for running := len(targets); running > 0; {
+			select {
+			case <-ready:
+				if waiting--; waiting == 0 {
+					reportMu.Lock()
+					fmt.Printf("\nWatching %s for changes (Ctrl+C to stop)...\n", strings.Join(inputs, ", "))
+					reportMu.Unlock()
 				}
 
-			case err, ok := <-watcher.Errors:
-				if !ok {
-					return nil
+			case err := <-errs:
+				if err != nil {
+					return err
 				}
-				fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+				running--
+
+			case status := <-done:
+				os.Exit(status)
 
-			case <-sigChan:
+			case <-ctx.Done():
 				fmt.Println("\nStopping watch...")
-				timerMu.Lock()
-				if debounceTimer != nil {
-					debounceTimer.Stop()
+				if onceOnChange {
+					os.Exit(watchOnceStopped)
 				}
-				timerMu.Unlock()
 				return nil
 			}
 		}
+		return nil
 	},
 	SilenceUsage: true,
 }
 
 func init() {
 	// Watch command flags (reuse generate flags)
-	watchCmd.Flags().StringVarP(&inputFile, "in", "i", "config.toml", "input TOML file")
-	watchCmd.Flags().StringVarP(&outputFile, "out", "o", "", "output Go file (required)")
+	watchCmd.Flags().StringArrayVarP(&inputFiles, "in", "i", []string{"config.toml"}, "input TOML or JSON file; repeat to deep-merge later files over earlier ones")
+	watchCmd.Flags().BoolVar(&appendArrays, "append-arrays", false, "when merging several --in files, append arrays instead of replacing them")
+	watchCmd.Flags().StringVar(&inputFormat, "input-format", "", "input format: 'toml' or 'json' (default: detected from the file extension)")
+	watchCmd.Flags().StringVar(&duplicates, "merge-duplicates", "", "accept concatenated TOML with repeated tables and keys: 'last' (later values win) or 'error' (fail on keys set twice)")
+	watchCmd.Flags().StringVarP(&outputFile, "out", "o", "", "output Go file (required unless --target is given or it is set in .cfgx.toml)")
+	watchCmd.Flags().StringArrayVar(&watchTargets, "target", nil, "`in=out` target to watch, with inputs separated by commas, in place of --in and --out (repeatable)")
 	watchCmd.Flags().StringVarP(&packageName, "pkg", "p", "", "package name (default: inferred from output path or 'config')")
 	watchCmd.Flags().BoolVar(&noEnv, "no-env", false, "disable environment variable overrides")
+	watchCmd.Flags().StringVar(&envPrefix, "env-prefix", "CONFIG", "prefix of environment variable override names, as in CONFIG_SERVER_PORT")
+	watchCmd.Flags().StringVar(&envStrict, "env-strictness", "error", "what to do with env var overrides that do not parse: 'error' fails, 'warn' keeps the file value and logs a warning")
+	watchCmd.Flags().BoolVar(&noToolConfig, "no-tool-config", false, "ignore the .cfgx.toml or cfgx.yaml settings file of the project")
 	watchCmd.Flags().StringVar(&maxFileSize, "max-file-size", "1MB", "maximum file size for file: references (e.g., 10MB, 1GB, 512KB)")
-	watchCmd.Flags().StringVar(&mode, "mode", "static", "generation mode: 'static' (values baked at build time) or 'getter' (runtime env var overrides)")
+	watchCmd.Flags().StringVar(&embedSize, "embed-threshold", "", "embed file: references larger than this size with go:embed instead of byte literals (e.g., 64KB)")
+	watchCmd.Flags().StringVar(&mode, "mode", "static", "generation mode: 'static' (values baked at build time), 'getter' (runtime env var overrides) 'hybrid' (baked values with a LoadOverrides function) or 'loader' (baked defaults with Load functions reading TOML at runtime)")
+	watchCmd.Flags().BoolVar(&crlf, "crlf", false, "write the generated file with CRLF line endings (default: LF)")
+	watchCmd.Flags().BoolVar(&trackUsage, "track-usage", false, "in getter mode, count reads of each key and generate a Usage function")
+	watchCmd.Flags().BoolVar(&envAtInit, "env-at-init", false, "in getter mode, read env vars once at package init so getters never allocate")
+	watchCmd.Flags().StringVar(&tags, "tags", "", "comma-separated struct tags to write on generated fields with the TOML key names (e.g., json,yaml)")
+	watchCmd.Flags().StringVar(&intType, "int-type", "", "Go type of generated integers: 'int64' (default) or 'int'")
+	watchCmd.Flags().StringVar(&style, "style", "", "in static mode, 'vars' (default) for a package variable per top-level key or 'compact' for a single Default function")
+	watchCmd.Flags().StringVar(&initialisms, "initialisms", "", "comma-separated initialisms to write in upper case in identifiers, 'default' for the Go conventional ones (e.g., default,SKU)")
+	watchCmd.Flags().StringArrayVar(&names, "name", nil, "`key=Name` giving the Go name of the field, variable or getter of a dotted key path (repeatable)")
+	watchCmd.Flags().StringVar(&precedence, "precedence", "", "in loader mode, comma-separated runtime layers of the generated Resolve, lowest first: 'file', 'env' and 'flag' (default: file,env, or file with --no-env)")
+	watchCmd.Flags().BoolVar(&summary, "summary", false, "generate a PrintSummary function that prints the effective config with secrets redacted")
+	watchCmd.Flags().BoolVar(&envDocs, "env-docs", false, "list the environment variables the generated code reads, with their types and defaults, in a comment")
+	watchCmd.Flags().BoolVar(&preserveOrder, "preserve-order", false, "generate struct fields in the order keys are defined in the TOML rather than alphabetically")
+	watchCmd.Flags().BoolVar(&dynamic, "dynamic-values", false, "resolve uuid:, random: and now: values at generation time (for test fixtures)")
+	watchCmd.Flags().StringArrayVar(&policies, "policy", nil, "TOML policy `file` of CEL rules the effective config must satisfy (repeatable)")
+	watchCmd.Flags().StringVar(&errFormat, "output-format", "text", "error output format: 'text' or 'gcc' (file:line:col: message)")
 	watchCmd.Flags().IntVar(&debounce, "debounce", 100, "debounce delay in milliseconds (prevents rapid regeneration)")
+	watchCmd.Flags().BoolVar(&onceOnChange, "once-on-change", false, "skip the initial generation, then regenerate on the first change and exit: 0 if generated, 1 if it failed, 2 if stopped before")
+	watchCmd.Flags().StringVar(&execCommand, "exec", "", "shell `command` to run after each successful generation, such as \"go build ./...\"")
+}
+
+// targetOptions returns the options of a target of the tool configuration
+// file: base, holding the settings of [generate], with the settings of the
+// target in place of those of flags not given on the command line.
+func targetOptions(given map[string]bool, base cfgx.GenerateOptions, s generateSettings) *cfgx.GenerateOptions {
+	opts := base
+	opts.OutputFile = s.Out
+	if len(s.In) > 0 {
+		opts.InputFiles = s.In
+	}
+	if s.Pkg != "" && !given["pkg"] {
+		opts.PackageName = s.Pkg
+	}
+	if s.Mode != "" && !given["mode"] {
+		opts.Mode = s.Mode
+	}
+	if s.Env != nil && !given["no-env"] {
+		opts.EnableEnv = *s.Env
+	}
+	if s.EnvPrefix != "" && !given["env-prefix"] {
+		opts.EnvPrefix = s.EnvPrefix
+	}
+	if s.EnvStrict != "" && !given["env-strictness"] {
+		opts.EnvOverrideStrictness = s.EnvStrict
+	}
+	if len(s.Tags) > 0 && !given["tags"] {
+		opts.StructTags = s.Tags
+	}
+	if s.IntType != "" && !given["int-type"] {
+		opts.IntType = s.IntType
+	}
+	if s.Style != "" && !given["style"] {
+		opts.Style = s.Style
+	}
+	if len(s.Initialisms) > 0 && !given["initialisms"] {
+		opts.Initialisms = s.Initialisms
+	}
+	if len(s.Precedence) > 0 && !given["precedence"] {
+		opts.Precedence = s.Precedence
+	}
+	return &opts
+}
 
-	watchCmd.MarkFlagRequired("out")
+// runExec runs command, if any, with the shell, streaming its output. A
+// failing command is reported but does not stop watching.
+func runExec(command string) {
+	if command == "" {
+		return
+	}
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.Command("cmd", "/C", command)
+	} else {
+		c = exec.Command("sh", "-c", command)
+	}
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	fmt.Printf("$ %s\n", command)
+	if err := c.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %s: %v\n", command, err)
+	}
 }
@@ -0,0 +1,88 @@
+package cfgx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/gomantics/cfgx/internal/envoverride"
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// Error describes a generation problem that can be traced back to a location in
+// the input file. GenerateFromFile and GenerateBytes return it for TOML syntax
+// errors and for invalid values such as missing file: references; use errors.As
+// to retrieve it. The error message is the same as the wrapped error's.
+type Error struct {
+	File   string // Input file path
+	Line   int    // 1-based line, 0 if unknown
+	Column int    // 1-based column, 0 if unknown
+	Key    string // Dotted key path, empty if unknown
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// locateError annotates err with the location in src it refers to. Errors that
+// cannot be attributed to a location are returned unchanged.
+func locateError(file string, src []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		return &Error{
+			File:   file,
+			Line:   parseErr.Position.Line,
+			Column: parseErr.Position.Col,
+			Err:    err,
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, col := offsetPosition(src, syntaxErr.Offset)
+		return &Error{File: file, Line: line, Column: col, Err: err}
+	}
+
+	var envErr *envoverride.Error
+	if errors.As(err, &envErr) {
+		located := &Error{File: file, Key: envErr.Key, Err: err}
+		if pos, ok := tomlsrc.Scan(src).Position(envErr.Key); ok {
+			located.Line = pos.Line
+			located.Column = pos.Column
+		}
+		return located
+	}
+
+	var keyErr *generator.KeyError
+	if errors.As(err, &keyErr) {
+		located := &Error{File: file, Key: keyErr.Key, Err: err}
+		if pos, ok := tomlsrc.Scan(src).Position(keyErr.Key); ok {
+			located.Line = pos.Line
+			located.Column = pos.Column
+		}
+		return located
+	}
+
+	return err
+}
+
+// offsetPosition converts a byte offset in src to a 1-based line and column.
+func offsetPosition(src []byte, offset int64) (line, col int) {
+	offset = min(max(offset, 0), int64(len(src)))
+	before := src[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - (bytes.LastIndexByte(before, '\n') + 1) + 1
+	return line, col
+}
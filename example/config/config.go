@@ -1,8 +1,13 @@
 // Code generated by cfgx. DO NOT EDIT.
+//cfgx:record in="config.toml" mode=static env=true max-file-size=1048576
 
 package config
 
-import "time"
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
 
 type AppConfig struct {
 	Logging AppLoggingConfig
@@ -31,12 +36,40 @@ type CacheConfig struct {
 	Ttl        time.Duration
 }
 
+// Redacted returns a copy of c with its secrets replaced by "[redacted]", or
+// zeroed if they are not strings.
+func (c CacheConfig) Redacted() CacheConfig {
+	c.Redis = c.Redis.Redacted()
+	return c
+}
+
+// String formats c as the %+v verb does, with its secrets redacted.
+func (c CacheConfig) String() string {
+	type plain CacheConfig // Without methods, so that fmt does not call String
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
 type CacheRedisConfig struct {
 	Addr     string
 	Db       int64
 	Password string
 }
 
+// Redacted returns a copy of c with its secrets replaced by "[redacted]", or
+// zeroed if they are not strings.
+func (c CacheRedisConfig) Redacted() CacheRedisConfig {
+	if c.Password != "" {
+		c.Password = "[redacted]"
+	}
+	return c
+}
+
+// String formats c as the %+v verb does, with its secrets redacted.
+func (c CacheRedisConfig) String() string {
+	type plain CacheRedisConfig // Without methods, so that fmt does not call String
+	return fmt.Sprintf("%+v", plain(c.Redacted()))
+}
+
 type DatabaseConfig struct {
 	ConnMaxLifetime time.Duration
 	Dsn             string
@@ -207,3 +240,15 @@ var (
 		Weights:        []float64{1, 2.5, 3.7},
 	}
 )
+
+// NewHTTPServer returns an http.Server for handler configured from the [server] table.
+func (c ServerConfig) NewHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           c.Addr,
+		Handler:        handler,
+		ReadTimeout:    c.ReadTimeout,
+		WriteTimeout:   c.WriteTimeout,
+		IdleTimeout:    c.IdleTimeout,
+		MaxHeaderBytes: int(c.MaxHeaderBytes),
+	}
+}
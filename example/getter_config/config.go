@@ -1,8 +1,10 @@
 // Code generated by cfgx. DO NOT EDIT.
+//cfgx:record in="../config/config.toml" mode=getter env=true max-file-size=1048576
 
 package getter_config
 
 import (
+	"net/http"
 	"os"
 	"strconv"
 	"time"
@@ -22,14 +24,22 @@ type databaseConfig struct{}
 
 type databasepoolConfig struct{}
 
-type endpointsItem struct{}
-
-type featuresItem struct{}
-
 type serverConfig struct{}
 
 type serviceConfig struct{}
 
+type endpointsItem struct {
+	Methods   []string
+	Path      string
+	RateLimit int64
+}
+
+type featuresItem struct {
+	Enabled  bool
+	Name     string
+	Priority int64
+}
+
 func (appConfig) Logging() apploggingConfig {
 	return apploggingConfig{}
 }
@@ -226,54 +236,6 @@ func (databasepoolConfig) MinSize() int64 {
 	return 2
 }
 
-func (endpointsItem) Methods() []string {
-	if v := os.Getenv("CONFIG_ENDPOINTS_METHODS"); v != "" {
-		// Array overrides not supported via env vars
-	}
-	return []string{"GET", "POST"}
-}
-
-func (endpointsItem) Path() string {
-	if v := os.Getenv("CONFIG_ENDPOINTS_PATH"); v != "" {
-		return v
-	}
-	return "/api/v1"
-}
-
-func (endpointsItem) RateLimit() int64 {
-	if v := os.Getenv("CONFIG_ENDPOINTS_RATE_LIMIT"); v != "" {
-		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return i
-		}
-	}
-	return 100
-}
-
-func (featuresItem) Enabled() bool {
-	if v := os.Getenv("CONFIG_FEATURES_ENABLED"); v != "" {
-		if b, err := strconv.ParseBool(v); err == nil {
-			return b
-		}
-	}
-	return true
-}
-
-func (featuresItem) Name() string {
-	if v := os.Getenv("CONFIG_FEATURES_NAME"); v != "" {
-		return v
-	}
-	return "authentication"
-}
-
-func (featuresItem) Priority() int64 {
-	if v := os.Getenv("CONFIG_FEATURES_PRIORITY"); v != "" {
-		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
-			return i
-		}
-	}
-	return 1
-}
-
 func (serverConfig) Addr() string {
 	if v := os.Getenv("CONFIG_SERVER_ADDR"); v != "" {
 		return v
@@ -427,6 +389,98 @@ func (serviceConfig) Weights() []float64 {
 	return []float64{1, 2.5, 3.7}
 }
 
+func Endpoints() []endpointsItem {
+	items := []endpointsItem{
+		{
+			Methods:   []string{"GET", "POST"},
+			Path:      "/api/v1",
+			RateLimit: 100,
+		},
+		{
+			Methods:   []string{"GET", "POST", "PUT", "DELETE"},
+			Path:      "/api/v2",
+			RateLimit: 200,
+		},
+	}
+	if v := os.Getenv("CONFIG_ENDPOINTS_0_PATH"); v != "" {
+		items[0].Path = v
+	}
+	if v := os.Getenv("CONFIG_ENDPOINTS_0_RATE_LIMIT"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			items[0].RateLimit = i
+		}
+	}
+	if v := os.Getenv("CONFIG_ENDPOINTS_1_PATH"); v != "" {
+		items[1].Path = v
+	}
+	if v := os.Getenv("CONFIG_ENDPOINTS_1_RATE_LIMIT"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			items[1].RateLimit = i
+		}
+	}
+	return items
+}
+
+func Features() []featuresItem {
+	items := []featuresItem{
+		{
+			Enabled:  true,
+			Name:     "authentication",
+			Priority: 1,
+		},
+		{
+			Enabled:  true,
+			Name:     "rate_limiting",
+			Priority: 2,
+		},
+		{
+			Enabled:  false,
+			Name:     "caching",
+			Priority: 3,
+		},
+	}
+	if v := os.Getenv("CONFIG_FEATURES_0_ENABLED"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			items[0].Enabled = b
+		}
+	}
+	if v := os.Getenv("CONFIG_FEATURES_0_NAME"); v != "" {
+		items[0].Name = v
+	}
+	if v := os.Getenv("CONFIG_FEATURES_0_PRIORITY"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			items[0].Priority = i
+		}
+	}
+	if v := os.Getenv("CONFIG_FEATURES_1_ENABLED"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			items[1].Enabled = b
+		}
+	}
+	if v := os.Getenv("CONFIG_FEATURES_1_NAME"); v != "" {
+		items[1].Name = v
+	}
+	if v := os.Getenv("CONFIG_FEATURES_1_PRIORITY"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			items[1].Priority = i
+		}
+	}
+	if v := os.Getenv("CONFIG_FEATURES_2_ENABLED"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			items[2].Enabled = b
+		}
+	}
+	if v := os.Getenv("CONFIG_FEATURES_2_NAME"); v != "" {
+		items[2].Name = v
+	}
+	if v := os.Getenv("CONFIG_FEATURES_2_PRIORITY"); v != "" {
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			items[2].Priority = i
+		}
+	}
+	return items
+}
+
 func Name() string {
 	if v := os.Getenv("CONFIG_NAME"); v != "" {
 		return v
@@ -435,11 +489,21 @@ func Name() string {
 }
 
 var (
-	App       appConfig
-	Cache     cacheConfig
-	Database  databaseConfig
-	Endpoints []endpointsItem
-	Features  []featuresItem
-	Server    serverConfig
-	Service   serviceConfig
+	App      appConfig
+	Cache    cacheConfig
+	Database databaseConfig
+	Server   serverConfig
+	Service  serviceConfig
 )
+
+// NewHTTPServer returns an http.Server for handler configured from the [server] table.
+func (c serverConfig) NewHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           c.Addr(),
+		Handler:        handler,
+		ReadTimeout:    c.ReadTimeout(),
+		WriteTimeout:   c.WriteTimeout(),
+		IdleTimeout:    c.IdleTimeout(),
+		MaxHeaderBytes: int(c.MaxHeaderBytes()),
+	}
+}
@@ -0,0 +1,229 @@
+package cfgx
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/gomantics/cfgx/internal/envoverride"
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/pkgutil"
+)
+
+// GenerateFromMap generates Go code from configuration that has already been
+// decoded, skipping TOML parsing. Integers of any size, floats, bools,
+// strings, time.Time values, slices and maps with string keys are accepted;
+// data itself is not modified.
+//
+// The same options as GenerateBytes apply, except that InputFile is only used
+// to resolve file: references relative to its directory and no record is
+// written, since there is no input file to regenerate from. Annotations such
+// as cfgx:type live in TOML comments and are therefore not available.
+// Companion files are not included.
+func GenerateFromMap(data map[string]any, opts *GenerateOptions) ([]byte, error) {
+	if opts == nil {
+		opts = &GenerateOptions{}
+	}
+
+	normalized, err := normalizeTable(data, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := validateStructTags(opts.StructTags); err != nil {
+		return nil, err
+	}
+	if err := validateEnvPrefix(opts.EnvPrefix); err != nil {
+		return nil, err
+	}
+	if err := validateIntType(opts.IntType); err != nil {
+		return nil, err
+	}
+
+	mode := opts.Mode
+	if mode == "" {
+		mode = "static"
+	}
+	if err := validateStyle(opts.Style, mode); err != nil {
+		return nil, err
+	}
+	var overridden map[string]string
+	if opts.EnableEnv && mode != "getter" {
+		if err := generator.ResolveDefines(normalized); err != nil {
+			return nil, err
+		}
+		if err := generator.ResolveExtends(normalized); err != nil {
+			return nil, err
+		}
+		overridden = envoverride.Overrides(normalized, envPrefixOf(opts))
+		if err := envoverride.Apply(normalized, envPrefixOf(opts)); err != nil {
+			return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
+		}
+	}
+
+	packageName := opts.PackageName
+	if packageName == "" {
+		packageName = "config"
+		if opts.OutputFile != "" {
+			packageName = pkgutil.InferName(opts.OutputFile)
+		}
+	}
+
+	var inputDir string
+	if opts.InputFile != "" {
+		inputDir = filepath.Dir(opts.InputFile)
+		if opts.FS != nil {
+			inputDir = path.Dir(opts.InputFile)
+		}
+	}
+
+	maxFileSize := opts.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+
+	gen := generator.New(
+		generator.WithPackageName(packageName),
+		generator.WithEnvOverride(opts.EnableEnv),
+		generator.WithEnvPrefix(opts.EnvPrefix),
+		generator.WithInputDir(inputDir),
+		generator.WithMaxFileSize(maxFileSize),
+		generator.WithMode(mode),
+		generator.WithCRLF(opts.CRLF),
+		generator.WithDynamicValues(opts.DynamicValues),
+		generator.WithUsageTracking(opts.TrackUsage),
+		generator.WithSummary(opts.Summary),
+		generator.WithStructTags(opts.StructTags),
+		generator.WithIntType(cmp.Or(opts.IntType, "int64")),
+		generator.WithStyle(opts.Style),
+		generator.WithStats(opts.Stats),
+		generator.WithFS(opts.FS),
+		generator.WithFileCache(opts.FileCache.generatorCache()),
+		generator.WithOverridden(overridden),
+	)
+
+	files, err := gen.GenerateFilesFromMap(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code: %w", err)
+	}
+	return files[""], nil
+}
+
+// normalizeTable returns a copy of table with its values converted to the
+// types the TOML decoder produces, which is what the generator expects.
+func normalizeTable(table map[string]any, prefix string) (map[string]any, error) {
+	out := make(map[string]any, len(table))
+	for k, v := range table {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		normalized, err := normalizeValue(v, key)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = normalized
+	}
+	return out, nil
+}
+
+// normalizeValue converts a decoded value at the given key path.
+func normalizeValue(v any, key string) (any, error) {
+	switch val := v.(type) {
+	case string, bool, int64, float64, time.Time:
+		return val, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid number %s", key, val)
+		}
+		return f, nil
+	case map[string]any:
+		return normalizeTable(val, key)
+	case []map[string]any:
+		items := make([]map[string]any, len(val))
+		for i, item := range val {
+			normalized, err := normalizeTable(item, key)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = normalized
+		}
+		return items, nil
+	case nil:
+		return nil, fmt.Errorf("%s: null values are not supported", key)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%s: %d overflows int64", key, rv.Uint())
+		}
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		table := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			table[iter.Key().String()] = iter.Value().Interface()
+		}
+		return normalizeTable(table, key)
+	case reflect.Slice, reflect.Array:
+		items := make([]any, rv.Len())
+		tables := make([]map[string]any, 0, rv.Len())
+		for i := range rv.Len() {
+			normalized, err := normalizeValue(rv.Index(i).Interface(), key)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = normalized
+			if table, ok := normalized.(map[string]any); ok {
+				tables = append(tables, table)
+			}
+		}
+		// A non-empty slice of tables is an array of tables, as decoded from [[key]]
+		if len(tables) > 0 && len(tables) == len(items) {
+			return tables, nil
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%s: unsupported value of type %T", key, v)
+}
+
+// decodeJSON decodes a JSON object into the value types used for TOML input.
+// Numbers without a fraction or exponent become int64, others float64.
+func decodeJSON(src []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+
+	var data map[string]any
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("failed to parse JSON: unexpected data after the top-level object")
+	}
+	if data == nil {
+		return nil, fmt.Errorf("failed to parse JSON: the top-level value must be an object")
+	}
+	return normalizeTable(data, "")
+}
@@ -0,0 +1,82 @@
+package cfgx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateFromMap(t *testing.T) {
+	tomlData := []byte(`
+name = "svc"
+ratio = 0.5
+
+[server]
+port = 8080
+timeout = "5s"
+tags = ["a", "b"]
+
+[[endpoints]]
+path = "/v1"
+`)
+
+	data := map[string]any{
+		"name":  "svc",
+		"ratio": float32(0.5),
+		"server": map[string]any{
+			"port":    8080,
+			"timeout": "5s",
+			"tags":    []string{"a", "b"},
+		},
+		"endpoints": []map[string]string{{"path": "/v1"}},
+	}
+
+	for _, mode := range []string{"static", "getter"} {
+		want, err := GenerateWithOptions(tomlData, "config", false, "", 0, mode)
+		require.NoError(t, err)
+
+		got, err := GenerateFromMap(data, &GenerateOptions{Mode: mode})
+		require.NoError(t, err)
+		require.Equal(t, string(want), string(got), mode)
+	}
+
+	// The caller's map is left alone
+	require.Equal(t, 8080, data["server"].(map[string]any)["port"])
+}
+
+func TestGenerateFromMap_Resolves(t *testing.T) {
+	data := map[string]any{
+		"defines": map[string]any{"timeout": "30s"},
+		"a":       map[string]any{"timeout": "ref:defines.timeout", "port": 1},
+		"b":       map[string]any{"extends": "a", "port": "expr: a.port + 1"},
+	}
+
+	output, err := GenerateFromMap(data, nil)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Timeout: 30 * time.Second,")
+	require.Contains(t, outputStr, "Port:    2,")
+	require.NotContains(t, outputStr, "Defines")
+	require.Contains(t, data, "defines", "the caller's map is left alone")
+}
+
+func TestGenerateFromMap_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]any
+		want string
+	}{
+		{"nil", map[string]any{"a": map[string]any{"b": nil}}, "a.b: null values are not supported"},
+		{"overflow", map[string]any{"n": uint64(1 << 63)}, "n: 9223372036854775808 overflows int64"},
+		{"unsupported", map[string]any{"d": time.Second.Seconds, "x": 1}, "d: unsupported value of type func() float64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateFromMap(tt.data, nil)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package cfgx
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Region markers delimiting generated code injected into an existing file.
+const (
+	regionBegin = "// cfgx:begin "
+	regionEnd   = "// cfgx:end"
+)
+
+// injectRegion replaces the lines between "// cfgx:begin <region>" and
+// "// cfgx:end" in host with the declarations of generated, and adds the
+// imports they need to host's imports.
+func injectRegion(host, generated []byte, region string) ([]byte, error) {
+	imports, decls, err := splitGenerated(generated)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(string(host), "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == strings.TrimSpace(regionBegin+region):
+			if begin >= 0 {
+				return nil, fmt.Errorf("region %q is defined more than once", region)
+			}
+			begin = i
+		case begin >= 0 && end < 0 && (trimmed == regionEnd || trimmed == regionEnd+" "+region):
+			end = i
+		}
+	}
+	if begin < 0 {
+		return nil, fmt.Errorf("region %q not found: add %q and %q lines", region, regionBegin+region, regionEnd)
+	}
+	if end < 0 {
+		return nil, fmt.Errorf("region %q has no %q line", region, regionEnd)
+	}
+
+	var buf bytes.Buffer
+	for _, line := range lines[:begin+1] {
+		buf.WriteString(line)
+	}
+	buf.WriteString("// Code in this region is generated by cfgx. DO NOT EDIT.\n\n")
+	buf.Write(decls)
+	buf.WriteString("\n")
+	for _, line := range lines[end:] {
+		buf.WriteString(line)
+	}
+
+	src, err := addImports(buf.Bytes(), imports)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format %s region: %w", region, err)
+	}
+	return formatted, nil
+}
+
+// splitGenerated returns the import paths and the source of the declarations
+// of a generated file, without its header, package clause and imports.
+func splitGenerated(src []byte) ([]string, []byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse generated code: %w", err)
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imports = append(imports, path)
+	}
+
+	end := file.Name.End()
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			end = gen.End()
+		}
+	}
+	return imports, bytes.TrimSpace(src[fset.Position(end).Offset:]), nil
+}
+
+// addImports adds the import paths missing from src to its first import
+// declaration, or to a new one after the package clause.
+func addImports(src []byte, paths []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ImportsOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	existing := make(map[string]bool)
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		existing[path] = true
+	}
+	var specs strings.Builder
+	for _, path := range paths {
+		if !existing[path] {
+			fmt.Fprintf(&specs, "\t%q\n", path)
+		}
+	}
+	if specs.Len() == 0 {
+		return src, nil
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		if gen.Lparen.IsValid() {
+			at := fset.Position(gen.Rparen).Offset
+			return concat(src[:at], []byte(specs.String()), src[at:]), nil
+		}
+
+		// Turn a single import into a parenthesized one
+		start, end := fset.Position(gen.Pos()).Offset, fset.Position(gen.End()).Offset
+		spec := src[fset.Position(gen.Specs[0].Pos()).Offset:end]
+		return concat(src[:start], []byte("import (\n\t"), spec, []byte("\n"+specs.String()+")"), src[end:]), nil
+	}
+	at := fset.Position(file.Name.End()).Offset
+	return concat(src[:at], []byte("\n\nimport (\n"+specs.String()+")"), src[at:]), nil
+}
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// injectFiles injects the generated main file into the region of the existing
+// output file. Companion files need files of their own, so they are rejected.
+func injectFiles(opts *GenerateOptions, parts, embedded map[string][]byte) (map[string][]byte, error) {
+	for part := range parts {
+		if part != "" {
+			return nil, fmt.Errorf("cannot inject into a region: the config needs the companion file %s", PartPath(opts.OutputFile, part))
+		}
+	}
+	for name := range embedded {
+		return nil, fmt.Errorf("cannot inject into a region: the config embeds %s", name)
+	}
+
+	host, err := os.ReadFile(opts.OutputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", opts.OutputFile, err)
+	}
+	injected, err := injectRegion(host, parts[""], opts.Region)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", opts.OutputFile, err)
+	}
+	return map[string][]byte{opts.OutputFile: injected}, nil
+}
@@ -0,0 +1,228 @@
+// Package apidiff compares the exported API of two versions of a generated
+// Go file, to report the changes that break code using it, such as the
+// fields that become getter methods when the generation mode changes.
+package apidiff
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"sort"
+)
+
+// API is the exported API of a Go file, as a description of each exported
+// identifier by its path. The members of a value are reached through it,
+// such as "Server.Addr" for a field or getter method of Server and
+// "Servers[].Host" for one of the elements of Servers, so that the same
+// value compares equal whether it is a field or a method. Descriptions give
+// the kind and type, such as "var ServerConfig" or "method func() string".
+type API map[string]string
+
+// Extract returns the API of the Go source file src.
+func Extract(src []byte) (API, error) {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+
+	x := extractor{api: make(API), types: make(map[string]ast.Expr), members: make(map[string]map[string]member)}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv != nil && len(fn.Recv.List) == 1 && fn.Name.IsExported() {
+			x.addMember(receiverType(fn.Recv.List[0].Type), fn.Name.Name, member{"method " + types.ExprString(fn.Type), getterResult(fn.Type)})
+		}
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.TYPE {
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				x.types[ts.Name.Name] = ts.Type
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					for _, f := range st.Fields.List {
+						for _, name := range f.Names {
+							if name.IsExported() {
+								x.addMember(ts.Name.Name, name.Name, member{"field " + types.ExprString(f.Type), f.Type})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.IsExported() {
+				x.api[d.Name.Name] = types.ExprString(d.Type)
+				x.walk(d.Name.Name, getterResult(d.Type), nil)
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if !s.Name.IsExported() {
+						continue
+					}
+					if _, ok := s.Type.(*ast.StructType); ok {
+						x.api[s.Name.Name] = "type struct"
+					} else {
+						x.api[s.Name.Name] = "type " + types.ExprString(s.Type)
+					}
+					x.walk(s.Name.Name, s.Name, nil)
+				case *ast.ValueSpec:
+					for i, name := range s.Names {
+						if !name.IsExported() {
+							continue
+						}
+						typ := s.Type
+						if typ == nil && i < len(s.Values) {
+							if lit, ok := s.Values[i].(*ast.CompositeLit); ok {
+								typ = lit.Type
+							}
+						}
+						desc := d.Tok.String()
+						if typ != nil {
+							desc += " " + types.ExprString(typ)
+						}
+						x.api[name.Name] = desc
+						x.walk(name.Name, typ, nil)
+					}
+				}
+			}
+		}
+	}
+	return x.api, nil
+}
+
+// member is an exported field or method of a type declared in the file.
+type member struct {
+	desc string
+	typ  ast.Expr // Type the member gives access to, nil if none
+}
+
+// extractor collects the API of a parsed file.
+type extractor struct {
+	api     API
+	types   map[string]ast.Expr          // Type declarations, by name
+	members map[string]map[string]member // Fields and methods, by type and member name
+}
+
+func (x *extractor) addMember(typeName, name string, m member) {
+	if x.members[typeName] == nil {
+		x.members[typeName] = make(map[string]member)
+	}
+	x.members[typeName][name] = m
+}
+
+// walk adds the members reachable from the value at path, of type typ. seen
+// holds the types declared in the file that path already goes through.
+func (x *extractor) walk(path string, typ ast.Expr, seen []string) {
+	switch t := typ.(type) {
+	case *ast.StarExpr:
+		x.walk(path, t.X, seen)
+	case *ast.ArrayType:
+		x.walk(path+"[]", t.Elt, seen)
+	case *ast.Ident:
+		if _, ok := x.types[t.Name]; !ok || slices.Contains(seen, t.Name) {
+			return
+		}
+		seen = append(seen, t.Name)
+		for _, name := range slices.Sorted(maps.Keys(x.members[t.Name])) {
+			m := x.members[t.Name][name]
+			x.api[path+"."+name] = m.desc
+			x.walk(path+"."+name, m.typ, seen)
+		}
+	}
+}
+
+// receiverType returns the name of the type of a method receiver.
+func receiverType(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if id, ok := expr.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
+
+// getterResult returns the result type of a function taking no parameters
+// and returning one value, such as a getter, or nil.
+func getterResult(fn *ast.FuncType) ast.Expr {
+	if fn.Params.NumFields() != 0 || fn.Results.NumFields() != 1 {
+		return nil
+	}
+	return fn.Results.List[0].Type
+}
+
+// Change is an identifier of the API added, removed or changed.
+type Change struct {
+	Path string // Path of the identifier, as in API
+	Old  string // Description in the old API, "" if added
+	New  string // Description in the new API, "" if removed
+}
+
+// Breaking reports whether code using the old API may not compile against
+// the new one: anything but an addition is.
+func (c Change) Breaking() bool {
+	return c.Old != ""
+}
+
+func (c Change) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s: added (%s)", c.Path, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s: removed (%s)", c.Path, c.Old)
+	}
+	return fmt.Sprintf("%s: %s is now %s", c.Path, c.Old, c.New)
+}
+
+// Compare returns the changes from the old API to the new one, sorted by
+// path. The members of a removed identifier are not reported separately.
+func Compare(old, new API) []Change {
+	var changes []Change
+	for path, desc := range old {
+		if newDesc := new[path]; newDesc != desc {
+			changes = append(changes, Change{Path: path, Old: desc, New: newDesc})
+		}
+	}
+	for path, desc := range new {
+		if _, ok := old[path]; !ok {
+			changes = append(changes, Change{Path: path, New: desc})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+
+	// Drop the members of identifiers added or removed as a whole
+	var kept []Change
+	var whole []Change
+	for _, c := range changes {
+		if slices.ContainsFunc(whole, func(w Change) bool { return isMember(c.Path, w.Path) && (w.Old == "") == (c.Old == "") }) {
+			continue
+		}
+		if c.Old == "" || c.New == "" {
+			whole = append(whole, c)
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// isMember reports whether path is reached through parent.
+func isMember(path, parent string) bool {
+	return len(path) > len(parent) && path[:len(parent)] == parent && (path[len(parent)] == '.' || path[len(parent)] == '[')
+}
+
+// Breaking returns the number of breaking changes.
+func Breaking(changes []Change) int {
+	n := 0
+	for _, c := range changes {
+		if c.Breaking() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package apidiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const static = `package config
+
+type ServerConfig struct {
+	Addr string
+	TLS  ServerTLSConfig
+}
+
+type ServerTLSConfig struct {
+	Cert []byte
+}
+
+type ServersItem struct {
+	Host string
+}
+
+func (c ServerConfig) String() string { return "" }
+
+const Version = "1"
+
+var (
+	Name    string = "svc"
+	Server         = ServerConfig{Addr: ":8080"}
+	Servers        = []ServersItem{{Host: "a"}}
+	port           = 8080
+)
+`
+
+const getter = `package config
+
+type serverConfig struct{}
+
+type serverTLSConfig struct{}
+
+type serversItem struct {
+	Host string
+}
+
+func (serverConfig) Addr() string { return "" }
+
+func (serverConfig) TLS() serverTLSConfig { return serverTLSConfig{} }
+
+func (serverTLSConfig) Cert() []byte { return nil }
+
+func Name() string { return "svc" }
+
+func Servers() []serversItem { return nil }
+
+func Usage() map[string]int64 { return nil }
+
+var Server serverConfig
+`
+
+func TestExtract(t *testing.T) {
+	api, err := Extract([]byte(static))
+	require.NoError(t, err)
+	require.Equal(t, API{
+		"ServerConfig":          "type struct",
+		"ServerConfig.Addr":     "field string",
+		"ServerConfig.String":   "method func() string",
+		"ServerConfig.TLS":      "field ServerTLSConfig",
+		"ServerConfig.TLS.Cert": "field []byte",
+		"ServerTLSConfig":       "type struct",
+		"ServerTLSConfig.Cert":  "field []byte",
+		"ServersItem":           "type struct",
+		"ServersItem.Host":      "field string",
+		"Version":               "const",
+		"Name":                  "var string",
+		"Server":                "var ServerConfig",
+		"Server.Addr":           "field string",
+		"Server.String":         "method func() string",
+		"Server.TLS":            "field ServerTLSConfig",
+		"Server.TLS.Cert":       "field []byte",
+		"Servers":               "var []ServersItem",
+		"Servers[].Host":        "field string",
+	}, api)
+
+	_, err = Extract([]byte("not go"))
+	require.Error(t, err)
+}
+
+func TestCompare(t *testing.T) {
+	old, err := Extract([]byte(static))
+	require.NoError(t, err)
+	new, err := Extract([]byte(getter))
+	require.NoError(t, err)
+
+	var lines []string
+	for _, c := range Compare(old, new) {
+		lines = append(lines, c.String())
+	}
+	require.Equal(t, []string{
+		"Name: var string is now func() string",
+		"Server: var ServerConfig is now var serverConfig",
+		"Server.Addr: field string is now method func() string",
+		"Server.String: removed (method func() string)",
+		"Server.TLS: field ServerTLSConfig is now method func() serverTLSConfig",
+		"Server.TLS.Cert: field []byte is now method func() []byte",
+		"ServerConfig: removed (type struct)",
+		"ServerTLSConfig: removed (type struct)",
+		"Servers: var []ServersItem is now func() []serversItem",
+		"ServersItem: removed (type struct)",
+		"Usage: added (func() map[string]int64)",
+		"Version: removed (const)",
+	}, lines)
+
+	changes := Compare(old, new)
+	require.Equal(t, 11, Breaking(changes))
+	require.Empty(t, Compare(old, old))
+}
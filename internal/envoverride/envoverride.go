@@ -4,47 +4,135 @@ package envoverride
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/present"
 )
 
 // Apply applies environment variable overrides to TOML data.
-// Environment variables follow the pattern: CONFIG_<SECTION>_<KEY>
-func Apply(data map[string]any) error {
-	for key, value := range data {
-		prefix := "CONFIG_" + strings.ToUpper(key)
+// Environment variables follow the pattern: <PREFIX>_<SECTION>_<KEY>, with
+// prefix "CONFIG" by default. The first value that does not parse as the
+// type of the value it overrides is reported as *Error.
+func Apply(data map[string]any, envPrefix string) error {
+	return applyNested(data, "", envPrefix, func(err *Error) error { return err })
+}
 
-		switch val := value.(type) {
-		case map[string]any:
-			// Nested map - recursively apply overrides
-			if err := applyNested(val, prefix); err != nil {
-				return fmt.Errorf("error in section %s: %w", key, err)
-			}
-		default:
-			// Top-level value - check for override
-			envKey := prefix
-			if envVal := os.Getenv(envKey); envVal != "" {
-				converted, err := convertValue(envVal, value)
-				if err != nil {
-					return fmt.Errorf("invalid value for %s: %w", envKey, err)
-				}
-				data[key] = converted
-			}
+// ApplyValid is Apply for the values that parse. The values overridden by the
+// others keep their value and are reported as *Error, in key order.
+func ApplyValid(data map[string]any, envPrefix string) []error {
+	var errs []*Error
+	applyNested(data, "", envPrefix, func(err *Error) error {
+		errs = append(errs, err)
+		return nil
+	})
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Key < errs[j].Key })
+
+	result := make([]error, len(errs))
+	for i, err := range errs {
+		result[i] = err
+	}
+	return result
+}
+
+// Error is an environment variable override that does not parse as the type
+// of the value it overrides.
+type Error struct {
+	Key    string // Dotted key path of the overridden value
+	EnvVar string // Name of the environment variable
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid value for %s (%s): %v", e.EnvVar, e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Check reports every environment variable override of data that Apply
+// would reject, in key order, without applying any.
+func Check(data map[string]any, envPrefix string) []error {
+	var errs []error
+	walk(data, "", envPrefix, func(path, envKey string, err error) {
+		if err != nil {
+			errs = append(errs, &Error{Key: path, EnvVar: envKey, Err: err})
 		}
+	})
+	return errs
+}
+
+// Overrides returns the environment variable that Apply or ApplyValid would
+// override each value of data with, by dotted key path, without applying
+// any. Variables that do not parse are left out.
+func Overrides(data map[string]any, envPrefix string) map[string]string {
+	overrides := make(map[string]string)
+	walk(data, "", envPrefix, func(path, envKey string, err error) {
+		if err == nil {
+			overrides[path] = envKey
+		}
+	})
+	return overrides
+}
+
+// walk calls visit, in key order, for each value of a table whose dotted key
+// path is prefix that an environment variable is set for, with the error of
+// parsing it as the type of the value, if any.
+func walk(data map[string]any, prefix, envPrefix string, visit func(path, envKey string, err error)) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	return nil
+	for _, key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		envKey := envPrefix + "_" + strings.ToUpper(key)
+
+		if nested, ok := data[key].(map[string]any); ok {
+			walk(nested, path, envKey, visit)
+			continue
+		}
+		envVal := os.Getenv(envKey)
+		if envVal == "" {
+			continue
+		}
+
+		var err error
+		if arr, ok := data[key].([]any); ok {
+			if len(arr) == 0 {
+				continue
+			}
+			_, err = convertArray(envVal, arr)
+		} else {
+			_, err = convertValue(envVal, data[key])
+		}
+		visit(path, envKey, err)
+	}
 }
 
-// applyNested applies environment variable overrides to nested maps
-func applyNested(data map[string]any, prefix string) error {
+// applyNested applies environment variable overrides to a table whose
+// dotted key path is prefix. Values that do not parse are passed to fail,
+// which stops applying by returning an error.
+func applyNested(data map[string]any, prefix, envPrefix string, fail func(*Error) error) error {
 	for key, value := range data {
-		envKey := prefix + "_" + strings.ToUpper(key)
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		envKey := envPrefix + "_" + strings.ToUpper(key)
 
 		switch val := value.(type) {
 		case map[string]any:
 			// Further nested map
-			if err := applyNested(val, envKey); err != nil {
+			if err := applyNested(val, path, envKey, fail); err != nil {
 				return err
 			}
 		case []any:
@@ -52,10 +140,12 @@ func applyNested(data map[string]any, prefix string) error {
 			// For arrays, we support comma-separated values for primitives
 			if envVal := os.Getenv(envKey); envVal != "" {
 				if len(val) > 0 {
-					// Determine element type from first element
-					converted, err := convertArray(envVal, val[0])
+					converted, err := convertArray(envVal, val)
 					if err != nil {
-						return fmt.Errorf("invalid array value for %s: %w", envKey, err)
+						if err := fail(&Error{Key: path, EnvVar: envKey, Err: err}); err != nil {
+							return err
+						}
+						continue
 					}
 					data[key] = converted
 				}
@@ -65,7 +155,10 @@ func applyNested(data map[string]any, prefix string) error {
 			if envVal := os.Getenv(envKey); envVal != "" {
 				converted, err := convertValue(envVal, value)
 				if err != nil {
-					return fmt.Errorf("invalid value for %s: %w", envKey, err)
+					if err := fail(&Error{Key: path, EnvVar: envKey, Err: err}); err != nil {
+						return err
+					}
+					continue
 				}
 				data[key] = converted
 			}
@@ -102,24 +195,72 @@ func convertValue[T any](envVal string, originalVal T) (any, error) {
 		}
 		return v, nil
 
+	case time.Time:
+		v, err := time.Parse(time.RFC3339, envVal)
+		if err != nil {
+			return nil, fmt.Errorf("expected RFC 3339 datetime: %w", err)
+		}
+		return v, nil
+
 	default:
 		return envVal, nil
 	}
 }
 
 // convertArray converts a comma-separated environment variable to an array
-func convertArray[T any](envVal string, sampleElem T) (any, error) {
+// of values of the element type generated code declares arr with. Elements
+// of arrays of durations and of byte sizes, which TOML holds as strings, must
+// parse as such and are kept as written, as the generator expects them.
+func convertArray(envVal string, arr []any) (any, error) {
+	convert := func(s string) (any, error) { return convertValue(s, arr[0]) }
+	switch {
+	case generator.ElemType(arr) == "time.Duration":
+		convert = convertDuration
+	case isSizeArray(arr):
+		convert = convertSize
+	}
+
 	parts := strings.Split(envVal, ",")
 	result := make([]any, 0, len(parts))
 
-	for _, part := range parts {
-		part = strings.TrimSpace(part)
-		converted, err := convertValue(part, sampleElem)
+	for i, part := range parts {
+		converted, err := convert(strings.TrimSpace(part))
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("element %d: %w", i, err)
 		}
 		result = append(result, converted)
 	}
 
 	return result, nil
 }
+
+// isSizeArray reports whether arr holds byte sizes such as "512KB": strings
+// of a number of bytes with a unit, which present.ParseSize parses.
+func isSizeArray(arr []any) bool {
+	for _, v := range arr {
+		s, ok := v.(string)
+		if !ok || strings.TrimLeft(s, "0123456789") == s {
+			return false
+		}
+		if _, err := present.ParseSize(s); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// convertSize checks that s is a byte size such as "512KB".
+func convertSize(s string) (any, error) {
+	if _, err := present.ParseSize(s); err != nil {
+		return nil, fmt.Errorf("expected byte size: %w", err)
+	}
+	return s, nil
+}
+
+// convertDuration checks that s is a duration such as "1m30s".
+func convertDuration(s string) (any, error) {
+	if _, err := time.ParseDuration(s); err != nil {
+		return nil, fmt.Errorf("expected duration: %w", err)
+	}
+	return s, nil
+}
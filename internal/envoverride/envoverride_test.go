@@ -3,6 +3,7 @@ package envoverride
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -17,7 +18,7 @@ func TestApply_String(t *testing.T) {
 	os.Setenv("CONFIG_SERVER_ADDR", ":9090")
 	defer os.Unsetenv("CONFIG_SERVER_ADDR")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	serverMap := data["server"].(map[string]any)
@@ -34,7 +35,7 @@ func TestApply_Int(t *testing.T) {
 	os.Setenv("CONFIG_DATABASE_MAX_CONNS", "50")
 	defer os.Unsetenv("CONFIG_DATABASE_MAX_CONNS")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	dbMap := data["database"].(map[string]any)
@@ -51,7 +52,7 @@ func TestApply_Float(t *testing.T) {
 	os.Setenv("CONFIG_CACHE_TTL", "60.75")
 	defer os.Unsetenv("CONFIG_CACHE_TTL")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	cacheMap := data["cache"].(map[string]any)
@@ -68,7 +69,7 @@ func TestApply_Bool(t *testing.T) {
 	os.Setenv("CONFIG_APP_DEBUG", "true")
 	defer os.Unsetenv("CONFIG_APP_DEBUG")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	appMap := data["app"].(map[string]any)
@@ -85,7 +86,7 @@ func TestApply_Array(t *testing.T) {
 	os.Setenv("CONFIG_SERVICE_PORTS", "9000,9001,9002")
 	defer os.Unsetenv("CONFIG_SERVICE_PORTS")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	serviceMap := data["service"].(map[string]any)
@@ -99,6 +100,46 @@ func TestApply_Array(t *testing.T) {
 	}
 }
 
+func TestApply_InvalidArrayElement(t *testing.T) {
+	data := map[string]any{
+		"service": map[string]any{
+			"ports": []any{int64(8080)},
+		},
+	}
+
+	t.Setenv("CONFIG_SERVICE_PORTS", "9000,abc,9002")
+
+	err := Apply(data, "CONFIG")
+	var envErr *Error
+	require.ErrorAs(t, err, &envErr)
+	require.Equal(t, "service.ports", envErr.Key)
+	require.EqualError(t, err, `invalid value for CONFIG_SERVICE_PORTS (service.ports): element 1: expected integer: strconv.ParseInt: parsing "abc": invalid syntax`)
+}
+
+func TestApplyValid(t *testing.T) {
+	data := map[string]any{
+		"port": int64(80),
+		"service": map[string]any{
+			"name":  "api",
+			"ports": []any{int64(8080)},
+		},
+	}
+
+	t.Setenv("CONFIG_PORT", "eighty")
+	t.Setenv("CONFIG_SERVICE_NAME", "web")
+	t.Setenv("CONFIG_SERVICE_PORTS", "1,x")
+
+	errs := ApplyValid(data, "CONFIG")
+	require.Len(t, errs, 2)
+	require.ErrorContains(t, errs[0], "invalid value for CONFIG_PORT (port)")
+	require.ErrorContains(t, errs[1], "invalid value for CONFIG_SERVICE_PORTS (service.ports): element 1")
+
+	require.Equal(t, int64(80), data["port"])
+	service := data["service"].(map[string]any)
+	require.Equal(t, "web", service["name"])
+	require.Equal(t, []any{int64(8080)}, service["ports"])
+}
+
 func TestApply_StringArray(t *testing.T) {
 	data := map[string]any{
 		"service": map[string]any{
@@ -109,7 +150,7 @@ func TestApply_StringArray(t *testing.T) {
 	os.Setenv("CONFIG_SERVICE_ORIGINS", "https://example.com,https://api.example.com")
 	defer os.Unsetenv("CONFIG_SERVICE_ORIGINS")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	serviceMap := data["service"].(map[string]any)
@@ -123,6 +164,35 @@ func TestApply_StringArray(t *testing.T) {
 	}
 }
 
+func TestApply_DurationAndSizeArrays(t *testing.T) {
+	newData := func() map[string]any {
+		return map[string]any{
+			"retry": map[string]any{
+				"backoff": []any{"100ms", "1s"},
+				"buffers": []any{"4KB", "1MB"},
+			},
+		}
+	}
+
+	t.Setenv("CONFIG_RETRY_BACKOFF", "50ms, 2m30s")
+	t.Setenv("CONFIG_RETRY_BUFFERS", "512, 64kb,2GB")
+	data := newData()
+	require.NoError(t, Apply(data, "CONFIG"))
+
+	retry := data["retry"].(map[string]any)
+	require.Equal(t, []any{"50ms", "2m30s"}, retry["backoff"])
+	require.Equal(t, []any{"512", "64kb", "2GB"}, retry["buffers"])
+
+	t.Setenv("CONFIG_RETRY_BACKOFF", "1s,soon")
+	err := Apply(newData(), "CONFIG")
+	require.ErrorContains(t, err, "invalid value for CONFIG_RETRY_BACKOFF (retry.backoff): element 1: expected duration")
+
+	t.Setenv("CONFIG_RETRY_BACKOFF", "1s")
+	t.Setenv("CONFIG_RETRY_BUFFERS", "1MB,lots")
+	err = Apply(newData(), "CONFIG")
+	require.ErrorContains(t, err, `invalid value for CONFIG_RETRY_BUFFERS (retry.buffers): element 1: expected byte size: invalid size "lots"`)
+}
+
 func TestApply_DeepNesting(t *testing.T) {
 	data := map[string]any{
 		"app": map[string]any{
@@ -137,7 +207,7 @@ func TestApply_DeepNesting(t *testing.T) {
 	os.Setenv("CONFIG_APP_LOGGING_ROTATION_MAX_SIZE", "500")
 	defer os.Unsetenv("CONFIG_APP_LOGGING_ROTATION_MAX_SIZE")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	appMap := data["app"].(map[string]any)
@@ -154,7 +224,7 @@ func TestApply_NoEnvVar(t *testing.T) {
 		},
 	}
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	// Value should remain unchanged
@@ -172,7 +242,7 @@ func TestApply_InvalidInt(t *testing.T) {
 	os.Setenv("CONFIG_DATABASE_MAX_CONNS", "not-a-number")
 	defer os.Unsetenv("CONFIG_DATABASE_MAX_CONNS")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.Error(t, err, "expected error for invalid int value")
 }
 
@@ -186,7 +256,7 @@ func TestApply_InvalidBool(t *testing.T) {
 	os.Setenv("CONFIG_APP_DEBUG", "not-a-bool")
 	defer os.Unsetenv("CONFIG_APP_DEBUG")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.Error(t, err, "expected error for invalid bool value")
 }
 
@@ -200,7 +270,7 @@ func TestApply_InvalidFloat(t *testing.T) {
 	os.Setenv("CONFIG_CACHE_TTL", "not-a-float")
 	defer os.Unsetenv("CONFIG_CACHE_TTL")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.Error(t, err, "expected error for invalid float value")
 }
 
@@ -223,7 +293,7 @@ func TestApply_MultipleSections(t *testing.T) {
 	defer os.Unsetenv("CONFIG_DATABASE_DSN")
 	defer os.Unsetenv("CONFIG_DATABASE_MAX_CONNS")
 
-	err := Apply(data)
+	err := Apply(data, "CONFIG")
 	require.NoError(t, err, "Apply() should not error")
 
 	serverMap := data["server"].(map[string]any)
@@ -265,3 +335,77 @@ func TestConvertValue(t *testing.T) {
 		})
 	}
 }
+
+func TestCheck(t *testing.T) {
+	data := map[string]any{
+		"port": int64(80),
+		"database": map[string]any{
+			"max_conns": int64(10),
+			"debug":     false,
+			"hosts":     []any{int64(1)},
+			"name":      "app",
+		},
+	}
+
+	t.Setenv("CONFIG_PORT", "eighty")
+	t.Setenv("CONFIG_DATABASE_MAX_CONNS", "12")
+	t.Setenv("CONFIG_DATABASE_DEBUG", "maybe")
+	t.Setenv("CONFIG_DATABASE_HOSTS", "1,x")
+	t.Setenv("CONFIG_DATABASE_NAME", "other")
+
+	errs := Check(data, "CONFIG")
+	require.Len(t, errs, 3)
+
+	var envErr *Error
+	require.ErrorAs(t, errs[0], &envErr)
+	require.Equal(t, "database.debug", envErr.Key)
+	require.Equal(t, "CONFIG_DATABASE_DEBUG", envErr.EnvVar)
+	require.ErrorContains(t, errs[1], "invalid value for CONFIG_DATABASE_HOSTS (database.hosts): element 1: expected integer")
+	require.ErrorContains(t, errs[2], "invalid value for CONFIG_PORT (port): expected integer")
+
+	require.Equal(t, int64(10), data["database"].(map[string]any)["max_conns"], "Check must not apply overrides")
+}
+
+func TestOverrides(t *testing.T) {
+	data := map[string]any{
+		"port": int64(80),
+		"database": map[string]any{
+			"max_conns": int64(10),
+			"hosts":     []any{},
+			"name":      "app",
+		},
+	}
+
+	t.Setenv("CONFIG_PORT", "eighty")
+	t.Setenv("CONFIG_DATABASE_MAX_CONNS", "12")
+	t.Setenv("CONFIG_DATABASE_HOSTS", "a,b")
+
+	require.Equal(t, map[string]string{"database.max_conns": "CONFIG_DATABASE_MAX_CONNS"}, Overrides(data, "CONFIG"))
+	require.Equal(t, int64(10), data["database"].(map[string]any)["max_conns"], "Overrides must not apply overrides")
+}
+
+func TestApply_Prefix(t *testing.T) {
+	data := map[string]any{
+		"port":   int64(80),
+		"server": map[string]any{"host": "localhost"},
+	}
+
+	t.Setenv("CONFIG_PORT", "81")
+	t.Setenv("MYAPP_PORT", "82")
+	t.Setenv("MYAPP_SERVER_HOST", "example.com")
+
+	require.NoError(t, Apply(data, "MYAPP"))
+	require.Equal(t, int64(82), data["port"])
+	require.Equal(t, "example.com", data["server"].(map[string]any)["host"])
+}
+
+func TestApply_Datetime(t *testing.T) {
+	data := map[string]any{"released_at": time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	t.Setenv("CONFIG_RELEASED_AT", "2025-02-03T04:05:06+01:00")
+	require.NoError(t, Apply(data, "CONFIG"))
+	require.True(t, time.Date(2025, time.February, 3, 3, 5, 6, 0, time.UTC).Equal(data["released_at"].(time.Time)))
+
+	t.Setenv("CONFIG_RELEASED_AT", "tomorrow")
+	require.ErrorContains(t, Apply(data, "CONFIG"), "invalid value for CONFIG_RELEASED_AT (released_at): expected RFC 3339 datetime")
+}
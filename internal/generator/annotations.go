@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// annotation returns the value of a cfgx annotation on the given dotted key path.
+func (g *Generator) annotation(key, name string) (string, bool) {
+	if g.src == nil {
+		return "", false
+	}
+	return g.src.Annotation(key, name)
+}
+
+// annotated returns the keys carrying the named annotation that are part of
+// the data being generated, in source order. Tables split off with
+// cfgx:package are generated separately, so their keys are skipped in the
+// main run and vice versa.
+func (g *Generator) annotated(name string) []string {
+	var keys []string
+	for _, key := range g.src.Annotated(name) {
+		top, _, _ := strings.Cut(key, ".")
+		if _, ok := g.data[top]; ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// writeTypeDoc writes the doc comment of a generated struct type, derived from the
+// annotations on the TOML table it was generated from. Nothing is written for
+// tables without documented annotations.
+func (g *Generator) writeTypeDoc(buf *bytes.Buffer, structName string) {
+	key, ok := g.structKeys[structName]
+	if !ok {
+		return
+	}
+
+	var lines []string
+	if owner, ok := g.annotation(key, "owner"); ok && owner != "" {
+		lines = append(lines, "Owner: "+owner)
+	}
+	lines = append(lines, g.lifecycleDoc(key)...)
+
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(buf, "// %s holds the [%s] table.\n", structName, key)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "//\n// %s\n", line)
+	}
+}
+
+// writeFieldDoc writes the doc comment of a generated field, variable or getter
+// for the given dotted key path, prefixed with indent.
+func (g *Generator) writeFieldDoc(buf *bytes.Buffer, key, indent string) {
+	for i, line := range g.lifecycleDoc(key) {
+		if i > 0 {
+			fmt.Fprintf(buf, "%s//\n", indent)
+		}
+		fmt.Fprintf(buf, "%s// %s\n", indent, line)
+	}
+}
+
+// lifecycleDoc returns doc paragraphs for the stability and removed-in
+// annotations on key. Deprecation uses the standard "Deprecated:" form so
+// that linters and gopls flag uses of the generated identifier.
+func (g *Generator) lifecycleDoc(key string) []string {
+	var lines []string
+
+	if stability, ok := g.annotation(key, "stability"); ok {
+		switch stability {
+		case "experimental":
+			lines = append(lines, "Experimental: this setting may change or be removed without notice.")
+		case "deprecated":
+			lines = append(lines, "Deprecated: this setting is no longer supported.")
+		}
+	}
+
+	if version, ok := g.annotation(key, "removed-in"); ok && version != "" {
+		lines = append(lines, fmt.Sprintf("Deprecated: this setting will be removed in %s.", version))
+	}
+
+	return lines
+}
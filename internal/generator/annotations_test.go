@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_OwnerAnnotation(t *testing.T) {
+	data := []byte(`
+# cfgx:owner=platform-team
+[database]
+dsn = "postgres://localhost/app"
+
+[database.pool]
+size = 10
+
+[server]
+addr = ":8080"
+`)
+
+	for _, mode := range []string{"static", "getter"} {
+		t.Run(mode, func(t *testing.T) {
+			gen := New(WithMode(mode))
+			output, err := gen.Generate(data)
+			require.NoError(t, err, "Generate() should not error")
+
+			outputStr := string(output)
+			require.Contains(t, outputStr, "holds the [database] table.\n//\n// Owner: platform-team\ntype ")
+			require.Equal(t, 1, strings.Count(outputStr, "// Owner:"), "only the annotated table should be documented")
+		})
+	}
+}
+
+func TestGenerator_LifecycleAnnotations(t *testing.T) {
+	data := []byte(`
+# cfgx:stability=experimental
+[beta]
+enabled = true
+
+[server]
+# cfgx:removed-in=2.0
+legacy = true
+addr = ":8080"
+
+timeout = 30 # cfgx:stability=deprecated
+`)
+
+	t.Run("static", func(t *testing.T) {
+		output, err := New().Generate(data)
+		require.NoError(t, err, "Generate() should not error")
+
+		outputStr := string(output)
+		require.Contains(t, outputStr, "// BetaConfig holds the [beta] table.\n//\n// Experimental:")
+		require.Contains(t, outputStr, "\t// Deprecated: this setting will be removed in 2.0.\n\tLegacy bool\n")
+		require.Contains(t, outputStr, "\t// Deprecated: this setting is no longer supported.\n\tTimeout int64\n")
+		require.Contains(t, outputStr, "\tAddr string\n", "unannotated fields should have no doc")
+	})
+
+	t.Run("getter", func(t *testing.T) {
+		output, err := New(WithMode("getter")).Generate(data)
+		require.NoError(t, err, "Generate() should not error")
+
+		require.Contains(t, string(output), "// Deprecated: this setting will be removed in 2.0.\nfunc (serverConfig) Legacy() bool {")
+	})
+}
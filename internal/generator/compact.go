@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithStyle sets how static mode exposes the values: "vars" (the default)
+// declares a package variable for each top-level key, and "compact" a Config
+// type holding them all and a Default function returning them, so that the
+// generated package holds no global state. Generated functions reading the
+// configuration, such as Validate, are then methods of Config.
+func WithStyle(style string) Option {
+	return func(g *Generator) {
+		g.style = style
+	}
+}
+
+// compact reports whether the values are generated in compact style.
+func (g *Generator) compact() bool {
+	return g.style == "compact"
+}
+
+// validateStyle checks that compact style is only used in static mode.
+func (g *Generator) validateStyle() error {
+	switch g.style {
+	case "", "vars":
+	case "compact":
+		if g.mode != "static" {
+			return fmt.Errorf("compact style requires static mode, not %s mode", g.mode)
+		}
+	default:
+		return fmt.Errorf("invalid style %q: must be 'vars' or 'compact'", g.style)
+	}
+	return nil
+}
+
+// writeCompact writes, in compact style, the Config type with a field for
+// each top-level key and the Default function returning its values as a
+// single literal, in place of the package variables.
+func (g *Generator) writeCompact(buf *bytes.Buffer, data map[string]any) error {
+	g.structKeys["Config"] = ""
+
+	buf.WriteString("// Config holds the whole configuration, as returned by Default.\n")
+	if err := g.generateStruct(buf, "Config", data); err != nil {
+		return err
+	}
+	buf.WriteString("\n\n")
+
+	buf.WriteString("// Default returns the configuration baked in at generation time.\n")
+	buf.WriteString("func Default() Config {\n")
+	buf.WriteString("\treturn Config")
+	if err := g.generateStructInit(buf, "Config", data, 1); err != nil {
+		return err
+	}
+	buf.WriteString("\n}\n")
+	return nil
+}
+
+// funcDecl returns the start of the declaration of a generated function
+// reading the configuration with the given signature, such as
+// "Validate() error": a method of Config, with receiver c, in compact style.
+func (g *Generator) funcDecl(signature string) string {
+	if g.compact() {
+		return "func (c Config) " + signature
+	}
+	return "func " + signature
+}
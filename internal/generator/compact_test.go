@@ -0,0 +1,35 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_CompactStyle(t *testing.T) {
+	data := []byte(`
+name = "api"
+token = "t" # cfgx:runtime-only
+
+[server]
+port = 8080 # cfgx: min=1
+
+[[workers]]
+queue = "a"
+`)
+
+	output, err := New(WithStyle("compact"), WithSummary(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.NotContains(t, outputStr, "var (")
+	require.Contains(t, outputStr, "type Config struct {\n\tName    string\n\tServer  ServerConfig\n\tWorkers []WorkersItem\n}")
+	require.Contains(t, outputStr, "func Default() Config {\n\treturn Config{\n\t\tName: \"api\",\n\t\tServer: ServerConfig{\n\t\t\tPort: 8080,\n\t\t},\n")
+	require.Contains(t, outputStr, "func (Config) Token() string {")
+	require.Contains(t, outputStr, "func (c Config) Validate() error {")
+	require.Contains(t, outputStr, "if v := c.Server.Port; !(v >= 1) {")
+	require.Contains(t, outputStr, "func (c Config) PrintSummary(w io.Writer) {")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"workers[0].queue\\t%q\\n\", c.Workers[0].Queue)")
+
+	_, err = New(WithStyle("compact"), WithMode("hybrid")).Generate(data)
+	require.EqualError(t, err, "compact style requires static mode, not hybrid mode")
+}
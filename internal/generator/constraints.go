@@ -0,0 +1,385 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// metaTable is the top-level table holding constraints for keys elsewhere in
+// the configuration, as an alternative to constraint annotations.
+const metaTable = "_meta"
+
+// constraintNames lists the constraint annotations, which are also the keys
+// accepted in the _meta table.
+var constraintNames = []string{"enum", "max", "min", "nonempty", "regex"}
+
+// constraints are the value constraints on a key:
+//
+//	port = 8080 # cfgx: min=1 max=65535
+//	level = "info" # cfgx:enum=debug,info,warn,error
+//	name = "api" # cfgx:nonempty regex=^[a-z]+$
+//
+// or equivalently in the _meta table:
+//
+//	[_meta.server.port]
+//	min = 1
+//	max = 65535
+type constraints struct {
+	min, max string   // Bounds as written, parsed according to the value's type
+	regex    string   // Pattern string values must match
+	nonempty bool     // Whether strings and arrays must not be empty
+	enum     []string // Allowed values, as written
+}
+
+// takeMeta removes the _meta table from data and returns the constraints it
+// declares, by dotted key path.
+func takeMeta(data map[string]any) (map[string]constraints, error) {
+	v, ok := data[metaTable]
+	if !ok {
+		return nil, nil
+	}
+	delete(data, metaTable)
+
+	table, ok := v.(map[string]any)
+	if !ok {
+		return nil, &KeyError{Key: metaTable, Err: fmt.Errorf("must be a table")}
+	}
+	meta := make(map[string]constraints)
+	if err := collectMeta(meta, data, "", table); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// collectMeta walks a _meta table below prefix. Tables whose keys are all
+// constraint names hold the constraints for prefix; others nest further.
+func collectMeta(meta map[string]constraints, data map[string]any, prefix string, table map[string]any) error {
+	if prefix != "" && isConstraintTable(table) {
+		if len(lookupValues(data, prefix)) == 0 {
+			return &KeyError{Key: metaTable + "." + prefix, Err: fmt.Errorf("no value named %q to constrain", prefix)}
+		}
+		c, err := metaConstraints(table)
+		if err != nil {
+			return &KeyError{Key: metaTable + "." + prefix, Err: err}
+		}
+		meta[prefix] = c
+		return nil
+	}
+
+	for k, v := range table {
+		key := joinKey(prefix, k)
+		sub, ok := v.(map[string]any)
+		if !ok {
+			return &KeyError{Key: metaTable + "." + key, Err: fmt.Errorf("expected a table of constraints (%s)", strings.Join(constraintNames, ", "))}
+		}
+		if err := collectMeta(meta, data, key, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isConstraintTable reports whether every key of table names a constraint.
+func isConstraintTable(table map[string]any) bool {
+	if len(table) == 0 {
+		return false
+	}
+	for k := range table {
+		if !isConstraintName(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func isConstraintName(name string) bool {
+	for _, n := range constraintNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// metaConstraints converts a _meta constraint table.
+func metaConstraints(table map[string]any) (constraints, error) {
+	var c constraints
+	for name, v := range table {
+		switch name {
+		case "min", "max":
+			var bound string
+			switch v := v.(type) {
+			case int64, float64, string:
+				bound = fmt.Sprint(v)
+			default:
+				return c, fmt.Errorf("%s must be a number or duration string", name)
+			}
+			if name == "min" {
+				c.min = bound
+			} else {
+				c.max = bound
+			}
+		case "regex":
+			s, ok := v.(string)
+			if !ok {
+				return c, fmt.Errorf("regex must be a string")
+			}
+			c.regex = s
+		case "nonempty":
+			b, ok := v.(bool)
+			if !ok {
+				return c, fmt.Errorf("nonempty must be a bool")
+			}
+			c.nonempty = b
+		case "enum":
+			items, ok := v.([]any)
+			if !ok {
+				return c, fmt.Errorf("enum must be an array")
+			}
+			for _, item := range items {
+				switch item.(type) {
+				case string, int64:
+					c.enum = append(c.enum, fmt.Sprint(item))
+				default:
+					return c, fmt.Errorf("enum values must be strings or integers")
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// keyConstraints returns the constraints on every key of the data being
+// generated, from annotations and the _meta table, annotations taking
+// precedence.
+func (g *Generator) keyConstraints() map[string]constraints {
+	all := make(map[string]constraints)
+	for key, c := range g.meta {
+		top, _, _ := strings.Cut(key, ".")
+		if _, ok := g.data[top]; ok {
+			all[key] = c
+		}
+	}
+	if g.src == nil {
+		return all
+	}
+
+	for _, name := range constraintNames {
+		for _, key := range g.annotated(name) {
+			value, _ := g.annotation(key, name)
+			c := all[key]
+			switch name {
+			case "min":
+				c.min = value
+			case "max":
+				c.max = value
+			case "regex":
+				c.regex = value
+			case "nonempty":
+				c.nonempty = value == "" || value == "true"
+			case "enum":
+				c.enum = nil
+				for _, item := range strings.Split(value, ",") {
+					c.enum = append(c.enum, strings.TrimSpace(item))
+				}
+			}
+			all[key] = c
+		}
+	}
+	return all
+}
+
+// validateConstraints checks values against their constraints at generation
+// time and records a runtime check for each constraint, so values overridden
+// through the environment in getter mode are checked by Validate too.
+func (g *Generator) validateConstraints(data map[string]any) error {
+	all := g.keyConstraints()
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := lookupValues(data, key)
+		if len(values) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("constraints must annotate a value")}
+		}
+		if err := g.constrain(key, values, all[key]); err != nil {
+			return &KeyError{Key: key, Err: err}
+		}
+	}
+	return nil
+}
+
+// constrain checks values, the values of key, against c and records the runtime checks.
+func (g *Generator) constrain(key string, values []any, c constraints) error {
+	goType := g.keyType(key, values[0])
+
+	for _, bound := range []struct {
+		name, value, op, msg string
+	}{
+		{"min", c.min, ">=", "%v is less than the minimum "},
+		{"max", c.max, "<=", "%v is greater than the maximum "},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		limit, literal, err := g.parseBound(goType, bound.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", bound.name, err)
+		}
+		for _, v := range values {
+			n, _ := g.boundValue(goType, v)
+			if bound.op == ">=" && n < limit || bound.op == "<=" && n > limit {
+				return fmt.Errorf(bound.msg+"%s", v, bound.value)
+			}
+		}
+		g.addCheck(check{key: key, cond: "v " + bound.op + " " + literal, msg: bound.msg + escapeVerbs(bound.value)}, "")
+	}
+
+	if c.regex != "" {
+		if goType != "string" {
+			return fmt.Errorf("regex requires a string value, got %s", goType)
+		}
+		re, err := regexp.Compile(c.regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+		for _, v := range values {
+			if !re.MatchString(v.(string)) {
+				return fmt.Errorf("%q does not match %s", v, c.regex)
+			}
+		}
+		if g.addCheck(check{key: key, cond: fmt.Sprintf("regexp.MustCompile(%q).MatchString(v)", c.regex), msg: "%q does not match " + escapeVerbs(c.regex)}, "") {
+			g.extra["regexp"] = true
+		}
+	}
+
+	if c.nonempty {
+		var cond, msg string
+		switch {
+		case goType == "string":
+			cond, msg = `v != ""`, "must not be empty (got %q)"
+		case strings.HasPrefix(goType, "[]"):
+			cond, msg = "len(v) > 0", "must not be empty (got %v)"
+		default:
+			return fmt.Errorf("nonempty requires a string or array value, got %s", goType)
+		}
+		for _, v := range values {
+			if v == "" || isEmptyArray(v) {
+				return fmt.Errorf("must not be empty")
+			}
+		}
+		g.addCheck(check{key: key, cond: cond, msg: msg}, "")
+	}
+
+	if len(c.enum) > 0 {
+		var conds []string
+		verb := "%q"
+		for _, item := range c.enum {
+			switch {
+			case goType == "string":
+				conds = append(conds, fmt.Sprintf("v == %q", item))
+			case isInteger(goType):
+				n, err := strconv.ParseInt(item, 10, 64)
+				if err != nil {
+					return fmt.Errorf("enum value %q is not an integer", item)
+				}
+				if err := valueTypes[goType].check(n); err != nil {
+					return fmt.Errorf("enum value %w", err)
+				}
+				conds = append(conds, fmt.Sprintf("v == %d", n))
+				verb = "%d"
+			default:
+				return fmt.Errorf("enum requires a string or integer value, got %s", goType)
+			}
+		}
+		allowed := strings.Join(c.enum, ", ")
+		for _, v := range values {
+			if !contains(c.enum, fmt.Sprint(v)) {
+				return fmt.Errorf(verb+" is not one of %s", v, allowed)
+			}
+		}
+		g.addCheck(check{key: key, cond: strings.Join(conds, " || "), msg: verb + " is not one of " + escapeVerbs(allowed)}, "")
+	}
+	return nil
+}
+
+// parseBound parses a min or max bound for a value of goType and returns it
+// as a float64 for generation-time checks and as a Go expression.
+func (g *Generator) parseBound(goType, bound string) (float64, string, error) {
+	if isInteger(goType) {
+		n, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("%q is not an integer", bound)
+		}
+		if err := valueTypes[goType].check(n); err != nil {
+			return 0, "", err
+		}
+		return float64(n), strconv.FormatInt(n, 10), nil
+	}
+	switch goType {
+	case "float64":
+		f, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("%q is not a number", bound)
+		}
+		return f, strconv.FormatFloat(f, 'g', -1, 64), nil
+	case "time.Duration":
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, "", fmt.Errorf("%q is not a duration", bound)
+		}
+		var buf bytes.Buffer
+		g.writeDurationLiteral(&buf, bound)
+		return float64(d), buf.String(), nil
+	}
+	return 0, "", fmt.Errorf("requires a number or duration value, got %s", goType)
+}
+
+// boundValue converts a value of goType for comparison with a parsed bound.
+func (g *Generator) boundValue(goType string, v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		if goType == "time.Duration" {
+			d, err := time.ParseDuration(v)
+			return float64(d), err == nil
+		}
+	}
+	return 0, false
+}
+
+// isEmptyArray reports whether v is an array without elements.
+func isEmptyArray(v any) bool {
+	switch v := v.(type) {
+	case []any:
+		return len(v) == 0
+	case []map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeVerbs escapes % in text included in a generated format string.
+func escapeVerbs(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Constraints(t *testing.T) {
+	data := []byte(`
+name = "api" # cfgx:nonempty regex=^[a-z]+$
+
+[server]
+port = 8080 # cfgx: min=1 max=65535
+timeout = "30s" # cfgx: min=1s
+level = "info" # cfgx:enum=debug,info,warn,error
+ratio = 0.25 # cfgx: max=1
+hosts = ["a"] # cfgx:nonempty
+shard = 2 # cfgx:enum=1,2,3
+
+[_meta.server.port]
+max = 9000
+
+[_meta."server.level"]
+nonempty = true
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.NotContains(t, outputStr, "Meta", "the _meta table should not be generated")
+	require.Contains(t, outputStr, `if v := Server.Port; !(v >= 1) {`)
+	require.Contains(t, outputStr, `errs = append(errs, fmt.Errorf("server.port: %v is less than the minimum 1", v))`)
+	require.Contains(t, outputStr, `if v := Server.Port; !(v <= 65535) {`, "annotations should take precedence over _meta")
+	require.Contains(t, outputStr, `if v := Server.Timeout; !(v >= 1*time.Second) {`)
+	require.Contains(t, outputStr, `if v := Server.Ratio; !(v <= 1) {`)
+	require.Contains(t, outputStr, `if v := Server.Level; !(v == "debug" || v == "info" || v == "warn" || v == "error") {`)
+	require.Contains(t, outputStr, `errs = append(errs, fmt.Errorf("server.level: %q is not one of debug, info, warn, error", v))`)
+	require.Contains(t, outputStr, `if v := Server.Level; !(v != "") {`)
+	require.Contains(t, outputStr, `if v := Server.Shard; !(v == 1 || v == 2 || v == 3) {`)
+	require.Contains(t, outputStr, `if v := Server.Hosts; !(len(v) > 0) {`)
+	require.Contains(t, outputStr, `if v := Name; !(regexp.MustCompile("^[a-z]+$").MatchString(v)) {`)
+	require.Contains(t, outputStr, `"regexp"`)
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `if v := Server.Port(); !(v >= 1) {`)
+}
+
+func TestGenerator_ConstraintErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "below minimum",
+			toml: "[server]\nport = 0 # cfgx: min=1\n",
+			want: "server.port: 0 is less than the minimum 1",
+		},
+		{
+			name: "above maximum duration",
+			toml: "[server]\ntimeout = \"10m\" # cfgx: max=5m\n",
+			want: "server.timeout: 10m is greater than the maximum 5m",
+		},
+		{
+			name: "regex mismatch",
+			toml: "name = \"API\" # cfgx:regex=^[a-z]+$\n",
+			want: `name: "API" does not match ^[a-z]+$`,
+		},
+		{
+			name: "empty string",
+			toml: "name = \"\" # cfgx:nonempty\n",
+			want: "name: must not be empty",
+		},
+		{
+			name: "not in enum",
+			toml: "level = \"trace\" # cfgx:enum=debug,info\n",
+			want: `level: "trace" is not one of debug, info`,
+		},
+		{
+			name: "violation in array of tables",
+			toml: "[[pools]]\nsize = 5 # cfgx: max=10\n\n[[pools]]\nsize = 50\n",
+			want: "pools.size: 50 is greater than the maximum 10",
+		},
+		{
+			name: "bound of wrong type",
+			toml: "port = 1 # cfgx: min=1.5\n",
+			want: `port: invalid min: "1.5" is not an integer`,
+		},
+		{
+			name: "min on a string",
+			toml: "name = \"a\" # cfgx: min=1\n",
+			want: "name: invalid min: requires a number or duration value, got string",
+		},
+		{
+			name: "invalid regex",
+			toml: "name = \"a\" # cfgx:regex=(\n",
+			want: "name: invalid regex",
+		},
+		{
+			name: "meta for missing key",
+			toml: "port = 1\n\n[_meta.prot]\nmin = 1\n",
+			want: `_meta.prot: no value named "prot" to constrain`,
+		},
+		{
+			name: "meta with unknown constraint",
+			toml: "port = 1\n\n[_meta]\nport = 5\n",
+			want: "_meta.port: expected a table of constraints",
+		},
+		{
+			name: "meta violation",
+			toml: "port = 0\n\n[_meta.port]\nmin = 1\n",
+			want: "port: 0 is less than the minimum 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
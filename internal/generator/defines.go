@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// definesKey is the top-level table holding reusable values.
+	definesKey = "defines"
+
+	// refPrefix marks a string value as a reference to a value in the defines table.
+	refPrefix = "ref:"
+)
+
+// ResolveDefines expands references to the top-level defines table in place.
+// A string value of the form "ref:defines.<path>" is replaced by a copy of the
+// value at that path, which may itself be a table or reference another define.
+// The defines table is removed afterwards, so it does not appear in the
+// generated code.
+func ResolveDefines(data map[string]any) error {
+	raw, ok := data[definesKey]
+	if !ok {
+		return nil
+	}
+	defines, ok := raw.(map[string]any)
+	if !ok {
+		return &KeyError{Key: definesKey, Err: fmt.Errorf("must be a table")}
+	}
+
+	r := &refResolver{defines: defines, state: make(map[string]int)}
+	for k, v := range data {
+		resolved, err := r.expand(k, v)
+		if err != nil {
+			return err
+		}
+		data[k] = resolved
+	}
+	delete(data, definesKey)
+	return nil
+}
+
+// refResolver tracks which defines have been expanded, to detect cycles.
+type refResolver struct {
+	defines map[string]any
+	state   map[string]int
+	stack   []string
+}
+
+// expand returns v, found at the given key path, with references expanded.
+func (r *refResolver) expand(key string, v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		if !strings.HasPrefix(val, refPrefix) {
+			return v, nil
+		}
+		return r.lookup(key, strings.TrimPrefix(val, refPrefix))
+	case map[string]any:
+		for k, item := range val {
+			expanded, err := r.expand(joinKey(key, k), item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = expanded
+		}
+	case []map[string]any:
+		for _, item := range val {
+			if _, err := r.expand(key, item); err != nil {
+				return nil, err
+			}
+		}
+	case []any:
+		for i, item := range val {
+			expanded, err := r.expand(key, item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = expanded
+		}
+	}
+	return v, nil
+}
+
+// lookup returns a copy of the referenced define, expanding it first.
+func (r *refResolver) lookup(key, ref string) (any, error) {
+	name, ok := strings.CutPrefix(ref, definesKey+".")
+	if !ok {
+		return nil, &KeyError{Key: key, Err: fmt.Errorf("reference %q must point into the %s table", ref, definesKey)}
+	}
+
+	parts := strings.Split(name, ".")
+	parent := r.defines
+	if len(parts) > 1 {
+		if parent, ok = lookupTable(r.defines, strings.Join(parts[:len(parts)-1], ".")); !ok {
+			return nil, &KeyError{Key: key, Err: fmt.Errorf("undefined reference %q", ref)}
+		}
+	}
+	last := parts[len(parts)-1]
+	v, ok := parent[last]
+	if !ok {
+		return nil, &KeyError{Key: key, Err: fmt.Errorf("undefined reference %q", ref)}
+	}
+
+	switch r.state[ref] {
+	case resolving:
+		return nil, &KeyError{
+			Key: key,
+			Err: fmt.Errorf("reference cycle: %s -> %s", strings.Join(r.stack, " -> "), ref),
+		}
+	case resolved:
+		return deepCopy(v), nil
+	}
+
+	r.state[ref] = resolving
+	r.stack = append(r.stack, ref)
+	expanded, err := r.expand(joinKey(definesKey, name), v)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+	r.state[ref] = resolved
+	parent[last] = expanded
+	return deepCopy(expanded), nil
+}
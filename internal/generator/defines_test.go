@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveDefines(t *testing.T) {
+	var data map[string]any
+	_, err := toml.Decode(`
+[defines]
+default_timeout = "30s"
+long_timeout = "ref:defines.default_timeout"
+regions = ["eu-west-1", "us-east-1"]
+
+[defines.pool]
+max_open = 25
+idle_timeout = "ref:defines.default_timeout"
+
+[server]
+read_timeout = "ref:defines.default_timeout"
+shutdown_timeout = "ref:defines.long_timeout"
+regions = "ref:defines.regions"
+pool = "ref:defines.pool"
+
+[[workers]]
+timeout = "ref:defines.default_timeout"
+tags = ["ref:defines.default_timeout", "plain"]
+`, &data)
+	require.NoError(t, err)
+	require.NoError(t, ResolveDefines(data))
+
+	require.NotContains(t, data, "defines")
+
+	server := data["server"].(map[string]any)
+	require.Equal(t, "30s", server["read_timeout"])
+	require.Equal(t, "30s", server["shutdown_timeout"])
+	require.Equal(t, []any{"eu-west-1", "us-east-1"}, server["regions"])
+	require.Equal(t, map[string]any{"max_open": int64(25), "idle_timeout": "30s"}, server["pool"])
+
+	worker := data["workers"].([]map[string]any)[0]
+	require.Equal(t, "30s", worker["timeout"])
+	require.Equal(t, []any{"30s", "plain"}, worker["tags"])
+}
+
+func TestResolveDefines_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		toml    string
+		wantKey string
+		want    string
+	}{
+		{
+			name:    "undefined",
+			toml:    "[defines]\na = 1\n\n[server]\nport = \"ref:defines.b\"\n",
+			wantKey: "server.port",
+			want:    "undefined reference \"defines.b\"",
+		},
+		{
+			name:    "outside defines",
+			toml:    "[defines]\na = 1\n\n[server]\nport = \"ref:server.other\"\n",
+			wantKey: "server.port",
+			want:    "must point into the defines table",
+		},
+		{
+			name: "cycle",
+			toml: "[defines]\na = \"ref:defines.b\"\nb = \"ref:defines.a\"\n",
+			want: "reference cycle:",
+		},
+		{
+			name:    "defines not a table",
+			toml:    "defines = 1\n",
+			wantKey: "defines",
+			want:    "must be a table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			_, err := toml.Decode(tt.toml, &data)
+			require.NoError(t, err)
+
+			err = ResolveDefines(data)
+			require.Error(t, err)
+			var keyErr *KeyError
+			require.ErrorAs(t, err, &keyErr)
+			if tt.wantKey != "" {
+				require.Equal(t, tt.wantKey, keyErr.Key)
+			}
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerator_Defines(t *testing.T) {
+	output, err := New().Generate([]byte(`
+[defines]
+default_timeout = "30s"
+
+[server]
+read_timeout = "ref:defines.default_timeout"
+`))
+	require.NoError(t, err)
+
+	outputStr := string(output)
+	require.NotContains(t, outputStr, "Defines")
+	require.Contains(t, outputStr, "ReadTimeout time.Duration")
+	require.Contains(t, outputStr, "ReadTimeout: 30 * time.Second,")
+}
@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dynamicValues maps each dynamic value prefix to the function producing its
+// value from the text after the prefix. Values are resolved once, at generation
+// time, so every generation run produces different code.
+var dynamicValues = map[string]func(arg string, now time.Time) (any, error){
+	"uuid:":   dynamicUUID,
+	"random:": dynamicRandom,
+	"now:":    dynamicNow,
+}
+
+// resolveDynamicValues replaces uuid:, random: and now: strings with generated
+// values and reports whether any were found.
+func (g *Generator) resolveDynamicValues(data map[string]any) (bool, error) {
+	now := time.Now().UTC()
+	found := false
+
+	var resolve func(key string, v any) (any, error)
+	resolve = func(key string, v any) (any, error) {
+		switch val := v.(type) {
+		case string:
+			for prefix, fn := range dynamicValues {
+				if arg, ok := strings.CutPrefix(val, prefix); ok {
+					found = true
+					generated, err := fn(arg, now)
+					if err != nil {
+						return nil, &KeyError{Key: key, Err: err}
+					}
+					return generated, nil
+				}
+			}
+		case map[string]any:
+			for k, item := range val {
+				resolved, err := resolve(joinKey(key, k), item)
+				if err != nil {
+					return nil, err
+				}
+				val[k] = resolved
+			}
+		case []map[string]any:
+			for _, item := range val {
+				if _, err := resolve(key, item); err != nil {
+					return nil, err
+				}
+			}
+		case []any:
+			for i, item := range val {
+				resolved, err := resolve(key, item)
+				if err != nil {
+					return nil, err
+				}
+				val[i] = resolved
+			}
+		}
+		return v, nil
+	}
+
+	_, err := resolve("", data)
+	return found, err
+}
+
+// dynamicUUID generates a UUID for "uuid:v4".
+func dynamicUUID(arg string, _ time.Time) (any, error) {
+	if arg != "v4" {
+		return nil, fmt.Errorf("unsupported uuid version %q (want uuid:v4)", arg)
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// dynamicRandom generates "random:hex:<bytes>" strings and "random:int:<max>"
+// integers in [0, max).
+func dynamicRandom(arg string, _ time.Time) (any, error) {
+	kind, size, _ := strings.Cut(arg, ":")
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid random:%s: want a positive size, e.g. random:hex:16 or random:int:1000", arg)
+	}
+
+	switch kind {
+	case "hex":
+		b := make([]byte, n)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(b), nil
+	case "int":
+		v, err := rand.Int(rand.Reader, big.NewInt(n))
+		if err != nil {
+			return nil, err
+		}
+		return v.Int64(), nil
+	}
+	return nil, fmt.Errorf("unsupported random kind %q (want hex or int)", kind)
+}
+
+// dynamicNow formats the generation time for "now:rfc3339", "now:date" and
+// "now:unix".
+func dynamicNow(arg string, now time.Time) (any, error) {
+	switch arg {
+	case "rfc3339":
+		return now.Format(time.RFC3339), nil
+	case "date":
+		return now.Format(time.DateOnly), nil
+	case "unix":
+		return now.Unix(), nil
+	}
+	return nil, fmt.Errorf("unsupported now format %q (want rfc3339, date or unix)", arg)
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_DynamicValues(t *testing.T) {
+	data := []byte(`
+[seed]
+id = "uuid:v4"
+token = "random:hex:8"
+shard = "random:int:4"
+build_time = "now:rfc3339"
+build_date = "now:date"
+build_unix = "now:unix"
+ids = ["uuid:v4", "uuid:v4"]
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	require.Regexp(t, `Id: +"uuid:v4",`, string(output), "dynamic values should be opt-in")
+
+	output, err = New(WithDynamicValues(true)).Generate(data)
+	require.NoError(t, err)
+
+	outputStr := string(output)
+	require.Regexp(t, `Id: +"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}",`, outputStr)
+	require.Regexp(t, `Token: +"[0-9a-f]{16}",`, outputStr)
+	require.Regexp(t, `Shard: +[0-3],`, outputStr)
+	require.Regexp(t, `BuildTime: +"\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ",`, outputStr)
+	require.Regexp(t, `BuildDate: +"\d{4}-\d\d-\d\d",`, outputStr)
+	require.Regexp(t, `BuildUnix: +\d+,`, outputStr)
+
+	ids := regexp.MustCompile(`"([0-9a-f-]{36})"`).FindAllStringSubmatch(outputStr, -1)
+	require.Len(t, ids, 3)
+	require.NotEqual(t, ids[1][1], ids[2][1], "each value should be generated separately")
+}
+
+func TestGenerator_DynamicValueErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{"uuid version", `id = "uuid:v1"`, "id: unsupported uuid version \"v1\""},
+		{"random kind", `x = "random:base64:8"`, "unsupported random kind \"base64\""},
+		{"random size", `x = "random:hex:zero"`, "want a positive size"},
+		{"now format", `x = "now:kitchen"`, "unsupported now format \"kitchen\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithDynamicValues(true)).Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gomantics/sx"
+)
+
+// embedDir is the directory, next to a generated file, holding the files it
+// embeds.
+const embedDir = "cfgx_embed"
+
+// embedded is a file: reference generated as a go:embed variable.
+type embedded struct {
+	varName string // Name of the []byte variable
+	file    string // Path of the embedded copy, relative to the generated file
+}
+
+// WithEmbedThreshold makes file: references to files larger than threshold
+// bytes generate a //go:embed variable instead of a []byte literal, which
+// keeps the generated file small for certificate bundles and the like. The
+// referenced files are copied into a cfgx_embed directory next to the
+// generated file and returned by EmbeddedFiles. Zero disables embedding.
+func WithEmbedThreshold(threshold int64) Option {
+	return func(g *Generator) {
+		g.embedThreshold = threshold
+	}
+}
+
+// EmbeddedFiles returns the copies of the files embedded by the code last
+// generated, keyed by slash-separated path relative to the directory of the
+// main output file. They must be written along with the generated files.
+func (g *Generator) EmbeddedFiles() map[string][]byte {
+	return g.assets
+}
+
+// shouldEmbed reports whether a referenced file of the given size is embedded.
+func (g *Generator) shouldEmbed(size int) bool {
+	return g.embedThreshold > 0 && int64(size) > g.embedThreshold
+}
+
+// embedFile returns the variable embedding the file referenced by ref, whose
+// content has been read, and records the copy to write on first use.
+func (g *Generator) embedFile(ref string, content []byte) string {
+	if e, ok := g.embeds[ref]; ok {
+		return e.varName
+	}
+
+	base := path.Base(filepath.ToSlash(normalizeRefPath(strings.TrimPrefix(ref, "file:"))))
+	base = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, base)
+
+	used := make(map[string]bool)
+	for _, e := range g.embeds {
+		used[e.file] = true
+		used[e.varName] = true
+	}
+	ext := path.Ext(base)
+	e := embedded{varName: "embedded" + sx.PascalCase(strings.ReplaceAll(base, ".", "_")), file: embedDir + "/" + base}
+	for i := 2; used[e.file] || used[e.varName]; i++ {
+		e.file = fmt.Sprintf("%s/%s_%d%s", embedDir, strings.TrimSuffix(base, ext), i, ext)
+		e.varName = fmt.Sprintf("embedded%s%d", sx.PascalCase(strings.ReplaceAll(base, ".", "_")), i)
+	}
+
+	g.embeds[ref] = e
+	g.assets[g.assetDir+e.file] = content
+	return e.varName
+}
+
+// writeEmbeds writes the go:embed variables of the embedded files.
+func (g *Generator) writeEmbeds(buf *bytes.Buffer) {
+	if len(g.embeds) == 0 {
+		return
+	}
+	g.extra["_ embed"] = true
+
+	list := make([]embedded, 0, len(g.embeds))
+	for _, e := range g.embeds {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].varName < list[j].varName })
+
+	for _, e := range list {
+		fmt.Fprintf(buf, "\n//go:embed %s\n", e.file)
+		fmt.Fprintf(buf, "var %s []byte\n", e.varName)
+	}
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_EmbedThreshold(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "ca.pem"), []byte("0123456789"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b", "ca.pem"), []byte("9876543210"), 0644))
+
+	data := []byte(`
+[tls]
+ca = "file:a/ca.pem"
+other_ca = "file:b/ca.pem"
+same_ca = "file:a/ca.pem"
+
+# cfgx:package=certs
+[certs]
+ca = "file:a/ca.pem"
+`)
+
+	g := New(WithInputDir(dir), WithMode("getter"), WithEmbedThreshold(5))
+	files, err := g.GenerateFiles(data)
+	require.NoError(t, err)
+	outputStr := string(files[""])
+	require.Contains(t, outputStr, "\t_ \"embed\"\n")
+	require.Contains(t, outputStr, "\treturn embeddedCaPem\n")
+	require.Contains(t, outputStr, "\treturn embeddedCaPem2\n")
+	require.Contains(t, outputStr, "//go:embed cfgx_embed/ca.pem\nvar embeddedCaPem []byte")
+	require.Contains(t, outputStr, "//go:embed cfgx_embed/ca_2.pem\nvar embeddedCaPem2 []byte")
+	require.Contains(t, string(files["certs/"]), "//go:embed cfgx_embed/ca.pem\n")
+
+	require.Equal(t, map[string][]byte{
+		"cfgx_embed/ca.pem":       []byte("0123456789"),
+		"cfgx_embed/ca_2.pem":     []byte("9876543210"),
+		"certs/cfgx_embed/ca.pem": []byte("0123456789"),
+	}, g.EmbeddedFiles())
+
+	// Files at or below the threshold stay literals
+	g = New(WithInputDir(dir), WithEmbedThreshold(10))
+	output, err := g.Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "go:embed")
+	require.Empty(t, g.EmbeddedFiles())
+}
@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// resolveEncrypted handles the keys annotated cfgx:encrypted, whose values
+// are ciphertext written as "<cipher>:<key ID>:<base64>":
+//
+//	[db]
+//	password = "aes:APP_KEY:3q2+7w..." # cfgx:encrypted
+//
+// In static and hybrid mode, the values are decrypted now, with the ciphers
+// registered with cfgx.RegisterCipher, and generated as plain strings. Getter
+// and loader mode keep the ciphertext, typed cfgx:type=encrypted, so that it
+// is decrypted at runtime by the ciphers registered in the generated package.
+func (g *Generator) resolveEncrypted(data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+	for _, key := range g.annotated("encrypted") {
+		if _, ok := g.types[key]; ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:encrypted cannot be combined with cfgx:type")}
+		}
+		parent, name := data, key
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			var ok bool
+			if parent, ok = lookupTable(data, key[:dot]); !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:encrypted is not supported in arrays of tables and cfgx:map tables")}
+			}
+			name = key[dot+1:]
+		}
+		value, ok := parent[name]
+		if !ok {
+			continue
+		}
+		if err := valueTypes["encrypted"].check(value); err != nil {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:encrypted: %w", err)}
+		}
+
+		switch g.mode {
+		case "getter", "loader":
+			g.types[key] = "encrypted"
+			g.useSnippet("encrypted")
+		default:
+			plaintext, err := snippets.Encrypted(value.(string)).Decrypt(g.ctx)
+			if err != nil {
+				return &KeyError{Key: key, Err: err}
+			}
+			parent[name] = plaintext
+			// Plaintext that looks like a duration or a file: reference is
+			// still a string
+			g.types[key] = "string"
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package generator
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+func TestGenerator_Encrypted(t *testing.T) {
+	t.Setenv("CFGX_TEST_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	value, err := snippets.Encrypt(context.Background(), "aes", "CFGX_TEST_KEY", []byte("1h"))
+	require.NoError(t, err)
+	data := fmt.Appendf(nil, "[database]\npassword = %q # cfgx:encrypted\n", value)
+
+	// Static mode decrypts at generation, keeping the plaintext a string
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Password string")
+	require.Contains(t, outputStr, `Password: "1h",`)
+	require.Contains(t, outputStr, `c.Password = "[redacted]"`)
+	require.NotContains(t, outputStr, string(value))
+
+	// Getter and loader mode decrypt at runtime
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (databaseConfig) Password() Encrypted {")
+	require.Contains(t, outputStr, fmt.Sprintf("return %q", value))
+	require.Contains(t, outputStr, "func RegisterCipher(name string, c Cipher) {")
+	require.NotContains(t, outputStr, `"1h"`)
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Password Encrypted `toml:\"password\"`")
+	require.Contains(t, string(output), "\"database.password\": func(v string) any {\n\t\tif e := Encrypted(v); e.IsValid() {")
+
+	t.Setenv("CFGX_TEST_KEY", base64.StdEncoding.EncodeToString(make([]byte, 16)))
+	_, err = New().Generate(data)
+	require.ErrorContains(t, err, "database.password: failed to decrypt with aes key CFGX_TEST_KEY")
+
+	_, err = New().Generate([]byte("password = \"hunter2\" # cfgx:encrypted\n"))
+	require.EqualError(t, err, "password: cfgx:encrypted: encrypted value must be <cipher>:<key ID>:<base64 ciphertext>")
+
+	_, err = New().Generate([]byte("password = \"rot13:K:AAAA\" # cfgx:encrypted\n"))
+	require.EqualError(t, err, `password: unknown cipher "rot13"`)
+
+	_, err = New().Generate([]byte("password = \"aes:K:AAAA\" # cfgx:encrypted type=string\n"))
+	require.EqualError(t, err, "password: cfgx:encrypted cannot be combined with cfgx:type")
+
+	_, err = New().Generate([]byte("[[workers]]\nkey = \"aes:K:AAAA\" # cfgx:encrypted\n"))
+	require.EqualError(t, err, "workers.key: cfgx:encrypted is not supported in arrays of tables and cfgx:map tables")
+}
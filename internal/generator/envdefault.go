@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// validateEnvDefaults checks the keys annotated cfgx:env-default, whose
+// default is expanded from the environment at runtime in getter mode, for
+// settings such as paths that vary per machine:
+//
+//	[storage]
+//	data_dir = "/var/lib/app" # cfgx:env-default=${XDG_DATA_HOME:-$HOME/.local/share}/app
+//
+// The TOML value remains the default when the expansion is empty.
+func (g *Generator) validateEnvDefaults(data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+
+	for _, key := range g.annotated("env-default") {
+		if g.mode != "getter" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:env-default requires getter mode")}
+		}
+		values := lookupValues(data, key)
+		if len(values) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:env-default must annotate a value")}
+		}
+		if path := strings.Split(key, "."); len(path) > 1 {
+			if _, ok := lookupTable(data, strings.Join(path[:len(path)-1], ".")); !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:env-default is not supported in arrays of tables")}
+			}
+		}
+		if goType := g.keyType(key, values[0]); goType != "string" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:env-default requires a string value, got %s", goType)}
+		}
+		if expr, _ := g.annotation(key, "env-default"); expr == "" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:env-default needs a value such as $HOME/.app")}
+		}
+		g.useSnippet("expand")
+	}
+	return nil
+}
+
+// envDefault returns the cfgx:env-default expression of key, if any.
+func (g *Generator) envDefault(key string) (string, bool) {
+	expr, ok := g.annotation(key, "env-default")
+	return expr, ok && expr != ""
+}
+
+// writeEnvDefaultGetterBody writes the body of a string getter whose default
+// is the runtime expansion of expr, falling back to defaultValue.
+func (g *Generator) writeEnvDefaultGetterBody(buf *bytes.Buffer, envVarName, expr string, defaultValue any) {
+	g.extra["os"] = true
+	fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+	buf.WriteString("\t\treturn v\n")
+	buf.WriteString("\t}\n")
+	fmt.Fprintf(buf, "\tif v := expandEnv(%q); v != \"\" {\n", expr)
+	buf.WriteString("\t\treturn v\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn ")
+	g.writeValue(buf, defaultValue)
+	buf.WriteString("\n")
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_EnvDefault(t *testing.T) {
+	data := []byte(`
+cache_dir = "/tmp/app" # cfgx:env-default=${XDG_CACHE_HOME:-$HOME/.cache}/app
+
+[storage]
+data_dir = "/var/lib/app" # cfgx:env-default=$HOME/.app
+`)
+
+	output, err := New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func (storageConfig) DataDir() string {\n\tif v := os.Getenv(\"CONFIG_STORAGE_DATA_DIR\"); v != \"\" {\n\t\treturn v\n\t}\n\tif v := expandEnv(\"$HOME/.app\"); v != \"\" {\n\t\treturn v\n\t}\n\treturn \"/var/lib/app\"\n}")
+	require.Contains(t, outputStr, "if v := expandEnv(\"${XDG_CACHE_HOME:-$HOME/.cache}/app\"); v != \"\" {")
+	require.Contains(t, outputStr, "func expandEnv(s string) string {")
+}
+
+func TestGenerator_EnvDefaultErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		mode string
+		toml string
+		want string
+	}{
+		{
+			name: "static mode",
+			mode: "static",
+			toml: "dir = \"/tmp\" # cfgx:env-default=$HOME\n",
+			want: "dir: cfgx:env-default requires getter mode",
+		},
+		{
+			name: "not a string",
+			mode: "getter",
+			toml: "port = 80 # cfgx:env-default=$PORT\n",
+			want: "port: cfgx:env-default requires a string value, got int64",
+		},
+		{
+			name: "empty",
+			mode: "getter",
+			toml: "dir = \"/tmp\" # cfgx:env-default\n",
+			want: "dir: cfgx:env-default needs a value",
+		},
+		{
+			name: "array of tables",
+			mode: "getter",
+			toml: "[[mounts]]\ndir = \"/tmp\" # cfgx:env-default=$HOME\n",
+			want: "mounts.dir: cfgx:env-default is not supported in arrays of tables",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(WithMode(tt.mode)).Generate([]byte(tt.toml))
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gomantics/cfgx/internal/present"
+)
+
+// WithEnvDocs generates a comment block after the imports listing every
+// environment variable the generated code reads, with the type and default
+// value of the key it overrides, so operators need not read the getters to
+// find their names.
+func WithEnvDocs(enable bool) Option {
+	return func(g *Generator) {
+		g.envDocs = enable
+	}
+}
+
+// envVar is an environment variable read by the generated code.
+type envVar struct {
+	name   string // Variable name, such as CONFIG_SERVER_PORT
+	goType string // Go type of the value it overrides
+	value  string // Default value, as written in the config
+}
+
+// writeEnvDocs writes the comment block listing the environment variables
+// read by the generated code, if enabled and there are any:
+//
+//	// Environment variables
+//	//
+//	// Getters read these environment variables, which override the default
+//	// values shown when set:
+//	//
+//	//	CONFIG_SERVER_ADDR  string  ":8080"
+//	//	CONFIG_SERVER_PORT  int64   8080
+func (g *Generator) writeEnvDocs(buf *bytes.Buffer, data map[string]any) {
+	if !g.envDocs {
+		return
+	}
+	vars := g.envVars("", g.envPrefix, data, false)
+	if len(vars) == 0 {
+		return
+	}
+
+	var reader string
+	switch g.mode {
+	case "getter":
+		reader = "Getters read"
+	case "hybrid":
+		reader = "LoadOverrides reads"
+	case "loader":
+		reader = "Load reads"
+	}
+	buf.WriteString("// Environment variables\n")
+	buf.WriteString("//\n")
+	fmt.Fprintf(buf, "// %s these environment variables, which override the default\n", reader)
+	buf.WriteString("// values shown when set")
+	if slices.ContainsFunc(vars, func(v envVar) bool { return strings.HasPrefix(v.goType, "[]") }) {
+		buf.WriteString(". Arrays are given as comma-separated values")
+	}
+	buf.WriteString(":\n")
+	buf.WriteString("//\n")
+
+	var table bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	for _, v := range vars {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", v.name, v.goType, v.value)
+	}
+	tw.Flush()
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		buf.WriteString("//\t" + strings.TrimRight(line, " ") + "\n")
+	}
+	buf.WriteString("\n")
+}
+
+// envVars returns the environment variables read for the values of table,
+// found at the dotted key path keyPath and named with the prefix env, in key
+// order. Items are elements of arrays of tables.
+func (g *Generator) envVars(keyPath, env string, table map[string]any, item bool) []envVar {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var vars []envVar
+	for _, k := range keys {
+		value := table[k]
+		fieldKey := joinKey(keyPath, k)
+		fieldEnv := env + "_" + strings.ToUpper(k)
+
+		if nested, ok := value.(map[string]any); ok {
+			vars = append(vars, g.envVars(fieldKey, fieldEnv, nested, item)...)
+			continue
+		}
+		if items, ok := tables(value); ok {
+			for i, elem := range items {
+				vars = append(vars, g.envVars(fieldKey, fmt.Sprintf("%s_%d", fieldEnv, i), elem, true)...)
+			}
+			continue
+		}
+		if !g.readsEnv(fieldKey, value, item) {
+			continue
+		}
+
+		v := envVar{name: fieldEnv, goType: g.keyType(fieldKey, value), value: present.Formatter{}.Value(value)}
+		if v.goType == "[]byte" || v.goType == "Asset" {
+			v.goType = "file path"
+		}
+		if g.isSecret(fieldKey) {
+			v.value = Redacted
+		}
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// readsEnv reports whether the generated code reads the value of key from an
+// environment variable. In loader mode, the env layer sets every value but
+// those of maps, arrays of tables and arrays of arrays.
+func (g *Generator) readsEnv(key string, value any, item bool) bool {
+	if g.mode != "loader" {
+		return g.overridable(key, value, item)
+	}
+	if item || !slices.Contains(g.layers(), "env") {
+		return false
+	}
+	switch v := value.(type) {
+	case *mapTable:
+		return false
+	case []any:
+		if len(v) > 0 {
+			_, nested := v[0].([]any)
+			return !nested
+		}
+	}
+	return true
+}
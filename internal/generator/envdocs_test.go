@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_EnvDocs(t *testing.T) {
+	data := []byte(`
+name = "svc"
+hosts = ["a", "b"]
+
+[server]
+port = 8080
+timeout = "30s"
+password = "hunter2"
+
+[[server.routes]]
+path = "/"
+`)
+
+	output, err := New(WithMode("getter"), WithEnvDocs(true)).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `// Environment variables
+//
+// Getters read these environment variables, which override the default
+// values shown when set:
+//
+//	CONFIG_NAME                  string         "svc"
+//	CONFIG_SERVER_PASSWORD       string         [redacted]
+//	CONFIG_SERVER_PORT           int64          8080
+//	CONFIG_SERVER_ROUTES_0_PATH  string         "/"
+//	CONFIG_SERVER_TIMEOUT        time.Duration  30s
+`)
+
+	output, err = New(WithMode("hybrid"), WithEnvDocs(true), WithEnvPrefix("APP")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "// values shown when set. Arrays are given as comma-separated values:\n")
+	require.Contains(t, string(output), "//\tAPP_HOSTS  ")
+	require.NotContains(t, string(output), "ROUTES_0")
+
+	output, err = New(WithEnvDocs(true)).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "// Environment variables")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "// Environment variables")
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// WithEnvAtInit makes getter-mode getters read their environment variables
+// once, when the package is initialized, into package-level variables that
+// the getters then return. Reads then cost no more than in static mode and
+// never allocate, while overrides still apply at program startup; changes
+// to the environment made later are not seen.
+func WithEnvAtInit(enable bool) Option {
+	return func(g *Generator) {
+		g.envAtInit = enable
+	}
+}
+
+// writeGetter writes a getter for key with the given signature, such as
+// "func (serverConfig) Addr() string", whose body is written by body. With
+// env-at-init the body instead initializes a package-level variable, written
+// after the getter so that the getter keeps its doc comment, and the getter
+// returns that variable.
+func (g *Generator) writeGetter(buf *bytes.Buffer, signature, goType, key string, body func(*bytes.Buffer)) {
+	fmt.Fprintf(buf, "%s {\n", signature)
+	g.writeUsageCount(buf, key)
+	if !g.envAtInit {
+		body(buf)
+		buf.WriteString("}\n\n")
+		return
+	}
+
+	name := g.initVarName(key)
+	fmt.Fprintf(buf, "\treturn %s\n", name)
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(buf, "var %s = func() %s {\n", name, goType)
+	body(buf)
+	buf.WriteString("}()\n\n")
+}
+
+// initVarName returns a unique name for the package-level variable holding
+// the value of key with env-at-init, e.g. "initServerMaxConns" for
+// "server.max_conns".
+func (g *Generator) initVarName(key string) string {
+	var name strings.Builder
+	name.WriteString("init")
+	for _, part := range strings.Split(key, ".") {
+		name.WriteString(g.pascalCase(part))
+	}
+
+	unique := name.String()
+	for i := 2; g.initVars[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name.String(), i)
+	}
+	g.initVars[unique] = true
+	return unique
+}
@@ -0,0 +1,43 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_EnvAtInit(t *testing.T) {
+	data := []byte(`
+name = "svc"
+
+[server]
+addr = ":8080"
+timeout = "5s"
+schedule = "*/5 * * * *" # cfgx:type=cron
+`)
+
+	output, err := New(WithMode("getter"), WithEnvAtInit(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func (serverConfig) Addr() string {\n\treturn initServerAddr\n}")
+	require.Contains(t, outputStr, "var initServerAddr = func() string {\n\tif v := os.Getenv(\"CONFIG_SERVER_ADDR\"); v != \"\" {\n\t\treturn v\n\t}\n\treturn \":8080\"\n}()")
+	require.Contains(t, outputStr, "func (serverConfig) Timeout() time.Duration {\n\treturn initServerTimeout\n}")
+	require.Contains(t, outputStr, "var initServerSchedule = func() CronSpec {")
+	require.Contains(t, outputStr, "func Name() string {\n\treturn initName\n}")
+
+	output, err = New(WithMode("getter"), WithEnvAtInit(true), WithUsageTracking(true)).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func Name() string {\n\tusageCounts[3].Add(1)\n\treturn initName\n}")
+}
+
+func TestGenerator_EnvAtInitNames(t *testing.T) {
+	g := New()
+	g.initVars = make(map[string]bool)
+	require.Equal(t, "initServerMaxConns", g.initVarName("server.max_conns"))
+	require.Equal(t, "initServerMaxConns2", g.initVarName("server_max.conns"))
+}
+
+func TestGenerator_EnvAtInitRequiresGetterMode(t *testing.T) {
+	_, err := New(WithMode("static"), WithEnvAtInit(true)).Generate([]byte("name = \"svc\"\n"))
+	require.ErrorContains(t, err, "reading env vars at init requires getter mode")
+}
@@ -0,0 +1,380 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// exprPrefix marks a string value as an expression evaluated at generation time.
+const exprPrefix = "expr:"
+
+// exprVars are the built-in variables available to expressions.
+var exprVars = map[string]func() number{
+	"num_cpu": func() number { return number{i: int64(runtime.NumCPU())} },
+}
+
+// exprFuncs are the built-in functions available to expressions, with their arity.
+var exprFuncs = map[string]struct {
+	args int
+	fn   func(args []number) number
+}{
+	"min":   {2, func(a []number) number { return pick(a[0], a[1], a[0].less(a[1])) }},
+	"max":   {2, func(a []number) number { return pick(a[0], a[1], a[1].less(a[0])) }},
+	"abs":   {1, func(a []number) number { return a[0].apply(math.Abs, func(i int64) int64 { return max(i, -i) }) }},
+	"ceil":  {1, func(a []number) number { return a[0].round(math.Ceil) }},
+	"floor": {1, func(a []number) number { return a[0].round(math.Floor) }},
+}
+
+// number is an integer or floating-point expression value. Operations on two
+// integers stay integral, as in Go.
+type number struct {
+	i       int64
+	f       float64
+	isFloat bool
+}
+
+func (n number) float() float64 {
+	if n.isFloat {
+		return n.f
+	}
+	return float64(n.i)
+}
+
+func (n number) value() any {
+	if n.isFloat {
+		return n.f
+	}
+	return n.i
+}
+
+func (n number) less(m number) bool {
+	if n.isFloat || m.isFloat {
+		return n.float() < m.float()
+	}
+	return n.i < m.i
+}
+
+func (n number) apply(f func(float64) float64, i func(int64) int64) number {
+	if n.isFloat {
+		return number{f: f(n.f), isFloat: true}
+	}
+	return number{i: i(n.i)}
+}
+
+func (n number) round(f func(float64) float64) number {
+	if n.isFloat {
+		return number{i: int64(f(n.f))}
+	}
+	return n
+}
+
+func pick(a, b number, first bool) number {
+	if first {
+		return a
+	}
+	return b
+}
+
+// resolveExpressions evaluates every "expr:" string in data and replaces it
+// with the resulting int64 or float64.
+func (g *Generator) resolveExpressions(data map[string]any) error {
+	r := &exprResolver{root: data, state: make(map[string]int)}
+	return r.walk("", data)
+}
+
+// exprResolver evaluates expressions, following references to other keys and
+// detecting cycles between them.
+type exprResolver struct {
+	root  map[string]any
+	state map[string]int
+	stack []string
+}
+
+// walk evaluates the expressions in the table at the given key path.
+func (r *exprResolver) walk(path string, table map[string]any) error {
+	for k, v := range table {
+		key := joinKey(path, k)
+		switch val := v.(type) {
+		case string:
+			if strings.HasPrefix(val, exprPrefix) {
+				if _, err := r.resolve(key, table, k); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			if err := r.walk(key, val); err != nil {
+				return err
+			}
+		case []map[string]any:
+			for i, item := range val {
+				if err := r.walk(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the numeric value of table[name], found at the given key
+// path, evaluating it first if it is an expression.
+func (r *exprResolver) resolve(key string, table map[string]any, name string) (number, error) {
+	switch val := table[name].(type) {
+	case int64:
+		return number{i: val}, nil
+	case float64:
+		return number{f: val, isFloat: true}, nil
+	case string:
+		src, ok := strings.CutPrefix(val, exprPrefix)
+		if !ok {
+			break
+		}
+		if r.state[key] == resolving {
+			return number{}, &KeyError{
+				Key: key,
+				Err: fmt.Errorf("expression cycle: %s -> %s", strings.Join(r.stack, " -> "), key),
+			}
+		}
+
+		r.state[key] = resolving
+		r.stack = append(r.stack, key)
+		scope := strings.TrimSuffix(key, name)
+		p := &exprParser{src: src, lookup: func(ref string) (number, error) {
+			return r.lookup(strings.TrimSuffix(scope, "."), table, ref)
+		}}
+		n, err := p.parse()
+		r.stack = r.stack[:len(r.stack)-1]
+		delete(r.state, key)
+		if err != nil {
+			var keyErr *KeyError
+			if errors.As(err, &keyErr) {
+				return number{}, err
+			}
+			return number{}, &KeyError{Key: key, Err: fmt.Errorf("invalid expression %q: %w", strings.TrimSpace(src), err)}
+		}
+		table[name] = n.value()
+		return n, nil
+	}
+	return number{}, fmt.Errorf("%s is not a number", key)
+}
+
+// lookup resolves a key reference, first relative to the table the expression
+// is in, then from the top level.
+func (r *exprResolver) lookup(scope string, table map[string]any, ref string) (number, error) {
+	if v, ok := exprVars[ref]; ok {
+		return v(), nil
+	}
+
+	parts := strings.Split(ref, ".")
+	parent := strings.Join(parts[:len(parts)-1], ".")
+	name := parts[len(parts)-1]
+
+	if parent == "" {
+		if _, ok := table[name]; ok {
+			return r.resolve(joinKey(scope, name), table, name)
+		}
+	} else if t, ok := lookupTable(table, parent); ok {
+		if _, ok := t[name]; ok {
+			return r.resolve(joinKey(scope, ref), t, name)
+		}
+	}
+
+	t := r.root
+	if parent != "" {
+		var ok bool
+		if t, ok = lookupTable(r.root, parent); !ok {
+			return number{}, fmt.Errorf("unknown key %q", ref)
+		}
+	}
+	if _, ok := t[name]; !ok {
+		return number{}, fmt.Errorf("unknown key %q", ref)
+	}
+	return r.resolve(ref, t, name)
+}
+
+// exprParser is a recursive descent parser evaluating expressions of the form
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/" | "%") unary }
+//	unary  = [ "-" ] primary
+//	primary = number | key | func "(" expr { "," expr } ")" | "(" expr ")"
+type exprParser struct {
+	src    string
+	pos    int
+	lookup func(ref string) (number, error)
+}
+
+func (p *exprParser) parse() (number, error) {
+	n, err := p.expr()
+	if err != nil {
+		return number{}, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return number{}, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	return n, nil
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// accept consumes the next non-space character if it is one of chars.
+func (p *exprParser) accept(chars string) (byte, bool) {
+	p.skipSpace()
+	if p.pos < len(p.src) && strings.IndexByte(chars, p.src[p.pos]) >= 0 {
+		p.pos++
+		return p.src[p.pos-1], true
+	}
+	return 0, false
+}
+
+func (p *exprParser) expr() (number, error) {
+	n, err := p.term()
+	for err == nil {
+		op, ok := p.accept("+-")
+		if !ok {
+			break
+		}
+		var m number
+		if m, err = p.term(); err == nil {
+			n, err = arith(op, n, m)
+		}
+	}
+	return n, err
+}
+
+func (p *exprParser) term() (number, error) {
+	n, err := p.unary()
+	for err == nil {
+		op, ok := p.accept("*/%")
+		if !ok {
+			break
+		}
+		var m number
+		if m, err = p.unary(); err == nil {
+			n, err = arith(op, n, m)
+		}
+	}
+	return n, err
+}
+
+func (p *exprParser) unary() (number, error) {
+	if _, ok := p.accept("-"); ok {
+		n, err := p.primary()
+		return n.apply(func(f float64) float64 { return -f }, func(i int64) int64 { return -i }), err
+	}
+	return p.primary()
+}
+
+func (p *exprParser) primary() (number, error) {
+	if _, ok := p.accept("("); ok {
+		n, err := p.expr()
+		if err != nil {
+			return number{}, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return number{}, fmt.Errorf("missing )")
+		}
+		return n, nil
+	}
+
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '.' {
+			break
+		}
+		p.pos++
+	}
+	tok := p.src[start:p.pos]
+	switch {
+	case tok == "":
+		if p.pos == len(p.src) {
+			return number{}, fmt.Errorf("unexpected end of expression")
+		}
+		return number{}, fmt.Errorf("unexpected %q", p.src[p.pos:])
+	case tok[0] >= '0' && tok[0] <= '9':
+		if i, err := strconv.ParseInt(tok, 10, 64); err == nil {
+			return number{i: i}, nil
+		}
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return number{}, fmt.Errorf("invalid number %q", tok)
+		}
+		return number{f: f, isFloat: true}, nil
+	}
+
+	if _, ok := p.accept("("); !ok {
+		return p.lookup(tok)
+	}
+	fn, ok := exprFuncs[tok]
+	if !ok {
+		return number{}, fmt.Errorf("unknown function %s", tok)
+	}
+	var args []number
+	for {
+		n, err := p.expr()
+		if err != nil {
+			return number{}, err
+		}
+		args = append(args, n)
+		if _, ok := p.accept(","); !ok {
+			break
+		}
+	}
+	if _, ok := p.accept(")"); !ok {
+		return number{}, fmt.Errorf("missing ) after arguments to %s", tok)
+	}
+	if len(args) != fn.args {
+		return number{}, fmt.Errorf("%s takes %d argument(s), got %d", tok, fn.args, len(args))
+	}
+	return fn.fn(args), nil
+}
+
+// arith applies a binary operator, keeping integer arithmetic when both
+// operands are integers.
+func arith(op byte, a, b number) (number, error) {
+	if !a.isFloat && !b.isFloat {
+		switch op {
+		case '+':
+			return number{i: a.i + b.i}, nil
+		case '-':
+			return number{i: a.i - b.i}, nil
+		case '*':
+			return number{i: a.i * b.i}, nil
+		case '/', '%':
+			if b.i == 0 {
+				return number{}, fmt.Errorf("division by zero")
+			}
+			if op == '/' {
+				return number{i: a.i / b.i}, nil
+			}
+			return number{i: a.i % b.i}, nil
+		}
+	}
+
+	x, y := a.float(), b.float()
+	switch op {
+	case '+':
+		return number{f: x + y, isFloat: true}, nil
+	case '-':
+		return number{f: x - y, isFloat: true}, nil
+	case '*':
+		return number{f: x * y, isFloat: true}, nil
+	case '/':
+		if y == 0 {
+			return number{}, fmt.Errorf("division by zero")
+		}
+		return number{f: x / y, isFloat: true}, nil
+	}
+	return number{}, fmt.Errorf("%% requires integer operands")
+}
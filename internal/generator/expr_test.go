@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExpressions(t *testing.T) {
+	var data map[string]any
+	_, err := toml.Decode(`
+base_port = 8000
+ratio = 0.5
+
+[server]
+workers = "expr: num_cpu * 2"
+port = "expr: base_port + 80"
+admin_port = "expr: port + 1"
+queue = "expr: (workers + 1) * 10 % 7"
+half = "expr: ratio * workers"
+capped = "expr: min(workers, 4)"
+rounded = "expr: ceil(10 / 4.0)"
+negative = "expr: -abs(-3)"
+
+[[pools]]
+size = 4
+idle = "expr: size / 2"
+`, &data)
+	require.NoError(t, err)
+
+	g := New()
+	require.NoError(t, g.resolveExpressions(data))
+
+	workers := int64(runtime.NumCPU()) * 2
+	server := data["server"].(map[string]any)
+	require.Equal(t, workers, server["workers"])
+	require.Equal(t, int64(8080), server["port"])
+	require.Equal(t, int64(8081), server["admin_port"], "references to other expressions should be resolved")
+	require.Equal(t, (workers+1)*10%7, server["queue"])
+	require.Equal(t, 0.5*float64(workers), server["half"])
+	require.Equal(t, min(workers, 4), server["capped"])
+	require.Equal(t, int64(3), server["rounded"])
+	require.Equal(t, int64(-3), server["negative"])
+
+	require.Equal(t, int64(2), data["pools"].([]map[string]any)[0]["idle"])
+}
+
+func TestResolveExpressions_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		toml    string
+		wantKey string
+		want    string
+	}{
+		{"unknown key", `x = "expr: y + 1"`, "x", `invalid expression "y + 1": unknown key "y"`},
+		{"not a number", "name = \"a\"\nx = \"expr: name * 2\"", "x", "name is not a number"},
+		{"cycle", "a = \"expr: b\"\nb = \"expr: a\"", "", "expression cycle:"},
+		{"division by zero", `x = "expr: 1 / 0"`, "x", "division by zero"},
+		{"syntax", `x = "expr: (1 + 2"`, "x", "missing )"},
+		{"trailing", `x = "expr: 1 2"`, "x", `unexpected "2"`},
+		{"unknown function", `x = "expr: sqrt(4)"`, "x", "unknown function sqrt"},
+		{"arity", `x = "expr: min(1)"`, "x", "min takes 2 argument(s), got 1"},
+		{"float modulo", `x = "expr: 1.5 % 2"`, "x", "% requires integer operands"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			_, err := toml.Decode(tt.toml, &data)
+			require.NoError(t, err)
+
+			err = New().resolveExpressions(data)
+			require.Error(t, err)
+			var keyErr *KeyError
+			require.ErrorAs(t, err, &keyErr)
+			if tt.wantKey != "" {
+				require.Equal(t, tt.wantKey, keyErr.Key)
+			}
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerator_Expressions(t *testing.T) {
+	output, err := New(WithMode("getter")).Generate([]byte(`
+[server]
+port = 8080
+metrics_port = "expr: port + 1"
+`))
+	require.NoError(t, err)
+
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func (serverConfig) MetricsPort() int64 {")
+	require.Contains(t, outputStr, "return 8081")
+}
@@ -0,0 +1,147 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// extendsKey is the table key naming another table to inherit keys from.
+const extendsKey = "extends"
+
+// ResolveExtends resolves table inheritance in data in place. A table with an
+// extends key naming another table by its dotted path, e.g.
+//
+//	[server_b]
+//	extends = "server_a"
+//	port = 8081
+//
+// gets every key of that table it does not define itself, merging nested
+// tables recursively. The extends key is removed. Inheritance is transitive,
+// and cycles are reported as errors.
+func ResolveExtends(data map[string]any) error {
+	r := &extendsResolver{root: data, state: make(map[string]int)}
+	for k, v := range data {
+		if err := r.resolveValue(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolution states of a table in extendsResolver.
+const (
+	resolving = 1
+	resolved  = 2
+)
+
+// extendsResolver tracks which tables have been resolved, to detect cycles.
+type extendsResolver struct {
+	root  map[string]any
+	state map[string]int
+	stack []string
+}
+
+// resolveValue resolves the tables within v, found at the given key path.
+func (r *extendsResolver) resolveValue(key string, v any) error {
+	switch val := v.(type) {
+	case map[string]any:
+		return r.resolve(key, val)
+	case []map[string]any:
+		// Items of an array of tables can extend a table, but cannot be
+		// extended themselves, so they are not tracked by path.
+		for i, item := range val {
+			if err := r.resolveTable(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve resolves the table at the given key path, once.
+func (r *extendsResolver) resolve(key string, table map[string]any) error {
+	switch r.state[key] {
+	case resolved:
+		return nil
+	case resolving:
+		return &KeyError{
+			Key: key + "." + extendsKey,
+			Err: fmt.Errorf("inheritance cycle: %s -> %s", strings.Join(r.stack, " -> "), key),
+		}
+	}
+
+	r.state[key] = resolving
+	r.stack = append(r.stack, key)
+	err := r.resolveTable(key, table)
+	r.stack = r.stack[:len(r.stack)-1]
+	r.state[key] = resolved
+	return err
+}
+
+// resolveTable merges the table's base into it, then resolves its children.
+func (r *extendsResolver) resolveTable(key string, table map[string]any) error {
+	if ext, ok := table[extendsKey]; ok {
+		name, ok := ext.(string)
+		if !ok || name == "" {
+			return &KeyError{Key: key + "." + extendsKey, Err: fmt.Errorf("must be a string naming a table")}
+		}
+		base, ok := lookupTable(r.root, name)
+		if !ok {
+			return &KeyError{Key: key + "." + extendsKey, Err: fmt.Errorf("no table named %q", name)}
+		}
+		if err := r.resolve(name, base); err != nil {
+			return err
+		}
+		delete(table, extendsKey)
+		inherit(table, base)
+	}
+
+	for k, v := range table {
+		if err := r.resolveValue(joinKey(key, k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inherit copies the keys of base that table does not define into table,
+// merging nested tables that both define.
+func inherit(table, base map[string]any) {
+	for k, v := range base {
+		own, ok := table[k]
+		if !ok {
+			table[k] = deepCopy(v)
+			continue
+		}
+		ownTable, ok1 := own.(map[string]any)
+		baseTable, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			inherit(ownTable, baseTable)
+		}
+	}
+}
+
+// deepCopy copies tables and arrays so that inheriting tables do not share them.
+func deepCopy(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(val))
+		for k, item := range val {
+			m[k] = deepCopy(item)
+		}
+		return m
+	case []map[string]any:
+		items := make([]map[string]any, len(val))
+		for i, item := range val {
+			items[i] = deepCopy(item).(map[string]any)
+		}
+		return items
+	case []any:
+		items := make([]any, len(val))
+		for i, item := range val {
+			items[i] = deepCopy(item)
+		}
+		return items
+	}
+	return v
+}
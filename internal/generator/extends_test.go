@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveExtends(t *testing.T) {
+	var data map[string]any
+	_, err := toml.Decode(`
+[server_a]
+host = "a.internal"
+port = 8080
+
+[server_a.tls]
+enabled = true
+min_version = "1.2"
+
+[server_b]
+extends = "server_a"
+port = 8081
+
+[server_b.tls]
+min_version = "1.3"
+
+[server_c]
+extends = "server_b"
+host = "c.internal"
+
+[[replicas]]
+extends = "server_a"
+host = "r1.internal"
+`, &data)
+	require.NoError(t, err)
+	require.NoError(t, ResolveExtends(data))
+
+	b := data["server_b"].(map[string]any)
+	require.NotContains(t, b, "extends")
+	require.Equal(t, "a.internal", b["host"])
+	require.Equal(t, int64(8081), b["port"])
+	require.Equal(t, map[string]any{"enabled": true, "min_version": "1.3"}, b["tls"])
+
+	c := data["server_c"].(map[string]any)
+	require.Equal(t, "c.internal", c["host"])
+	require.Equal(t, int64(8081), c["port"], "inheritance should be transitive")
+	require.Equal(t, "1.3", c["tls"].(map[string]any)["min_version"])
+
+	replica := data["replicas"].([]map[string]any)[0]
+	require.Equal(t, "r1.internal", replica["host"])
+	require.Equal(t, int64(8080), replica["port"])
+
+	// Inherited tables are copies
+	c["tls"].(map[string]any)["enabled"] = false
+	require.Equal(t, true, b["tls"].(map[string]any)["enabled"])
+}
+
+func TestResolveExtends_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		toml    string
+		wantKey string
+		want    string
+	}{
+		{
+			name:    "cycle",
+			toml:    "[a]\nextends = \"b\"\n\n[b]\nextends = \"c\"\n\n[c]\nextends = \"a\"\n",
+			wantKey: ".extends",
+			want:    "inheritance cycle:",
+		},
+		{
+			name:    "self",
+			toml:    "[a]\nextends = \"a\"\n",
+			wantKey: "a.extends",
+			want:    "inheritance cycle: a -> a",
+		},
+		{
+			name:    "nested table extends its parent",
+			toml:    "[a]\nx = 1\n\n[a.b]\nextends = \"a\"\n",
+			wantKey: "a.extends",
+			want:    "inheritance cycle: a -> a.b -> a",
+		},
+		{
+			name:    "unknown table",
+			toml:    "[a]\nextends = \"missing\"\n",
+			wantKey: "a.extends",
+			want:    "no table named \"missing\"",
+		},
+		{
+			name:    "not a string",
+			toml:    "[a]\nextends = 1\n",
+			wantKey: "a.extends",
+			want:    "must be a string naming a table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data map[string]any
+			_, err := toml.Decode(tt.toml, &data)
+			require.NoError(t, err)
+
+			err = ResolveExtends(data)
+			require.Error(t, err)
+			var keyErr *KeyError
+			require.ErrorAs(t, err, &keyErr)
+			require.Contains(t, keyErr.Key, tt.wantKey)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerator_Extends(t *testing.T) {
+	output, err := New().Generate([]byte(`
+[server_a]
+host = "a.internal"
+port = 8080
+
+[server_b]
+extends = "server_a"
+port = 8081
+`))
+	require.NoError(t, err)
+
+	outputStr := string(output)
+	require.NotContains(t, outputStr, "Extends")
+	require.Contains(t, outputStr, "ServerB = ServerBConfig{")
+	require.Contains(t, outputStr, "Host: \"a.internal\",")
+	require.Contains(t, outputStr, "Port: 8081,")
+}
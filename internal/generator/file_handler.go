@@ -1,36 +1,122 @@
 package generator
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 )
 
+// fileWorkers is the maximum number of file: references read at once.
+const fileWorkers = 8
+
+// fileContent is the outcome of reading a file: reference, kept so that
+// each referenced file is read once per run.
+type fileContent struct {
+	data []byte
+	err  error
+}
+
 // isFileReference checks if a string value is a file reference (starts with "file:").
 func (g *Generator) isFileReference(s string) bool {
 	return strings.HasPrefix(s, "file:")
 }
 
-// loadFileContent reads a file and returns its contents as bytes.
+// loadFileContent returns the contents of a file: reference, from the
+// content cache filled by loadFiles if it was loaded already.
+func (g *Generator) loadFileContent(filePath string) ([]byte, error) {
+	if c, ok := g.files[filePath]; ok {
+		return c.data, c.err
+	}
+	g.reference(filePath)
+	return g.readFileContent(filePath)
+}
+
+// reference records the file of the file: reference ref as read by the run,
+// for ReferencedFiles.
+func (g *Generator) reference(ref string) {
+	if g.fsys != nil || g.referenced == nil {
+		return
+	}
+	g.referenced[g.refPath(ref)] = true
+}
+
+// ReferencedFiles returns the paths of the files read for file: references
+// by the code last generated, sorted, so that watchers can regenerate when
+// they change, whether they could be read or not. Files read from the file
+// system of WithFS are not included.
+func (g *Generator) ReferencedFiles() []string {
+	return slices.Sorted(maps.Keys(g.referenced))
+}
+
+// refPath returns the path of the file of the file: reference ref, resolved
+// relative to the input directory.
+func (g *Generator) refPath(ref string) string {
+	relativePath := normalizeRefPath(strings.TrimPrefix(ref, "file:"))
+	if g.inputDir != "" && !isAbsRefPath(relativePath) {
+		return filepath.Join(g.inputDir, relativePath)
+	}
+	return relativePath
+}
+
+// loadFiles reads the files of refs, file: references, into the content
+// cache, in parallel with at most fileWorkers at once, so that configs
+// referencing many certificates do not read them one after another.
+func (g *Generator) loadFiles(refs []string) {
+	var pending []string
+	for _, ref := range refs {
+		g.reference(ref)
+		if _, ok := g.files[ref]; !ok && !slices.Contains(pending, ref) {
+			pending = append(pending, ref)
+		}
+	}
+
+	results := make([]fileContent, len(pending))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(fileWorkers, len(pending)) {
+		wg.Go(func() {
+			for i := range next {
+				data, err := g.readFileContent(pending[i])
+				results[i] = fileContent{data: data, err: err}
+			}
+		})
+	}
+	for i := range pending {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	if g.files == nil {
+		g.files = make(map[string]fileContent, len(pending))
+	}
+	for i, ref := range pending {
+		g.files[ref] = results[i]
+	}
+}
+
+// readFileContent reads a file and returns its contents as bytes.
 // The file path is resolved relative to the inputDir.
 // Returns an error if the file doesn't exist, can't be read, or exceeds maxFileSize.
-func (g *Generator) loadFileContent(filePath string) ([]byte, error) {
-	// Strip "file:" prefix
-	relativePath := strings.TrimPrefix(filePath, "file:")
+func (g *Generator) readFileContent(filePath string) ([]byte, error) {
+	if g.fsys != nil {
+		return g.loadFSFileContent(normalizeRefPath(strings.TrimPrefix(filePath, "file:")))
+	}
 
 	// Resolve path relative to input directory
-	var resolvedPath string
-	if g.inputDir != "" {
-		resolvedPath = filepath.Join(g.inputDir, relativePath)
-	} else {
-		resolvedPath = relativePath
-	}
+	resolvedPath := g.refPath(filePath)
 
 	// Check file exists and get size
 	fileInfo, err := os.Stat(resolvedPath)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return nil, fmt.Errorf("file not found: %s (referenced in config)", resolvedPath)
 		}
 		return nil, fmt.Errorf("failed to stat file %s: %w", resolvedPath, err)
@@ -42,11 +128,69 @@ func (g *Generator) loadFileContent(filePath string) ([]byte, error) {
 			resolvedPath, g.maxFileSize, fileInfo.Size())
 	}
 
+	if content, ok := g.fileCache.get(resolvedPath, fileInfo); ok {
+		return content, nil
+	}
+
 	// Read file
 	content, err := os.ReadFile(resolvedPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", resolvedPath, err)
 	}
 
+	g.fileCache.put(resolvedPath, fileInfo, content)
+	return content, nil
+}
+
+// loadFSFileContent is readFileContent for references resolved in the
+// generator's fs.FS, where paths are slash-separated and relative to its root.
+func (g *Generator) loadFSFileContent(relativePath string) ([]byte, error) {
+	if isAbsRefPath(relativePath) {
+		return nil, fmt.Errorf("absolute file: reference %s cannot be resolved in a file system", relativePath)
+	}
+	resolvedPath := path.Join(filepath.ToSlash(g.inputDir), filepath.ToSlash(relativePath))
+	if !fs.ValidPath(resolvedPath) {
+		return nil, fmt.Errorf("file: reference %s is outside the file system", resolvedPath)
+	}
+
+	fileInfo, err := fs.Stat(g.fsys, resolvedPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("file not found: %s (referenced in config)", resolvedPath)
+		}
+		return nil, fmt.Errorf("failed to stat file %s: %w", resolvedPath, err)
+	}
+
+	if g.maxFileSize > 0 && fileInfo.Size() > g.maxFileSize {
+		return nil, fmt.Errorf("file %s exceeds max size %d bytes (actual: %d bytes)",
+			resolvedPath, g.maxFileSize, fileInfo.Size())
+	}
+
+	if content, ok := g.fileCache.get(resolvedPath, fileInfo); ok {
+		return content, nil
+	}
+
+	content, err := fs.ReadFile(g.fsys, resolvedPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", resolvedPath, err)
+	}
+	g.fileCache.put(resolvedPath, fileInfo, content)
 	return content, nil
 }
+
+// normalizeRefPath converts a file: reference path to the host OS format.
+// Both forward slashes and backslashes are accepted as separators, so configs
+// written on Windows resolve the same way everywhere.
+func normalizeRefPath(p string) string {
+	return filepath.FromSlash(strings.ReplaceAll(p, "\\", "/"))
+}
+
+// isAbsRefPath reports whether a normalized reference path is absolute, either
+// for the host OS or as a Windows drive-letter path such as "C:/certs/ca.pem".
+func isAbsRefPath(p string) bool {
+	if filepath.IsAbs(p) {
+		return true
+	}
+	return len(p) >= 3 && p[1] == ':' && (p[2] == '/' || p[2] == '\\') &&
+		(p[0] >= 'a' && p[0] <= 'z' || p[0] >= 'A' && p[0] <= 'Z')
+}
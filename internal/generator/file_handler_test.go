@@ -1,9 +1,14 @@
 package generator
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -216,3 +221,97 @@ content = "file:files/small.txt"`,
 		})
 	}
 }
+
+// countingFS is a file system counting the files read from it.
+type countingFS struct {
+	fstest.MapFS
+	mu    sync.Mutex
+	reads map[string]int
+}
+
+func (c *countingFS) ReadFile(name string) ([]byte, error) {
+	c.mu.Lock()
+	c.reads[name]++
+	c.mu.Unlock()
+	return fs.ReadFile(c.MapFS, name)
+}
+
+func TestGenerator_FileReferencesReadOnce(t *testing.T) {
+	fsys := &countingFS{MapFS: fstest.MapFS{}, reads: make(map[string]int)}
+	var toml strings.Builder
+	toml.WriteString("[certs]\n")
+	for i := range 20 {
+		fsys.MapFS[fmt.Sprintf("certs/%d.pem", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("cert %d", i))}
+		fmt.Fprintf(&toml, "cert%02d = \"file:certs/%d.pem\"\n", i, i)
+	}
+	toml.WriteString("copy = \"file:certs/0.pem\"\n")
+
+	output, err := New(WithFS(fsys)).Generate([]byte(toml.String()))
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Cert19: []byte{")
+	require.Len(t, fsys.reads, 20)
+	for name, n := range fsys.reads {
+		require.Equal(t, 1, n, "%s should be read once", name)
+	}
+
+	// Failures are reported for the first key in order
+	delete(fsys.MapFS, "certs/3.pem")
+	delete(fsys.MapFS, "certs/7.pem")
+	_, err = New(WithFS(fsys)).Generate([]byte(toml.String()))
+	require.EqualError(t, err, "certs.cert03: file not found: certs/3.pem (referenced in config)")
+}
+
+func TestGenerator_ReferencedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), []byte("cert"), 0644))
+	data := []byte("[tls]\ncert = \"file:cert.pem\"\nkey = \"file:missing.pem\"\n")
+
+	gen := New(WithInputDir(dir))
+	_, err := gen.Generate(data)
+	require.Error(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "cert.pem"), filepath.Join(dir, "missing.pem")}, gen.ReferencedFiles(),
+		"missing files should be referenced too")
+
+	_, err = gen.Generate([]byte("[tls]\ncert = \"file:cert.pem\"\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "cert.pem")}, gen.ReferencedFiles())
+}
+
+func TestGenerator_WindowsStyleReferences(t *testing.T) {
+	// CRLF input with backslash-separated file: references, as written on Windows
+	data := []byte("[tls]\r\ncert = 'file:files\\cert.txt'\r\nname = \"x\"\r\n")
+
+	gen := New(WithInputDir("../../testdata"))
+	output, err := gen.Generate(data)
+	require.NoError(t, err, "backslash references should resolve on every OS")
+
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Cert []byte")
+	require.NotContains(t, outputStr, "\r", "output should use LF line endings")
+}
+
+func TestGenerator_CRLFOutput(t *testing.T) {
+	gen := New(WithCRLF(true))
+	output, err := gen.Generate([]byte("[app]\nname = \"x\"\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, strings.Count(string(output), "\n"), strings.Count(string(output), "\r\n"),
+		"every line should end with CRLF")
+}
+
+func TestIsAbsRefPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"certs/server.crt", false},
+		{normalizeRefPath(`certs\server.crt`), false},
+		{"C:/certs/server.crt", true},
+		{`d:\certs\server.crt`, true},
+		{"c:relative", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, isAbsRefPath(tt.path), "isAbsRefPath(%q)", tt.path)
+	}
+}
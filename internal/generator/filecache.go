@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// FileCache keeps the contents of files read for file: references across
+// generation runs, such as the regenerations of watch mode, so that a file
+// is only read again once its size or modification time changes. It is safe
+// for concurrent use, but must not be shared between file systems.
+type FileCache struct {
+	mu    sync.Mutex
+	files map[string]cachedFile
+}
+
+// cachedFile is the content of a file as of its size and modification time.
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+	data    []byte
+}
+
+// NewFileCache returns an empty FileCache.
+func NewFileCache() *FileCache {
+	return &FileCache{files: make(map[string]cachedFile)}
+}
+
+// WithFileCache makes file: references read through c, reusing the contents
+// of files unchanged since an earlier run.
+func WithFileCache(c *FileCache) Option {
+	return func(g *Generator) {
+		g.fileCache = c
+	}
+}
+
+// get returns the cached content of the file at path, if the file described
+// by info is unchanged since it was cached. A nil cache holds nothing.
+func (c *FileCache) get(path string, info fs.FileInfo) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.files[path]
+	if !ok || f.size != info.Size() || !f.modTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+	return f.data, true
+}
+
+// put caches data as the content of the file at path described by info.
+func (c *FileCache) put(path string, info fs.FileInfo, data []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[path] = cachedFile{size: info.Size(), modTime: info.ModTime(), data: data}
+}
@@ -3,20 +3,107 @@ package generator
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"go/format"
+	"io/fs"
+	"maps"
+	"runtime/trace"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/gomantics/cfgx/internal/policy"
+	"github.com/gomantics/cfgx/internal/record"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
 )
 
 // Generator handles the conversion of TOML config to Go code.
 type Generator struct {
-	packageName string // The package name for the generated code
-	envOverride bool   // Whether to enable environment variable override support
-	inputDir    string // Directory of input TOML file for resolving relative file paths
-	maxFileSize int64  // Maximum file size in bytes for file: references
-	mode        string // Generation mode: "static" or "getter"
+	packageName    string            // The package name for the generated code
+	envOverride    bool              // Whether to enable environment variable override support
+	envPrefix      string            // Prefix of environment variable names, such as "CONFIG"
+	inputDir       string            // Directory of input TOML file for resolving relative file paths
+	maxFileSize    int64             // Maximum file size in bytes for file: references
+	mode           string            // Generation mode: "static", "getter", "hybrid" or "loader"
+	record         *record.Record    // Generation record written into the header (optional)
+	stats          *Stats            // Generation statistics to fill in (optional)
+	source         *tomlsrc.Source   // Scanned TOML source for annotations (optional)
+	crlf           bool              // Whether to emit CRLF line endings instead of LF
+	dynamic        bool              // Whether to resolve uuid:, random: and now: values
+	usage          bool              // Whether getters count their reads for Usage
+	envAtInit      bool              // Whether getters read env vars once, at package init
+	summary        bool              // Whether to generate PrintSummary
+	envDocs        bool              // Whether to list the environment variables read in a comment
+	preserveOrder  bool              // Whether to generate fields in source order rather than sorted
+	overridden     map[string]string // Dotted key path -> env var that overrode it at generation
+	initialisms    map[string]string // Lower case initialism -> its form in identifiers, such as "url" -> "URL"
+	nameOverrides  map[string]string // Dotted key path -> Go name of its field, variable or getter
+	embedThreshold int64             // Size above which file: references are embedded (0 disables)
+	typeHints      map[string]string // Dotted key path -> cfgx:type name from a sidecar file
+	structTags     []string          // Struct tag keys written on every struct field, such as "json"
+	fsys           fs.FS             // File system for file: references (optional, defaults to the OS)
+	policy         *policy.Policy    // Rules the resolved configuration must satisfy (optional)
+	precedence     []string          // Runtime layers of loader mode, lowest first
+	fileCache      *FileCache        // Contents of files read in earlier runs (optional)
+	intType        string            // Go type of integers: "int64" or "int"
+	style          string            // How static mode exposes values: "vars" or "compact"
+	ctx            context.Context   // Cancels generation between stages
+
+	// Per-run state, reset by Generate
+	src         *tomlsrc.Source        // Annotations for the current run
+	structKeys  map[string]string      // Struct type name -> dotted TOML key path
+	extra       map[string]bool        // Imports needed by generated helpers
+	types       map[string]string      // Dotted key path -> cfgx:type name
+	snippets    map[string]bool        // Snippet files to emit
+	checks      []check                // Runtime checks for the generated Validate
+	data        map[string]any         // Parsed TOML data
+	parts       map[string]*part       // Companion files, by part name
+	generated   bool                   // Whether any dynamic values were resolved
+	usageKeys   []string               // Keys whose getters count reads, by counter index
+	initVars    map[string]bool        // Package-level variables holding env-at-init values
+	meta        map[string]constraints // Constraints from the _meta table, by dotted key path
+	embeds      map[string]embedded    // Embedded file: references, by reference
+	assets      map[string][]byte      // Embedded file copies of all packages, by path
+	assetDir    string                 // Directory of this package's embedded files, relative to the main output
+	order       map[string]int         // Dotted key path -> index of its first source entry, if preserving order
+	runtimeOnly map[string]string      // Dotted key path -> Go type of keys read from env by getters in static mode
+	files       map[string]fileContent // Content cache of file: references, by reference
+	referenced  map[string]bool        // Paths of the files of file: references of all packages
+	optional    map[string]bool        // Dotted key paths annotated cfgx:optional, whose fields are pointers
+}
+
+// part is a companion file generated next to the main file, for helpers that
+// depend on third-party packages. Optional helpers are behind a build tag.
+type part struct {
+	buildTag string
+	imports  map[string]bool
+	body     bytes.Buffer
+}
+
+// KeyError is returned when generation fails because of the value of a specific key.
+type KeyError struct {
+	Key string // Dotted TOML key path, e.g. "server.tls_cert"
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// joinKey appends a key to a dotted key path.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
 }
 
 // Option configures a Generator.
@@ -36,6 +123,16 @@ func WithEnvOverride(enable bool) Option {
 	}
 }
 
+// WithEnvPrefix sets the prefix of environment variable names, which are
+// <PREFIX>_<SECTION>_<KEY>. Defaults to "CONFIG".
+func WithEnvPrefix(prefix string) Option {
+	return func(g *Generator) {
+		if prefix != "" {
+			g.envPrefix = prefix
+		}
+	}
+}
+
 // WithInputDir sets the input directory for resolving relative file paths.
 func WithInputDir(dir string) Option {
 	return func(g *Generator) {
@@ -57,13 +154,95 @@ func WithMode(mode string) Option {
 	}
 }
 
+// WithRecord sets the generation record written below the generated-code header.
+func WithRecord(rec *record.Record) Option {
+	return func(g *Generator) {
+		g.record = rec
+	}
+}
+
+// WithSource sets the scanned TOML source used for annotations and positions.
+// This is needed when the data passed to Generate was re-encoded and no longer
+// carries the original comments. If unset, Generate scans its input.
+func WithSource(src *tomlsrc.Source) Option {
+	return func(g *Generator) {
+		g.source = src
+	}
+}
+
+// WithCRLF makes the generator emit CRLF line endings. Output uses LF by default,
+// regardless of the line endings of the input.
+func WithCRLF(enable bool) Option {
+	return func(g *Generator) {
+		g.crlf = enable
+	}
+}
+
+// WithDynamicValues enables values generated at generation time: strings such
+// as "uuid:v4", "random:hex:16" or "now:rfc3339" are replaced by a fresh value
+// on every run. Without it they are kept as plain strings.
+func WithDynamicValues(enable bool) Option {
+	return func(g *Generator) {
+		g.dynamic = enable
+	}
+}
+
+// WithUsageTracking makes getter-mode getters count how often they are called,
+// reported by a generated Usage function, to find keys that are never read.
+func WithUsageTracking(enable bool) Option {
+	return func(g *Generator) {
+		g.usage = enable
+	}
+}
+
+// WithFS makes file: references resolve in fsys instead of the OS file system,
+// relative to the input directory, which is then a slash-separated path in
+// fsys. This keeps generation free of OS file access, e.g. under js/wasm.
+func WithFS(fsys fs.FS) Option {
+	return func(g *Generator) {
+		g.fsys = fsys
+	}
+}
+
+// WithIntType sets the Go type integers are generated as, "int64" by default
+// or "int", for code that would otherwise convert them everywhere. Keys can
+// still be given the other type with a cfgx:type annotation.
+func WithIntType(intType string) Option {
+	return func(g *Generator) {
+		g.intType = intType
+	}
+}
+
+// WithPolicy makes generation fail unless the configuration satisfies the
+// rules of p, evaluated once references, inheritance and computed values are
+// resolved.
+func WithPolicy(p *policy.Policy) Option {
+	return func(g *Generator) {
+		g.policy = p
+	}
+}
+
+// WithContext makes generation stop with the error of ctx once it is done,
+// checked between stages, and passes ctx to the ciphers decrypting
+// cfgx:encrypted values.
+func WithContext(ctx context.Context) Option {
+	return func(g *Generator) {
+		if ctx != nil {
+			g.ctx = ctx
+		}
+	}
+}
+
 // New creates a new Generator with the given options.
 func New(opts ...Option) *Generator {
 	g := &Generator{
 		packageName: "config",
 		envOverride: true,
+		envPrefix:   "CONFIG",
 		maxFileSize: 1024 * 1024, // 1MB default
 		mode:        "static",    // default to static mode
+		intType:     "int64",
+		ctx:         context.Background(),
 	}
 	for _, opt := range opts {
 		opt(g)
@@ -71,7 +250,7 @@ func New(opts ...Option) *Generator {
 	return g
 }
 
-// stripSuffix removes "Config" or "Item" suffix from a struct name.
+// stripSuffix removes the "Config", "Item" or "Entry" suffix from a struct name.
 // This prevents nested structs from accumulating multiple suffixes
 // (e.g., "AppConfigLoggingConfig" -> "AppLogging").
 func stripSuffix(name string) string {
@@ -81,111 +260,384 @@ func stripSuffix(name string) string {
 	if strings.HasSuffix(name, "Item") {
 		return strings.TrimSuffix(name, "Item")
 	}
+	if strings.HasSuffix(name, "Entry") {
+		return strings.TrimSuffix(name, "Entry")
+	}
 	return name
 }
 
-// writeGetterImports writes the necessary imports for getter mode.
-func (g *Generator) writeGetterImports(buf *bytes.Buffer, data map[string]any) {
-	needsTime := g.needsTimeImport(data)
-	needsStrconv := g.needsStrconvImport(data)
+// imports returns the packages the generated code needs, in import order:
+// those marked in g.extra by the code emitted, such as os and strconv for
+// getters parsing environment variables, and time for the values needing it.
+func (g *Generator) imports(data map[string]any) []string {
+	set := make(map[string]bool)
+	for imp := range g.extra {
+		set[imp] = true
+	}
+	if g.needsTimeImport(data) {
+		set["time"] = true
+	}
+	return sortImports(set)
+}
 
-	// Always need os for os.Getenv in getter mode
-	if needsTime || needsStrconv {
-		buf.WriteString("import (\n")
-		buf.WriteString("\t\"os\"\n")
-		if needsStrconv {
-			buf.WriteString("\t\"strconv\"\n")
+// sortImports returns the imports in set with standard library packages first,
+// the way goimports groups them.
+func sortImports(set map[string]bool) []string {
+	imports := make([]string, 0, len(set))
+	for imp := range set {
+		imports = append(imports, imp)
+	}
+	sort.Slice(imports, func(i, j int) bool {
+		si, sj := isStdlib(imports[i]), isStdlib(imports[j])
+		if si != sj {
+			return si
 		}
-		if needsTime {
-			buf.WriteString("\t\"time\"\n")
+		return imports[i] < imports[j]
+	})
+	return imports
+}
+
+// isStdlib reports whether an import path belongs to the standard library.
+func isStdlib(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// writeImports writes the import declaration for the given packages.
+func writeImports(buf *bytes.Buffer, imports []string) {
+	switch len(imports) {
+	case 0:
+	case 1:
+		fmt.Fprintf(buf, "import %s\n\n", importSpec(imports[0]))
+	default:
+		buf.WriteString("import (\n")
+		for i, imp := range imports {
+			if i > 0 && isStdlib(imports[i-1]) && !isStdlib(imp) {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(buf, "\t%s\n", importSpec(imp))
 		}
 		buf.WriteString(")\n\n")
-	} else {
-		buf.WriteString("import \"os\"\n\n")
 	}
 }
 
-// needsStrconvImport checks if the data needs strconv import (for int64, float64, bool).
-func (g *Generator) needsStrconvImport(data map[string]any) bool {
-	for _, v := range data {
-		if g.checkStrconvNeeded(v) {
-			return true
-		}
+// importSpec formats an import for an import declaration. Imports with a
+// name, such as "_ embed", are given as the name and path separated by a space.
+func importSpec(imp string) string {
+	if name, path, ok := strings.Cut(imp, " "); ok {
+		return fmt.Sprintf("%s %q", name, path)
 	}
-	return false
+	return fmt.Sprintf("%q", imp)
 }
 
-// checkStrconvNeeded recursively checks if a value needs strconv.
-func (g *Generator) checkStrconvNeeded(v any) bool {
-	switch val := v.(type) {
-	case int64, int, float64, bool:
-		return true
-	case map[string]any:
-		for _, nested := range val {
-			if g.checkStrconvNeeded(nested) {
-				return true
-			}
-		}
-	case []map[string]any:
-		for _, item := range val {
-			for _, nested := range item {
-				if g.checkStrconvNeeded(nested) {
-					return true
-				}
-			}
-		}
-	case []any:
-		for _, item := range val {
-			if g.checkStrconvNeeded(item) {
-				return true
-			}
-		}
+// writeHeader writes the generated-code header, the record and the package clause.
+func (g *Generator) writeHeader(buf *bytes.Buffer, partName, buildTag string) {
+	buf.WriteString(record.Header + "\n")
+	if g.record != nil {
+		rec := *g.record
+		rec.Part = partName
+		rec.Dynamic = g.generated
+		rec.Usage = g.usage
+		buf.WriteString(rec.String() + "\n")
+	}
+	buf.WriteString("\n")
+	if buildTag != "" {
+		fmt.Fprintf(buf, "//go:build %s\n\n", buildTag)
+	}
+	fmt.Fprintf(buf, "package %s\n\n", g.packageName)
+}
+
+// addPart returns the companion file with the given name, creating it if needed.
+func (g *Generator) addPart(name, buildTag string) *part {
+	p, ok := g.parts[name]
+	if !ok {
+		p = &part{buildTag: buildTag, imports: make(map[string]bool)}
+		g.parts[name] = p
+	}
+	return p
+}
+
+// finish formats generated source and applies the configured line endings.
+func (g *Generator) finish(src []byte) ([]byte, error) {
+	formatted, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, src)
+	}
+
+	if g.crlf {
+		formatted = bytes.ReplaceAll(formatted, []byte("\n"), []byte("\r\n"))
 	}
-	return false
+	return formatted, nil
 }
 
 // Generate parses TOML data and generates Go code.
+// Companion files, if any, are discarded; use GenerateFiles to get them.
 func (g *Generator) Generate(tomlData []byte) ([]byte, error) {
+	files, err := g.GenerateFiles(tomlData)
+	if err != nil {
+		return nil, err
+	}
+	return files[""], nil
+}
+
+// GenerateFiles parses TOML data and generates Go code. The main file is
+// returned under the empty name; companion files generated for helpers that
+// need third-party packages are returned under their part name (e.g. "redis"),
+// to be written as <output>_<part>.go. Tables annotated with cfgx:package are
+// generated into their own package, returned under "<package>/" followed by
+// the part name.
+func (g *Generator) GenerateFiles(tomlData []byte) (map[string][]byte, error) {
+	start := time.Now()
+
+	region := trace.StartRegion(context.Background(), "cfgx.parse")
 	var data map[string]any
 	if err := toml.Unmarshal(tomlData, &data); err != nil {
+		region.End()
 		return nil, fmt.Errorf("failed to parse TOML: %w", err)
 	}
+	region.End()
 
-	// Validate all file references before generating code
-	if err := g.validateFileReferences(data); err != nil {
+	src := g.source
+	if src == nil {
+		src = tomlsrc.Scan(tomlData)
+	}
+	return g.generateFiles(data, src, start)
+}
+
+// GenerateFilesFromMap is GenerateFiles for data that has already been
+// decoded. Values must have the types the TOML decoder produces: int64,
+// float64, bool, string, time.Time, []any, []map[string]any and
+// map[string]any. Data is modified while references and inheritance are
+// resolved. Annotations are taken from the source set with WithSource, if any.
+func (g *Generator) GenerateFilesFromMap(data map[string]any) (map[string][]byte, error) {
+	src := g.source
+	if src == nil {
+		src = tomlsrc.Scan(nil)
+	}
+	return g.generateFiles(data, src, time.Now())
+}
+
+// generateFiles resolves references, inheritance and computed values in data
+// and generates the main file, its companion files and its sections.
+func (g *Generator) generateFiles(data map[string]any, src *tomlsrc.Source, start time.Time) (map[string][]byte, error) {
+	region := trace.StartRegion(context.Background(), "cfgx.resolve")
+	if err := ResolveDefines(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := ResolveExtends(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	g.generated = false
+	if g.dynamic {
+		var err error
+		if g.generated, err = g.resolveDynamicValues(data); err != nil {
+			region.End()
+			return nil, err
+		}
+	}
+	if err := g.resolveExpressions(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	meta, err := takeMeta(data)
+	if err != nil {
+		region.End()
+		return nil, err
+	}
+	g.meta = meta
+	if err := g.policy.Check(data); err != nil {
+		region.End()
 		return nil, err
 	}
+	region.End()
 
-	var buf bytes.Buffer
+	g.src = src
+	g.assets = make(map[string][]byte)
+	g.referenced = make(map[string]bool)
 
-	buf.WriteString("// Code generated by cfgx. DO NOT EDIT.\n\n")
-	buf.WriteString(fmt.Sprintf("package %s\n\n", g.packageName))
+	sections, err := g.splitSections(data)
+	if err != nil {
+		return nil, err
+	}
 
-	// Generate imports based on mode
-	if g.mode == "getter" {
-		g.writeGetterImports(&buf, data)
-	} else {
-		needsTime := g.needsTimeImport(data)
-		if needsTime {
-			buf.WriteString("import \"time\"\n\n")
+	files, err := g.generate(data, start)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range slices.Sorted(maps.Keys(sections)) {
+		sectionFiles, err := g.sectionGenerator(pkg).generate(sections[pkg], time.Now())
+		if err != nil {
+			return nil, err
+		}
+		for name, generated := range sectionFiles {
+			files[pkg+"/"+name] = generated
 		}
 	}
+	return files, nil
+}
+
+// generate generates the files for data, which has been parsed and resolved.
+func (g *Generator) generate(data map[string]any, start time.Time) (map[string][]byte, error) {
+	g.structKeys = make(map[string]string)
+	g.extra = make(map[string]bool)
+	g.parts = make(map[string]*part)
+	g.snippets = make(map[string]bool)
+	g.checks = nil
+	g.usageKeys = nil
+	g.initVars = make(map[string]bool)
+	g.embeds = make(map[string]embedded)
+	g.order = nil
+	g.files = nil
+	g.data = data
+
+	if g.envAtInit && g.mode != "getter" {
+		return nil, fmt.Errorf("reading env vars at init requires getter mode")
+	}
+	if err := g.validateStyle(); err != nil {
+		return nil, err
+	}
+
+	if err := g.resolveMaps(data); err != nil {
+		return nil, err
+	}
+	parsed := time.Now()
+	if err := g.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Validate all file references before generating code, except those of
+	// keys typed as literal strings
+	region := trace.StartRegion(context.Background(), "cfgx.analysis")
+	if err := g.validateTypes(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.resolveEncrypted(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateFileReferences(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateFormats(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validatePathParents(); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateConstraints(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateEnvDefaults(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateOptional(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateNames(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.validateIdentifiers(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	if err := g.resolveRuntimeOnly(data); err != nil {
+		region.End()
+		return nil, err
+	}
+	region.End()
+	analyzed := time.Now()
+	if err := g.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	region = trace.StartRegion(context.Background(), "cfgx.emit")
+	defer region.End()
+
+	// The body is generated first so that helpers can request imports
+	var body bytes.Buffer
 
 	// Generate code based on mode
 	if g.mode == "getter" {
-		if err := g.generateStructsAndGetters(&buf, data); err != nil {
+		if err := g.generateStructsAndGetters(&body, data); err != nil {
 			return nil, err
 		}
 	} else {
-		if err := g.generateStructsAndVars(&buf, data); err != nil {
+		if err := g.generateStructsAndVars(&body, data); err != nil {
 			return nil, err
 		}
 	}
 
-	formatted, err := format.Source(buf.Bytes())
+	g.writeSnippets(&body)
+	g.writeEmbeds(&body)
+
+	if err := g.writeUsage(&body); err != nil {
+		return nil, err
+	}
+	if err := g.writeValidate(&body); err != nil {
+		return nil, err
+	}
+	if err := g.writeLoadOverrides(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeLoader(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeSummary(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeResourceAttributes(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeLogLevel(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeHelpers(&body, data); err != nil {
+		return nil, err
+	}
+	if err := g.writeTLSHelpers(&body, data); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	g.writeHeader(&buf, "", "")
+	writeImports(&buf, g.imports(data))
+	g.writeEnvDocs(&buf, data)
+	buf.Write(body.Bytes())
+
+	formatted, err := g.finish(buf.Bytes())
 	if err != nil {
-		return nil, fmt.Errorf("failed to format generated code: %w\n%s", err, buf.String())
+		return nil, err
 	}
+	files := map[string][]byte{"": formatted}
 
-	return formatted, nil
+	for name, p := range g.parts {
+		buf.Reset()
+		g.writeHeader(&buf, name, p.buildTag)
+		writeImports(&buf, sortImports(p.imports))
+		buf.Write(p.body.Bytes())
+
+		if files[name], err = g.finish(buf.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if g.stats != nil {
+		g.stats.ParseTime = parsed.Sub(start)
+		g.stats.AnalysisTime = analyzed.Sub(parsed)
+		g.stats.EmitTime = time.Since(analyzed)
+		g.stats.OutputBytes = len(formatted)
+		g.collectStats(data)
+	}
+
+	return files, nil
 }
@@ -1,6 +1,8 @@
 package generator
 
 import (
+	"go/parser"
+	"go/token"
 	"os"
 	"strings"
 	"testing"
@@ -198,6 +200,38 @@ max_conns = 25
 	require.Contains(t, outputStr, "databaseConfig", "output missing databaseConfig type")
 }
 
+func TestGenerator_GetterImports(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{name: "strings", data: "name = \"api\"\n", want: []string{"os"}},
+		{name: "numbers", data: "port = 8080\nratio = 0.5\ndebug = true\n", want: []string{"os", "strconv"}},
+		{name: "durations", data: "timeout = \"30s\"\n", want: []string{"os", "time"}},
+		{name: "arrays", data: "ports = [80, 443]\n", want: []string{"os"}},
+		{name: "annotated", data: "workers = 4 # cfgx:type=int\n", want: []string{"os", "strconv"}},
+		{name: "annotated string", data: "sku = \"1h\" # cfgx:type=string\n", want: []string{"os"}},
+		{name: "arrays of tables", data: "[[servers]]\nhosts = [\"a\"]\n", want: nil},
+		{name: "numbers in arrays of tables", data: "[[servers]]\nport = 80\n", want: []string{"os", "strconv"}},
+		{name: "maps", data: "[limits] # cfgx:map\napi = 10\n", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := New(WithMode("getter")).Generate([]byte(tt.data))
+			require.NoError(t, err)
+
+			file, err := parser.ParseFile(token.NewFileSet(), "config.go", output, parser.ImportsOnly)
+			require.NoError(t, err)
+			var imports []string
+			for _, imp := range file.Imports {
+				imports = append(imports, strings.Trim(imp.Path.Value, `"`))
+			}
+			require.Equal(t, tt.want, imports)
+		})
+	}
+}
+
 func TestGenerator_GetterMode_NestedStructs(t *testing.T) {
 	data := []byte(`
 [database]
@@ -250,6 +284,45 @@ ports = [8080, 8081]
 	require.Contains(t, outputStr, "return []int64{8080, 8081}", "output missing ports default")
 }
 
+func TestGenerator_GetterMode_ArraysOfTables(t *testing.T) {
+	data := []byte(`
+[[servers]]
+host = "localhost"
+port = 8080
+timeout = "5s"
+
+[servers.tls]
+cert = "a.pem"
+
+[[servers]]
+host = "example.com"
+port = 443
+timeout = "10s"
+
+[servers.tls]
+cert = "b.pem"
+
+[service]
+endpoints = [{ path = "/v1", weight = 0.5 }]
+`)
+
+	output, err := New(WithPackageName("config"), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+
+	require.NotContains(t, outputStr, "return nil")
+	require.Contains(t, outputStr, "type serversItem struct {\n\tHost    string\n\tPort    int64\n\tTimeout time.Duration\n\tTls     serversTlsConfig\n}")
+	require.Contains(t, outputStr, "func Servers() []serversItem {\n\titems := []serversItem{\n\t\t{\n\t\t\tHost:    \"localhost\",")
+	require.Contains(t, outputStr, "if v := os.Getenv(\"CONFIG_SERVERS_1_PORT\"); v != \"\" {\n\t\tif i, err := strconv.ParseInt(v, 10, 64); err == nil {\n\t\t\titems[1].Port = i\n\t\t}\n\t}")
+	require.Contains(t, outputStr, "if d, err := time.ParseDuration(v); err == nil {\n\t\t\titems[0].Timeout = d")
+	require.Contains(t, outputStr, "if v := os.Getenv(\"CONFIG_SERVERS_0_TLS_CERT\"); v != \"\" {\n\t\titems[0].Tls.Cert = v\n\t}")
+
+	require.Contains(t, outputStr, "type serviceendpointsItem struct {")
+	require.Contains(t, outputStr, "func (serviceConfig) Endpoints() []serviceendpointsItem {")
+	require.Contains(t, outputStr, "if f, err := strconv.ParseFloat(v, 64); err == nil {\n\t\t\titems[0].Weight = f")
+	require.Contains(t, outputStr, `os.Getenv("CONFIG_SERVICE_ENDPOINTS_0_PATH")`)
+}
+
 func TestGenerator_GetterMode_NoDuplicateMethods(t *testing.T) {
 	data := []byte(`
 [cache]
@@ -393,3 +466,35 @@ addr = ":8080"
 	require.Contains(t, outputStr, "var (", "output missing var block")
 	require.Contains(t, outputStr, "Server serverConfig", "output missing Server var declaration")
 }
+
+func TestGenerator_Stats(t *testing.T) {
+	data := []byte(`
+[server]
+addr = ":8080"
+cert = "file:files/small.txt"
+
+[server.tls]
+enabled = true
+
+[[endpoints]]
+path = "/a"
+
+[[endpoints]]
+path = "/b"
+`)
+
+	var stats Stats
+	gen := New(WithInputDir("../../testdata"), WithStats(&stats))
+	output, err := gen.Generate(data)
+	require.NoError(t, err, "Generate() should not error")
+
+	small, err := os.ReadFile("../../testdata/files/small.txt")
+	require.NoError(t, err)
+
+	require.Equal(t, 3, stats.Structs, "server, server.tls and endpoints")
+	require.Equal(t, 5, stats.Fields, "addr, cert, tls, tls.enabled and path")
+	require.Equal(t, int64(len(small)), stats.EmbeddedBytes)
+	require.Equal(t, len(output), stats.OutputBytes)
+	require.NotEmpty(t, stats.HeaviestKeys)
+	require.Equal(t, "server.cert", stats.HeaviestKeys[0].Key, "embedded file should be the heaviest key")
+}
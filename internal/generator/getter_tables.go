@@ -0,0 +1,162 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tables returns the elements of v if it is an array of tables.
+func tables(v any) ([]map[string]any, bool) {
+	switch val := v.(type) {
+	case []map[string]any:
+		return val, len(val) > 0
+	case []any:
+		if len(val) == 0 {
+			return nil, false
+		}
+		items := make([]map[string]any, 0, len(val))
+		for _, item := range val {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			items = append(items, m)
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+// mergeItems returns the fields of the struct type of items, the elements of
+// an array of tables, which may each set a different subset of them. Nested
+// tables are merged the same way and nested arrays of tables concatenated;
+// any other field has the value of the first item setting it.
+func mergeItems(items []map[string]any) map[string]any {
+	if len(items) == 1 {
+		return items[0]
+	}
+	merged := make(map[string]any)
+	nested := make(map[string][]map[string]any)
+	for _, item := range items {
+		for k, v := range item {
+			if table, ok := v.(map[string]any); ok {
+				nested[k] = append(nested[k], table)
+			} else if elems, ok := tables(v); ok {
+				nested[k] = append(nested[k], elems...)
+				v = []map[string]any(nil)
+			}
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+		}
+	}
+	for k, elems := range nested {
+		switch merged[k].(type) {
+		case map[string]any:
+			merged[k] = mergeItems(elems)
+		case []map[string]any:
+			merged[k] = elems
+		}
+	}
+	return merged
+}
+
+// writeTablesGetter writes a getter-mode getter for key, an array of tables,
+// that returns the elements baked in at generation time with each scalar
+// field overridable through an environment variable naming the element's
+// index, e.g. CONFIG_SERVERS_0_PORT for the port of the first server:
+//
+//	func Servers() []serversItem {
+//		items := []serversItem{
+//			{Host: "localhost", Port: 8080},
+//		}
+//		if v := os.Getenv("CONFIG_SERVERS_0_PORT"); v != "" {
+//			if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+//				items[0].Port = i
+//			}
+//		}
+//		return items
+//	}
+//
+// Items use field structs as in static mode, so a fresh slice is returned on
+// every call and callers may modify it.
+func (g *Generator) writeTablesGetter(buf *bytes.Buffer, signature, key, itemType, envPrefix string, items []map[string]any) error {
+	goType := "[]" + itemType
+	var err error
+	g.writeGetter(buf, signature, goType, key, func(buf *bytes.Buffer) {
+		fmt.Fprintf(buf, "\titems := %s", goType)
+		if err = g.writeArrayOfTablesInit(buf, itemType, items, 1); err != nil {
+			return
+		}
+		buf.WriteString("\n")
+		for i, item := range items {
+			g.writeElementOverrides(buf, fmt.Sprintf("items[%d]", i), key, fmt.Sprintf("%s_%d", envPrefix, i), item)
+		}
+		buf.WriteString("\treturn items\n")
+	})
+	return err
+}
+
+// writeElementOverrides writes the environment overrides for the scalar
+// fields of target, an element of the array of tables key or a table nested
+// in one. Fields of annotated types and arrays keep their baked-in values.
+func (g *Generator) writeElementOverrides(buf *bytes.Buffer, target, key, envPrefix string, table map[string]any) {
+	fields := make([]string, 0, len(table))
+	for k := range table {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		fieldKey := joinKey(key, field)
+		fieldTarget := target + "." + g.fieldName(fieldKey)
+		envVarName := envPrefix + "_" + strings.ToUpper(field)
+
+		if nested, ok := table[field].(map[string]any); ok {
+			g.writeElementOverrides(buf, fieldTarget, fieldKey, envVarName, nested)
+			continue
+		}
+		goType, ok := g.genericType(fieldKey, table[field])
+		if !ok {
+			continue
+		}
+
+		name, parse, parsed := "", "", goType
+		switch goType {
+		case "string":
+			g.extra["os"] = true
+			fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+			fmt.Fprintf(buf, "\t\t%s = v\n", fieldTarget)
+			buf.WriteString("\t}\n")
+			continue
+		case "float64":
+			name, parse = "f", "strconv.ParseFloat(v, 64)"
+		case "bool":
+			name, parse = "b", "strconv.ParseBool(v)"
+		case "time.Duration":
+			name, parse = "d", "time.ParseDuration(v)"
+		case "time.Time":
+			name, parse = "t", "time.Parse(time.RFC3339, v)"
+		default:
+			if parse, parsed, ok = integerParser(goType, "v"); !ok {
+				continue
+			}
+			name = "i"
+		}
+		value := name
+		if parsed != goType {
+			value = goType + "(" + name + ")"
+		}
+		g.extra["os"] = true
+		if strings.HasPrefix(parse, "strconv.") {
+			g.extra["strconv"] = true
+		}
+		fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+		fmt.Fprintf(buf, "\t\tif %s, err := %s; err == nil {\n", name, parse)
+		fmt.Fprintf(buf, "\t\t\t%s = %s\n", fieldTarget, value)
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+	}
+}
@@ -0,0 +1,306 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// helperKind describes how a TOML value is converted for a helper.
+type helperKind int
+
+const (
+	helperString   helperKind = iota // string value, used as is
+	helperInt                        // int64 value, converted to int
+	helperDuration                   // duration string, or int64 seconds
+)
+
+// helperField maps a conventional TOML key onto a client option.
+type helperField struct {
+	key    string
+	target string
+	kind   helperKind
+}
+
+// sqlPoolFields are the database/sql pool settings applied by the sql helper.
+var sqlPoolFields = []helperField{
+	{"max_open_conns", "SetMaxOpenConns", helperInt},
+	{"max_idle_conns", "SetMaxIdleConns", helperInt},
+	{"conn_max_lifetime", "SetConnMaxLifetime", helperDuration},
+	{"conn_max_idle_time", "SetConnMaxIdleTime", helperDuration},
+}
+
+// httpServerFields are the http.Server fields set by the http helper.
+var httpServerFields = []helperField{
+	{"addr", "Addr", helperString},
+	{"read_timeout", "ReadTimeout", helperDuration},
+	{"read_header_timeout", "ReadHeaderTimeout", helperDuration},
+	{"write_timeout", "WriteTimeout", helperDuration},
+	{"idle_timeout", "IdleTimeout", helperDuration},
+	{"max_header_bytes", "MaxHeaderBytes", helperInt},
+}
+
+// redisFields are the redis.Options fields set by the redis helper.
+var redisFields = []helperField{
+	{"network", "Network", helperString},
+	{"addr", "Addr", helperString},
+	{"client_name", "ClientName", helperString},
+	{"protocol", "Protocol", helperInt},
+	{"username", "Username", helperString},
+	{"password", "Password", helperString},
+	{"db", "DB", helperInt},
+	{"max_retries", "MaxRetries", helperInt},
+	{"dial_timeout", "DialTimeout", helperDuration},
+	{"read_timeout", "ReadTimeout", helperDuration},
+	{"write_timeout", "WriteTimeout", helperDuration},
+	{"pool_size", "PoolSize", helperInt},
+	{"pool_timeout", "PoolTimeout", helperDuration},
+	{"min_idle_conns", "MinIdleConns", helperInt},
+	{"max_idle_conns", "MaxIdleConns", helperInt},
+	{"conn_max_idle_time", "ConnMaxIdleTime", helperDuration},
+	{"conn_max_lifetime", "ConnMaxLifetime", helperDuration},
+}
+
+// writeHelpers generates client adapters for tables annotated with cfgx:helper:
+//
+//	# cfgx:helper=sql
+//	[database]
+//	driver = "postgres"
+//	dsn = "postgres://localhost/app"
+//	max_open_conns = 25
+//
+// The sql and http helpers only need the standard library and are written into
+// the main file. The redis helper imports go-redis, so it is written into a companion
+// file behind the cfgx_redis build tag; projects that do not use it never see
+// the dependency.
+func (g *Generator) writeHelpers(buf *bytes.Buffer, data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+
+	for _, key := range g.annotated("helper") {
+		kind, _ := g.annotation(key, "helper")
+		table, ok := lookupTable(data, key)
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:helper must annotate a table")}
+		}
+		typeName := g.structName(key)
+
+		var err error
+		switch kind {
+		case "sql":
+			err = g.writeSQLHelper(buf, typeName, key, table)
+		case "http":
+			err = g.writeHTTPHelper(buf, typeName, key, table)
+		case "redis":
+			p := g.addPart("redis", "cfgx_redis")
+			err = g.writeRedisHelper(p, typeName, key, table)
+		default:
+			err = &KeyError{Key: key, Err: fmt.Errorf("unknown cfgx:helper %q (want sql, http or redis)", kind)}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSQLHelper generates Apply, and Open when the table has driver and dsn keys.
+func (g *Generator) writeSQLHelper(buf *bytes.Buffer, typeName, key string, table map[string]any) error {
+	if err := g.checkMethodConflicts(key, table, "apply", "open"); err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, f := range sqlPoolFields {
+		expr, ok, err := g.helperValue(g.extra, key, table, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			lines = append(lines, fmt.Sprintf("\tdb.%s(%s)\n", f.target, expr))
+		}
+	}
+	_, hasDriver := table["driver"].(string)
+	_, hasDSN := table["dsn"].(string)
+	if len(lines) == 0 && !(hasDriver && hasDSN) {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:helper=sql found no driver/dsn or pool settings")}
+	}
+
+	g.extra["database/sql"] = true
+
+	fmt.Fprintf(buf, "\n// Apply applies the connection pool settings of the [%s] table to db.\n", key)
+	fmt.Fprintf(buf, "func (c %s) Apply(db *sql.DB) {\n", typeName)
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	buf.WriteString("}\n")
+
+	if hasDriver && hasDSN {
+		fmt.Fprintf(buf, "\n// Open opens the database described by the [%s] table and applies its\n", key)
+		buf.WriteString("// connection pool settings.\n")
+		fmt.Fprintf(buf, "func (c %s) Open() (*sql.DB, error) {\n", typeName)
+		fmt.Fprintf(buf, "\tdb, err := sql.Open(%s, %s)\n", g.field("c", key, "driver"), g.field("c", key, "dsn"))
+		buf.WriteString("\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+		buf.WriteString("\tc.Apply(db)\n")
+		buf.WriteString("\treturn db, nil\n")
+		buf.WriteString("}\n")
+	}
+
+	return nil
+}
+
+// writeHTTPHelper generates NewHTTPServer returning an http.Server configured
+// from the table.
+func (g *Generator) writeHTTPHelper(buf *bytes.Buffer, typeName, key string, table map[string]any) error {
+	if err := g.checkMethodConflicts(key, table, "new_http_server"); err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, f := range httpServerFields {
+		expr, ok, err := g.helperValue(g.extra, key, table, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			lines = append(lines, fmt.Sprintf("\t\t%s: %s,\n", f.target, expr))
+		}
+	}
+	if len(lines) == 0 {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:helper=http found no server settings")}
+	}
+	// Handler goes right after Addr, as in the http.Server declaration
+	handler := "\t\tHandler: handler,\n"
+	if _, ok := table["addr"]; ok {
+		lines = append(lines[:1], append([]string{handler}, lines[1:]...)...)
+	} else {
+		lines = append([]string{handler}, lines...)
+	}
+
+	g.extra["net/http"] = true
+
+	fmt.Fprintf(buf, "\n// NewHTTPServer returns an http.Server for handler configured from the [%s] table.\n", key)
+	fmt.Fprintf(buf, "func (c %s) NewHTTPServer(handler http.Handler) *http.Server {\n", typeName)
+	buf.WriteString("\treturn &http.Server{\n")
+	for _, line := range lines {
+		buf.WriteString(line)
+	}
+	buf.WriteString("\t}\n}\n")
+
+	return nil
+}
+
+// writeRedisHelper generates Options returning go-redis client options.
+func (g *Generator) writeRedisHelper(p *part, typeName, key string, table map[string]any) error {
+	if err := g.checkMethodConflicts(key, table, "options"); err != nil {
+		return err
+	}
+
+	var lines []string
+	for _, f := range redisFields {
+		expr, ok, err := g.helperValue(p.imports, key, table, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			lines = append(lines, fmt.Sprintf("\t\t%s: %s,\n", f.target, expr))
+		}
+	}
+	if len(lines) == 0 {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:helper=redis found no redis settings")}
+	}
+
+	p.imports["github.com/redis/go-redis/v9"] = true
+
+	fmt.Fprintf(&p.body, "\n// Options returns go-redis client options for the [%s] table.\n", key)
+	fmt.Fprintf(&p.body, "func (c %s) Options() *redis.Options {\n", typeName)
+	p.body.WriteString("\treturn &redis.Options{\n")
+	for _, line := range lines {
+		p.body.WriteString(line)
+	}
+	p.body.WriteString("\t}\n}\n")
+
+	return nil
+}
+
+// helperValue returns the Go expression converting the table's value for f to
+// the type the target expects. It reports false if the key is absent.
+func (g *Generator) helperValue(imports map[string]bool, tableKey string, table map[string]any, f helperField) (string, bool, error) {
+	v, ok := table[f.key]
+	if !ok {
+		return "", false, nil
+	}
+	expr := g.field("c", tableKey, f.key)
+
+	switch f.kind {
+	case helperString:
+		if _, ok := v.(string); ok {
+			return expr, true, nil
+		}
+	case helperInt:
+		if _, ok := v.(int64); ok {
+			return "int(" + expr + ")", true, nil
+		}
+	case helperDuration:
+		if conv, ok := g.durationExpr(imports, expr, v); ok {
+			return conv, true, nil
+		}
+	}
+	return "", false, &KeyError{Key: joinKey(tableKey, f.key), Err: fmt.Errorf("unexpected value %v for %s", v, f.target)}
+}
+
+// durationExpr returns a time.Duration expression for a field holding either a
+// duration string (already generated as time.Duration) or an integer number of
+// seconds.
+func (g *Generator) durationExpr(imports map[string]bool, expr string, v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		if g.isDurationString(val) {
+			return expr, true
+		}
+	case int64:
+		imports["time"] = true
+		return "time.Duration(" + expr + ") * time.Second", true
+	}
+	return "", false
+}
+
+// field returns the expression reading key of the table at tableKey on
+// receiver recv.
+func (g *Generator) field(recv, tableKey, key string) string {
+	expr := recv + "." + g.fieldName(joinKey(tableKey, key))
+	if g.mode == "getter" {
+		expr += "()"
+	}
+	return expr
+}
+
+// structName returns the generated type name of the table at key.
+func (g *Generator) structName(key string) string {
+	names := make([]string, 0, 1)
+	for name, k := range g.structKeys {
+		if k == key && strings.HasSuffix(name, "Config") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// checkMethodConflicts returns an error if the table has a key that would be
+// generated with the same name as a helper method.
+func (g *Generator) checkMethodConflicts(tableKey string, table map[string]any, methods ...string) error {
+	for k := range table {
+		for _, m := range methods {
+			if g.fieldName(joinKey(tableKey, k)) == g.pascalCase(m) {
+				return &KeyError{Key: joinKey(tableKey, k), Err: fmt.Errorf("conflicts with the generated %s method", g.pascalCase(m))}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_SQLHelper(t *testing.T) {
+	data := []byte(`
+# cfgx:helper=sql
+[database]
+driver = "postgres"
+dsn = "postgres://localhost/app"
+max_open_conns = 25
+conn_max_lifetime = "5m"
+conn_max_idle_time = 60
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\"database/sql\"")
+	require.Contains(t, outputStr, "func (c DatabaseConfig) Apply(db *sql.DB) {")
+	require.Contains(t, outputStr, "db.SetMaxOpenConns(int(c.MaxOpenConns))")
+	require.Contains(t, outputStr, "db.SetConnMaxLifetime(c.ConnMaxLifetime)")
+	require.Contains(t, outputStr, "db.SetConnMaxIdleTime(time.Duration(c.ConnMaxIdleTime) * time.Second)")
+	require.Contains(t, outputStr, "sql.Open(c.Driver, c.Dsn)")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func (c databaseConfig) Apply(db *sql.DB) {")
+	require.Contains(t, string(output), "db.SetMaxOpenConns(int(c.MaxOpenConns()))")
+}
+
+func TestGenerator_HTTPHelper(t *testing.T) {
+	data := []byte(`
+# cfgx:helper=http
+[server]
+addr = ":8080"
+read_timeout = "15s"
+write_timeout = 30
+max_header_bytes = 1048576
+shutdown_timeout = "10s"
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\"net/http\"")
+	require.Contains(t, outputStr, "func (c ServerConfig) NewHTTPServer(handler http.Handler) *http.Server {")
+	require.Contains(t, outputStr, "Handler:        handler,")
+	require.Contains(t, outputStr, "ReadTimeout:    c.ReadTimeout,")
+	require.Contains(t, outputStr, "WriteTimeout:   time.Duration(c.WriteTimeout) * time.Second,")
+	require.Contains(t, outputStr, "MaxHeaderBytes: int(c.MaxHeaderBytes),")
+	require.NotContains(t, outputStr, "ShutdownTimeout: c.")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Addr:           c.Addr(),")
+}
+
+func TestGenerator_RedisHelper(t *testing.T) {
+	data := []byte(`
+[cache]
+enabled = true
+
+# cfgx:helper=redis
+[cache.redis]
+addr = "localhost:6379"
+db = 2
+read_timeout = "3s"
+`)
+
+	files, err := New().GenerateFiles(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(files[""]), "redis.Options", "redis helper belongs in the companion file")
+
+	redis := string(files["redis"])
+	require.Contains(t, redis, "//go:build cfgx_redis")
+	require.Contains(t, redis, "\"github.com/redis/go-redis/v9\"")
+	require.Contains(t, redis, "func (c CacheRedisConfig) Options() *redis.Options {")
+	require.Contains(t, redis, "DB:          int(c.Db),")
+}
+
+func TestGenerator_HelperErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "unknown helper",
+			toml: "# cfgx:helper=mongo\n[db]\naddr = \"x\"\n",
+			want: "unknown cfgx:helper",
+		},
+		{
+			name: "no recognized keys",
+			toml: "# cfgx:helper=sql\n[db]\nname = \"x\"\n",
+			want: "found no driver/dsn",
+		},
+		{
+			name: "wrong type",
+			toml: "# cfgx:helper=sql\n[db]\nmax_open_conns = \"many\"\n",
+			want: "db.max_open_conns",
+		},
+		{
+			name: "method conflict",
+			toml: "# cfgx:helper=sql\n[db]\nmax_open_conns = 1\napply = true\n",
+			want: "conflicts with the generated Apply method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// overrideParsers maps the Go types LoadOverrides can parse from an
+// environment variable, besides integers, to the parsing expression, given
+// the string.
+var overrideParsers = map[string]string{
+	"float64":       "strconv.ParseFloat(%s, 64)",
+	"bool":          "strconv.ParseBool(%s)",
+	"time.Duration": "time.ParseDuration(%s)",
+	"time.Time":     "time.Parse(time.RFC3339, %s)",
+}
+
+// overrideParser returns the expression parsing a string, given as %s, into
+// a value of goType, along with the Go type of its result, which must be
+// converted to goType if they differ. ok is false for the types that cannot
+// be parsed from environment variables.
+func overrideParser(goType string) (parse, parsed string, ok bool) {
+	if parse, ok := overrideParsers[goType]; ok {
+		return parse, goType, true
+	}
+	return integerParser(goType, "%s")
+}
+
+// writeLoadOverrides generates, in hybrid mode, the LoadOverrides function
+// that applies CONFIG_<SECTION>_<KEY> environment variables to the package
+// variables, which otherwise hold the values baked in as in static mode.
+// Arrays take comma-separated values and []byte file references a path to
+// read. Values of annotated types are parsed as the getters parse them, by
+// configParsers. Optional values and arrays of tables are not overridden.
+func (g *Generator) writeLoadOverrides(buf *bytes.Buffer, data map[string]any) error {
+	if g.mode != "hybrid" {
+		return nil
+	}
+
+	g.extra["errors"] = true
+
+	fmt.Fprintf(buf, "\n// LoadOverrides applies overrides from %s_* environment variables to the\n", g.envPrefix)
+	buf.WriteString("// package variables. Call it once at startup, before the configuration is\n")
+	buf.WriteString("// read; values that cannot be parsed are left unchanged and reported.\n")
+	buf.WriteString("func LoadOverrides() error {\n")
+	buf.WriteString("\tvar errs []error\n")
+	g.writeOverrides(buf, nil, data)
+	buf.WriteString("\treturn errors.Join(errs...)\n")
+	buf.WriteString("}\n")
+	if len(g.parsedKeys()) > 0 {
+		g.writeConfigParsers(buf)
+	}
+	return nil
+}
+
+// writeOverrides writes the overrides for the values in table, found at path.
+func (g *Generator) writeOverrides(buf *bytes.Buffer, path []string, table map[string]any) {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		p := append(path[:len(path):len(path)], key)
+		value := table[key]
+		if nested, ok := value.(map[string]any); ok {
+			g.writeOverrides(buf, p, nested)
+			continue
+		}
+		if _, ok := tables(value); ok {
+			continue
+		}
+		key, envVarName := strings.Join(p, "."), g.envPrefix+"_"+strings.ToUpper(strings.Join(p, "_"))
+		if vt, ok := g.valueTypeOf(key); ok && !vt.generic && !vt.file && !g.optional[key] {
+			g.writeTypedOverride(buf, g.accessor(p), key, envVarName, vt)
+			continue
+		}
+		goType, ok := g.genericType(key, value)
+		if g.types[key] == "asset" {
+			goType, ok = "Asset", true
+		}
+		if !ok {
+			continue
+		}
+		g.writeOverride(buf, g.accessor(p), key, envVarName, goType)
+	}
+}
+
+// writeTypedOverride writes the override of target, the value of key of the
+// annotated type vt, from envVarName, parsed by configParsers.
+func (g *Generator) writeTypedOverride(buf *bytes.Buffer, target, key, envVarName string, vt valueType) {
+	g.extra["fmt"] = true
+	g.extra["os"] = true
+	fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+	fmt.Fprintf(buf, "\t\tif x := configParsers[%q](v); x != nil {\n", key)
+	fmt.Fprintf(buf, "\t\t\t%s = x.(%s)\n", target, vt.goType)
+	buf.WriteString("\t\t} else {\n")
+	fmt.Fprintf(buf, "\t\t\terrs = append(errs, fmt.Errorf(%q, v))\n", "invalid value for "+envVarName+" ("+key+"): %q is not a valid "+g.types[key])
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+}
+
+// writeOverride writes the override of target, the value of key of type
+// goType, from envVarName. Errors name the variable, the key and, for arrays,
+// the index of the element that does not parse.
+func (g *Generator) writeOverride(buf *bytes.Buffer, target, key, envVarName, goType string) {
+	fail := fmt.Sprintf("errs = append(errs, fmt.Errorf(%q, err))", "invalid value for "+envVarName+" ("+key+"): %w")
+
+	switch goType {
+	case "[]byte", "Asset":
+		value := "data"
+		if goType == "Asset" {
+			value = "newAsset(path, data)"
+		}
+		g.extra["fmt"] = true
+		g.extra["os"] = true
+		fmt.Fprintf(buf, "\tif path := os.Getenv(%q); path != \"\" {\n", envVarName)
+		buf.WriteString("\t\tif data, err := os.ReadFile(path); err != nil {\n")
+		fmt.Fprintf(buf, "\t\t\t%s\n", fail)
+		buf.WriteString("\t\t} else {\n")
+		fmt.Fprintf(buf, "\t\t\t%s = %s\n", target, value)
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		return
+	case "string":
+		g.extra["os"] = true
+		fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+		fmt.Fprintf(buf, "\t\t%s = v\n", target)
+		buf.WriteString("\t}\n")
+		return
+	case "[]string":
+		g.extra["os"] = true
+		g.extra["strings"] = true
+		fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+		fmt.Fprintf(buf, "\t\t%s = nil\n", target)
+		buf.WriteString("\t\tfor _, s := range strings.Split(v, \",\") {\n")
+		fmt.Fprintf(buf, "\t\t\t%s = append(%s, strings.TrimSpace(s))\n", target, target)
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		return
+	}
+
+	elemType, isArray := strings.CutPrefix(goType, "[]")
+	parse, parsed, ok := overrideParser(elemType)
+	if !ok {
+		return
+	}
+	value := "x"
+	if parsed != elemType {
+		value = elemType + "(x)"
+	}
+	g.extra["fmt"] = true
+	g.extra["os"] = true
+	if strings.HasPrefix(parse, "strconv.") {
+		g.extra["strconv"] = true
+	}
+
+	fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+	if !isArray {
+		fmt.Fprintf(buf, "\t\tif x, err := %s; err != nil {\n", fmt.Sprintf(parse, "v"))
+		fmt.Fprintf(buf, "\t\t\t%s\n", fail)
+		buf.WriteString("\t\t} else {\n")
+		fmt.Fprintf(buf, "\t\t\t%s = %s\n", target, value)
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+		return
+	}
+
+	g.extra["strings"] = true
+	fmt.Fprintf(buf, "\t\tvar items %s\n", goType)
+	buf.WriteString("\t\tvar err error\n")
+	buf.WriteString("\t\tfor i, s := range strings.Split(v, \",\") {\n")
+	fmt.Fprintf(buf, "\t\t\tvar x %s\n", parsed)
+	fmt.Fprintf(buf, "\t\t\tif x, err = %s; err != nil {\n", fmt.Sprintf(parse, "strings.TrimSpace(s)"))
+	buf.WriteString("\t\t\t\terr = fmt.Errorf(\"element %d: %w\", i, err)\n")
+	buf.WriteString("\t\t\t\tbreak\n")
+	buf.WriteString("\t\t\t}\n")
+	fmt.Fprintf(buf, "\t\t\titems = append(items, %s)\n", value)
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t\tif err != nil {\n")
+	fmt.Fprintf(buf, "\t\t\t%s\n", fail)
+	buf.WriteString("\t\t} else {\n")
+	fmt.Fprintf(buf, "\t\t\t%s = items\n", target)
+	buf.WriteString("\t\t}\n")
+	buf.WriteString("\t}\n")
+}
@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_HybridMode(t *testing.T) {
+	data := []byte(`
+name = "svc"
+
+[server]
+addr = ":8080"
+port = 8080
+timeout = "5s"
+hosts = ["a", "b"]
+ports = [80, 443]
+schedule = "*/5 * * * *" # cfgx:type=cron
+
+[[endpoints]]
+path = "/v1"
+`)
+
+	output, err := New(WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+
+	// Values are baked in as in static mode
+	require.Contains(t, outputStr, "type ServerConfig struct {")
+	require.Contains(t, outputStr, "Timeout:  5 * time.Second,")
+
+	require.Contains(t, outputStr, "func LoadOverrides() error {\n\tvar errs []error\n")
+	require.Contains(t, outputStr, "if v := os.Getenv(\"CONFIG_NAME\"); v != \"\" {\n\t\tName = v\n\t}")
+	require.Contains(t, outputStr, "if x, err := strconv.ParseInt(v, 10, 64); err != nil {\n\t\t\terrs = append(errs, fmt.Errorf(\"invalid value for CONFIG_SERVER_PORT (server.port): %w\", err))\n\t\t} else {\n\t\t\tServer.Port = x\n\t\t}")
+	require.Contains(t, outputStr, "if x, err := time.ParseDuration(v); err != nil {")
+	require.Contains(t, outputStr, "Server.Hosts = append(Server.Hosts, strings.TrimSpace(s))")
+	require.Contains(t, outputStr, "if x, err = strconv.ParseInt(strings.TrimSpace(s), 10, 64); err != nil {")
+	require.Contains(t, outputStr, "Server.Ports = items")
+	require.Contains(t, outputStr, "if x := configParsers[\"server.schedule\"](v); x != nil {\n\t\t\tServer.Schedule = x.(CronSpec)\n\t\t} else {\n\t\t\terrs = append(errs, fmt.Errorf(\"invalid value for CONFIG_SERVER_SCHEDULE (server.schedule): %q is not a valid cron\", v))\n\t\t}")
+	require.Contains(t, outputStr, "var configParsers = map[string]func(v string) any{\n\t\"server.schedule\": func(v string) any {\n\t\tif s := CronSpec(v); s.IsValid() {")
+	require.NotContains(t, outputStr, "CONFIG_ENDPOINTS")
+
+	output, err = New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "LoadOverrides")
+}
+
+func TestGenerator_HybridModeConflict(t *testing.T) {
+	_, err := New(WithMode("hybrid")).Generate([]byte("load_overrides = true\n"))
+	require.ErrorContains(t, err, "load_overrides: conflicts with the generated LoadOverrides function")
+
+	_, err = New(WithMode("hybrid"), WithNameOverrides(map[string]string{"overrides": "LoadOverrides"})).Generate([]byte("overrides = true\n"))
+	require.ErrorContains(t, err, "overrides: conflicts with the generated LoadOverrides function")
+}
@@ -0,0 +1,163 @@
+package generator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// identifiers records the identifiers of the generated package while the
+// keys are checked.
+type identifiers struct {
+	pkg      map[string]string // Package-level identifiers of keys, with the key
+	reserved map[string]string // Package-level identifiers generated for no key, described
+	top      map[string]string // Identifiers generated for no key that top-level keys must not take
+}
+
+// validateIdentifiers checks, before any code is generated, that no two keys
+// are generated as the same identifier, such as maxConns and max_conns both
+// becoming the field MaxConns: fields of one struct, package-level
+// variables, getters and struct types. Keys must not take the identifiers
+// the chosen mode and options generate for no key either, such as Validate
+// or the types of the snippets copied in.
+func (g *Generator) validateIdentifiers(data map[string]any) error {
+	reserved, methods := g.reservedIdentifiers()
+	// Top-level keys are package variables, except in compact style, and
+	// fields of Config in compact style and loader mode
+	top := maps.Clone(methods)
+	if !g.compact() {
+		maps.Copy(top, reserved)
+	}
+	ids := &identifiers{pkg: make(map[string]string), reserved: reserved, top: top}
+	return g.checkIdentifiers(ids, "", "", data, false)
+}
+
+// reservedIdentifiers returns the identifiers the run generates besides
+// those of keys, described for errors: package-level ones, and the methods
+// of Config in compact style, where functions reading the configuration are
+// generated as methods, and Validate in loader mode.
+func (g *Generator) reservedIdentifiers() (reserved, methods map[string]string) {
+	reserved = make(map[string]string)
+	methods = make(map[string]string)
+	reserveFunc := func(name string) {
+		if g.compact() || g.mode == "loader" && name == "Validate" {
+			methods[name] = "the generated " + name + " method"
+			return
+		}
+		reserved[name] = "the generated " + name + " function"
+	}
+
+	names := slices.Collect(maps.Keys(g.snippets))
+	if len(g.optional) > 0 {
+		names = append(names, "ptr")
+	}
+	if g.mode == "loader" {
+		names = append(names, "load")
+		if len(g.layers()) > 1 {
+			names = append(names, "layers")
+		}
+		for _, name := range loaderFuncs {
+			reserved[name] = "the generated " + name + " of loader mode"
+		}
+	}
+	for _, name := range names {
+		for decl, kind := range snippets.Decls(name) {
+			reserved[decl] = fmt.Sprintf("the generated %s %s", decl, kind)
+		}
+	}
+
+	if g.compact() {
+		reserved["Config"] = "the generated Config type"
+		reserved["Default"] = "the generated Default function"
+	}
+	if len(g.checks) > 0 {
+		reserveFunc("Validate")
+	}
+	if g.usage && g.mode == "getter" {
+		reserved["Usage"] = "the generated Usage function"
+		reserved["usageKeys"] = "the generated usageKeys variable"
+		reserved["usageCounts"] = "the generated usageCounts variable"
+	}
+	if g.summary {
+		reserveFunc("PrintSummary")
+		reserved["summarySource"] = "the generated summarySource function"
+		reserved["summaryOptional"] = "the generated summaryOptional function"
+	}
+	if g.mode == "hybrid" {
+		reserved["LoadOverrides"] = "the generated LoadOverrides function"
+		reserved["configParsers"] = "the generated configParsers variable"
+	}
+	// A top-level cfgx:slog-level key is reported by writeLogLevel instead
+	if g.src != nil && slices.ContainsFunc(g.annotated("slog-level"), func(key string) bool { return strings.Contains(key, ".") }) {
+		reserveFunc("LogLevel")
+	}
+	if g.src != nil && len(g.annotated("otel-resource")) > 0 {
+		reserveFunc("ResourceAttributes")
+	}
+	return reserved, methods
+}
+
+// checkIdentifiers checks the identifiers generated for the keys of table,
+// found at the dotted key path keyPath and generated as the struct type
+// typeName, or at the top level if empty. Package-level identifiers are
+// recorded in ids with the key they are generated for. Items are elements of
+// arrays of tables, or tables nested in them, whose struct types are named
+// as in static mode in every mode.
+func (g *Generator) checkIdentifiers(ids *identifiers, typeName, keyPath string, table map[string]any, item bool) error {
+	fields := make(map[string]string)
+	var reserved map[string]string
+	if typeName == "" {
+		fields, reserved = ids.pkg, ids.top
+	}
+	for _, k := range slices.Sorted(maps.Keys(table)) {
+		key := joinKey(keyPath, k)
+		name := g.fieldName(key)
+		if other, ok := fields[name]; ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("collides with %s: both are generated as %s", other, name)}
+		}
+		if what, ok := reserved[name]; ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("conflicts with %s", what)}
+		}
+		fields[name] = key
+
+		var (
+			nested     map[string]any
+			nestedType string
+			nestedItem = item
+		)
+		part := g.pascalCase(k)
+		if g.mode == "getter" && !item {
+			part = g.camelCase(k)
+		}
+		switch v := table[k].(type) {
+		case map[string]any:
+			nested, nestedType = v, stripSuffix(typeName)+part+"Config"
+		case *mapTable:
+			nested, nestedType, nestedItem = v.fields, v.elem, true
+		default:
+			items, ok := tables(v)
+			if !ok {
+				continue
+			}
+			nested, nestedType, nestedItem = mergeItems(items), stripSuffix(typeName)+part+"Item", true
+		}
+		if nested == nil {
+			continue
+		}
+
+		if other, ok := ids.pkg[nestedType]; ok && other != key {
+			return &KeyError{Key: key, Err: fmt.Errorf("collides with %s: both are generated as %s", other, nestedType)}
+		}
+		if what, ok := ids.reserved[nestedType]; ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("conflicts with %s", what)}
+		}
+		ids.pkg[nestedType] = key
+		if err := g.checkIdentifiers(ids, nestedType, key, nested, nestedItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
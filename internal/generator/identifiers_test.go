@@ -0,0 +1,198 @@
+package generator
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_IdentifierCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		opts    []Option
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "struct fields",
+			data:    "[server]\nmaxConns = 1\nmax_conns = 2\n",
+			wantErr: "server.max_conns: collides with server.maxConns: both are generated as MaxConns",
+		},
+		{
+			name:    "variables",
+			data:    "api-key = \"a\"\napi_key = \"b\"\n",
+			wantErr: "api_key: collides with api-key: both are generated as ApiKey",
+		},
+		{
+			name:    "getters",
+			mode:    "getter",
+			data:    "api-key = \"a\"\napi_key = \"b\"\n",
+			wantErr: "api_key: collides with api-key: both are generated as ApiKey",
+		},
+		{
+			name:    "keys of different items",
+			data:    "[[workers]]\nqueueName = \"a\"\n\n[[workers]]\nqueue_name = \"b\"\n",
+			wantErr: "workers.queue_name: collides with workers.queueName: both are generated as QueueName",
+		},
+		{
+			name:    "struct types",
+			data:    "[db]\n[db.primary]\nhost = \"a\"\n\n[db_primary]\nhost = \"b\"\n",
+			wantErr: "db_primary: collides with db.primary: both are generated as DbPrimaryConfig",
+		},
+		{
+			name:    "variable and struct type",
+			data:    "server_config = \"a\"\n\n[server]\nhost = \"b\"\n",
+			wantErr: "server_config: collides with server: both are generated as ServerConfig",
+		},
+		{
+			name:    "cfgx:name",
+			data:    "[server]\naddr = \"a\" # cfgx:name=Host\nhost = \"b\"\n",
+			wantErr: "server.host: collides with server.addr: both are generated as Host",
+		},
+		{
+			name:    "locale snippet type",
+			data:    "locale = \"en-US\" # cfgx:type=locale\n",
+			wantErr: "locale: conflicts with the generated Locale type",
+		},
+		{
+			name:    "locale snippet type and getter",
+			mode:    "getter",
+			data:    "[i18n]\nlocale = \"en-US\" # cfgx:type=locale\n\n[locale]\nname = \"a\"\n",
+			wantErr: "locale: conflicts with the generated Locale type",
+		},
+		{
+			name: "locale snippet type in a table",
+			data: "[i18n]\nlocale = \"en-US\" # cfgx:type=locale\n",
+		},
+		{
+			name:    "cron snippet type",
+			data:    "cron_spec = \"@daily\" # cfgx:type=cron\n",
+			wantErr: "cron_spec: conflicts with the generated CronSpec type",
+		},
+		{
+			name: "cron snippet type and table",
+			data: "[jobs]\nnightly = \"@daily\" # cfgx:type=cron\n\n[cron]\nbounds = 1\n",
+		},
+		{
+			name:    "time zone snippet type",
+			mode:    "getter",
+			data:    "time_zone = \"UTC\" # cfgx:type=tz\n",
+			wantErr: "time_zone: conflicts with the generated TimeZone type",
+		},
+		{
+			name: "time zone snippet type and table",
+			data: "[app]\nzone = \"UTC\" # cfgx:type=tz\n\n[time]\nzones = 1\n",
+		},
+		{
+			name:    "Validate",
+			data:    "validate = true\n\n[db]\nport = 1 # cfgx:format=port\n",
+			wantErr: "validate: conflicts with the generated Validate function",
+		},
+		{
+			name: "Validate without checks",
+			data: "validate = true\n\n[db]\nport = 1\n",
+		},
+		{
+			name:    "Validate method",
+			opts:    []Option{WithStyle("compact")},
+			data:    "validate = true\n\n[db]\nport = 1 # cfgx:format=port\n",
+			wantErr: "validate: conflicts with the generated Validate method",
+		},
+		{
+			name: "Default in compact style",
+			opts: []Option{WithStyle("compact")},
+			data: "default = true\n",
+		},
+		{
+			name:    "Usage",
+			mode:    "getter",
+			opts:    []Option{WithUsageTracking(true)},
+			data:    "usage = 1\n",
+			wantErr: "usage: conflicts with the generated Usage function",
+		},
+		{
+			name:    "PrintSummary",
+			opts:    []Option{WithSummary(true)},
+			data:    "print_summary = true\n",
+			wantErr: "print_summary: conflicts with the generated PrintSummary function",
+		},
+		{
+			name:    "LoadOverrides",
+			mode:    "hybrid",
+			data:    "load_overrides = true\n",
+			wantErr: "load_overrides: conflicts with the generated LoadOverrides function",
+		},
+		{
+			name:    "Resolve",
+			mode:    "loader",
+			data:    "resolve = true\n",
+			wantErr: "resolve: conflicts with the generated Resolve of loader mode",
+		},
+		{
+			name:    "ResourceAttributes",
+			data:    "resource_attributes = true\n\n# cfgx:otel-resource\n[service]\nname = \"api\"\n",
+			wantErr: "resource_attributes: conflicts with the generated ResourceAttributes function",
+		},
+		{
+			name: "renamed",
+			data: "[server]\naddr = \"a\" # cfgx:name=Address\nhost = \"b\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			if tt.mode != "" {
+				opts = append(opts, WithMode(tt.mode))
+			}
+			_, err := New(opts...).Generate([]byte(tt.data))
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestGenerator_TablesNamedAfterImports(t *testing.T) {
+	// Tables named after packages the generated code imports must not shadow
+	// them in any mode, such as a getter-mode struct type named time.
+	data := []byte(`
+[time]
+timeout = "5s"
+port = 8080
+ratio = 1.5
+debug = true
+
+[time.os]
+path = "x"
+
+[[strconv]]
+n = 1
+
+[fmt]
+at = 2024-01-01T00:00:00Z
+
+[errors]
+list = ["a"]
+`)
+	fset := token.NewFileSet()
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	for _, mode := range []string{"static", "getter", "hybrid", "loader"} {
+		t.Run(mode, func(t *testing.T) {
+			output, err := New(WithMode(mode)).Generate(data)
+			require.NoError(t, err)
+
+			file, err := parser.ParseFile(fset, "config.go", output, 0)
+			require.NoError(t, err)
+			_, err = conf.Check("config", fset, []*ast.File{file}, nil)
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// loaderFuncs are the identifiers generated in loader mode besides the
+// snippets, which keys must not take.
+var loaderFuncs = []string{"Config", "Default", "Load", "LoadFS", "LoadEmbedded", "Sources", "Provenance", "Resolve", "DefineFlags", "configParsers", "resolveConfig"}
+
+// writeLoader generates, in loader mode, the Config type holding the whole
+// configuration and Default returning the values baked in as in static mode.
+// The Load functions decoding a TOML file over them at runtime, and Resolve
+// applying the layers of the configured precedence, go to the "loader"
+// companion file, since they need a TOML parser. Struct fields carry
+// toml tags naming their key, which the loader decodes by, and annotated types
+// are parsed from loaded strings by configParsers.
+func (g *Generator) writeLoader(buf *bytes.Buffer, data map[string]any) error {
+	if g.mode != "loader" {
+		return nil
+	}
+	keys := g.sortedKeys("", data)
+
+	buf.WriteString("\n// Config holds the whole configuration, as returned by Default and Load.\n")
+	buf.WriteString("type Config struct {\n")
+	for _, key := range keys {
+		fmt.Fprintf(buf, "\t%s %s%s\n", g.fieldName(key), g.topLevelType(key, data[key]), g.structTag(key, data[key]))
+	}
+	buf.WriteString("}")
+	if err := g.writeRedacted(buf, "Config", data); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+
+	buf.WriteString("\n// Default returns the configuration baked in at generation time. Slices are\n")
+	buf.WriteString("// shared with the package variables and must not be modified.\n")
+	buf.WriteString("func Default() *Config {\n")
+	buf.WriteString("\treturn &Config{\n")
+	for _, key := range keys {
+		fmt.Fprintf(buf, "\t\t%s: %s,\n", g.fieldName(key), g.fieldName(key))
+	}
+	buf.WriteString("\t}\n")
+	buf.WriteString("}\n")
+
+	g.writeConfigParsers(buf)
+
+	p := g.addPart("loader", "")
+	p.imports["errors"] = true
+	for _, imp := range snippets.Imports("load") {
+		p.imports[imp] = true
+	}
+	layers := g.layers()
+	if len(layers) > 1 {
+		for _, imp := range snippets.Imports("layers") {
+			p.imports[imp] = true
+		}
+	}
+	withFlags := slices.Contains(layers, "flag")
+
+	envNote := ""
+	if slices.Contains(layers, "env") {
+		envNote = "\n// " + g.envPrefix + "_* environment variables override the file, as in Resolve."
+	}
+	p.body.WriteString(`// Load returns the defaults overridden by the TOML file at path. Keys the file
+// does not set keep their default, arrays are replaced as a whole and unknown
+// keys are an error. file: references are read relative to the file.` + envNote + `
+func Load(path string) (*Config, error) {
+	c, _, err := Resolve(Sources{File: path})
+	return c, err
+}
+
+// LoadFS is Load for the TOML file at path in fsys, such as an embed.FS.
+// file: references are read from fsys too.
+func LoadFS(fsys fs.FS, path string) (*Config, error) {
+	c, _, err := Resolve(Sources{File: path, FS: fsys})
+	return c, err
+}
+
+// LoadEmbedded returns the defaults overridden by the TOML file at path in
+// fsys, typically a default config embedded with go:embed, then by the TOML
+// file at override if it exists. Binaries can thus ship their default config
+// and read a local override of it with the same types.` + envNote + `
+func LoadEmbedded(fsys fs.FS, path, override string) (*Config, error) {
+	c := Default()
+	if err := loadFSConfig(c, fsys, path, configParsers, nil); err != nil {
+		return nil, err
+	}
+	src := Sources{File: override}
+	if _, err := os.Stat(override); errors.Is(err, fs.ErrNotExist) {
+		src.File = ""
+	}
+	if _, err := resolveConfig(c, src); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Sources holds the inputs of the layers Resolve applies.
+type Sources struct {
+	File string // TOML file of the file layer, skipped if empty
+	FS   fs.FS  // File system File is read from, the OS if nil
+`)
+	if withFlags {
+		p.imports["flag"] = true
+		p.body.WriteString("\tFlags *flag.FlagSet // Parsed flags, defined with DefineFlags, of the flag layer (optional)\n")
+	}
+	p.body.WriteString(`}
+
+// Provenance maps the dotted key path of every value a layer set to the
+// layer: "file", "env" or "flag". Values left at their default are absent.
+type Provenance map[string]string
+
+`)
+	fmt.Fprintf(&p.body, "// Resolve returns the defaults overridden by the %s, in increasing\n", layerList(layers))
+	p.body.WriteString(`// order of precedence, and the layer each value came from.
+func Resolve(src Sources) (*Config, Provenance, error) {
+	c := Default()
+	prov, err := resolveConfig(c, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, prov, nil
+}
+
+// resolveConfig applies the layers of Resolve over c.
+func resolveConfig(c *Config, src Sources) (Provenance, error) {
+	prov := make(Provenance)
+`)
+	for _, layer := range layers {
+		switch layer {
+		case "file":
+			p.body.WriteString(`	if src.File != "" {
+		var err error
+		if src.FS != nil {
+			err = loadFSConfig(c, src.FS, src.File, configParsers, prov)
+		} else {
+			err = loadOSConfig(c, src.File, configParsers, prov)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+`)
+		case "env":
+			fmt.Fprintf(&p.body, "\tif err := applyConfigLayer(c, envConfigLayer(%q), configParsers, prov); err != nil {\n", g.envPrefix)
+			p.body.WriteString("\t\treturn nil, err\n\t}\n")
+		case "flag":
+			p.body.WriteString(`	if src.Flags != nil {
+		if err := applyConfigLayer(c, flagConfigLayer(src.Flags), configParsers, prov); err != nil {
+			return nil, err
+		}
+	}
+`)
+		}
+	}
+	p.body.WriteString("\treturn prov, nil\n}\n\n")
+
+	if withFlags {
+		p.body.WriteString(`// DefineFlags defines on fs a string flag for every value the flag layer can
+// set, named by its dotted key path, such as -server.addr. Arrays are
+// comma-separated and file contents are read from the path given.
+func DefineFlags(fs *flag.FlagSet) {
+	defineConfigFlags(fs, Default())
+}
+
+`)
+	}
+	p.body.WriteString(snippets.Source("load"))
+	if len(layers) > 1 {
+		p.body.WriteString("\n" + snippets.Source("layers"))
+	}
+	return nil
+}
+
+// WithPrecedence sets the runtime layers the loader-mode Resolve applies over
+// the defaults, lowest precedence first: "file", "env" and "flag". The file
+// layer must be included. Defaults to just the file layer.
+func WithPrecedence(layers []string) Option {
+	return func(g *Generator) {
+		g.precedence = layers
+	}
+}
+
+// layers returns the runtime layers of loader mode, lowest precedence first.
+// Environment variables override the file by default, unless env overrides
+// are disabled.
+func (g *Generator) layers() []string {
+	if len(g.precedence) == 0 {
+		if g.envOverride {
+			return []string{"file", "env"}
+		}
+		return []string{"file"}
+	}
+	return g.precedence
+}
+
+// layerList names layers in a doc comment, e.g. "file, env and flag layers".
+func layerList(layers []string) string {
+	if len(layers) == 1 {
+		return layers[0] + " layer"
+	}
+	return strings.Join(layers[:len(layers)-1], ", ") + " and " + layers[len(layers)-1] + " layers"
+}
+
+// writeConfigParsers writes configParsers, which parses the strings of the
+// keys of annotated types as the getters do, for loader mode to decode loaded
+// values and hybrid mode environment variables.
+func (g *Generator) writeConfigParsers(buf *bytes.Buffer) {
+	buf.WriteString("\n// configParsers parses strings of the keys of annotated types, by key\n")
+	buf.WriteString("// path. A parser returns nil for an invalid value.\n")
+	buf.WriteString("var configParsers = map[string]func(v string) any{\n")
+	for _, key := range g.parsedKeys() {
+		fmt.Fprintf(buf, "\t%q: func(v string) any {\n", key)
+		for _, line := range strings.Split(strings.TrimSuffix(valueTypes[g.types[key]].parse, "\n"), "\n") {
+			buf.WriteString("\t\t" + line + "\n")
+		}
+		buf.WriteString("\t\treturn nil\n")
+		buf.WriteString("\t},\n")
+	}
+	buf.WriteString("}\n")
+}
+
+// parsedKeys returns the keys configParsers parses, sorted. Strings and
+// integers decode as inferred values do, and assets as file contents do.
+func (g *Generator) parsedKeys() []string {
+	var keys []string
+	for _, key := range slices.Sorted(maps.Keys(g.types)) {
+		if vt := valueTypes[g.types[key]]; !vt.generic && !vt.file {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// topLevelType returns the Go type of the package variable of a top-level key.
+func (g *Generator) topLevelType(key string, value any) string {
+	switch val := value.(type) {
+	case map[string]any:
+		return g.pascalCase(key) + "Config"
+	case []map[string]any:
+		return "[]" + g.pascalCase(key) + "Item"
+	case []any:
+		if len(val) > 0 {
+			if _, ok := val[0].(map[string]any); ok {
+				return "[]" + g.pascalCase(key) + "Item"
+			}
+		}
+		return g.toGoType(value)
+	}
+	return g.fieldType(key, value)
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_LoaderMode(t *testing.T) {
+	data := []byte(`
+name = "svc"
+budget = "10%" # cfgx:type=percent
+
+[server]
+addr = ":8080"
+timeout = "5s"
+
+[[servers]]
+host = "a"
+`)
+
+	files, err := New(WithMode("loader"), WithStructTags([]string{"json"})).GenerateFiles(data)
+	require.NoError(t, err)
+	outputStr := string(files[""])
+	require.Contains(t, outputStr, "\tAddr    string        `toml:\"addr\" json:\"addr\"`\n")
+	require.Contains(t, outputStr, "\tServer         = ServerConfig{\n")
+	require.Contains(t, outputStr, "type Config struct {\n\tBudget  float64       `toml:\"budget\" json:\"budget\"`\n\tName    string        `toml:\"name\" json:\"name\"`\n\tServer  ServerConfig  `toml:\"server\" json:\"server\"`\n\tServers []ServersItem `toml:\"servers\" json:\"servers\"`\n}")
+	require.Contains(t, outputStr, "func Default() *Config {\n\treturn &Config{\n\t\tBudget:  Budget,\n")
+	require.Contains(t, outputStr, "\t\"budget\": func(v string) any {\n\t\tif r, ok := parsePercent(v); ok {\n\t\t\treturn r\n\t\t}\n\t\treturn nil\n\t},\n")
+	require.NotContains(t, outputStr, "BurntSushi")
+
+	loader := string(files["loader"])
+	require.Contains(t, loader, "\t\"github.com/BurntSushi/toml\"\n")
+	require.Contains(t, loader, "func Load(path string) (*Config, error) {")
+	require.Contains(t, loader, "func LoadFS(fsys fs.FS, path string) (*Config, error) {")
+	require.Contains(t, loader, "func LoadEmbedded(fsys fs.FS, path, override string) (*Config, error) {")
+	require.Contains(t, loader, "func decodeConfig(")
+
+	files, err = New().GenerateFiles(data)
+	require.NoError(t, err)
+	require.NotContains(t, files, "loader")
+	require.NotContains(t, string(files[""]), "toml:")
+}
+
+func TestGenerator_LoaderParsersSkipGenericTypes(t *testing.T) {
+	// Sized integers and strings decode by reflection as inferred values do,
+	// so configParsers has no entry using strconv, which the main file does
+	// not import
+	data := []byte("[db]\nport = 5432 # cfgx:type=uint16\nname = \"x\" # cfgx:type=string\n")
+	files, err := New(WithMode("loader")).GenerateFiles(data)
+	require.NoError(t, err)
+	main := string(files[""])
+	require.Contains(t, main, "var configParsers = map[string]func(v string) any{}\n")
+	require.NotContains(t, main, "strconv")
+}
+
+func TestGenerator_LoaderModeValidate(t *testing.T) {
+	output, err := New(WithMode("loader")).Generate([]byte("[db]\nport = 5432 # cfgx:format=port\n"))
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func (c *Config) Validate() error {")
+	require.Contains(t, outputStr, "if v := c.Db.Port; !(validPort(v)) {")
+
+	_, err = New(WithMode("loader")).Generate([]byte("validate = true\n\n[db]\nport = 5432 # cfgx:format=port\n"))
+	require.EqualError(t, err, "validate: conflicts with the generated Validate method")
+}
+
+func TestGenerator_LoaderModeConflict(t *testing.T) {
+	_, err := New(WithMode("loader")).Generate([]byte("load = true\n"))
+	require.ErrorContains(t, err, "load: conflicts with the generated Load of loader mode")
+}
+
+func TestGenerator_LoaderPrecedence(t *testing.T) {
+	data := []byte("[server]\naddr = \":8080\"\n")
+
+	files, err := New(WithMode("loader")).GenerateFiles(data)
+	require.NoError(t, err)
+	loader := string(files["loader"])
+	require.Contains(t, loader, "// Resolve returns the defaults overridden by the file and env layers, in increasing\n")
+	require.Contains(t, loader, "// CONFIG_* environment variables override the file, as in Resolve.\nfunc Load(")
+	require.Contains(t, loader, "\tif err := applyConfigLayer(c, envConfigLayer(\"CONFIG\"), configParsers, prov); err != nil {\n")
+
+	files, err = New(WithMode("loader"), WithEnvOverride(false)).GenerateFiles(data)
+	require.NoError(t, err)
+	loader = string(files["loader"])
+	require.Contains(t, loader, "// Resolve returns the defaults overridden by the file layer, in increasing\n")
+	require.Contains(t, loader, "func Resolve(src Sources) (*Config, Provenance, error) {")
+	require.NotContains(t, loader, "envConfigLayer")
+	require.NotContains(t, loader, "DefineFlags")
+	require.NotContains(t, loader, "\t\"flag\"\n")
+
+	files, err = New(WithMode("loader"), WithPrecedence([]string{"file", "flag", "env"})).GenerateFiles(data)
+	require.NoError(t, err)
+	loader = string(files["loader"])
+	require.Contains(t, loader, "// Resolve returns the defaults overridden by the file, flag and env layers, in increasing\n")
+	require.Contains(t, loader, "\tFlags *flag.FlagSet")
+	require.Contains(t, loader, "func DefineFlags(fs *flag.FlagSet) {")
+	require.Contains(t, loader, "func applyConfigLayer(")
+	flagAt := strings.Index(loader, "applyConfigLayer(c, flagConfigLayer(src.Flags)")
+	envAt := strings.Index(loader, "applyConfigLayer(c, envConfigLayer(\"CONFIG\")")
+	require.True(t, flagAt > 0 && envAt > flagAt, "layers should apply lowest precedence first")
+
+	_, err = New(WithMode("loader")).Generate([]byte("resolve = true\n"))
+	require.ErrorContains(t, err, "resolve: conflicts with the generated Resolve of loader mode")
+}
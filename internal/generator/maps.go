@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// mapTable is the value of a table annotated cfgx:map, whose keys are data,
+// such as customer names, rather than settings. It is generated as a Go map
+// from the keys to the values, which must all have the same type. Tables are
+// generated as a map of an entry struct holding the keys of all of them.
+// Arrays of tables annotated cfgx:key are mapTables too, of their items by
+// the value of the key field.
+type mapTable struct {
+	entries map[string]any
+	elem    string         // Go type of the values
+	fields  map[string]any // Keys of all the entry tables, nil for other values
+	key     string         // Field the items are keyed by, for arrays of tables
+	order   []string       // Keys in source order
+	ordered bool           // Whether generated as an OrderedMap
+}
+
+// resolveMaps replaces the tables annotated cfgx:map in data with mapTable
+// values, so that they are generated as Go maps rather than structs.
+func (g *Generator) resolveMaps(data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+	for _, key := range g.annotated("map") {
+		parent, name := data, key
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			var ok bool
+			if parent, ok = lookupTable(data, key[:dot]); !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:map is only supported on tables nested in tables")}
+			}
+			name = key[dot+1:]
+		}
+		table, ok := parent[name].(map[string]any)
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:map requires a table, got %s", g.toGoType(parent[name]))}
+		}
+		m, err := g.newMapTable(key, table)
+		if err != nil {
+			return &KeyError{Key: key, Err: err}
+		}
+		parent[name] = m
+	}
+	if err := g.resolveKeyed(data); err != nil {
+		return err
+	}
+	return g.resolveOrdered(data)
+}
+
+// resolveKeyed replaces the arrays of tables annotated cfgx:key=<field> in
+// data with mapTable values, so that they are generated as Go maps of their
+// items by the value of that field, which every item must set to a
+// different string:
+//
+//	[[users]] # cfgx:key=email
+//	email = "ada@example.com"
+//	name = "Ada"
+//
+// generates Users as a map[string]UsersItem.
+func (g *Generator) resolveKeyed(data map[string]any) error {
+	for _, key := range g.annotated("key") {
+		field, _ := g.annotation(key, "key")
+		parent, name := data, key
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			var ok bool
+			if parent, ok = lookupTable(data, key[:dot]); !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:key is only supported on arrays of tables nested in tables")}
+			}
+			name = key[dot+1:]
+		}
+		items, ok := tables(parent[name])
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:key requires an array of tables, got %s", g.toGoType(parent[name]))}
+		}
+		if field == "" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:key requires the name of a field, as in cfgx:key=id")}
+		}
+
+		m := &mapTable{entries: make(map[string]any, len(items)), elem: g.elemTypeName(key, "Item"), fields: mergeItems(items), key: field}
+		index := make(map[string]int, len(items))
+		for i, item := range items {
+			k, ok := item[field].(string)
+			if !ok {
+				if _, set := item[field]; !set {
+					return &KeyError{Key: key, Err: fmt.Errorf("item %d has no %s", i, field)}
+				}
+				return &KeyError{Key: key, Err: fmt.Errorf("%s of item %d must be a string, got %s", field, i, g.toGoType(item[field]))}
+			}
+			if j, dup := index[k]; dup {
+				return &KeyError{Key: key, Err: fmt.Errorf("duplicate %s %q in items %d and %d", field, k, j, i)}
+			}
+			index[k] = i
+			m.entries[k] = item
+			m.order = append(m.order, k)
+		}
+		parent[name] = m
+	}
+	return nil
+}
+
+// resolveOrdered marks the map tables and keyed arrays of tables annotated
+// cfgx:ordered, which are generated as an OrderedMap keeping their keys in
+// the order they are written in rather than as a Go map:
+//
+//	[stages] # cfgx:map ordered
+//	build = "make"
+//	test = "make test"
+//
+// generates Stages as an OrderedMap[string], whose Keys are build and test,
+// in that order. Loaded files are decoded into Go maps, losing the order of
+// their keys, so loader mode does not support it.
+func (g *Generator) resolveOrdered(data map[string]any) error {
+	for _, key := range g.annotated("ordered") {
+		parent, name := data, key
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			parent, _ = lookupTable(data, key[:dot])
+			name = key[dot+1:]
+		}
+		m, ok := parent[name].(*mapTable)
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:ordered requires a cfgx:map table or a cfgx:key array of tables")}
+		}
+		if g.mode == "loader" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:ordered is not supported in loader mode")}
+		}
+		if m.order == nil {
+			m.order = g.sourceOrder(key, m.entries)
+		}
+		m.ordered = true
+	}
+	return nil
+}
+
+// sourceOrder returns the keys of entries, the table at key, in the order
+// they are first written in the source. Keys the source does not show, such
+// as those of inline tables, follow in sorted order.
+func (g *Generator) sourceOrder(key string, entries map[string]any) []string {
+	order := make([]string, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, e := range g.src.Entries {
+		rest, ok := strings.CutPrefix(e.Key, key+".")
+		if !ok {
+			continue
+		}
+		k, _, _ := strings.Cut(rest, ".")
+		if _, ok := entries[k]; ok && !seen[k] {
+			seen[k] = true
+			order = append(order, k)
+		}
+	}
+	for _, k := range slices.Sorted(maps.Keys(entries)) {
+		if !seen[k] {
+			order = append(order, k)
+		}
+	}
+	return order
+}
+
+// newMapTable checks that the values of table, annotated cfgx:map, all have
+// the same type and returns its mapTable.
+func (g *Generator) newMapTable(key string, table map[string]any) (*mapTable, error) {
+	if len(table) == 0 {
+		return nil, fmt.Errorf("cfgx:map table has no values to infer their type from")
+	}
+	m := &mapTable{entries: table}
+	for _, k := range slices.Sorted(maps.Keys(table)) {
+		elem := g.toGoType(table[k])
+		if entry, ok := table[k].(map[string]any); ok {
+			if m.fields == nil {
+				m.fields = make(map[string]any)
+			}
+			for field, v := range entry {
+				if _, ok := m.fields[field]; !ok {
+					m.fields[field] = v
+				}
+			}
+			elem = g.elemTypeName(key, "Entry")
+		}
+		switch {
+		case m.elem == "":
+			m.elem = elem
+		case elem != m.elem:
+			return nil, fmt.Errorf("cfgx:map values must all have the same type: %s is %s, not %s", k, elem, m.elem)
+		}
+	}
+	return m, nil
+}
+
+// elemTypeName returns the name of the struct type of the values of the map
+// table at key, named after it as nested struct types are with suffix, e.g.
+// "RateLimitsEntry" for the entries of "rate_limits".
+func (g *Generator) elemTypeName(key, suffix string) string {
+	var name strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		if g.mode == "getter" {
+			name.WriteString(g.camelCase(part))
+		} else {
+			name.WriteString(g.pascalCase(part))
+		}
+	}
+	name.WriteString(suffix)
+	return name.String()
+}
+
+// writeMapLiteral writes the map literal of m, with its keys sorted, or the
+// OrderedMap of m with its keys in order.
+func (g *Generator) writeMapLiteral(buf *bytes.Buffer, m *mapTable, indent int) error {
+	if m.ordered {
+		return g.writeOrderedMapLiteral(buf, m, indent)
+	}
+	fmt.Fprintf(buf, "map[string]%s{\n", m.elem)
+	indentStr := strings.Repeat("\t", indent+1)
+	for _, k := range slices.Sorted(maps.Keys(m.entries)) {
+		fmt.Fprintf(buf, "%s%q: ", indentStr, k)
+		if entry, ok := m.entries[k].(map[string]any); ok {
+			// Omit type name for gofmt -s compliance
+			if err := g.generateStructInit(buf, m.elem, entry, indent+1); err != nil {
+				return err
+			}
+		} else {
+			g.writeValueWithIndent(buf, m.entries[k], indent+1)
+		}
+		buf.WriteString(",\n")
+	}
+	buf.WriteString(strings.Repeat("\t", indent))
+	buf.WriteString("}")
+	return nil
+}
+
+// writeOrderedMapLiteral writes the expression building the OrderedMap of m
+// from its pairs, in order.
+func (g *Generator) writeOrderedMapLiteral(buf *bytes.Buffer, m *mapTable, indent int) error {
+	g.useSnippet("ordered")
+	fmt.Fprintf(buf, "newOrderedMap([]OrderedPair[%s]{\n", m.elem)
+	indentStr := strings.Repeat("\t", indent+1)
+	for _, k := range m.order {
+		fmt.Fprintf(buf, "%s{Key: %q, Value: ", indentStr, k)
+		if entry, ok := m.entries[k].(map[string]any); ok {
+			buf.WriteString(m.elem)
+			if err := g.generateStructInit(buf, m.elem, entry, indent+1); err != nil {
+				return err
+			}
+		} else {
+			g.writeValueWithIndent(buf, m.entries[k], indent+1)
+		}
+		buf.WriteString("},\n")
+	}
+	buf.WriteString(strings.Repeat("\t", indent))
+	buf.WriteString("})")
+	return nil
+}
@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_MapTables(t *testing.T) {
+	data := []byte(`
+[rate_limits] # cfgx:map
+acme = 100
+globex = 250
+
+[tenants] # cfgx:map
+[tenants.acme]
+rps = 10
+timeout = "5s"
+[tenants.globex]
+rps = 20
+api_key = "k"
+
+[server]
+addr = ":80"
+[server.weights] # cfgx:map
+a = 0.5
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "RateLimits map[string]int64 = map[string]int64{\n\t\t\"acme\":   100,\n\t\t\"globex\": 250,\n\t}")
+	require.Contains(t, outputStr, "type TenantsEntry struct {\n\tApiKey  string\n\tRps     int64\n\tTimeout time.Duration\n}")
+	require.Contains(t, outputStr, "\"acme\": {\n\t\t\tRps:     10,\n\t\t\tTimeout: 5 * time.Second,\n\t\t},")
+	require.Contains(t, outputStr, "Weights map[string]float64\n")
+	require.NotContains(t, outputStr, "RateLimitsConfig")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func RateLimits() map[string]int64 {\n\treturn map[string]int64{")
+	require.Contains(t, outputStr, "func Tenants() map[string]tenantsEntry {")
+	require.NotContains(t, outputStr, "RATE_LIMITS")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "Tenants    map[string]TenantsEntry `toml:\"tenants\"`")
+	require.Contains(t, outputStr, "\t\t\tentries[k] = entry.Redacted()\n")
+
+	_, err = New().Generate([]byte("[limits] # cfgx:map\na = 1\nb = \"x\"\n"))
+	require.EqualError(t, err, "limits: cfgx:map values must all have the same type: b is string, not int64")
+
+	_, err = New().Generate([]byte("limits = 1 # cfgx:map\n"))
+	require.EqualError(t, err, "limits: cfgx:map requires a table, got int64")
+
+	_, err = New().Generate([]byte("[limits] # cfgx:map\n[other]\na = 1\n"))
+	require.EqualError(t, err, "limits: cfgx:map table has no values to infer their type from")
+}
+
+func TestGenerator_KeyedTables(t *testing.T) {
+	data := []byte(`
+[[users]] # cfgx:key=email
+email = "a@x"
+name = "A"
+
+[[users]]
+email = "b@x"
+admin = true
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type UsersItem struct {\n\tAdmin bool\n\tEmail string\n\tName  string\n}")
+	require.Contains(t, outputStr, "Users map[string]UsersItem = map[string]UsersItem{\n\t\t\"a@x\": {\n\t\t\tEmail: \"a@x\",\n\t\t\tName:  \"A\",\n\t\t},")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Users map[string]UsersItem `toml:\"users\" cfgx:\"key=email\"`")
+
+	_, err = New().Generate([]byte("[[users]] # cfgx:key=email\nemail = \"a\"\n[[users]]\nemail = \"a\"\n"))
+	require.EqualError(t, err, "users: duplicate email \"a\" in items 0 and 1")
+
+	_, err = New().Generate([]byte("[[users]] # cfgx:key=email\nemail = \"a\"\n[[users]]\nname = \"n\"\n"))
+	require.EqualError(t, err, "users: item 1 has no email")
+
+	_, err = New().Generate([]byte("[[users]] # cfgx:key=email\nemail = 1\n"))
+	require.EqualError(t, err, "users: email of item 0 must be a string, got int64")
+}
+
+func TestGenerator_OrderedMaps(t *testing.T) {
+	data := []byte(`
+[stages] # cfgx:map ordered
+test = "make test"
+build = "make"
+
+[[users]] # cfgx:key=email ordered
+email = "z@x"
+
+[[users]]
+email = "a@x"
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type OrderedMap[V any] struct {")
+	require.Contains(t, outputStr, "Stages OrderedMap[string] = newOrderedMap([]OrderedPair[string]{\n\t\t{Key: \"test\", Value: \"make test\"},\n\t\t{Key: \"build\", Value: \"make\"},\n\t})")
+	require.Contains(t, outputStr, "{Key: \"z@x\", Value: UsersItem{\n\t\t\tEmail: \"z@x\",\n\t\t}},\n\t\t{Key: \"a@x\"")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func Stages() OrderedMap[string] {\n\treturn newOrderedMap(")
+
+	_, err = New(WithMode("loader")).Generate(data)
+	require.EqualError(t, err, "stages: cfgx:ordered is not supported in loader mode")
+
+	_, err = New().Generate([]byte("[stages] # cfgx:ordered\nbuild = \"make\"\n"))
+	require.EqualError(t, err, "stages: cfgx:ordered requires a cfgx:map table or a cfgx:key array of tables")
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gomantics/sx"
+)
+
+// WithInitialisms sets the initialisms, such as "ID" and "URL", that words
+// of keys are written as in generated identifiers, following the Go
+// convention: "api_url" becomes APIURL rather than ApiUrl, and apiURL when
+// unexported. Words are matched regardless of case.
+func WithInitialisms(initialisms []string) Option {
+	return func(g *Generator) {
+		g.initialisms = make(map[string]string, len(initialisms))
+		for _, initialism := range initialisms {
+			g.initialisms[strings.ToLower(initialism)] = initialism
+		}
+	}
+}
+
+// WithNameOverrides sets the Go names of the fields, variables and getters
+// generated for keys, by dotted key path, for keys whose derived name is
+// awkward or collides with another. They take precedence over the names given
+// with cfgx:name annotations.
+func WithNameOverrides(names map[string]string) Option {
+	return func(g *Generator) {
+		g.nameOverrides = names
+	}
+}
+
+// fieldName returns the name of the field, variable or getter generated for
+// key, a dotted key path: the name given with WithNameOverrides or a
+// cfgx:name annotation, if any, or else the exported identifier of its last
+// part. Elements of arrays of tables share the key path of the array.
+func (g *Generator) fieldName(key string) string {
+	if name, ok := g.nameOverrides[key]; ok {
+		return name
+	}
+	if g.src != nil {
+		if name, ok := g.annotation(key, "name"); ok && name != "" {
+			return name
+		}
+	}
+	return g.pascalCase(key[strings.LastIndex(key, ".")+1:])
+}
+
+// validateNames checks that the names given to keys with WithNameOverrides
+// and cfgx:name annotations are exported Go identifiers of keys in data.
+func (g *Generator) validateNames(data map[string]any) error {
+	keys := slices.Sorted(maps.Keys(g.nameOverrides))
+	if g.src != nil {
+		keys = append(keys, g.annotated("name")...)
+	}
+	for _, key := range keys {
+		name := g.fieldName(key)
+		if !token.IsIdentifier(name) || !token.IsExported(name) {
+			return &KeyError{Key: key, Err: fmt.Errorf("name %q is not an exported Go identifier", name)}
+		}
+		if len(lookupValues(data, key)) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("cannot be named %s: no such key", name)}
+		}
+	}
+	return nil
+}
+
+// pascalCase returns the exported identifier of key, such as "MaxConns" for
+// "max_conns".
+func (g *Generator) pascalCase(key string) string {
+	if len(g.initialisms) == 0 {
+		return sx.PascalCase(key)
+	}
+	return sx.PascalCase(g.words(key))
+}
+
+// camelCase returns the unexported identifier of key, such as "maxConns" for
+// "max_conns". An initialism starting it is all lower case, as in "apiURL".
+func (g *Generator) camelCase(key string) string {
+	if len(g.initialisms) == 0 {
+		return sx.CamelCase(key)
+	}
+	words := g.words(key)
+	if len(words) > 0 {
+		if _, ok := g.initialisms[strings.ToLower(words[0])]; ok {
+			words[0] = strings.ToLower(words[0])
+		}
+	}
+	return sx.CamelCase(words)
+}
+
+// words splits key into the words of its identifier, with initialisms
+// written as configured.
+func (g *Generator) words(key string) []string {
+	words := sx.SplitByCase(key)
+	for i, word := range words {
+		if initialism, ok := g.initialisms[strings.ToLower(word)]; ok {
+			words[i] = initialism
+		}
+	}
+	return words
+}
@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Initialisms(t *testing.T) {
+	g := New(WithInitialisms([]string{"API", "URL", "ID", "OAuth"}))
+	for key, want := range map[string][2]string{
+		"api_url":      {"APIURL", "apiURL"},
+		"user_id":      {"UserID", "userID"},
+		"id":           {"ID", "id"},
+		"oauth_client": {"OAuthClient", "oauthClient"},
+		"max_conns":    {"MaxConns", "maxConns"},
+		"rapid":        {"Rapid", "rapid"},
+	} {
+		require.Equal(t, want[0], g.pascalCase(key), key)
+		require.Equal(t, want[1], g.camelCase(key), key)
+	}
+
+	data := []byte(`
+[api]
+base_url = "https://example.com"
+client_id = "abc"
+`)
+	output, err := New(WithInitialisms([]string{"API", "URL", "ID"})).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "type APIConfig struct {\n\tBaseURL  string\n\tClientID string\n}")
+	require.Contains(t, string(output), "API = APIConfig{")
+
+	output, err = New(WithInitialisms([]string{"API", "URL", "ID"}), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func (apiConfig) BaseURL() string {")
+
+	output, err = New().Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "type ApiConfig struct {\n\tBaseUrl  string\n\tClientId string\n}")
+}
+
+func TestGenerator_NameOverrides(t *testing.T) {
+	data := []byte(`
+app_name = "svc" # cfgx:name=AppTitle
+
+[cluster]
+k8s_namespace = "prod" # cfgx:name=K8sNamespace
+dsn = "postgres://"
+
+[[workers]]
+queue_name = "q" # cfgx:name=Queue
+`)
+
+	output, err := New(WithNameOverrides(map[string]string{"cluster.dsn": "DataSourceName"})).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type ClusterConfig struct {\n\tDataSourceName string\n\tK8sNamespace   string\n}")
+	require.Contains(t, outputStr, "type WorkersItem struct {\n\tQueue string\n}")
+	require.Contains(t, outputStr, "AppTitle string = \"svc\"")
+	require.Contains(t, outputStr, "\t\tK8sNamespace:   \"prod\",\n")
+
+	output, err = New(WithMode("getter"), WithNameOverrides(map[string]string{"app_name": "Title"})).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func (clusterConfig) K8sNamespace() string {")
+	require.Contains(t, string(output), "func Title() string {")
+
+	_, err = New().Generate([]byte("port = 1 # cfgx:name=port\n"))
+	require.EqualError(t, err, `port: name "port" is not an exported Go identifier`)
+
+	_, err = New(WithNameOverrides(map[string]string{"server.addr": "Address"})).Generate([]byte("port = 1\n"))
+	require.EqualError(t, err, "server.addr: cannot be named Address: no such key")
+}
@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validateOptional records the keys annotated cfgx:optional, whose struct
+// fields are pointers that are nil where the key is not set, so that unset
+// values can be told from zero values. This matters in arrays of tables,
+// whose items may each set a different subset of the keys:
+//
+//	[[upstreams]]
+//	host = "a"
+//	weight = 2 # cfgx:optional
+//
+//	[[upstreams]]
+//	host = "b"
+//
+// and in loader mode, where a loaded file may leave them unset. Only values
+// of inferred types, cfgx:type=string and integer types can be optional.
+// Optional values are not overridden by environment variables, and getters
+// return values, so in getter mode only keys in arrays of tables can be
+// optional.
+func (g *Generator) validateOptional(data map[string]any) error {
+	g.optional = make(map[string]bool)
+	if g.src == nil {
+		return nil
+	}
+
+	for _, key := range g.annotated("optional") {
+		values := lookupValues(data, key)
+		if len(values) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:optional must annotate a value")}
+		}
+		for _, v := range values {
+			goType, ok := g.genericType(key, v)
+			if !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:optional is not supported with cfgx:type=%s", g.types[key])}
+			}
+			if goType == "struct" || strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:optional must annotate a single value")}
+			}
+		}
+		if _, ok := g.annotation(key, "runtime-only"); ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:optional cannot be combined with cfgx:runtime-only")}
+		}
+		if g.mode == "getter" && !inTables(data, key) {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:optional is only supported in arrays of tables in getter mode")}
+		}
+		g.optional[key] = true
+	}
+	return nil
+}
+
+// inTables reports whether key is a key of the items of an array of tables,
+// or of tables nested in them.
+func inTables(data map[string]any, key string) bool {
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 {
+		return false
+	}
+	_, ok := lookupTable(data, key[:dot])
+	return !ok
+}
+
+// fieldType returns the Go type of the struct field or package variable
+// holding the value of key, a pointer if the key is optional.
+func (g *Generator) fieldType(key string, v any) string {
+	goType := g.keyType(key, v)
+	if g.optional[key] {
+		return "*" + goType
+	}
+	return goType
+}
@@ -0,0 +1,93 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Optional(t *testing.T) {
+	data := []byte(`
+[server]
+host = "localhost"
+port = 8080 # cfgx:optional min=1
+
+[[upstreams]]
+host = "a"
+
+[[upstreams]]
+host = "b"
+weight = 2 # cfgx:optional
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type ServerConfig struct {\n\tHost string\n\tPort *int64\n}")
+	require.Contains(t, outputStr, "type UpstreamsItem struct {\n\tHost   string\n\tWeight *int64\n}")
+	require.Contains(t, outputStr, "Port: ptr[int64](8080),")
+	require.Contains(t, outputStr, "{\n\t\t\tHost: \"a\",\n\t\t},")
+	require.Contains(t, outputStr, "Weight: ptr[int64](2),")
+	require.Contains(t, outputStr, "func ptr[T any](v T) *T {")
+	require.Contains(t, outputStr, "if p := Server.Port; p != nil {\n\t\tif v := *p; !(v >= 1) {")
+
+	output, err = New(WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "CONFIG_SERVER_HOST")
+	require.NotContains(t, string(output), "CONFIG_SERVER_PORT")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port *int64 `toml:\"port\"`")
+
+	output, err = New(WithMode("getter")).Generate([]byte("[[upstreams]]\nhost = \"a\"\nweight = 2 # cfgx:optional\n"))
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Weight *int64")
+	require.NotContains(t, string(output), "CONFIG_UPSTREAMS_0_WEIGHT")
+}
+
+func TestGenerator_OptionalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		data    string
+		wantErr string
+	}{
+		{
+			name:    "array",
+			data:    "hosts = [\"a\"] # cfgx:optional\n",
+			wantErr: "hosts: cfgx:optional must annotate a single value",
+		},
+		{
+			name:    "table",
+			data:    "[server] # cfgx:optional\nhost = \"a\"\n",
+			wantErr: "server: cfgx:optional must annotate a single value",
+		},
+		{
+			name:    "annotated type",
+			data:    "ratio = \"50%\" # cfgx:type=percent optional\n",
+			wantErr: "ratio: cfgx:optional is not supported with cfgx:type=percent",
+		},
+		{
+			name:    "runtime-only",
+			data:    "token = \"t\" # cfgx:optional runtime-only\n",
+			wantErr: "token: cfgx:optional cannot be combined with cfgx:runtime-only",
+		},
+		{
+			name:    "getter",
+			mode:    "getter",
+			data:    "[server]\nport = 8080 # cfgx:optional\n",
+			wantErr: "server.port: cfgx:optional is only supported in arrays of tables in getter mode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.mode != "" {
+				opts = append(opts, WithMode(tt.mode))
+			}
+			_, err := New(opts...).Generate([]byte(tt.data))
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package generator
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// WithPreserveOrder generates struct fields and variables in the order their
+// keys are defined in the TOML source, keeping the grouping authors give
+// them, rather than alphabetically.
+func WithPreserveOrder(enable bool) Option {
+	return func(g *Generator) {
+		g.preserveOrder = enable
+	}
+}
+
+// sortedKeys returns the keys of table, found at the dotted key path
+// keyPath, in the order they are generated in: alphabetically or, if
+// preserving order, in source order. Keys the source does not define, such
+// as those of overlays, follow in alphabetical order.
+func (g *Generator) sortedKeys(keyPath string, table map[string]any) []string {
+	keys := slices.Sorted(maps.Keys(table))
+	if !g.preserveOrder || g.src == nil {
+		return keys
+	}
+	if g.order == nil {
+		g.order = sourceOrder(g.src.Entries)
+	}
+	index := func(key string) int {
+		if i, ok := g.order[joinKey(keyPath, key)]; ok {
+			return i
+		}
+		return math.MaxInt
+	}
+	slices.SortStableFunc(keys, func(a, b string) int {
+		return cmp.Compare(index(a), index(b))
+	})
+	return keys
+}
+
+// sourceOrder returns the index of the first of entries defining each key
+// path or a key below it, since dotted keys and nested table headers also
+// define the tables they go through.
+func sourceOrder(entries []tomlsrc.Entry) map[string]int {
+	order := make(map[string]int)
+	for i, e := range entries {
+		for j := range len(e.Key) {
+			if e.Key[j] != '.' {
+				continue
+			}
+			if _, ok := order[e.Key[:j]]; !ok {
+				order[e.Key[:j]] = i
+			}
+		}
+		if _, ok := order[e.Key]; !ok {
+			order[e.Key] = i
+		}
+	}
+	return order
+}
@@ -0,0 +1,43 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_PreserveOrder(t *testing.T) {
+	data := []byte(`
+name = "api"
+debug = true
+
+[server]
+port = 8080
+addr = ":80"
+tls.cert = "c"
+timeout = "5s"
+
+[[workers]]
+queue = "mail"
+count = 2
+
+[database]
+url = "postgres://"
+`)
+
+	output, err := New(WithPreserveOrder(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type ServerConfig struct {\n\tPort    int64\n\tAddr    string\n\tTls     ServerTlsConfig\n\tTimeout time.Duration\n}")
+	require.Contains(t, outputStr, "type WorkersItem struct {\n\tQueue string\n\tCount int64\n}")
+	require.Contains(t, outputStr, "\tName   string = \"api\"\n\tDebug  bool   = true\n\tServer        = ServerConfig{\n\t\tPort: 8080,\n\t\tAddr: \":80\",")
+	require.Regexp(t, `(?s)Server +=.*Workers =.*Database =`, outputStr)
+
+	output, err = New(WithPreserveOrder(true), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Regexp(t, `(?s)func \(serverConfig\) Port\(\).*func \(serverConfig\) Addr\(\)`, string(output))
+
+	output, err = New().Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "type ServerConfig struct {\n\tAddr    string\n\tPort    int64\n")
+}
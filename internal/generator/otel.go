@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// writeResourceAttributes generates a ResourceAttributes function from the
+// tables annotated with cfgx:otel-resource. Attribute names are the key paths
+// relative to the annotated table, so dotted TOML keys map directly onto
+// OpenTelemetry semantic conventions:
+//
+//	# cfgx:otel-resource
+//	[telemetry]
+//	service.name = "api"
+//	deployment.environment = "prod"
+//
+// The function returns plain values rather than attribute.KeyValue so that
+// generated code keeps no dependency on the OpenTelemetry SDK. Values are read
+// through the generated variables or getters, so env overrides apply.
+func (g *Generator) writeResourceAttributes(buf *bytes.Buffer, data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+	tables := g.annotated("otel-resource")
+	if len(tables) == 0 {
+		return nil
+	}
+
+	attrs := make(map[string]string)
+	for _, key := range tables {
+		table, ok := lookupTable(data, key)
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:otel-resource must annotate a table")}
+		}
+
+		expr := g.accessor(strings.Split(key, "."))
+		if err := g.collectAttributes(attrs, key, "", expr, table); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(buf, "\n// ResourceAttributes returns the OpenTelemetry resource attributes defined in [%s].\n",
+		strings.Join(tables, "], ["))
+	buf.WriteString(g.funcDecl("ResourceAttributes() map[string]any {\n"))
+	buf.WriteString("\treturn map[string]any{\n")
+	for _, name := range names {
+		fmt.Fprintf(buf, "\t\t%q: %s,\n", name, attrs[name])
+	}
+	buf.WriteString("\t}\n}\n")
+
+	return nil
+}
+
+// collectAttributes adds an attribute for every scalar or array value under table.
+// Arrays of tables have no attribute representation and are skipped.
+func (g *Generator) collectAttributes(attrs map[string]string, tableKey, prefix, expr string, table map[string]any) error {
+	for k, v := range table {
+		name := joinKey(prefix, k)
+		field := expr + "." + g.fieldName(joinKey(tableKey, name))
+		if g.mode == "getter" {
+			field += "()"
+		}
+
+		switch val := v.(type) {
+		case map[string]any:
+			if err := g.collectAttributes(attrs, tableKey, name, field, val); err != nil {
+				return err
+			}
+			continue
+		case []map[string]any:
+			continue
+		case []any:
+			if len(val) > 0 {
+				if _, ok := val[0].(map[string]any); ok {
+					continue
+				}
+			}
+		}
+
+		if _, dup := attrs[name]; dup {
+			return &KeyError{Key: joinKey(tableKey, name), Err: fmt.Errorf("duplicate resource attribute %q", name)}
+		}
+		attrs[name] = field
+	}
+	return nil
+}
+
+// accessor returns the Go expression reading the table or value at the given
+// key path. In getter mode, top-level tables are variables and everything else
+// is read through a getter, as are cfgx:runtime-only keys in other modes. In
+// compact style, values are fields of c, the receiver of the generated
+// methods of Config.
+func (g *Generator) accessor(path []string) string {
+	expr := g.fieldName(path[0])
+	if g.compact() {
+		expr = "c." + expr
+	}
+	if _, isTable := g.data[path[0]].(map[string]any); g.mode == "getter" && !isTable {
+		expr += "()"
+	}
+	for i := range path[1:] {
+		expr += "." + g.fieldName(strings.Join(path[:i+2], "."))
+		if g.mode == "getter" {
+			expr += "()"
+		}
+	}
+	if _, ok := g.runtimeOnly[strings.Join(path, ".")]; ok {
+		expr += "()"
+	}
+	return expr
+}
+
+// lookupTable returns the table at a dotted key path, following only tables.
+func lookupTable(data map[string]any, key string) (map[string]any, bool) {
+	table := data
+	for _, part := range strings.Split(key, ".") {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		table = next
+	}
+	return table, true
+}
@@ -0,0 +1,48 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_ResourceAttributes(t *testing.T) {
+	data := []byte(`
+# cfgx:otel-resource
+[telemetry]
+service.name = "api"
+service.version = "1.2.0"
+deployment.environment = "prod"
+
+[server]
+addr = ":8080"
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func ResourceAttributes() map[string]any {")
+	require.Contains(t, string(output), `"service.name":           Telemetry.Service.Name,`)
+	require.Contains(t, string(output), `"deployment.environment": Telemetry.Deployment.Environment,`)
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), `"service.name":           Telemetry.Service().Name(),`)
+}
+
+func TestGenerator_ResourceAttributesNotTable(t *testing.T) {
+	data := []byte(`
+[app]
+# cfgx:otel-resource
+name = "api"
+`)
+
+	_, err := New().Generate(data)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "app.name")
+}
+
+func TestGenerator_NoResourceAttributes(t *testing.T) {
+	output, err := New().Generate([]byte("[app]\nname = \"api\"\n"))
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "ResourceAttributes")
+}
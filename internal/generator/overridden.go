@@ -0,0 +1,24 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// WithOverridden sets the environment variables that overrode values of the
+// configuration at generation time, by dotted key path. Their literals get a
+// trailing comment naming the variable, so that reviewers can tell why the
+// generated value differs from the TOML.
+func WithOverridden(overridden map[string]string) Option {
+	return func(g *Generator) {
+		g.overridden = overridden
+	}
+}
+
+// writeOverriddenNote writes the trailing comment of the literal of key, if
+// an environment variable overrode it at generation time.
+func (g *Generator) writeOverriddenNote(buf *bytes.Buffer, key string) {
+	if envVar, ok := g.overridden[key]; ok {
+		fmt.Fprintf(buf, " // overridden by %s at generation", envVar)
+	}
+}
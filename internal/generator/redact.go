@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// needsRedaction reports whether value, the value of key, is a secret or a
+// table holding one, directly or in nested tables and arrays of tables.
+func (g *Generator) needsRedaction(key string, value any) bool {
+	if table, ok := value.(map[string]any); ok {
+		for k, v := range table {
+			if g.needsRedaction(joinKey(key, k), v) {
+				return true
+			}
+		}
+		return false
+	}
+	if items, ok := tables(value); ok {
+		return g.needsRedaction(key, mergeItems(items))
+	}
+	if m, ok := value.(*mapTable); ok && m.fields != nil {
+		return g.needsRedaction(key, m.fields)
+	}
+	return g.isSecret(key)
+}
+
+// writeRedacted generates the Redacted and String methods of the struct type
+// name holding fields, if any of them is a secret, so that printing the
+// struct with fmt, as loggers do, does not leak credentials. Secrets are
+// recognized as in the summary.
+func (g *Generator) writeRedacted(buf *bytes.Buffer, name string, fields map[string]any) error {
+	keyPath := g.structKeys[name]
+	if !g.needsRedaction(keyPath, fields) {
+		return nil
+	}
+	if err := g.checkMethodConflicts(keyPath, fields, "redacted", "string"); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(buf, "\n\n// Redacted returns a copy of c with its secrets replaced by %q, or\n", Redacted)
+	buf.WriteString("// zeroed if they are not strings.\n")
+	fmt.Fprintf(buf, "func (c %s) Redacted() %s {\n", name, name)
+	for _, k := range keys {
+		value := fields[k]
+		key := joinKey(keyPath, k)
+		if !g.needsRedaction(key, value) {
+			continue
+		}
+		field := "c." + g.fieldName(joinKey(keyPath, k))
+		if _, ok := value.(map[string]any); ok {
+			fmt.Fprintf(buf, "\t%s = %s.Redacted()\n", field, field)
+			continue
+		}
+		if _, ok := tables(value); ok {
+			itemType := stripSuffix(name) + g.pascalCase(k) + "Item"
+			fmt.Fprintf(buf, "\tif %s != nil {\n", field)
+			fmt.Fprintf(buf, "\t\titems := make([]%s, len(%s))\n", itemType, field)
+			fmt.Fprintf(buf, "\t\tfor i, item := range %s {\n", field)
+			buf.WriteString("\t\t\titems[i] = item.Redacted()\n")
+			buf.WriteString("\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = items\n", field)
+			buf.WriteString("\t}\n")
+			continue
+		}
+		if m, ok := value.(*mapTable); ok && m.fields != nil && m.ordered {
+			fmt.Fprintf(buf, "\tif %s.Len() > 0 {\n", field)
+			fmt.Fprintf(buf, "\t\tpairs := %s.Pairs()\n", field)
+			buf.WriteString("\t\tfor i := range pairs {\n")
+			buf.WriteString("\t\t\tpairs[i].Value = pairs[i].Value.Redacted()\n")
+			buf.WriteString("\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = newOrderedMap(pairs)\n", field)
+			buf.WriteString("\t}\n")
+			continue
+		}
+		if m, ok := value.(*mapTable); ok && m.fields != nil {
+			fmt.Fprintf(buf, "\tif %s != nil {\n", field)
+			fmt.Fprintf(buf, "\t\tentries := make(map[string]%s, len(%s))\n", m.elem, field)
+			fmt.Fprintf(buf, "\t\tfor k, entry := range %s {\n", field)
+			buf.WriteString("\t\t\tentries[k] = entry.Redacted()\n")
+			buf.WriteString("\t\t}\n")
+			fmt.Fprintf(buf, "\t\t%s = entries\n", field)
+			buf.WriteString("\t}\n")
+			continue
+		}
+		g.writeRedactField(buf, field, g.fieldType(key, value))
+	}
+	buf.WriteString("\treturn c\n")
+	buf.WriteString("}\n\n")
+
+	g.extra["fmt"] = true
+	buf.WriteString("// String formats c as the %+v verb does, with its secrets redacted.\n")
+	fmt.Fprintf(buf, "func (c %s) String() string {\n", name)
+	fmt.Fprintf(buf, "\ttype plain %s // Without methods, so that fmt does not call String\n", name)
+	buf.WriteString("\treturn fmt.Sprintf(\"%+v\", plain(c.Redacted()))\n")
+	buf.WriteString("}")
+	return nil
+}
+
+// writeRedactField writes the statement masking the secret field of type
+// goType. Slices are replaced, not modified, since they share their backing
+// array with the package variables, and maps are dropped for the same reason.
+func (g *Generator) writeRedactField(buf *bytes.Buffer, field, goType string) {
+	switch {
+	case goType == "string":
+		fmt.Fprintf(buf, "\tif %s != \"\" {\n", field)
+		fmt.Fprintf(buf, "\t\t%s = %q\n", field, Redacted)
+		buf.WriteString("\t}\n")
+	case goType == "[]string":
+		fmt.Fprintf(buf, "\tif %s != nil {\n", field)
+		fmt.Fprintf(buf, "\t\t%s = make([]string, len(%s))\n", field, field)
+		fmt.Fprintf(buf, "\t\tfor i := range %s {\n", field)
+		fmt.Fprintf(buf, "\t\t\t%s[i] = %q\n", field, Redacted)
+		buf.WriteString("\t\t}\n")
+		buf.WriteString("\t}\n")
+	case goType == "bool":
+		fmt.Fprintf(buf, "\t%s = false\n", field)
+	case isInteger(goType) || goType == "float64" || goType == "time.Duration":
+		fmt.Fprintf(buf, "\t%s = 0\n", field)
+	case strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") || strings.HasPrefix(goType, "*"):
+		fmt.Fprintf(buf, "\t%s = nil\n", field)
+	default:
+		fmt.Fprintf(buf, "\t%s = *new(%s)\n", field, goType)
+	}
+}
@@ -0,0 +1,52 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Redacted(t *testing.T) {
+	data := []byte(`
+api_key = "top"
+
+[database]
+dsn = "postgres://u:p@db/app" # cfgx:secret
+password_policy = "strict" # cfgx:secret=false
+max_conns = 5
+
+[database.auth]
+tokens = ["a", "b"] # cfgx:secret
+
+[[database.replicas]]
+host = "r1"
+password = "rp"
+
+[server]
+addr = ":8080"
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func (c DatabaseConfig) Redacted() DatabaseConfig {\n\tc.Auth = c.Auth.Redacted()\n\tif c.Dsn != \"\" {\n\t\tc.Dsn = \"[redacted]\"\n\t}\n\tif c.Replicas != nil {\n")
+	require.Contains(t, outputStr, "\t\t\titems[i] = item.Redacted()\n")
+	require.Contains(t, outputStr, "\t\tc.Tokens = make([]string, len(c.Tokens))\n")
+	require.Contains(t, outputStr, "func (c DatabaseReplicasItem) String() string {\n\ttype plain DatabaseReplicasItem")
+	require.Contains(t, outputStr, "return fmt.Sprintf(\"%+v\", plain(c.Redacted()))")
+	require.NotContains(t, outputStr, "c.PasswordPolicy =")
+	require.NotContains(t, outputStr, "func (c ServerConfig) String")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (c databasereplicasItem) Redacted() databasereplicasItem {")
+	require.NotContains(t, outputStr, "func (c databaseConfig) Redacted")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "func (c Config) Redacted() Config {\n\tif c.ApiKey != \"\" {\n\t\tc.ApiKey = \"[redacted]\"\n\t}\n\tc.Database = c.Database.Redacted()\n\treturn c\n}")
+
+	_, err = New().Generate([]byte("[db]\npassword = \"x\"\nstring = \"y\"\n"))
+	require.EqualError(t, err, "db.string: conflicts with the generated String method")
+}
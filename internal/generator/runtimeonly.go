@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// runtimeOnlyZeros are the values keys annotated cfgx:runtime-only default
+// to, by the Go types they may have besides integers.
+var runtimeOnlyZeros = map[string]any{
+	"string":        "",
+	"float64":       float64(0),
+	"bool":          false,
+	"time.Duration": "0s",
+}
+
+// runtimeOnlyZero returns the value a key of goType annotated
+// cfgx:runtime-only defaults to, if it may have that type.
+func runtimeOnlyZero(goType string) (any, bool) {
+	if isInteger(goType) {
+		return int64(0), true
+	}
+	zero, ok := runtimeOnlyZeros[goType]
+	return zero, ok
+}
+
+// resolveRuntimeOnly keeps the values of the keys annotated
+// cfgx:runtime-only, such as secrets, out of the generated code. In static
+// and hybrid mode, they are removed from data and read from their
+// environment variable by the getters writeRuntimeOnly generates instead of
+// fields. Getter and loader mode read environment variables at runtime
+// already, so their values are just replaced by the zero value.
+func (g *Generator) resolveRuntimeOnly(data map[string]any) error {
+	g.runtimeOnly = make(map[string]string)
+	if g.src == nil {
+		return nil
+	}
+	for _, key := range g.annotated("runtime-only") {
+		parent, name := data, key
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			var ok bool
+			if parent, ok = lookupTable(data, key[:dot]); !ok {
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:runtime-only is not supported in arrays of tables and cfgx:map tables")}
+			}
+			name = key[dot+1:]
+		}
+		value, ok := parent[name]
+		if !ok {
+			continue
+		}
+		goType := g.keyType(key, value)
+		zero, ok := runtimeOnlyZero(goType)
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:runtime-only requires a string, number, bool or duration, got %s", goType)}
+		}
+
+		switch g.mode {
+		case "getter", "loader":
+			parent[name] = zero
+		default:
+			delete(parent, name)
+			g.runtimeOnly[key] = goType
+		}
+	}
+	return nil
+}
+
+// writeRuntimeOnly writes, in static and hybrid mode, the getters of the keys
+// annotated cfgx:runtime-only: functions for top-level keys, or methods of
+// Config in compact style, and methods of the struct type of their table for
+// others, returning the zero value if their environment variable is not set.
+func (g *Generator) writeRuntimeOnly(buf *bytes.Buffer) {
+	for _, key := range slices.Sorted(maps.Keys(g.runtimeOnly)) {
+		goType := g.runtimeOnly[key]
+		envVarName := g.envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+
+		signature := fmt.Sprintf("func %s() %s", g.fieldName(key), goType)
+		if g.compact() {
+			signature = fmt.Sprintf("func (Config) %s() %s", g.fieldName(key), goType)
+		}
+		if dot := strings.LastIndex(key, "."); dot >= 0 {
+			signature = fmt.Sprintf("func (%s) %s() %s", g.structName(key[:dot]), g.fieldName(key), goType)
+		}
+
+		if goType == "time.Duration" {
+			g.extra["time"] = true
+		}
+
+		buf.WriteString("\n")
+		g.writeFieldDoc(buf, key, "")
+		zero, _ := runtimeOnlyZero(goType)
+		g.writeGetter(buf, signature, goType, key, func(buf *bytes.Buffer) {
+			g.writeGetterBody(buf, goType, envVarName, zero)
+		})
+	}
+}
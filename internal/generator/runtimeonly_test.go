@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_RuntimeOnly(t *testing.T) {
+	data := []byte(`
+token = "dev" # cfgx:runtime-only
+
+[database]
+host = "localhost"
+# cfgx:runtime-only
+password = "changeme"
+port = 5432 # cfgx:runtime-only min=1
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type DatabaseConfig struct {\n\tHost string\n}")
+	require.NotContains(t, outputStr, "changeme")
+	require.NotContains(t, outputStr, "5432")
+	require.Contains(t, outputStr, "func (DatabaseConfig) Password() string {\n\tif v := os.Getenv(\"CONFIG_DATABASE_PASSWORD\"); v != \"\" {\n\t\treturn v\n\t}\n\treturn \"\"\n}")
+	require.Contains(t, outputStr, "func Token() string {")
+	require.Contains(t, outputStr, "if v := Database.Port(); !(v >= 1) {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "changeme")
+	require.Contains(t, string(output), "func (databaseConfig) Port() int64 {")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "changeme")
+	require.Contains(t, string(output), "Password string `toml:\"password\"`")
+
+	_, err = New().Generate([]byte("hosts = [\"a\"] # cfgx:runtime-only\n"))
+	require.EqualError(t, err, "hosts: cfgx:runtime-only requires a string, number, bool or duration, got []string")
+
+	_, err = New().Generate([]byte("[[workers]]\nkey = \"k\" # cfgx:runtime-only\n"))
+	require.EqualError(t, err, "workers.key: cfgx:runtime-only is not supported in arrays of tables and cfgx:map tables")
+}
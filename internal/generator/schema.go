@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// schemaDialect is the JSON Schema version of the schemas Schema returns.
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches the durations time.ParseDuration accepts.
+const durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// schemaFormats maps the cfgx:format validators to JSON Schema formats.
+var schemaFormats = map[string]string{
+	"email":    "email",
+	"hostname": "hostname",
+	"uuid":     "uuid",
+}
+
+// jsonSchema is a JSON Schema, with the keywords Schema uses.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            int                    `json:"minLength,omitempty"`
+	MinItems             int                    `json:"minItems,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // false, or the schema of map values
+	Default              any                    `json:"default,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+	WriteOnly            bool                   `json:"writeOnly,omitempty"`
+}
+
+// Schema returns a JSON Schema describing TOML files shaped like data, for
+// editors and services outside Go to validate them with. Values get the
+// types the generator infers for them, such as durations and file:
+// references, which JSON Schema knows as strings matching a pattern, along
+// with their constraints and formats and their current value as default.
+// Tables do not allow keys they do not have, except cfgx:map tables, and no
+// key is required. The file is described as written: ref: references,
+// extends keys and expressions are not resolved. Data is decoded as for
+// GenerateFilesFromMap, and modified.
+func (g *Generator) Schema(data map[string]any) ([]byte, error) {
+	g.src = g.source
+	if g.src == nil {
+		g.src = tomlsrc.Scan(nil)
+	}
+	g.extra = make(map[string]bool)
+	g.snippets = make(map[string]bool)
+	g.data = data
+
+	meta, err := takeMeta(maps.Clone(data))
+	if err != nil {
+		return nil, err
+	}
+	g.meta = meta
+	if err := g.resolveMaps(data); err != nil {
+		return nil, err
+	}
+	if err := g.validateTypes(data); err != nil {
+		return nil, err
+	}
+
+	b := &schemaBuilder{g: g, constraints: g.keyConstraints()}
+	schema := b.tableSchema("", data)
+	schema.Schema = schemaDialect
+	if _, ok := data[metaTable]; ok {
+		schema.Properties[metaTable] = &jsonSchema{Type: "object"}
+	}
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// schemaBuilder builds the schema of the data being described.
+type schemaBuilder struct {
+	g           *Generator
+	constraints map[string]constraints // Constraints of every key, by key path
+}
+
+// tableSchema returns the schema of the table at key.
+func (b *schemaBuilder) tableSchema(key string, table map[string]any) *jsonSchema {
+	s := &jsonSchema{Type: "object", Properties: make(map[string]*jsonSchema), AdditionalProperties: false}
+	for k, v := range table {
+		s.Properties[k] = b.valueSchema(joinKey(key, k), v)
+	}
+	return s
+}
+
+// valueSchema returns the schema of value, the value of key.
+func (b *schemaBuilder) valueSchema(key string, value any) *jsonSchema {
+	switch v := value.(type) {
+	case map[string]any:
+		return b.tableSchema(key, v)
+	case *mapTable:
+		if v.key != "" {
+			// Keyed arrays of tables are written as arrays
+			return &jsonSchema{Type: "array", Items: withoutDefaults(b.tableSchema(key, v.fields))}
+		}
+		var elem *jsonSchema
+		if v.fields != nil {
+			elem = b.tableSchema(key, v.fields)
+		} else {
+			elem = b.valueSchema(key, v.entries[slices.Min(slices.Collect(maps.Keys(v.entries)))])
+		}
+		return &jsonSchema{Type: "object", AdditionalProperties: withoutDefaults(elem)}
+	}
+	if items, ok := tables(value); ok {
+		// Items may each have a different subset of the keys
+		fields := make(map[string]any)
+		for _, item := range items {
+			for k, v := range item {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+		}
+		return &jsonSchema{Type: "array", Items: withoutDefaults(b.tableSchema(key, fields))}
+	}
+
+	s := b.leafSchema(key, value)
+	b.constrainSchema(s, key)
+	if stability, _ := b.g.annotation(key, "stability"); stability == "deprecated" {
+		s.Deprecated = true
+	}
+	if b.g.isSecret(key) {
+		s.WriteOnly = true
+	} else {
+		s.Default = value
+	}
+	return s
+}
+
+// leafSchema returns the schema of a value other than a table, from its
+// TOML type refined by the Go type inferred for it.
+func (b *schemaBuilder) leafSchema(key string, value any) *jsonSchema {
+	s := &jsonSchema{}
+	switch v := value.(type) {
+	case string:
+		s.Type = "string"
+	case int64:
+		s.Type = "integer"
+	case float64:
+		s.Type = "number"
+	case bool:
+		s.Type = "boolean"
+	case []any:
+		s.Type = "array"
+		if len(v) > 0 {
+			s.Items = b.leafSchema(key, v[0])
+		}
+		return s
+	default:
+		// Datetimes, which TOML has and JSON does not
+		s.Type = "string"
+		s.Format = "date-time"
+	}
+
+	switch b.g.keyType(key, value) {
+	case "time.Duration":
+		s.Pattern = durationPattern
+	case "[]byte", "Asset":
+		s.Pattern = "^file:"
+	}
+	return s
+}
+
+// constrainSchema adds the constraints and format of key to its schema s.
+func (b *schemaBuilder) constrainSchema(s *jsonSchema, key string) {
+	c := b.constraints[key]
+	if s.Type == "integer" || s.Type == "number" {
+		for _, bound := range []struct {
+			value string
+			dst   **float64
+		}{{c.min, &s.Minimum}, {c.max, &s.Maximum}} {
+			if f, err := strconv.ParseFloat(bound.value, 64); err == nil {
+				*bound.dst = &f
+			}
+		}
+	}
+	if c.regex != "" && s.Type == "string" {
+		s.Pattern = c.regex
+	}
+	if c.nonempty {
+		switch s.Type {
+		case "string":
+			s.MinLength = 1
+		case "array":
+			s.MinItems = 1
+		}
+	}
+	for _, item := range c.enum {
+		if n, err := strconv.ParseInt(item, 10, 64); err == nil && s.Type == "integer" {
+			s.Enum = append(s.Enum, n)
+		} else {
+			s.Enum = append(s.Enum, item)
+		}
+	}
+
+	format, _ := b.g.annotation(key, "format")
+	switch {
+	case format == "port" && s.Type == "integer":
+		minPort, maxPort := 1.0, 65535.0
+		s.Minimum, s.Maximum = &minPort, &maxPort
+	case schemaFormats[format] != "":
+		s.Format = schemaFormats[format]
+	}
+}
+
+// withoutDefaults removes the defaults of s and the schemas it holds, which
+// describe the values of one element or entry among many.
+func withoutDefaults(s *jsonSchema) *jsonSchema {
+	s.Default = nil
+	for _, p := range s.Properties {
+		withoutDefaults(p)
+	}
+	if s.Items != nil {
+		withoutDefaults(s.Items)
+	}
+	if elem, ok := s.AdditionalProperties.(*jsonSchema); ok {
+		withoutDefaults(elem)
+	}
+	return s
+}
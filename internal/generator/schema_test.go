@@ -0,0 +1,63 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+func TestGenerator_Schema(t *testing.T) {
+	src := []byte(`
+name = "svc" # cfgx:nonempty
+
+[server]
+port = 8080 # cfgx:format=port
+timeout = "30s"
+level = "info" # cfgx:enum=debug,info
+api_key = "k"
+ca = "file:testdata/ca.crt"
+
+[[server.routes]]
+path = "/"
+
+[[server.routes]]
+path = "/admin"
+auth = true
+
+[limits] # cfgx:map
+acme = 100
+
+[[users]] # cfgx:key=email
+email = "a@x"
+`)
+	var data map[string]any
+	require.NoError(t, toml.Unmarshal(src, &data))
+
+	out, err := New(WithSource(tomlsrc.Scan(src))).Schema(data)
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(out, &schema))
+	require.Equal(t, schemaDialect, schema["$schema"])
+	require.Equal(t, false, schema["additionalProperties"])
+
+	props := schema["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "string", "minLength": 1.0, "default": "svc"}, props["name"])
+	require.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}}, props["limits"])
+	require.Equal(t, "array", props["users"].(map[string]any)["type"])
+
+	server := props["server"].(map[string]any)["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "integer", "minimum": 1.0, "maximum": 65535.0, "default": 8080.0}, server["port"])
+	require.Equal(t, map[string]any{"type": "string", "pattern": durationPattern, "default": "30s"}, server["timeout"])
+	require.Equal(t, []any{"debug", "info"}, server["level"].(map[string]any)["enum"])
+	require.Equal(t, map[string]any{"type": "string", "writeOnly": true}, server["api_key"])
+	require.Equal(t, "^file:", server["ca"].(map[string]any)["pattern"])
+
+	routes := server["routes"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)
+	require.Equal(t, map[string]any{"type": "boolean"}, routes["auth"])
+	require.Equal(t, map[string]any{"type": "string"}, routes["path"])
+}
@@ -0,0 +1,68 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// splitSections removes the top-level tables annotated with cfgx:package from
+// data and returns them grouped by package name. Each package is generated
+// into its own directory next to the main output file.
+func (g *Generator) splitSections(data map[string]any) (map[string]map[string]any, error) {
+	sections := make(map[string]map[string]any)
+	for _, key := range g.src.Annotated("package") {
+		pkg, _ := g.annotation(key, "package")
+		if strings.Contains(key, ".") {
+			return nil, &KeyError{Key: key, Err: fmt.Errorf("cfgx:package is only supported on top-level tables")}
+		}
+		table, ok := data[key].(map[string]any)
+		if !ok {
+			return nil, &KeyError{Key: key, Err: fmt.Errorf("cfgx:package must annotate a table")}
+		}
+		if !token.IsIdentifier(pkg) || pkg != strings.ToLower(pkg) {
+			return nil, &KeyError{Key: key, Err: fmt.Errorf("invalid package name %q", pkg)}
+		}
+		if pkg == g.packageName {
+			return nil, &KeyError{Key: key, Err: fmt.Errorf("cfgx:package=%s is the package of the main output", pkg)}
+		}
+
+		if sections[pkg] == nil {
+			sections[pkg] = make(map[string]any)
+		}
+		sections[pkg][key] = table
+		delete(data, key)
+	}
+	return sections, nil
+}
+
+// sectionGenerator returns a generator for the tables split into package pkg,
+// whose output lives one directory below the main output.
+func (g *Generator) sectionGenerator(pkg string) *Generator {
+	sub := *g
+	sub.packageName = pkg
+	sub.assetDir = pkg + "/"
+	sub.stats = nil
+	if g.record != nil {
+		rec := *g.record
+		rec.Input = sectionInputPath(rec.Input)
+		rec.Overlays = nil
+		for _, overlay := range g.record.Overlays {
+			rec.Overlays = append(rec.Overlays, sectionInputPath(overlay))
+		}
+		rec.Section = pkg
+		sub.record = &rec
+	}
+	return &sub
+}
+
+// sectionInputPath returns a record input path for a section, whose output is
+// one directory further from the input than the main output.
+func sectionInputPath(input string) string {
+	if filepath.IsAbs(filepath.FromSlash(input)) {
+		return input
+	}
+	return path.Join("..", input)
+}
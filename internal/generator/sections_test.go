@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/gomantics/cfgx/internal/record"
+)
+
+func TestGenerator_PackageSections(t *testing.T) {
+	data := []byte(`
+[app]
+name = "svc"
+
+# cfgx:package=dbconfig
+[database]
+host = "db.internal"
+port = 5432 # cfgx:format=port
+
+# cfgx:package=dbconfig
+[cache]
+addr = "cache:6379"
+`)
+
+	files, err := New(WithRecord(&record.Record{Input: "../config.toml", Mode: "static"})).GenerateFiles(data)
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	main := string(files[""])
+	require.Contains(t, main, "package config")
+	require.Contains(t, main, "App = AppConfig{")
+	require.NotContains(t, main, "Database")
+
+	section := string(files["dbconfig/"])
+	require.Contains(t, section, "package dbconfig")
+	require.Contains(t, section, "Database = DatabaseConfig{")
+	require.Contains(t, section, "Cache = CacheConfig{")
+	require.Contains(t, section, "func Validate() error {", "section checks belong to the section")
+	require.NotContains(t, section, "App")
+
+	rec, ok, err := record.Parse(files["dbconfig/"])
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "../../config.toml", rec.Input, "input should be relative to the section directory")
+	require.Equal(t, "dbconfig", rec.Section)
+}
+
+func TestGenerator_PackageSectionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "nested table",
+			toml: "[a]\n# cfgx:package=sub\n[a.b]\nx = 1\n",
+			want: "a.b: cfgx:package is only supported on top-level tables",
+		},
+		{
+			name: "not a table",
+			toml: "x = 1 # cfgx:package=sub\n",
+			want: "x: cfgx:package must annotate a table",
+		},
+		{
+			name: "invalid name",
+			toml: "# cfgx:package=Db-Config\n[db]\nx = 1\n",
+			want: "invalid package name \"Db-Config\"",
+		},
+		{
+			name: "main package",
+			toml: "# cfgx:package=config\n[db]\nx = 1\n",
+			want: "is the package of the main output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// writeLogLevel generates a LogLevel function for the string key annotated
+// with cfgx:slog-level:
+//
+//	[logging]
+//	level = "info" # cfgx:slog-level
+//
+// The value is parsed with slog.Level.UnmarshalText, so "debug", "WARN" and
+// offsets such as "info+2" are accepted. The TOML value is validated at
+// generation time and used as the fallback when an override does not parse.
+func (g *Generator) writeLogLevel(buf *bytes.Buffer, data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+	keys := g.annotated("slog-level")
+	if len(keys) == 0 {
+		return nil
+	}
+	if len(keys) > 1 {
+		return &KeyError{Key: keys[1], Err: fmt.Errorf("cfgx:slog-level is already set on %s", keys[0])}
+	}
+
+	key := keys[0]
+	path := strings.Split(key, ".")
+	if len(path) < 2 {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:slog-level must annotate a key inside a table")}
+	}
+	table, ok := lookupTable(data, strings.Join(path[:len(path)-1], "."))
+	if !ok {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:slog-level must annotate a key inside a table")}
+	}
+	value, ok := table[path[len(path)-1]].(string)
+	if !ok {
+		return &KeyError{Key: key, Err: fmt.Errorf("cfgx:slog-level must annotate a string")}
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		return &KeyError{Key: key, Err: fmt.Errorf("invalid log level %q: %w", value, err)}
+	}
+
+	g.extra["log/slog"] = true
+	fmt.Fprintf(buf, "\n// LogLevel returns %s parsed as a slog.Level, falling back to %s\n", key, level)
+	buf.WriteString("// if the value does not parse.\n")
+	buf.WriteString(g.funcDecl("LogLevel() slog.Level {\n"))
+	buf.WriteString("\tvar level slog.Level\n")
+	fmt.Fprintf(buf, "\tif err := level.UnmarshalText([]byte(%s)); err != nil {\n", g.accessor(path))
+	fmt.Fprintf(buf, "\t\treturn %s\n", levelLiteral(level))
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn level\n")
+	buf.WriteString("}\n")
+
+	return nil
+}
+
+// levelLiteral returns the Go expression for a slog level.
+func levelLiteral(level slog.Level) string {
+	switch level {
+	case slog.LevelDebug:
+		return "slog.LevelDebug"
+	case slog.LevelInfo:
+		return "slog.LevelInfo"
+	case slog.LevelWarn:
+		return "slog.LevelWarn"
+	case slog.LevelError:
+		return "slog.LevelError"
+	}
+	return fmt.Sprintf("slog.Level(%d)", int(level))
+}
@@ -0,0 +1,69 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_LogLevel(t *testing.T) {
+	data := []byte(`
+[logging]
+level = "warn" # cfgx:slog-level
+format = "json"
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "import \"log/slog\"")
+	require.Contains(t, outputStr, "func LogLevel() slog.Level {")
+	require.Contains(t, outputStr, "level.UnmarshalText([]byte(Logging.Level))")
+	require.Contains(t, outputStr, "return slog.LevelWarn")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "\"log/slog\"\n\t\"os\"")
+	require.Contains(t, outputStr, "level.UnmarshalText([]byte(Logging.Level()))")
+}
+
+func TestGenerator_LogLevelErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "invalid level",
+			toml: "[logging]\nlevel = \"loud\" # cfgx:slog-level\n",
+			want: "invalid log level",
+		},
+		{
+			name: "not a string",
+			toml: "[logging]\nlevel = 3 # cfgx:slog-level\n",
+			want: "must annotate a string",
+		},
+		{
+			name: "top-level key",
+			toml: "log_level = \"info\" # cfgx:slog-level\n",
+			want: "inside a table",
+		},
+		{
+			name: "conflict with LogLevel",
+			toml: "log_level = 1\n\n[logging]\nlevel = \"info\" # cfgx:slog-level\n",
+			want: "log_level: conflicts with the generated LogLevel function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	_, err := New(WithMode("loader")).Generate([]byte("log_level = 1\n\n[logging]\nlevel = \"info\" # cfgx:slog-level\n"))
+	require.EqualError(t, err, "log_level: conflicts with the generated LogLevel function")
+}
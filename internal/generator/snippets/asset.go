@@ -0,0 +1,10 @@
+package snippets
+
+// cfgx:snippet
+
+// Asset is the content of a file referenced with file:, along with its media
+// type, for serving it over HTTP.
+type Asset struct {
+	Content     []byte
+	ContentType string
+}
@@ -0,0 +1,60 @@
+package snippets
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// cfgx:snippet
+
+// assetTypes are the media types of assets by file extension. The table is
+// fixed, unlike the one of mime.TypeByExtension, which is extended from the
+// system, so that assets get the same type on every machine.
+var assetTypes = map[string]string{
+	".css":   "text/css; charset=utf-8",
+	".csv":   "text/csv; charset=utf-8",
+	".gif":   "image/gif",
+	".htm":   "text/html; charset=utf-8",
+	".html":  "text/html; charset=utf-8",
+	".ico":   "image/vnd.microsoft.icon",
+	".jpeg":  "image/jpeg",
+	".jpg":   "image/jpeg",
+	".js":    "text/javascript; charset=utf-8",
+	".json":  "application/json",
+	".md":    "text/markdown; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".pdf":   "application/pdf",
+	".pem":   "application/x-pem-file",
+	".png":   "image/png",
+	".svg":   "image/svg+xml",
+	".toml":  "application/toml",
+	".txt":   "text/plain; charset=utf-8",
+	".wasm":  "application/wasm",
+	".webp":  "image/webp",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".xml":   "text/xml; charset=utf-8",
+	".yaml":  "application/yaml",
+	".yml":   "application/yaml",
+}
+
+// assetContentType returns the media type of content, read from the file
+// name: the type of its extension or, failing that, the one sniffed from
+// content by http.DetectContentType.
+func assetContentType(name string, content []byte) string {
+	if t, ok := assetTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return t
+	}
+	return http.DetectContentType(content)
+}
+
+// newAsset returns the asset of content, read from the file name.
+func newAsset(name string, content []byte) Asset {
+	return Asset{Content: content, ContentType: assetContentType(name, content)}
+}
+
+// setFile sets a to content, read from the file name, for Load.
+func (a *Asset) setFile(name string, content []byte) {
+	*a = newAsset(name, content)
+}
@@ -0,0 +1,141 @@
+package snippets
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cfgx:snippet
+
+// CronSpec is a cron schedule in standard 5-field form (minute hour
+// day-of-month month day-of-week), the 6-field form with a leading seconds
+// field, or a descriptor such as "@daily" or "@every 5m".
+type CronSpec string
+
+// IsValid reports whether the spec is syntactically valid.
+func (s CronSpec) IsValid() bool {
+	spec := strings.TrimSpace(string(s))
+	if strings.HasPrefix(spec, "@") {
+		return cronDescriptorValid(spec)
+	}
+
+	fields := strings.Fields(spec)
+	bounds := cronBounds
+	switch len(fields) {
+	case 5:
+		bounds = cronBounds[1:]
+	case 6:
+	default:
+		return false
+	}
+
+	for i, field := range fields {
+		if !cronFieldValid(field, bounds[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the spec as written.
+func (s CronSpec) String() string {
+	return string(s)
+}
+
+type cronBound struct {
+	min, max int
+	names    []string
+}
+
+var cronBounds = []cronBound{
+	{0, 59, nil}, // second
+	{0, 59, nil}, // minute
+	{0, 23, nil}, // hour
+	{1, 31, nil}, // day of month
+	{1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+func cronDescriptorValid(spec string) bool {
+	switch spec {
+	case "@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly":
+		return true
+	}
+	every, ok := strings.CutPrefix(spec, "@every ")
+	if !ok {
+		return false
+	}
+	d := strings.TrimSpace(every)
+	if d == "" {
+		return false
+	}
+	// Accept the time.ParseDuration syntax without importing time
+	for d != "" {
+		i := 0
+		for i < len(d) && (d[i] == '.' || ('0' <= d[i] && d[i] <= '9')) {
+			i++
+		}
+		if i == 0 {
+			return false
+		}
+		if _, err := strconv.ParseFloat(d[:i], 64); err != nil {
+			return false
+		}
+		d = d[i:]
+		unit := ""
+		for _, u := range []string{"ns", "us", "µs", "ms", "h", "m", "s"} {
+			if strings.HasPrefix(d, u) {
+				unit = u
+				break
+			}
+		}
+		if unit == "" {
+			return false
+		}
+		d = d[len(unit):]
+	}
+	return true
+}
+
+func cronFieldValid(field string, b cronBound) bool {
+	for _, part := range strings.Split(field, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		if hasStep {
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 1 {
+				return false
+			}
+		}
+
+		if rng == "*" || rng == "?" {
+			continue
+		}
+
+		lo, hi, isRange := strings.Cut(rng, "-")
+		loN, ok := cronValue(lo, b)
+		if !ok {
+			return false
+		}
+		if !isRange {
+			continue
+		}
+		hiN, ok := cronValue(hi, b)
+		if !ok || hiN < loN {
+			return false
+		}
+	}
+	return true
+}
+
+func cronValue(s string, b cronBound) (int, bool) {
+	for i, name := range b.names {
+		if strings.EqualFold(s, name) {
+			return b.min + i, true
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < b.min || n > b.max {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,147 @@
+package snippets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// cfgx:snippet
+
+// Cipher encrypts and decrypts the values of Encrypted keys with the key
+// named by a key ID, such as the ARN of an AWS KMS key or the resource name
+// of a GCP KMS key. KMS-backed ciphers wrap the client of their SDK and are
+// registered with RegisterCipher.
+type Cipher interface {
+	Encrypt(ctx context.Context, keyID string, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+var (
+	ciphersMu sync.RWMutex
+	ciphers   = map[string]Cipher{"aes": AESCipher{}}
+)
+
+// RegisterCipher makes c available to Encrypted values under name, replacing
+// any cipher registered under it before. The built-in "aes" cipher is
+// AESCipher.
+func RegisterCipher(name string, c Cipher) {
+	ciphersMu.Lock()
+	defer ciphersMu.Unlock()
+	ciphers[name] = c
+}
+
+// Encrypted is a value encrypted with a registered Cipher, written as
+// "<cipher>:<key ID>:<base64 ciphertext>", such as
+// "aes:APP_KEY:3q2+7w...". The key ID may contain colons. The plaintext is
+// only ever returned by Decrypt.
+type Encrypted string
+
+// IsValid reports whether the value is well formed; the ciphertext itself is
+// only checked by Decrypt.
+func (e Encrypted) IsValid() bool {
+	_, _, _, err := parseEncrypted(string(e))
+	return err == nil
+}
+
+// Cipher returns the name of the cipher the value was encrypted with.
+func (e Encrypted) Cipher() string {
+	name, _, _, _ := parseEncrypted(string(e))
+	return name
+}
+
+// KeyID returns the ID of the key the value was encrypted with.
+func (e Encrypted) KeyID() string {
+	_, keyID, _, _ := parseEncrypted(string(e))
+	return keyID
+}
+
+// Decrypt returns the plaintext of the value, decrypted by its cipher.
+func (e Encrypted) Decrypt(ctx context.Context) (string, error) {
+	name, keyID, ciphertext, err := parseEncrypted(string(e))
+	if err != nil {
+		return "", err
+	}
+	ciphersMu.RLock()
+	c, ok := ciphers[name]
+	ciphersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown cipher %q", name)
+	}
+	plaintext, err := c.Decrypt(ctx, keyID, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt with %s key %s: %w", name, keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// parseEncrypted splits an encrypted value into its cipher, key ID and
+// decoded ciphertext.
+func parseEncrypted(s string) (name, keyID string, ciphertext []byte, err error) {
+	name, rest, ok := strings.Cut(s, ":")
+	i := strings.LastIndex(rest, ":")
+	if !ok || name == "" || i <= 0 {
+		return "", "", nil, errors.New("encrypted value must be <cipher>:<key ID>:<base64 ciphertext>")
+	}
+	ciphertext, err = base64.StdEncoding.DecodeString(rest[i+1:])
+	if err != nil || len(ciphertext) == 0 {
+		return "", "", nil, errors.New("encrypted value has an invalid base64 ciphertext")
+	}
+	return name, rest[:i], ciphertext, nil
+}
+
+// AESCipher is the built-in "aes" cipher: AES-GCM with the nonce prepended
+// to the ciphertext. Its key IDs name environment variables holding the
+// base64-encoded 16, 24 or 32 byte key, for deployments without a KMS.
+type AESCipher struct{}
+
+// Encrypt encrypts plaintext with the key in the environment variable keyID.
+func (AESCipher) Encrypt(_ context.Context, keyID string, plaintext []byte) ([]byte, error) {
+	aead, err := aesGCM(keyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts ciphertext with the key in the environment variable keyID.
+func (AESCipher) Decrypt(_ context.Context, keyID string, ciphertext []byte) ([]byte, error) {
+	aead, err := aesGCM(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, nil)
+}
+
+// aesGCM returns the AES-GCM cipher of the key in the environment variable
+// keyID.
+func aesGCM(keyID string) (cipher.AEAD, error) {
+	encoded := os.Getenv(keyID)
+	if encoded == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", keyID)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("environment variable %s is not valid base64: %w", keyID, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,21 @@
+package snippets
+
+import (
+	"os"
+	"strings"
+)
+
+// cfgx:snippet
+
+// expandEnv replaces $VAR and ${VAR} in s with the value of the environment
+// variable, and ${VAR:-fallback} with the expanded fallback when VAR is
+// unset or empty.
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		name, fallback, hasFallback := strings.Cut(name, ":-")
+		if v := os.Getenv(name); v != "" || !hasFallback {
+			return v
+		}
+		return expandEnv(fallback)
+	})
+}
@@ -0,0 +1,174 @@
+package snippets
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// cfgx:snippet
+
+// configLayer is a layer of configuration values given as strings by dotted
+// key path, such as environment variables or command-line flags.
+type configLayer struct {
+	name   string                          // Layer name recorded as provenance
+	lookup func(key string) (string, bool) // Value of key, if the layer sets it
+	source func(key string) string         // Where the layer sets key, for errors
+}
+
+// envConfigLayer returns the layer of environment variables named by prefix,
+// an underscore and the key path in upper case with dots replaced by
+// underscores, such as CONFIG_SERVER_ADDR. Empty variables are unset.
+func envConfigLayer(prefix string) configLayer {
+	name := func(key string) string {
+		return prefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	}
+	return configLayer{
+		name: "env",
+		lookup: func(key string) (string, bool) {
+			v := os.Getenv(name(key))
+			return v, v != ""
+		},
+		source: func(key string) string { return name(key) + " (" + key + ")" },
+	}
+}
+
+// flagConfigLayer returns the layer of the flags of fs, named by key path,
+// that were set on the command line.
+func flagConfigLayer(fs *flag.FlagSet) configLayer {
+	set := make(map[string]string)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	return configLayer{
+		name: "flag",
+		lookup: func(key string) (string, bool) {
+			v, ok := set[key]
+			return v, ok
+		},
+		source: func(key string) string { return "-" + key },
+	}
+}
+
+// defineConfigFlags defines a string flag on fs for every value of src, a
+// pointer to a configuration struct, that a flag can set, named by its key
+// path and defaulting to the current value.
+func defineConfigFlags(fs *flag.FlagSet, src any) {
+	configLeaves(reflect.ValueOf(src).Elem(), "", func(key string, v reflect.Value) {
+		var def string
+		switch {
+		case v.Type() == reflect.TypeOf([]byte(nil)):
+			// File contents, set from a path
+		case v.Kind() == reflect.Slice:
+			items := make([]string, v.Len())
+			for i := range items {
+				items[i] = fmt.Sprint(v.Index(i).Interface())
+			}
+			def = strings.Join(items, ",")
+		case v.Kind() == reflect.Pointer && v.IsNil():
+		default:
+			def = fmt.Sprint(v.Interface())
+		}
+		fs.String(key, def, "overrides "+key)
+	})
+}
+
+// applyConfigLayer sets the values of dst, a pointer to a configuration
+// struct, that layer sets, recording their key paths in prov.
+func applyConfigLayer(dst any, layer configLayer, parsers map[string]func(string) any, prov map[string]string) error {
+	d := configDecoder{parsers: parsers, readFile: os.ReadFile}
+	var err error
+	configLeaves(reflect.ValueOf(dst).Elem(), "", func(key string, v reflect.Value) {
+		s, ok := layer.lookup(key)
+		if !ok || err != nil {
+			return
+		}
+		if e := decodeConfigString(d, v, s, key); e != nil {
+			err = fmt.Errorf("invalid value for %s: %w", layer.source(key), e)
+			return
+		}
+		prov[key] = layer.name
+	})
+	return err
+}
+
+// configLeaves calls fn for every field of the struct v that a string can
+// set, with its dotted key path. Arrays of tables, arrays of arrays and maps
+// are left to the file layer.
+func configLeaves(v reflect.Value, prefix string, fn func(key string, field reflect.Value)) {
+	for i := range v.NumField() {
+		name := v.Type().Field(i).Tag.Get("toml")
+		if name == "" {
+			continue
+		}
+		key := configKey(prefix, name)
+		field := v.Field(i)
+		switch t := field.Type(); {
+		case t.Kind() == reflect.Struct && !reflect.PointerTo(t).Implements(configTextUnmarshalerType) && !reflect.PointerTo(t).Implements(configFileType):
+			configLeaves(field, key, fn)
+		case t.Kind() == reflect.Map:
+		case t.Kind() == reflect.Slice && t != reflect.TypeOf([]byte(nil)) &&
+			(t.Elem().Kind() == reflect.Struct && !reflect.PointerTo(t.Elem()).Implements(configTextUnmarshalerType) ||
+				t.Elem().Kind() == reflect.Slice && t.Elem() != reflect.TypeOf([]byte(nil)) || t.Elem().Kind() == reflect.Map):
+		default:
+			fn(key, field)
+		}
+	}
+}
+
+// decodeConfigString sets dst from s, the string form of the value of key.
+// Arrays are comma-separated and []byte values and assets are read from the
+// file at path s.
+func decodeConfigString(d configDecoder, dst reflect.Value, s, key string) error {
+	t := dst.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.Slice && t != reflect.TypeOf([]byte(nil)):
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		items := reflect.MakeSlice(t, len(parts), len(parts))
+		for i, part := range parts {
+			if err := decodeConfigString(d, items.Index(i), strings.TrimSpace(part), key); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		dst.Set(items)
+		return nil
+	case d.parsers[key] != nil:
+		return d.decode(dst, s, key, key)
+	case t == reflect.TypeOf([]byte(nil)) || reflect.PointerTo(t).Implements(configFileType):
+		return d.decode(dst, "file:"+s, key, key)
+	case t == configDurationType || reflect.PointerTo(t).Implements(configTextUnmarshalerType):
+		return d.decode(dst, s, key, key)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		return d.decode(dst, b, key, key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		return d.decode(dst, n, key, key)
+	case reflect.Float32, reflect.Float64:
+		x, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		return d.decode(dst, x, key, key)
+	}
+	return d.decode(dst, s, key, key)
+}
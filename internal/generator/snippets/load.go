@@ -0,0 +1,313 @@
+package snippets
+
+import (
+	"encoding"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// cfgx:snippet
+
+// loadOSConfig decodes the TOML file name over dst, a pointer to a struct
+// whose fields carry toml tags. file: references are read relative to the
+// directory of the file. Strings of the key paths in parsers are parsed by
+// them, which return nil for invalid values. The key paths of the values set
+// are recorded in prov, if not nil, as coming from the file layer.
+func loadOSConfig(dst any, name string, parsers map[string]func(string) any, prov map[string]string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(name)
+	return decodeConfig(dst, name, data, parsers, prov, func(ref string) ([]byte, error) {
+		if !filepath.IsAbs(ref) {
+			ref = filepath.Join(dir, ref)
+		}
+		return os.ReadFile(ref)
+	})
+}
+
+// loadFSConfig is loadOSConfig for a file of fsys, which file: references
+// are read from too.
+func loadFSConfig(dst any, fsys fs.FS, name string, parsers map[string]func(string) any, prov map[string]string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	dir := path.Dir(name)
+	return decodeConfig(dst, name, data, parsers, prov, func(ref string) ([]byte, error) {
+		return fs.ReadFile(fsys, path.Join(dir, ref))
+	})
+}
+
+// decodeConfig decodes the TOML data of the file name over dst. Keys absent
+// from data keep their value, arrays are replaced as a whole and unknown keys
+// are an error, so that typos do not go unnoticed.
+func decodeConfig(dst any, name string, data []byte, parsers map[string]func(string) any, prov map[string]string, readFile func(string) ([]byte, error)) error {
+	var table map[string]any
+	if err := toml.Unmarshal(data, &table); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	d := configDecoder{parsers: parsers, readFile: readFile, prov: prov, layer: "file"}
+	if err := d.decode(reflect.ValueOf(dst).Elem(), table, "", ""); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// configDecoder assigns decoded TOML values to configuration fields.
+type configDecoder struct {
+	parsers  map[string]func(string) any
+	readFile func(string) ([]byte, error)
+	prov     map[string]string // Layer of each value set, by key path (optional)
+	layer    string            // Name of the layer decoded, for prov
+}
+
+// configFile is implemented by the types of file: references other than
+// []byte, such as Asset, which keep more than the content of the file.
+type configFile interface {
+	setFile(name string, content []byte)
+}
+
+var (
+	configDurationType        = reflect.TypeOf(time.Duration(0))
+	configTextUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	configFileType            = reflect.TypeOf((*configFile)(nil)).Elem()
+)
+
+// decode assigns v, the decoded TOML value of key, to dst. The key path is
+// the key without array indices.
+func (d configDecoder) decode(dst reflect.Value, v any, key, keyPath string) error {
+	if parse, ok := d.parsers[keyPath]; ok {
+		if s, ok := v.(string); ok {
+			x := parse(s)
+			if x == nil {
+				return fmt.Errorf("%s: invalid value %q", key, s)
+			}
+			dst.Set(reflect.ValueOf(x))
+			return nil
+		}
+	}
+
+	src := reflect.ValueOf(v)
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+		return nil
+	case dst.Kind() == reflect.Pointer:
+		// Never decode into a value the defaults share
+		p := reflect.New(dst.Type().Elem())
+		if err := d.decode(p.Elem(), v, key, keyPath); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	case dst.Type() == configDurationType:
+		s, ok := v.(string)
+		if !ok {
+			return configTypeError(key, "a duration string", v)
+		}
+		x, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		dst.SetInt(int64(x))
+		return nil
+	case dst.Type() == reflect.TypeOf([]byte(nil)):
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, "file:") {
+			return configTypeError(key, "a file: reference", v)
+		}
+		content, err := d.readFile(strings.TrimPrefix(s, "file:"))
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		dst.SetBytes(content)
+		return nil
+	case reflect.PointerTo(dst.Type()).Implements(configFileType):
+		s, ok := v.(string)
+		if !ok || !strings.HasPrefix(s, "file:") {
+			return configTypeError(key, "a file: reference", v)
+		}
+		name := strings.TrimPrefix(s, "file:")
+		content, err := d.readFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		dst.Addr().Interface().(configFile).setFile(name, content)
+		return nil
+	case reflect.PointerTo(dst.Type()).Implements(configTextUnmarshalerType):
+		var s string
+		switch x := v.(type) {
+		case string:
+			s = x
+		case int64, float64:
+			s = fmt.Sprint(x)
+		default:
+			return configTypeError(key, "a string", v)
+		}
+		if err := dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return configTypeError(key, "a string", v)
+		}
+		dst.SetString(s)
+		if c, ok := dst.Interface().(interface{ IsValid() bool }); ok && !c.IsValid() {
+			return fmt.Errorf("%s: invalid value %q", key, s)
+		}
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return configTypeError(key, "a boolean", v)
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := v.(int64)
+		if !ok {
+			return configTypeError(key, "an integer", v)
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%s: %d overflows %s", key, n, dst.Type())
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(int64)
+		if !ok {
+			return configTypeError(key, "an integer", v)
+		}
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%s: %d overflows %s", key, n, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		switch x := v.(type) {
+		case float64:
+			dst.SetFloat(x)
+		case int64:
+			dst.SetFloat(float64(x))
+		default:
+			return configTypeError(key, "a number", v)
+		}
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			return configTypeError(key, "an array", v)
+		}
+		items := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := range src.Len() {
+			if err := d.decode(items.Index(i), src.Index(i).Interface(), fmt.Sprintf("%s[%d]", key, i), keyPath); err != nil {
+				return err
+			}
+		}
+		dst.Set(items)
+	case reflect.Map:
+		table, ok := v.(map[string]any)
+		if !ok {
+			return configTypeError(key, "a table", v)
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(table))
+		for k, x := range table {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := d.decode(elem, x, configKey(key, k), configKey(keyPath, k)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), elem)
+		}
+		dst.Set(m)
+	case reflect.Struct:
+		table, ok := v.(map[string]any)
+		if !ok {
+			return configTypeError(key, "a table", v)
+		}
+		keys := make([]string, 0, len(table))
+		for k := range table {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		fields := make(map[string]int, dst.NumField())
+		for i := range dst.NumField() {
+			if name := dst.Type().Field(i).Tag.Get("toml"); name != "" {
+				fields[name] = i
+			}
+		}
+		for _, k := range keys {
+			i, ok := fields[k]
+			if !ok {
+				return fmt.Errorf("%s: unknown key", configKey(key, k))
+			}
+			var err error
+			if field, ok := strings.CutPrefix(dst.Type().Field(i).Tag.Get("cfgx"), "key="); ok {
+				err = d.decodeKeyed(dst.Field(i), table[k], field, configKey(key, k), configKey(keyPath, k))
+			} else {
+				err = d.decode(dst.Field(i), table[k], configKey(key, k), configKey(keyPath, k))
+			}
+			if err != nil {
+				return err
+			}
+			// Values are recorded, not the tables holding them, nor the
+			// elements of arrays, which are replaced as a whole
+			if _, isTable := table[k].(map[string]any); d.prov != nil && key == keyPath && (!isTable || dst.Field(i).Kind() != reflect.Struct) {
+				d.prov[configKey(key, k)] = d.layer
+			}
+		}
+	default:
+		return fmt.Errorf("%s: cannot decode into %s", key, dst.Type())
+	}
+	return nil
+}
+
+// decodeKeyed assigns v, the decoded TOML value of key, an array of tables
+// annotated cfgx:key=<field>, to dst, a map of its items by the value of
+// their field.
+func (d configDecoder) decodeKeyed(dst reflect.Value, v any, field, key, keyPath string) error {
+	items, ok := v.([]map[string]any)
+	if !ok {
+		return configTypeError(key, "an array of tables", v)
+	}
+	m := reflect.MakeMapWithSize(dst.Type(), len(items))
+	for i, item := range items {
+		itemKey := fmt.Sprintf("%s[%d]", key, i)
+		k, ok := item[field].(string)
+		if !ok {
+			return configTypeError(configKey(itemKey, field), "a string", item[field])
+		}
+		if m.MapIndex(reflect.ValueOf(k)).IsValid() {
+			return fmt.Errorf("%s: duplicate %s %q", itemKey, field, k)
+		}
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		if err := d.decode(elem, item, itemKey, keyPath); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(k), elem)
+	}
+	dst.Set(m)
+	return nil
+}
+
+// configKey appends k to the dotted key path prefix.
+func configKey(prefix, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
+
+// configTypeError reports a value of key that is not of the wanted type.
+func configTypeError(key, want string, v any) error {
+	return fmt.Errorf("%s: expected %s, got %T", key, want, v)
+}
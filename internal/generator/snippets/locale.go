@@ -0,0 +1,113 @@
+package snippets
+
+import "strings"
+
+// cfgx:snippet
+
+// Locale is a BCP 47 language tag such as "en-US" or "zh-Hant-TW".
+type Locale string
+
+// IsValid reports whether the locale is a well-formed BCP 47 language tag.
+// It checks the syntax only, not that each subtag is registered.
+func (l Locale) IsValid() bool {
+	subtags := strings.Split(string(l), "-")
+	if len(subtags) == 0 || subtags[0] == "" {
+		return false
+	}
+	if strings.EqualFold(subtags[0], "x") {
+		return localePrivateUse(subtags[1:])
+	}
+
+	// language: 2-3 letters with up to three 3-letter extlangs, or 4-8 letters
+	i := 1
+	switch n := len(subtags[0]); {
+	case !localeAlpha(subtags[0]):
+		return false
+	case n == 2 || n == 3:
+		for j := 0; j < 3 && i < len(subtags) && len(subtags[i]) == 3 && localeAlpha(subtags[i]); j++ {
+			i++
+		}
+	case n < 4 || n > 8:
+		return false
+	}
+
+	// script
+	if i < len(subtags) && len(subtags[i]) == 4 && localeAlpha(subtags[i]) {
+		i++
+	}
+	// region
+	if i < len(subtags) && ((len(subtags[i]) == 2 && localeAlpha(subtags[i])) || (len(subtags[i]) == 3 && localeDigits(subtags[i]))) {
+		i++
+	}
+	// variants
+	for i < len(subtags) && localeVariant(subtags[i]) {
+		i++
+	}
+	// extensions
+	for i < len(subtags) && len(subtags[i]) == 1 && !strings.EqualFold(subtags[i], "x") && localeAlnum(subtags[i]) {
+		i++
+		start := i
+		for i < len(subtags) && len(subtags[i]) >= 2 && len(subtags[i]) <= 8 && localeAlnum(subtags[i]) {
+			i++
+		}
+		if i == start {
+			return false
+		}
+	}
+	// private use
+	if i < len(subtags) && strings.EqualFold(subtags[i], "x") {
+		return localePrivateUse(subtags[i+1:])
+	}
+	return i == len(subtags)
+}
+
+// String returns the locale as written.
+func (l Locale) String() string {
+	return string(l)
+}
+
+func localePrivateUse(subtags []string) bool {
+	if len(subtags) == 0 {
+		return false
+	}
+	for _, s := range subtags {
+		if len(s) < 1 || len(s) > 8 || !localeAlnum(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func localeVariant(s string) bool {
+	if !localeAlnum(s) {
+		return false
+	}
+	return (len(s) >= 5 && len(s) <= 8) || (len(s) == 4 && s[0] >= '0' && s[0] <= '9')
+}
+
+func localeAlpha(s string) bool {
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func localeDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func localeAlnum(s string) bool {
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package snippets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cfgx:snippet
+
+// OrderedMap is a map that keeps its keys in the order they are written in
+// the config file, for tables annotated cfgx:ordered.
+type OrderedMap[V any] struct {
+	pairs []OrderedPair[V]
+	index map[string]int
+}
+
+// OrderedPair is a key of an OrderedMap and its value.
+type OrderedPair[V any] struct {
+	Key   string
+	Value V
+}
+
+// newOrderedMap returns the OrderedMap of pairs, in order.
+func newOrderedMap[V any](pairs []OrderedPair[V]) OrderedMap[V] {
+	index := make(map[string]int, len(pairs))
+	for i, p := range pairs {
+		index[p.Key] = i
+	}
+	return OrderedMap[V]{pairs: pairs, index: index}
+}
+
+// Get returns the value of key and whether m has it.
+func (m OrderedMap[V]) Get(key string) (V, bool) {
+	i, ok := m.index[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return m.pairs[i].Value, true
+}
+
+// Len returns the number of keys of m.
+func (m OrderedMap[V]) Len() int {
+	return len(m.pairs)
+}
+
+// Keys returns the keys of m, in order.
+func (m OrderedMap[V]) Keys() []string {
+	keys := make([]string, len(m.pairs))
+	for i, p := range m.pairs {
+		keys[i] = p.Key
+	}
+	return keys
+}
+
+// Pairs returns a copy of the keys of m and their values, in order.
+func (m OrderedMap[V]) Pairs() []OrderedPair[V] {
+	return append([]OrderedPair[V](nil), m.pairs...)
+}
+
+// String formats m as fmt formats maps, with its keys in order.
+func (m OrderedMap[V]) String() string {
+	var b strings.Builder
+	b.WriteString("map[")
+	for i, p := range m.pairs {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s:%v", p.Key, p.Value)
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package snippets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cfgx:snippet
+
+// xdgDefaults are the XDG base directories, relative to the home directory,
+// used when their environment variable is unset.
+var xdgDefaults = map[string]string{
+	"XDG_CONFIG_HOME": ".config",
+	"XDG_DATA_HOME":   ".local/share",
+	"XDG_STATE_HOME":  ".local/state",
+	"XDG_CACHE_HOME":  ".cache",
+}
+
+// expandPath expands a leading ~ to the home directory and environment
+// variables in s, where unset XDG base directory variables such as
+// $XDG_CONFIG_HOME take their default below the home directory, and returns
+// the cleaned absolute path. It returns "" if the home directory is needed
+// but unknown.
+func expandPath(s string) string {
+	home, err := os.UserHomeDir()
+	if s == "~" || strings.HasPrefix(s, "~/") || strings.HasPrefix(s, "~"+string(filepath.Separator)) {
+		if err != nil {
+			return ""
+		}
+		s = home + s[1:]
+	}
+
+	ok := true
+	s = os.Expand(s, func(name string) string {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+		if dir, isXDG := xdgDefaults[name]; isXDG {
+			ok = ok && err == nil
+			return filepath.Join(home, dir)
+		}
+		return ""
+	})
+	if !ok {
+		return ""
+	}
+
+	abs, err := filepath.Abs(s)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// pathParentExists reports whether the parent directory of path exists.
+func pathParentExists(path string) bool {
+	info, err := os.Stat(filepath.Dir(path))
+	return err == nil && info.IsDir()
+}
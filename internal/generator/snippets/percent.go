@@ -0,0 +1,28 @@
+package snippets
+
+import (
+	"strconv"
+	"strings"
+)
+
+// cfgx:snippet
+
+// parsePercent parses a percentage such as "12.5%" into a ratio (0.125), or
+// a plain ratio such as "0.125" as is. It rejects values outside 0–100%.
+func parsePercent(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	if p, ok := strings.CutSuffix(s, "%"); ok {
+		s = strings.TrimSpace(p)
+		scale = 100
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	f /= scale
+	if f < 0 || f > 1 {
+		return 0, false
+	}
+	return f, true
+}
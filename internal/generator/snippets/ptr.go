@@ -0,0 +1,8 @@
+package snippets
+
+// cfgx:snippet
+
+// ptr returns a pointer to a copy of v, for the fields of optional keys.
+func ptr[T any](v T) *T {
+	return &v
+}
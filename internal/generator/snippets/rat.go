@@ -0,0 +1,14 @@
+package snippets
+
+import "math/big"
+
+// cfgx:snippet
+
+// mustRat parses a decimal or fraction validated at generation time.
+func mustRat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("config: invalid decimal " + s)
+	}
+	return r
+}
@@ -0,0 +1,154 @@
+// Package snippets holds Go code that is copied verbatim into generated files
+// for annotated value types. Each snippet is an ordinary Go file of this
+// package, so it is compiled and tested here, and the generator validates
+// values at generation time with exactly the code that ships.
+//
+// Everything below the "// cfgx:snippet" marker line of a file is emitted;
+// the package clause and imports above it are not.
+package snippets
+
+import (
+	"context"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.go
+var files embed.FS
+
+// marker separates the part of a snippet file that is emitted.
+const marker = "// cfgx:snippet\n"
+
+// Source returns the emitted part of the named snippet file (without ".go").
+func Source(name string) string {
+	src, err := files.ReadFile(name + ".go")
+	if err != nil {
+		panic("snippets: unknown snippet " + name)
+	}
+	_, body, ok := strings.Cut(string(src), marker)
+	if !ok {
+		panic("snippets: " + name + ".go has no marker line")
+	}
+	return strings.TrimLeft(body, "\n")
+}
+
+// Imports returns the import paths the named snippet needs.
+func Imports(name string) []string {
+	src, err := files.ReadFile(name + ".go")
+	if err != nil {
+		panic("snippets: unknown snippet " + name)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), name+".go", src, parser.ImportsOnly)
+	if err != nil {
+		panic("snippets: " + err.Error())
+	}
+
+	var imports []string
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imports = append(imports, path)
+	}
+	return imports
+}
+
+// Decls returns the package-level identifiers the named snippet declares,
+// which generated code must not declare again, mapped to their kind: "type",
+// "function", "variable" or "constant". Methods are not included.
+func Decls(name string) map[string]string {
+	src, err := files.ReadFile(name + ".go")
+	if err != nil {
+		panic("snippets: unknown snippet " + name)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), name+".go", src, parser.SkipObjectResolution)
+	if err != nil {
+		panic("snippets: " + err.Error())
+	}
+
+	decls := make(map[string]string)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil {
+				decls[d.Name.Name] = "function"
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					decls[s.Name.Name] = "type"
+				case *ast.ValueSpec:
+					kind := "variable"
+					if d.Tok == token.CONST {
+						kind = "constant"
+					}
+					for _, n := range s.Names {
+						decls[n.Name] = kind
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// AssetContentType exposes the assetfile snippet to the generator.
+func AssetContentType(name string, content []byte) string {
+	return assetContentType(name, content)
+}
+
+// ParsePercent exposes the percent snippet to the generator.
+func ParsePercent(s string) (float64, bool) {
+	return parsePercent(s)
+}
+
+// ExpandPath exposes the path snippet to the generator.
+func ExpandPath(s string) string {
+	return expandPath(s)
+}
+
+// ValidFormat exposes the format snippet to the generator.
+func ValidFormat(format string, v any) bool {
+	switch format {
+	case "email":
+		s, ok := v.(string)
+		return ok && validEmail(s)
+	case "hostname":
+		s, ok := v.(string)
+		return ok && validHostname(s)
+	case "port":
+		n, ok := v.(int64)
+		return ok && validPort(n)
+	case "uuid":
+		s, ok := v.(string)
+		return ok && validUUID(s)
+	}
+	return false
+}
+
+// ParseEncrypted exposes the encrypted snippet to the generator.
+func ParseEncrypted(s string) error {
+	_, _, _, err := parseEncrypted(s)
+	return err
+}
+
+// Encrypt exposes the ciphers of the encrypted snippet to cfgx.Encrypt. It
+// returns the Encrypted value of plaintext.
+func Encrypt(ctx context.Context, name, keyID string, plaintext []byte) (Encrypted, error) {
+	ciphersMu.RLock()
+	c, ok := ciphers[name]
+	ciphersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown cipher %q", name)
+	}
+	ciphertext, err := c.Encrypt(ctx, keyID, plaintext)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt with %s key %s: %w", name, keyID, err)
+	}
+	return Encrypted(name + ":" + keyID + ":" + base64.StdEncoding.EncodeToString(ciphertext)), nil
+}
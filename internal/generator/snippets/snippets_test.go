@@ -0,0 +1,440 @@
+package snippets
+
+import (
+	"context"
+	"encoding/base64"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSource(t *testing.T) {
+	src := Source("cron")
+	require.True(t, strings.HasPrefix(src, "// CronSpec is"), "snippet should start after the marker")
+	require.NotContains(t, src, "package snippets")
+	require.NotContains(t, src, "\nimport")
+}
+
+func TestImports(t *testing.T) {
+	require.Equal(t, []string{"strconv", "strings"}, Imports("cron"))
+	require.Equal(t, []string{"math/big"}, Imports("rat"))
+}
+
+func TestDecls(t *testing.T) {
+	decls := Decls("cron")
+	require.Equal(t, "type", decls["CronSpec"])
+	require.Equal(t, "variable", decls["cronBounds"])
+	require.Equal(t, "function", decls["cronValue"])
+	require.NotContains(t, decls, "IsValid", "methods should not be included")
+	require.Equal(t, map[string]string{"ptr": "function"}, Decls("ptr"))
+}
+
+func TestCronSpec_IsValid(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"*/5 * * * *", true},
+		{"0 9 * * MON-FRI", true},
+		{"30 0 9 1,15 * *", true},
+		{"0 0 ? * SUN", true},
+		{"@daily", true},
+		{"@every 1h30m", true},
+		{"* * * *", false},
+		{"60 * * * *", false},
+		{"0 24 * * *", false},
+		{"0 0 0 * *", false},
+		{"*/0 * * * *", false},
+		{"0 9 * * FRI-MON", false},
+		{"@every", false},
+		{"@every 5x", false},
+		{"@sometimes", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, CronSpec(tt.spec).IsValid(), "CronSpec(%q).IsValid()", tt.spec)
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"10%", 0.1, true},
+		{" 12.5 % ", 0.125, true},
+		{"100%", 1, true},
+		{"0.25", 0.25, true},
+		{"101%", 0, false},
+		{"-1%", 0, false},
+		{"1.5", 0, false},
+		{"ten%", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParsePercent(tt.s)
+		require.Equal(t, tt.ok, ok, "ParsePercent(%q)", tt.s)
+		require.InDelta(t, tt.want, got, 1e-12, "ParsePercent(%q)", tt.s)
+	}
+}
+
+func TestLocale_IsValid(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"en", true},
+		{"en-US", true},
+		{"zh-Hant-TW", true},
+		{"es-419", true},
+		{"de-CH-1901", true},
+		{"sl-rozaj-biske", true},
+		{"en-US-u-ca-gregory", true},
+		{"en-x-internal", true},
+		{"x-whatever", true},
+		{"", false},
+		{"en_US", false},
+		{"e", false},
+		{"en-", false},
+		{"en-US-u", false},
+		{"en-toolongsubtag", false},
+		{"en-x", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, Locale(tt.tag).IsValid(), "Locale(%q).IsValid()", tt.tag)
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		v      any
+		want   bool
+	}{
+		{"email", "ops@example.com", true},
+		{"email", "Ops <ops@example.com>", false},
+		{"email", "example.com", false},
+		{"hostname", "db-1.internal.example.com", true},
+		{"hostname", "localhost", true},
+		{"hostname", "-bad.example.com", false},
+		{"hostname", "under_score.example.com", false},
+		{"port", int64(5432), true},
+		{"port", int64(0), false},
+		{"port", int64(70000), false},
+		{"port", "5432", false},
+		{"uuid", "123e4567-e89b-12d3-a456-426614174000", true},
+		{"uuid", "123e4567e89b12d3a456426614174000", false},
+		{"unknown", "x", false},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, ValidFormat(tt.format, tt.v), "ValidFormat(%q, %v)", tt.format, tt.v)
+	}
+}
+
+func TestAssetContentType(t *testing.T) {
+	require.Equal(t, "text/css; charset=utf-8", assetContentType("static/site.CSS", nil))
+	require.Equal(t, "image/png", assetContentType("logo", []byte("\x89PNG\r\n\x1a\n")))
+	require.Equal(t, "text/plain; charset=utf-8", assetContentType("README", []byte("hello")))
+
+	var a Asset
+	a.setFile("app.js", []byte("x"))
+	require.Equal(t, Asset{Content: []byte("x"), ContentType: "text/javascript; charset=utf-8"}, a)
+}
+
+func TestTimeZone(t *testing.T) {
+	require.True(t, TimeZone("America/New_York").IsValid())
+	require.True(t, TimeZone("UTC").IsValid())
+	require.False(t, TimeZone("Mars/Olympus").IsValid())
+	require.False(t, TimeZone("").IsValid())
+
+	loc, err := TimeZone("Europe/Paris").Location()
+	require.NoError(t, err)
+	require.Equal(t, "Europe/Paris", loc.String())
+	again, err := TimeZone("Europe/Paris").Location()
+	require.NoError(t, err)
+	require.Same(t, loc, again)
+}
+
+func TestEncrypted(t *testing.T) {
+	t.Setenv("CFGX_TEST_KEY", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	ctx := context.Background()
+
+	e, err := Encrypt(ctx, "aes", "CFGX_TEST_KEY", []byte("hunter2"))
+	require.NoError(t, err)
+	require.True(t, e.IsValid())
+	require.Equal(t, "aes", e.Cipher())
+	require.Equal(t, "CFGX_TEST_KEY", e.KeyID())
+	plaintext, err := e.Decrypt(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", plaintext)
+
+	// Key IDs may contain colons
+	_, keyID, _, err := parseEncrypted("kms:arn:aws:kms:us-east-1:111:key/abc:AAAA")
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:kms:us-east-1:111:key/abc", keyID)
+
+	require.False(t, Encrypted("aes:AAAA").IsValid())
+	require.False(t, Encrypted("aes:KEY:not base64").IsValid())
+	_, err = Encrypted("rot13:KEY:AAAA").Decrypt(ctx)
+	require.ErrorContains(t, err, `unknown cipher "rot13"`)
+	_, err = Encrypted("aes:CFGX_TEST_KEY:AAAA").Decrypt(ctx)
+	require.ErrorContains(t, err, "ciphertext too short")
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("CFGX_HOME", "/home/gopher")
+	t.Setenv("CFGX_EMPTY", "")
+
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"$CFGX_HOME/.app", "/home/gopher/.app"},
+		{"${CFGX_HOME}/.app", "/home/gopher/.app"},
+		{"${CFGX_UNSET:-/tmp}/app", "/tmp/app"},
+		{"${CFGX_EMPTY:-/tmp}/app", "/tmp/app"},
+		{"${CFGX_HOME:-/tmp}/app", "/home/gopher/app"},
+		{"${CFGX_UNSET:-$CFGX_HOME/.local}/app", "/home/gopher/.local/app"},
+		{"$CFGX_UNSET/app", "/app"},
+		{"plain", "plain"},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, expandEnv(tt.s), "expandEnv(%q)", tt.s)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("CFGX_APP", "app")
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"~", home},
+		{"~/.app", filepath.Join(home, ".app")},
+		{"$XDG_CONFIG_HOME/$CFGX_APP", filepath.Join(home, ".config", "app")},
+		{"${XDG_DATA_HOME}/app/../app2", "/data/app2"},
+		{"/etc//app/", "/etc/app"},
+		{"rel/dir", filepath.Join(wd, "rel", "dir")},
+		{"a~b", filepath.Join(wd, "a~b")},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, expandPath(tt.s), "expandPath(%q)", tt.s)
+	}
+}
+
+func TestPathParentExists(t *testing.T) {
+	dir := t.TempDir()
+	require.True(t, pathParentExists(filepath.Join(dir, "app.db")))
+	require.False(t, pathParentExists(filepath.Join(dir, "missing", "app.db")))
+}
+
+func TestOrderedMap(t *testing.T) {
+	m := newOrderedMap([]OrderedPair[int]{{Key: "b", Value: 2}, {Key: "a", Value: 1}})
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, []string{"b", "a"}, m.Keys())
+	v, ok := m.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	_, ok = m.Get("c")
+	require.False(t, ok)
+	require.Equal(t, "map[b:2 a:1]", m.String())
+
+	pairs := m.Pairs()
+	pairs[0].Value = 3
+	v, _ = m.Get("b")
+	require.Equal(t, 2, v)
+}
+
+func TestLoadConfig(t *testing.T) {
+	type serverConfig struct {
+		Addr    string        `toml:"addr"`
+		Cert    []byte        `toml:"cert"`
+		Timeout time.Duration `toml:"timeout"`
+		Workers int8          `toml:"workers"`
+	}
+	type itemConfig struct {
+		Name   string   `toml:"name"`
+		Weight *big.Rat `toml:"weight"`
+	}
+	type config struct {
+		Name     string                `toml:"name"`
+		Schedule CronSpec              `toml:"schedule"`
+		Server   serverConfig          `toml:"server"`
+		Items    []itemConfig          `toml:"items"`
+		Labels   map[string]string     `toml:"labels"`
+		Users    map[string]itemConfig `toml:"users" cfgx:"key=name"`
+		CA       Asset                 `toml:"ca"`
+		Ratio    float64               `toml:"ratio"`
+		Budget   float64               `toml:"budget"`
+	}
+
+	fsys := fstest.MapFS{
+		"conf/app.toml": {Data: []byte(`
+schedule = "@daily"
+ratio = 1
+budget = "10%"
+ca = "file:certs/ca.pem"
+
+[server]
+cert = "file:certs/ca.pem"
+timeout = "5s"
+
+[labels]
+team = "core"
+
+[[items]]
+name = "a"
+weight = "1/3"
+
+[[users]]
+name = "b"
+weight = "1/2"
+`)},
+		"conf/certs/ca.pem": {Data: []byte("PEM")},
+	}
+
+	c := config{Name: "svc", Server: serverConfig{Addr: ":8080", Workers: 4}}
+	parsers := map[string]func(string) any{
+		"budget": func(v string) any {
+			if r, ok := parsePercent(v); ok {
+				return r
+			}
+			return nil
+		},
+	}
+	prov := make(map[string]string)
+	require.NoError(t, loadFSConfig(&c, fsys, "conf/app.toml", parsers, prov))
+	require.Equal(t, map[string]string{
+		"budget": "file", "ca": "file", "items": "file", "labels": "file", "ratio": "file", "schedule": "file",
+		"server.cert": "file", "server.timeout": "file", "users": "file",
+	}, prov)
+	require.Equal(t, "svc", c.Name)
+	require.Equal(t, CronSpec("@daily"), c.Schedule)
+	require.Equal(t, serverConfig{Addr: ":8080", Cert: []byte("PEM"), Timeout: 5 * time.Second, Workers: 4}, c.Server)
+	require.Len(t, c.Items, 1)
+	require.Equal(t, "1/3", c.Items[0].Weight.RatString())
+	require.Equal(t, map[string]string{"team": "core"}, c.Labels)
+	require.Equal(t, "1/2", c.Users["b"].Weight.RatString())
+	require.Equal(t, Asset{Content: []byte("PEM"), ContentType: "application/x-pem-file"}, c.CA)
+	require.Equal(t, 1.0, c.Ratio)
+	require.Equal(t, 0.1, c.Budget)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.toml")
+	require.NoError(t, os.WriteFile(file, []byte("[server]\naddr = \":9090\"\n"), 0644))
+	require.NoError(t, loadOSConfig(&c, file, parsers, nil))
+	require.Equal(t, ":9090", c.Server.Addr)
+
+	tests := []struct {
+		toml string
+		want string
+	}{
+		{"[server]\nport = 80\n", "server.port: unknown key"},
+		{"[server]\ntimeout = 5\n", "server.timeout: expected a duration string, got int64"},
+		{"[server]\nworkers = 300\n", "server.workers: 300 overflows int8"},
+		{"[server]\ncert = \"PEM\"\n", "server.cert: expected a file: reference, got string"},
+		{"schedule = \"daily\"\n", `schedule: invalid value "daily"`},
+		{"[[items]]\nweight = \"x\"\n", "items[0].weight:"},
+		{"[[users]]\nname = \"a\"\n[[users]]\nname = \"a\"\n", `users[1]: duplicate name "a"`},
+		{"[[users]]\nweight = \"1\"\n", "users[0].name: expected a string, got <nil>"},
+		{"name = [\"a\"]\n", "name: expected a string, got []interface {}"},
+		{"budget = \"lots\"\n", `budget: invalid value "lots"`},
+		{"name = \n", "app.toml: toml:"},
+	}
+	for _, tt := range tests {
+		require.NoError(t, os.WriteFile(file, []byte(tt.toml), 0644))
+		err := loadOSConfig(&c, file, parsers, nil)
+		require.ErrorContains(t, err, tt.want, tt.toml)
+		require.ErrorContains(t, err, file)
+	}
+}
+
+func TestConfigLayers(t *testing.T) {
+	type serverConfig struct {
+		Addr    string        `toml:"addr"`
+		Timeout time.Duration `toml:"timeout"`
+		Port    uint16        `toml:"port"`
+	}
+	type itemConfig struct {
+		Name string `toml:"name"`
+	}
+	type config struct {
+		Debug  bool              `toml:"debug"`
+		Hosts  []string          `toml:"hosts"`
+		Ratios []float64         `toml:"ratios"`
+		Server serverConfig      `toml:"server"`
+		Items  []itemConfig      `toml:"items"`
+		Labels map[string]string `toml:"labels"`
+		Weight *big.Rat          `toml:"weight"`
+	}
+	parsers := map[string]func(string) any{
+		"ratios": func(v string) any {
+			if r, ok := parsePercent(v); ok {
+				return r
+			}
+			return nil
+		},
+	}
+
+	c := config{Server: serverConfig{Addr: ":8080", Port: 80}, Hosts: []string{"a", "b"}}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	defineConfigFlags(fs, &c)
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	require.Equal(t, []string{"debug", "hosts", "ratios", "server.addr", "server.port", "server.timeout", "weight"}, names)
+	require.Equal(t, "a,b", fs.Lookup("hosts").DefValue)
+	require.Equal(t, ":8080", fs.Lookup("server.addr").DefValue)
+
+	t.Setenv("CONFIG_SERVER_ADDR", ":9090")
+	t.Setenv("CONFIG_SERVER_TIMEOUT", "5s")
+	t.Setenv("CONFIG_DEBUG", "")
+	require.NoError(t, fs.Parse([]string{"-server.addr=:7070", "-hosts", "x, y", "-ratios=10%,50%", "-weight=1/3"}))
+
+	prov := make(map[string]string)
+	require.NoError(t, applyConfigLayer(&c, envConfigLayer("CONFIG"), parsers, prov))
+	require.NoError(t, applyConfigLayer(&c, flagConfigLayer(fs), parsers, prov))
+	require.Equal(t, map[string]string{
+		"hosts": "flag", "ratios": "flag", "server.addr": "flag", "server.timeout": "env", "weight": "flag",
+	}, prov)
+	require.False(t, c.Debug)
+	require.Equal(t, []string{"x", "y"}, c.Hosts)
+	require.Equal(t, []float64{0.1, 0.5}, c.Ratios)
+	require.Equal(t, serverConfig{Addr: ":7070", Timeout: 5 * time.Second, Port: 80}, c.Server)
+	require.Equal(t, "1/3", c.Weight.RatString())
+
+	tests := []struct {
+		env, value, want string
+	}{
+		{"CONFIG_SERVER_PORT", "http", `invalid value for CONFIG_SERVER_PORT (server.port): strconv.ParseInt: parsing "http"`},
+		{"CONFIG_SERVER_PORT", "70000", "invalid value for CONFIG_SERVER_PORT (server.port): server.port: 70000 overflows uint16"},
+		{"CONFIG_RATIOS", "1%,lots", `invalid value for CONFIG_RATIOS (ratios): element 1: ratios: invalid value "lots"`},
+		{"CONFIG_DEBUG", "maybe", "invalid value for CONFIG_DEBUG (debug): strconv.ParseBool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env+"="+tt.value, func(t *testing.T) {
+			t.Setenv(tt.env, tt.value)
+			err := applyConfigLayer(&c, envConfigLayer("CONFIG"), parsers, make(map[string]string))
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package snippets
+
+import (
+	"sync"
+	"time"
+)
+
+// cfgx:snippet
+
+// TimeZone is the name of an IANA time zone, such as "America/New_York".
+type TimeZone string
+
+// timeZones caches the locations of the time zones loaded, by name.
+var timeZones sync.Map
+
+// Location returns the time zone, loaded from the tz database on first use.
+// Programs running where there is none, such as in scratch containers, can
+// embed one by importing time/tzdata.
+func (z TimeZone) Location() (*time.Location, error) {
+	if loc, ok := timeZones.Load(z); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(string(z))
+	if err != nil {
+		return nil, err
+	}
+	timeZones.Store(z, loc)
+	return loc, nil
+}
+
+// IsValid reports whether the time zone is in the tz database.
+func (z TimeZone) IsValid() bool {
+	if z == "" {
+		return false
+	}
+	_, err := z.Location()
+	return err == nil
+}
+
+// String returns the name of the time zone.
+func (z TimeZone) String() string {
+	return string(z)
+}
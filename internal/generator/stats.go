@@ -0,0 +1,111 @@
+package generator
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// maxHeaviestKeys is the number of keys reported in Stats.HeaviestKeys.
+const maxHeaviestKeys = 10
+
+// Stats reports where time and output size went during a single generation run.
+type Stats struct {
+	ParseTime    time.Duration // Time spent decoding TOML
+	AnalysisTime time.Duration // Time spent validating file references and resolving imports
+	EmitTime     time.Duration // Time spent writing and formatting Go code
+
+	Structs       int   // Number of distinct struct types
+	Fields        int   // Number of distinct struct fields
+	EmbeddedBytes int64 // Total size of files embedded via file: references
+	OutputBytes   int   // Size of the formatted output
+
+	// HeaviestKeys lists the keys with the largest emitted value literals, largest first.
+	HeaviestKeys []KeyWeight
+}
+
+// KeyWeight is the size of the Go literal emitted for a single key.
+type KeyWeight struct {
+	Key   string
+	Bytes int
+}
+
+// WithStats enables collection of generation statistics into s.
+func WithStats(s *Stats) Option {
+	return func(g *Generator) {
+		g.stats = s
+	}
+}
+
+// collectStats fills in the size-related statistics for data. It re-renders every
+// leaf value into a scratch buffer, so it is only run when statistics are requested.
+func (g *Generator) collectStats(data map[string]any) {
+	tables := make(map[string]bool)
+	fields := make(map[string]bool)
+	weights := make(map[string]int)
+
+	var scratch bytes.Buffer
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		if prefix != "" {
+			tables[prefix] = true
+		}
+		for k, v := range m {
+			key := joinKey(prefix, k)
+			if prefix != "" {
+				fields[key] = true
+			}
+
+			switch val := v.(type) {
+			case map[string]any:
+				walk(key, val)
+				continue
+			case []map[string]any:
+				for _, item := range val {
+					walk(key, item)
+				}
+				continue
+			case []any:
+				if len(val) > 0 {
+					if _, ok := val[0].(map[string]any); ok {
+						for _, item := range val {
+							if itemMap, ok := item.(map[string]any); ok {
+								walk(key, itemMap)
+							}
+						}
+						continue
+					}
+				}
+			case string:
+				if g.isFileReference(val) {
+					if content, err := g.loadFileContent(val); err == nil {
+						g.stats.EmbeddedBytes += int64(len(content))
+					}
+				}
+			}
+
+			scratch.Reset()
+			g.writeValue(&scratch, v)
+			weights[key] += scratch.Len()
+		}
+	}
+	walk("", data)
+
+	g.stats.Structs = len(tables)
+	g.stats.Fields = len(fields)
+
+	heaviest := make([]KeyWeight, 0, len(weights))
+	for key, n := range weights {
+		heaviest = append(heaviest, KeyWeight{Key: key, Bytes: n})
+	}
+	sort.Slice(heaviest, func(i, j int) bool {
+		if heaviest[i].Bytes != heaviest[j].Bytes {
+			return heaviest[i].Bytes > heaviest[j].Bytes
+		}
+		return heaviest[i].Key < heaviest[j].Key
+	})
+	if len(heaviest) > maxHeaviestKeys {
+		heaviest = heaviest[:maxHeaviestKeys]
+	}
+	g.stats.HeaviestKeys = heaviest
+}
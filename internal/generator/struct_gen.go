@@ -3,7 +3,9 @@ package generator
 import (
 	"bytes"
 	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gomantics/sx"
@@ -22,22 +24,18 @@ import (
 // ensuring proper naming conventions (e.g., "DatabaseConfig", "ServersItem") and
 // correct type references.
 func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]any) error {
-	keys := make([]string, 0, len(data))
-	for k := range data {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys) // deterministic output
+	keys := g.sortedKeys("", data)
 
 	allStructs := make(map[string]map[string]any)
 	for _, key := range keys {
 		if m, ok := data[key].(map[string]any); ok {
-			structName := sx.PascalCase(key) + "Config"
-			g.collectNestedStructs(allStructs, structName, m)
-		} else if arr, ok := data[key].([]map[string]any); ok {
-			if len(arr) > 0 {
-				structName := sx.PascalCase(key) + "Item"
-				g.collectNestedStructs(allStructs, structName, arr[0])
-			}
+			structName := g.pascalCase(key) + "Config"
+			g.collectNestedStructs(allStructs, structName, key, m)
+		} else if items, ok := tables(data[key]); ok {
+			structName := g.pascalCase(key) + "Item"
+			g.collectNestedStructs(allStructs, structName, key, mergeItems(items))
+		} else if m, ok := data[key].(*mapTable); ok && m.fields != nil {
+			g.collectNestedStructs(allStructs, m.elem, key, m.fields)
 		}
 	}
 
@@ -55,15 +53,24 @@ func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]an
 		buf.WriteString("\n\n")
 	}
 
+	if g.compact() {
+		if err := g.writeCompact(buf, data); err != nil {
+			return err
+		}
+		g.writeRuntimeOnly(buf)
+		return nil
+	}
+
 	buf.WriteString("var (\n")
 
 	for _, key := range keys {
-		varName := sx.PascalCase(key)
+		varName := g.fieldName(key)
 		value := data[key]
 
+		g.writeFieldDoc(buf, key, "\t")
 		switch val := value.(type) {
 		case map[string]any:
-			structName := sx.PascalCase(key) + "Config"
+			structName := g.pascalCase(key) + "Config"
 			fmt.Fprintf(buf, "\t%s = %s", varName, structName)
 			if err := g.generateStructInit(buf, structName, val, 0); err != nil {
 				return err
@@ -71,19 +78,19 @@ func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]an
 			buf.WriteString("\n")
 		case []map[string]any:
 			if len(val) > 0 {
-				structName := sx.PascalCase(key) + "Item"
+				structName := g.pascalCase(key) + "Item"
 				fmt.Fprintf(buf, "\t%s = []%s", varName, structName)
 				if err := g.writeArrayOfTablesInit(buf, structName, val, 0); err != nil {
 					return err
 				}
 				buf.WriteString("\n")
 			} else {
-				fmt.Fprintf(buf, "\t%s []%sItem\n", varName, sx.PascalCase(key))
+				fmt.Fprintf(buf, "\t%s []%sItem\n", varName, g.pascalCase(key))
 			}
 		case []any:
 			if len(val) > 0 {
 				if _, ok := val[0].(map[string]any); ok {
-					structName := sx.PascalCase(key) + "Item"
+					structName := g.pascalCase(key) + "Item"
 					fmt.Fprintf(buf, "\t%s = []%s", varName, structName)
 					if err := g.writeArrayOfTablesInit(buf, structName, val, 0); err != nil {
 						return err
@@ -93,6 +100,7 @@ func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]an
 					goType := g.toGoType(value)
 					fmt.Fprintf(buf, "\t%s %s = ", varName, goType)
 					g.writeValue(buf, value)
+					g.writeOverriddenNote(buf, key)
 					buf.WriteString("\n")
 				}
 			} else {
@@ -101,14 +109,16 @@ func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]an
 			}
 		default:
 			// Generate simple variable
-			goType := g.toGoType(value)
+			goType := g.fieldType(key, value)
 			fmt.Fprintf(buf, "\t%s %s = ", varName, goType)
-			g.writeValue(buf, value)
+			g.writeKeyValue(buf, key, value, 0)
+			g.writeOverriddenNote(buf, key)
 			buf.WriteString("\n")
 		}
 	}
 
 	buf.WriteString(")\n")
+	g.writeRuntimeOnly(buf)
 
 	return nil
 }
@@ -121,34 +131,33 @@ func (g *Generator) generateStructsAndVars(buf *bytes.Buffer, data map[string]an
 // (e.g., "DatabaseConfig" -> "DatabaseCredentialsConfig" for nested credentials).
 // It handles:
 //   - Nested maps (inline tables) - suffixed with "Config"
-//   - Arrays of maps (array of tables) - suffixed with "Item"
+//   - Arrays of maps (array of tables) - suffixed with "Item", with the fields
+//     of all items, which may each set a different subset of them
 //
 // The structs map is populated with name->fields mapping, ensuring each struct type
-// is only processed once (deduplication via existence check).
-func (g *Generator) collectNestedStructs(structs map[string]map[string]any, name string, data map[string]any) {
+// is only processed once (deduplication via existence check). The dotted TOML key
+// path of each struct is recorded in g.structKeys for annotation lookups.
+func (g *Generator) collectNestedStructs(structs map[string]map[string]any, name, keyPath string, data map[string]any) {
 	if _, exists := structs[name]; exists {
 		return
 	}
 
 	structs[name] = data
+	g.structKeys[name] = keyPath
 
 	for key, val := range data {
 		switch v := val.(type) {
 		case map[string]any:
-			nestedName := stripSuffix(name) + sx.PascalCase(key) + "Config"
-			g.collectNestedStructs(structs, nestedName, v)
-		case []any:
-			// Check if it's an array of maps
-			if len(v) > 0 {
-				if m, ok := v[0].(map[string]any); ok {
-					nestedName := stripSuffix(name) + sx.PascalCase(key) + "Item"
-					g.collectNestedStructs(structs, nestedName, m)
-				}
+			nestedName := stripSuffix(name) + g.pascalCase(key) + "Config"
+			g.collectNestedStructs(structs, nestedName, joinKey(keyPath, key), v)
+		case *mapTable:
+			if v.fields != nil {
+				g.collectNestedStructs(structs, v.elem, joinKey(keyPath, key), v.fields)
 			}
-		case []map[string]any:
-			if len(v) > 0 {
-				nestedName := stripSuffix(name) + sx.PascalCase(key) + "Item"
-				g.collectNestedStructs(structs, nestedName, v[0])
+		default:
+			if items, ok := tables(v); ok {
+				nestedName := stripSuffix(name) + g.pascalCase(key) + "Item"
+				g.collectNestedStructs(structs, nestedName, joinKey(keyPath, key), mergeItems(items))
 			}
 		}
 	}
@@ -162,37 +171,66 @@ func (g *Generator) collectNestedStructs(structs map[string]map[string]any, name
 // struct name to maintain uniqueness (e.g., "DatabaseConfig" with a "server" field
 // becomes "DatabaseConfigServerConfig" type).
 //
-// Fields are sorted alphabetically for deterministic output.
+// Fields are sorted alphabetically, or in source order if preserving order.
 func (g *Generator) generateStruct(buf *bytes.Buffer, name string, fields map[string]any) error {
+	g.writeTypeDoc(buf, name)
 	fmt.Fprintf(buf, "type %s struct {\n", name)
 
-	fieldNames := make([]string, 0, len(fields))
-	for k := range fields {
-		fieldNames = append(fieldNames, k)
-	}
-	sort.Strings(fieldNames)
+	fieldNames := g.sortedKeys(g.structKeys[name], fields)
 
 	for _, fieldName := range fieldNames {
 		value := fields[fieldName]
-		goFieldName := sx.PascalCase(fieldName)
-		goType := g.toGoType(value)
+		goFieldName := g.fieldName(joinKey(g.structKeys[name], fieldName))
+		goType := g.fieldType(joinKey(g.structKeys[name], fieldName), value)
 
 		// Handle nested structs - prefix with parent struct name
 		if _, ok := value.(map[string]any); ok {
-			goType = stripSuffix(name) + sx.PascalCase(fieldName) + "Config"
+			goType = stripSuffix(name) + g.pascalCase(fieldName) + "Config"
 		} else if arr, ok := value.([]any); ok && len(arr) > 0 {
 			if _, isMap := arr[0].(map[string]any); isMap {
-				goType = "[]" + stripSuffix(name) + sx.PascalCase(fieldName) + "Item"
+				goType = "[]" + stripSuffix(name) + g.pascalCase(fieldName) + "Item"
 			}
 		} else if arr, ok := value.([]map[string]any); ok && len(arr) > 0 {
-			goType = "[]" + stripSuffix(name) + sx.PascalCase(fieldName) + "Item"
+			goType = "[]" + stripSuffix(name) + g.pascalCase(fieldName) + "Item"
 		}
 
-		fmt.Fprintf(buf, "\t%s %s\n", goFieldName, goType)
+		g.writeFieldDoc(buf, joinKey(g.structKeys[name], fieldName), "\t")
+		fmt.Fprintf(buf, "\t%s %s%s\n", goFieldName, goType, g.structTag(fieldName, value))
 	}
 
 	buf.WriteString("}")
-	return nil
+	return g.writeRedacted(buf, name, fields)
+}
+
+// WithStructTags writes a tag for each of the given keys, such as "json" or
+// "yaml", on every generated struct field, naming the field by its TOML key so
+// the config marshals back to its original shape.
+func WithStructTags(tags []string) Option {
+	return func(g *Generator) {
+		g.structTags = tags
+	}
+}
+
+// structTag returns the struct tag, preceded by a space, of the field for the
+// TOML key of the given value, or "" if no tags are configured. Loader mode
+// always tags fields with toml, which its decoder reads, and maps of arrays
+// of tables annotated cfgx:key with the field they are keyed by.
+func (g *Generator) structTag(key string, value any) string {
+	tags := g.structTags
+	if g.mode == "loader" && !slices.Contains(tags, "toml") {
+		tags = append([]string{"toml"}, tags...)
+	}
+	if len(tags) == 0 {
+		return ""
+	}
+	parts := make([]string, len(tags))
+	for i, tag := range tags {
+		parts[i] = tag + ":" + strconv.Quote(key)
+	}
+	if m, ok := value.(*mapTable); ok && m.key != "" && g.mode == "loader" {
+		parts = append(parts, "cfgx:"+strconv.Quote("key="+m.key))
+	}
+	return " `" + strings.Join(parts, " ") + "`"
 }
 
 // generateStructInit generates struct initialization code with proper indentation
@@ -200,52 +238,49 @@ func (g *Generator) generateStruct(buf *bytes.Buffer, name string, fields map[st
 // syntax for complex nested structures.
 //
 // For nested maps, it generates inline struct literals with the appropriate type name.
-// For arrays of structs, it delegates to writeArrayOfStructs or handles simple arrays.
+// For arrays of tables, it writes a slice literal of the item type, whose elements are
+// initialized recursively. Other arrays are written as simple values.
 // Simple values are written as literals using writeValue.
 //
 // The indent parameter controls the indentation level for proper formatting of nested
-// structures. Fields are sorted alphabetically for deterministic output.
+// structures. Fields are sorted as in generateStruct.
 func (g *Generator) generateStructInit(buf *bytes.Buffer, parentStructName string, data map[string]any, indent int) error {
 	buf.WriteString("{\n")
 
-	keys := make([]string, 0, len(data))
-	for k := range data {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys) // deterministic output
+	keys := g.sortedKeys(g.structKeys[parentStructName], data)
 
 	indentStr := strings.Repeat("\t", indent+1)
 	for _, key := range keys {
 		value := data[key]
-		fieldName := sx.PascalCase(key)
+		fieldName := g.fieldName(joinKey(g.structKeys[parentStructName], key))
 
 		buf.WriteString(indentStr)
 		fmt.Fprintf(buf, "%s: ", fieldName)
 
 		switch val := value.(type) {
 		case map[string]any:
-			structType := stripSuffix(parentStructName) + sx.PascalCase(key) + "Config"
+			structType := stripSuffix(parentStructName) + g.pascalCase(key) + "Config"
 			buf.WriteString(structType)
 			if err := g.generateStructInit(buf, structType, val, indent+1); err != nil {
 				return err
 			}
-		case []any:
-			if len(val) > 0 {
-				if _, ok := val[0].(map[string]any); ok {
-					g.writeArrayOfStructs(buf, val, indent+1)
-				} else {
-					g.writeValueWithIndent(buf, value, indent+1)
-				}
-			} else {
+		case []any, []map[string]any:
+			if _, ok := tables(value); !ok {
 				g.writeValueWithIndent(buf, value, indent+1)
+				break
+			}
+			itemType := stripSuffix(parentStructName) + g.pascalCase(key) + "Item"
+			buf.WriteString("[]" + itemType)
+			if err := g.writeArrayOfTablesInit(buf, itemType, val, indent+1); err != nil {
+				return err
 			}
-		case []map[string]any:
-			g.writeArrayOfStructs(buf, val, indent+1)
 		default:
-			g.writeValueWithIndent(buf, value, indent+1)
+			g.writeKeyValue(buf, joinKey(g.structKeys[parentStructName], key), value, indent+1)
 		}
 
-		buf.WriteString(",\n")
+		buf.WriteString(",")
+		g.writeOverriddenNote(buf, joinKey(g.structKeys[parentStructName], key))
+		buf.WriteString("\n")
 	}
 
 	buf.WriteString(strings.Repeat("\t", indent))
@@ -301,94 +336,23 @@ func (g *Generator) writeArrayOfTablesInit(buf *bytes.Buffer, structName string,
 	return nil
 }
 
-// writeArrayOfStructs writes an array of struct initializations using compact inline
-// syntax. Unlike writeArrayOfTablesInit, this generates inline struct literals without
-// the type name prefix, making it more suitable for deeply nested structures.
-//
-// Example output:
-//
-//	{
-//	    {Host: "localhost", Port: 8080},
-//	    {Host: "example.com", Port: 443},
-//	}
-//
-// Fields within each struct are written in sorted order and separated by commas on a
-// single line. This function handles both []any and []map[string]any input types.
-func (g *Generator) writeArrayOfStructs(buf *bytes.Buffer, arr any, indent int) {
-	buf.WriteString("{\n")
-	indentStr := strings.Repeat("\t", indent+1)
-
-	switch val := arr.(type) {
-	case []any:
-		for _, item := range val {
-			if m, ok := item.(map[string]any); ok {
-				buf.WriteString(indentStr)
-				buf.WriteString("{")
-				// Inline struct fields
-				keys := make([]string, 0, len(m))
-				for k := range m {
-					keys = append(keys, k)
-				}
-				sort.Strings(keys)
-
-				for i, k := range keys {
-					if i > 0 {
-						buf.WriteString(", ")
-					}
-					buf.WriteString(sx.PascalCase(k))
-					buf.WriteString(": ")
-					g.writeValue(buf, m[k])
-				}
-				buf.WriteString("},\n")
-			}
-		}
-	case []map[string]any:
-		for _, m := range val {
-			buf.WriteString(indentStr)
-			buf.WriteString("{")
-			// Inline struct fields
-			keys := make([]string, 0, len(m))
-			for k := range m {
-				keys = append(keys, k)
-			}
-			sort.Strings(keys)
-
-			for i, k := range keys {
-				if i > 0 {
-					buf.WriteString(", ")
-				}
-				buf.WriteString(sx.PascalCase(k))
-				buf.WriteString(": ")
-				g.writeValue(buf, m[k])
-			}
-			buf.WriteString("},\n")
-		}
-	}
-
-	buf.WriteString(strings.Repeat("\t", indent))
-	buf.WriteString("}")
-}
-
 // generateStructsAndGetters generates empty struct types and getter methods for getter mode.
 // This is an alternative to generateStructsAndVars that creates methods instead of fields.
 func (g *Generator) generateStructsAndGetters(buf *bytes.Buffer, data map[string]any) error {
-	keys := make([]string, 0, len(data))
-	for k := range data {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys) // deterministic output
+	keys := g.sortedKeys("", data)
 
-	// Collect all struct names
+	// Collect all struct names. Items of arrays of tables get field structs,
+	// as in static mode, since their values are baked into slices.
 	allStructs := make(map[string]map[string]any)
+	itemStructs := make(map[string]map[string]any)
 	for _, key := range keys {
 		if m, ok := data[key].(map[string]any); ok {
-			structName := sx.CamelCase(key) + "Config"
-			g.collectNestedStructsForGetters(allStructs, structName, m)
-		} else if arr, ok := data[key].([]map[string]any); ok {
-			if len(arr) > 0 {
-				structName := sx.CamelCase(key) + "Item"
-				g.collectNestedStructsForGetters(allStructs, structName, arr[0])
-			}
+			structName := g.camelCase(key) + "Config"
+			g.collectNestedStructsForGetters(allStructs, itemStructs, structName, key, m)
+		} else if items, ok := tables(data[key]); ok {
+			g.collectNestedStructs(itemStructs, g.camelCase(key)+"Item", key, mergeItems(items))
+		} else if m, ok := data[key].(*mapTable); ok && m.fields != nil {
+			g.collectNestedStructs(itemStructs, m.elem, key, m.fields)
 		}
 	}
 
@@ -400,9 +364,23 @@ func (g *Generator) generateStructsAndGetters(buf *bytes.Buffer, data map[string
 	sort.Strings(structNames)
 
 	for _, name := range structNames {
+		g.writeTypeDoc(buf, name)
 		fmt.Fprintf(buf, "type %s struct{}\n\n", name)
 	}
 
+	itemNames := make([]string, 0, len(itemStructs))
+	for name := range itemStructs {
+		itemNames = append(itemNames, name)
+	}
+	sort.Strings(itemNames)
+
+	for _, name := range itemNames {
+		if err := g.generateStruct(buf, name, itemStructs[name]); err != nil {
+			return err
+		}
+		buf.WriteString("\n\n")
+	}
+
 	// Generate getter methods for each struct
 	generated := make(map[string]bool)
 	for _, name := range structNames {
@@ -412,56 +390,33 @@ func (g *Generator) generateStructsAndGetters(buf *bytes.Buffer, data map[string
 		}
 	}
 
-	// Generate top-level getter functions for simple variables
+	// Generate top-level getter functions for simple variables and arrays of tables
 	for _, key := range keys {
 		value := data[key]
-
-		// Only generate getters for non-struct, non-array-of-structs values
-		switch val := value.(type) {
-		case map[string]any, []map[string]any:
+		if _, ok := value.(map[string]any); ok {
 			// Skip structs - they will be var declarations
 			continue
-		case []any:
-			// Check if it's an array of maps (structs)
-			if len(val) > 0 {
-				if _, ok := val[0].(map[string]any); ok {
-					// Skip array of structs
-					continue
-				}
-			}
-			// Generate getter for array of primitives
-			if err := g.generateTopLevelGetter(buf, key, value); err != nil {
-				return err
-			}
-		default:
-			// Generate getter for simple types
-			if err := g.generateTopLevelGetter(buf, key, value); err != nil {
+		}
+		if items, ok := tables(value); ok {
+			itemType := g.camelCase(key) + "Item"
+			g.writeFieldDoc(buf, key, "")
+			signature := fmt.Sprintf("func %s() []%s", g.fieldName(key), itemType)
+			if err := g.writeTablesGetter(buf, signature, key, itemType, g.envPrefix+"_"+strings.ToUpper(key), items); err != nil {
 				return err
 			}
+			continue
+		}
+		if err := g.generateTopLevelGetter(buf, key, value); err != nil {
+			return err
 		}
 	}
 
-	// Generate var declarations (only for structs and arrays of structs)
+	// Generate var declarations (only for structs)
 	buf.WriteString("var (\n")
 	for _, key := range keys {
-		varName := sx.PascalCase(key)
-		value := data[key]
-
-		switch val := value.(type) {
-		case map[string]any:
-			structName := sx.CamelCase(key) + "Config"
-			fmt.Fprintf(buf, "\t%s %s\n", varName, structName)
-		case []map[string]any:
-			structName := sx.CamelCase(key) + "Item"
-			fmt.Fprintf(buf, "\t%s []%s\n", varName, structName)
-		case []any:
-			// Check if it's an array of maps (structs)
-			if len(val) > 0 {
-				if _, ok := val[0].(map[string]any); ok {
-					structName := sx.CamelCase(key) + "Item"
-					fmt.Fprintf(buf, "\t%s []%s\n", varName, structName)
-				}
-			}
+		if _, ok := data[key].(map[string]any); ok {
+			g.writeFieldDoc(buf, key, "\t")
+			fmt.Fprintf(buf, "\t%s %s\n", g.fieldName(key), g.camelCase(key)+"Config")
 		}
 	}
 	buf.WriteString(")\n")
@@ -470,30 +425,25 @@ func (g *Generator) generateStructsAndGetters(buf *bytes.Buffer, data map[string
 }
 
 // collectNestedStructsForGetters is similar to collectNestedStructs but for getter mode.
-func (g *Generator) collectNestedStructsForGetters(structs map[string]map[string]any, name string, data map[string]any) {
+// The items of arrays of tables, and the tables nested in them, are collected into
+// items instead, as field structs.
+func (g *Generator) collectNestedStructsForGetters(structs, items map[string]map[string]any, name, keyPath string, data map[string]any) {
 	if _, exists := structs[name]; exists {
 		return
 	}
 
 	structs[name] = data
+	g.structKeys[name] = keyPath
 
 	for key, val := range data {
-		switch v := val.(type) {
-		case map[string]any:
-			nestedName := stripSuffix(name) + sx.CamelCase(key) + "Config"
-			g.collectNestedStructsForGetters(structs, nestedName, v)
-		case []any:
-			if len(v) > 0 {
-				if m, ok := v[0].(map[string]any); ok {
-					nestedName := stripSuffix(name) + sx.CamelCase(key) + "Item"
-					g.collectNestedStructsForGetters(structs, nestedName, m)
-				}
-			}
-		case []map[string]any:
-			if len(v) > 0 {
-				nestedName := stripSuffix(name) + sx.CamelCase(key) + "Item"
-				g.collectNestedStructsForGetters(structs, nestedName, v[0])
-			}
+		if v, ok := val.(map[string]any); ok {
+			nestedName := stripSuffix(name) + g.camelCase(key) + "Config"
+			g.collectNestedStructsForGetters(structs, items, nestedName, joinKey(keyPath, key), v)
+		} else if elems, ok := tables(val); ok {
+			nestedName := stripSuffix(name) + g.camelCase(key) + "Item"
+			g.collectNestedStructs(items, nestedName, joinKey(keyPath, key), mergeItems(elems))
+		} else if m, ok := val.(*mapTable); ok && m.fields != nil {
+			g.collectNestedStructs(items, m.elem, joinKey(keyPath, key), m.fields)
 		}
 	}
 }
@@ -506,15 +456,13 @@ func (g *Generator) generateGetterMethods(buf *bytes.Buffer, structName string,
 	}
 	generated[structName] = true
 
-	fieldNames := make([]string, 0, len(fields))
-	for k := range fields {
-		fieldNames = append(fieldNames, k)
-	}
-	sort.Strings(fieldNames)
+	fieldNames := g.sortedKeys(g.structKeys[structName], fields)
 
 	for _, fieldName := range fieldNames {
 		value := fields[fieldName]
-		goFieldName := sx.PascalCase(fieldName)
+		goFieldName := g.fieldName(joinKey(g.structKeys[structName], fieldName))
+
+		g.writeFieldDoc(buf, joinKey(g.structKeys[structName], fieldName), "")
 
 		// Build env var name
 		var envVarName string
@@ -526,7 +474,7 @@ func (g *Generator) generateGetterMethods(buf *bytes.Buffer, structName string,
 
 		// Handle nested structs - they need their own getter methods
 		if nestedMap, ok := value.(map[string]any); ok {
-			nestedStructName := stripSuffix(structName) + sx.CamelCase(fieldName) + "Config"
+			nestedStructName := stripSuffix(structName) + g.camelCase(fieldName) + "Config"
 			// Generate method that returns nested struct
 			fmt.Fprintf(buf, "func (%s) %s() %s {\n", structName, goFieldName, nestedStructName)
 			fmt.Fprintf(buf, "\treturn %s{}\n", nestedStructName)
@@ -538,27 +486,20 @@ func (g *Generator) generateGetterMethods(buf *bytes.Buffer, structName string,
 			continue
 		}
 
-		// Handle arrays of structs - for now, return empty slice (limitation)
-		if arr, ok := value.([]any); ok && len(arr) > 0 {
-			if _, isMap := arr[0].(map[string]any); isMap {
-				nestedStructName := stripSuffix(structName) + sx.CamelCase(fieldName) + "Item"
-				goType := "[]" + nestedStructName
-				// For arrays of structs, return default empty value
-				fmt.Fprintf(buf, "func (%s) %s() %s {\n", structName, goFieldName, goType)
-				fmt.Fprintf(buf, "\t// Arrays of structs cannot be overridden via env vars\n")
-				fmt.Fprintf(buf, "\treturn nil\n")
-				buf.WriteString("}\n\n")
-				continue
+		key := joinKey(g.structKeys[structName], fieldName)
+		if items, ok := tables(value); ok {
+			itemType := stripSuffix(structName) + g.camelCase(fieldName) + "Item"
+			signature := fmt.Sprintf("func (%s) %s() []%s", structName, goFieldName, itemType)
+			if err := g.writeTablesGetter(buf, signature, key, itemType, envVarName, items); err != nil {
+				return err
 			}
+			continue
 		}
-
-		if arr, ok := value.([]map[string]any); ok && len(arr) > 0 {
-			nestedStructName := stripSuffix(structName) + sx.CamelCase(fieldName) + "Item"
-			goType := "[]" + nestedStructName
-			fmt.Fprintf(buf, "func (%s) %s() %s {\n", structName, goFieldName, goType)
-			fmt.Fprintf(buf, "\t// Arrays of structs cannot be overridden via env vars\n")
-			fmt.Fprintf(buf, "\treturn nil\n")
-			buf.WriteString("}\n\n")
+		if vt, ok := g.valueTypeOf(key); ok {
+			signature := fmt.Sprintf("func (%s) %s() %s", structName, goFieldName, vt.goType)
+			g.writeGetter(buf, signature, vt.goType, key, func(buf *bytes.Buffer) {
+				g.writeTypedGetterBody(buf, vt, envVarName, value)
+			})
 			continue
 		}
 
@@ -566,7 +507,7 @@ func (g *Generator) generateGetterMethods(buf *bytes.Buffer, structName string,
 		goType := g.toGoType(value)
 
 		// Generate getter method based on type
-		if err := g.generateGetterMethod(buf, structName, goFieldName, goType, envVarName, value); err != nil {
+		if err := g.generateGetterMethod(buf, key, structName, goFieldName, goType, envVarName, value); err != nil {
 			return err
 		}
 	}
@@ -575,28 +516,57 @@ func (g *Generator) generateGetterMethods(buf *bytes.Buffer, structName string,
 }
 
 // generateGetterMethod generates a single getter method with env var override.
-func (g *Generator) generateGetterMethod(buf *bytes.Buffer, structName, fieldName, goType, envVarName string, defaultValue any) error {
-	fmt.Fprintf(buf, "func (%s) %s() %s {\n", structName, fieldName, goType)
-	g.writeGetterBody(buf, goType, envVarName, defaultValue)
-	buf.WriteString("}\n\n")
+func (g *Generator) generateGetterMethod(buf *bytes.Buffer, key, structName, fieldName, goType, envVarName string, defaultValue any) error {
+	signature := fmt.Sprintf("func (%s) %s() %s", structName, fieldName, goType)
+	g.writeGetter(buf, signature, goType, key, func(buf *bytes.Buffer) {
+		if expr, ok := g.envDefault(key); ok {
+			g.writeEnvDefaultGetterBody(buf, envVarName, expr, defaultValue)
+			return
+		}
+		g.writeGetterBody(buf, goType, envVarName, defaultValue)
+	})
 	return nil
 }
 
 // generateTopLevelGetter generates a top-level getter function (not a method) for simple variables.
 func (g *Generator) generateTopLevelGetter(buf *bytes.Buffer, varName string, defaultValue any) error {
-	funcName := sx.PascalCase(varName)
+	funcName := g.fieldName(varName)
 	goType := g.toGoType(defaultValue)
-	envVarName := "CONFIG_" + strings.ToUpper(varName)
+	envVarName := g.envPrefix + "_" + strings.ToUpper(varName)
 
-	fmt.Fprintf(buf, "func %s() %s {\n", funcName, goType)
-	g.writeGetterBody(buf, goType, envVarName, defaultValue)
-	buf.WriteString("}\n\n")
+	g.writeFieldDoc(buf, varName, "")
+	if vt, ok := g.valueTypeOf(varName); ok {
+		g.writeGetter(buf, fmt.Sprintf("func %s() %s", funcName, vt.goType), vt.goType, varName, func(buf *bytes.Buffer) {
+			g.writeTypedGetterBody(buf, vt, envVarName, defaultValue)
+		})
+		return nil
+	}
+	g.writeGetter(buf, fmt.Sprintf("func %s() %s", funcName, goType), goType, varName, func(buf *bytes.Buffer) {
+		if expr, ok := g.envDefault(varName); ok {
+			g.writeEnvDefaultGetterBody(buf, envVarName, expr, defaultValue)
+			return
+		}
+		g.writeGetterBody(buf, goType, envVarName, defaultValue)
+	})
 	return nil
 }
 
 // writeGetterBody generates the common body logic for getter functions/methods.
 // This handles env var checking, type conversion, and default value fallback.
 func (g *Generator) writeGetterBody(buf *bytes.Buffer, goType, envVarName string, defaultValue any) {
+	// Maps of cfgx:map tables are not overridable: return a fresh copy
+	if _, ok := defaultValue.(*mapTable); ok {
+		buf.WriteString("\treturn ")
+		g.writeValue(buf, defaultValue)
+		buf.WriteString("\n")
+		return
+	}
+
+	g.extra["os"] = true
+	if isInteger(goType) || goType == "float64" || goType == "bool" {
+		g.extra["strconv"] = true
+	}
+
 	// Special handling for []byte (file references) - check for file path in env var
 	if goType == "[]byte" {
 		buf.WriteString("\t// Check for file path to load\n")
@@ -619,10 +589,6 @@ func (g *Generator) writeGetterBody(buf *bytes.Buffer, goType, envVarName string
 	switch goType {
 	case "string":
 		buf.WriteString("\t\treturn v\n")
-	case "int64":
-		buf.WriteString("\t\tif i, err := strconv.ParseInt(v, 10, 64); err == nil {\n")
-		buf.WriteString("\t\t\treturn i\n")
-		buf.WriteString("\t\t}\n")
 	case "float64":
 		buf.WriteString("\t\tif f, err := strconv.ParseFloat(v, 64); err == nil {\n")
 		buf.WriteString("\t\t\treturn f\n")
@@ -635,9 +601,17 @@ func (g *Generator) writeGetterBody(buf *bytes.Buffer, goType, envVarName string
 		buf.WriteString("\t\tif d, err := time.ParseDuration(v); err == nil {\n")
 		buf.WriteString("\t\t\treturn d\n")
 		buf.WriteString("\t\t}\n")
+	case "time.Time":
+		buf.WriteString("\t\tif t, err := time.Parse(time.RFC3339, v); err == nil {\n")
+		buf.WriteString("\t\t\treturn t\n")
+		buf.WriteString("\t\t}\n")
 	default:
-		// Handle arrays of primitives (for now, don't support env override)
-		if strings.HasPrefix(goType, "[]") {
+		if isInteger(goType) {
+			for _, line := range strings.Split(strings.TrimSuffix(valueTypes[goType].parse, "\n"), "\n") {
+				buf.WriteString("\t\t" + line + "\n")
+			}
+		} else if strings.HasPrefix(goType, "[]") {
+			// Handle arrays of primitives (for now, don't support env override)
 			buf.WriteString("\t\t// Array overrides not supported via env vars\n")
 		}
 	}
@@ -651,7 +625,7 @@ func (g *Generator) writeGetterBody(buf *bytes.Buffer, goType, envVarName string
 }
 
 // envVarName generates an environment variable name from a struct name and field name.
-// Format: CONFIG_SECTION_KEY
+// Format: <PREFIX>_SECTION_KEY, with prefix CONFIG by default
 func (g *Generator) envVarName(structName, fieldName string) string {
 	// Remove "Config" or "Item" suffix from struct name
 	section := stripSuffix(structName)
@@ -662,5 +636,5 @@ func (g *Generator) envVarName(structName, fieldName string) string {
 	sectionUpper := strings.ToUpper(sx.SnakeCase(section))
 	fieldUpper := strings.ToUpper(fieldName)
 
-	return "CONFIG_" + sectionUpper + "_" + fieldUpper
+	return g.envPrefix + "_" + sectionUpper + "_" + fieldUpper
 }
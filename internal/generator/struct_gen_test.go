@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -103,6 +104,21 @@ port = 8081`
 	require.Contains(t, outputStr, `Name: "web2"`, "missing second item")
 }
 
+func TestGenerator_NestedArrayOfTables(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/nested_arrays.toml")
+	require.NoError(t, err)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\t\t\tRules: []FeaturesRulesItem{\n\t\t\t\t{\n\t\t\t\t\tChecks: []FeaturesRulesChecksItem{\n")
+	require.Contains(t, outputStr, "Plugins: []AppPluginsItem{")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Rules: []featuresRulesItem{")
+}
+
 func TestGenerator_DeeplyNestedStructs(t *testing.T) {
 	toml := `[app.logging.rotation]
 enabled = true
@@ -119,3 +135,24 @@ max_size = 100`
 	require.Contains(t, outputStr, "type AppLoggingConfig struct", "missing mid-level struct")
 	require.Contains(t, outputStr, "type AppLoggingRotationConfig struct", "missing deep struct")
 }
+
+func TestGenerator_StructTags(t *testing.T) {
+	data := []byte(`
+[database]
+max_conns = 10
+
+[[database.replicas]]
+host = "r1"
+`)
+
+	output, err := New(WithStructTags([]string{"json", "yaml"})).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\tMaxConns int64                  `json:\"max_conns\" yaml:\"max_conns\"`\n")
+	require.Contains(t, outputStr, "\tReplicas []DatabaseReplicasItem `json:\"replicas\" yaml:\"replicas\"`\n")
+	require.Contains(t, outputStr, "\tHost string `json:\"host\" yaml:\"host\"`\n")
+
+	output, err = New().Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "json:")
+}
@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gomantics/sx"
+
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// Redacted replaces the values of secrets in the summary and in the outputs
+// of cfgx meant for reading, such as cfgx diff.
+const Redacted = "[redacted]"
+
+// secretWords are the words, or runs of words joined by underscores, of key
+// names that mark a value as a secret without a cfgx:secret annotation.
+var secretWords = []string{"password", "passwd", "secret", "token", "api_key", "apikey", "private_key", "dsn", "credentials"}
+
+// WithSummary generates a PrintSummary function that writes the effective
+// configuration as an aligned table, for logging it at startup.
+func WithSummary(enable bool) Option {
+	return func(g *Generator) {
+		g.summary = enable
+	}
+}
+
+// isSecret reports whether the value of key is redacted in the summary.
+func (g *Generator) isSecret(key string) bool {
+	return IsSecret(g.src, key)
+}
+
+// IsSecret reports whether the value of key, in the TOML source src, is a
+// secret: keys annotated cfgx:secret or cfgx:encrypted, and keys whose name
+// suggests a credential, word for word, unless annotated cfgx:secret=false. Src may be nil.
+func IsSecret(src *tomlsrc.Source, key string) bool {
+	if src != nil {
+		if value, ok := src.Annotation(key, "secret"); ok {
+			return value == "" || value == "true"
+		}
+		if _, ok := src.Annotation(key, "encrypted"); ok {
+			return true
+		}
+	}
+	// Matching whole words keeps keys such as max_tokens out
+	words := sx.SplitByCase(key[strings.LastIndex(key, ".")+1:])
+	name := "_" + strings.ToLower(strings.Join(words, "_")) + "_"
+	for _, word := range secretWords {
+		if strings.Contains(name, "_"+word+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSummary generates PrintSummary, which writes every key with its
+// effective value. Outside static mode a source column tells whether the
+// value is the default or came from an environment variable.
+func (g *Generator) writeSummary(buf *bytes.Buffer, data map[string]any) error {
+	if !g.summary {
+		return nil
+	}
+
+	g.extra["fmt"] = true
+	g.extra["io"] = true
+	g.extra["text/tabwriter"] = true
+
+	buf.WriteString("\n// PrintSummary writes the effective configuration to w as an aligned table,\n")
+	buf.WriteString("// one key per line, with secrets redacted.")
+	switch g.mode {
+	case "getter":
+		buf.WriteString(" Values read from environment\n")
+		buf.WriteString("// variables are marked with the variable they came from.\n")
+	case "hybrid":
+		buf.WriteString(" Values overridden by environment\n")
+		buf.WriteString("// variables are marked with the variable they came from, which assumes\n")
+		buf.WriteString("// LoadOverrides has been called.\n")
+	default:
+		buf.WriteString("\n")
+	}
+	buf.WriteString(g.funcDecl("PrintSummary(w io.Writer) {\n"))
+	buf.WriteString("\ttw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)\n")
+	if g.mode == "static" {
+		buf.WriteString("\tfmt.Fprintln(tw, \"KEY\\tVALUE\")\n")
+	} else {
+		buf.WriteString("\tfmt.Fprintln(tw, \"KEY\\tVALUE\\tSOURCE\")\n")
+	}
+	root := ""
+	if g.compact() {
+		root = "c"
+	}
+	sourced := g.writeSummaryRows(buf, "", "", root, g.envPrefix, data, false)
+	buf.WriteString("\ttw.Flush()\n")
+	buf.WriteString("}\n")
+
+	if sourced {
+		g.extra["os"] = true
+		buf.WriteString("\n// summarySource reports where a value printed by PrintSummary came from.\n")
+		buf.WriteString("func summarySource(env string) string {\n")
+		buf.WriteString("\tif os.Getenv(env) != \"\" {\n")
+		buf.WriteString("\t\treturn \"env \" + env\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\treturn \"default\"\n")
+		buf.WriteString("}\n")
+	}
+	if len(g.optional) > 0 {
+		buf.WriteString("\n// summaryOptional formats the value of an optional key printed by\n")
+		buf.WriteString("// PrintSummary with verb, or \"unset\".\n")
+		buf.WriteString("func summaryOptional[T any](p *T, verb string) string {\n")
+		buf.WriteString("\tif p == nil {\n")
+		buf.WriteString("\t\treturn \"unset\"\n")
+		buf.WriteString("\t}\n")
+		buf.WriteString("\treturn fmt.Sprintf(verb, *p)\n")
+		buf.WriteString("}\n")
+	}
+	return nil
+}
+
+// writeSummaryRows writes the summary rows for the values in table, found at
+// the dotted key path keyPath and labelled with label, which includes the
+// indexes of array elements. Values are read through access, the Go
+// expression for the table, and may be overridden by environment variables
+// prefixed with env. Items are elements of arrays of tables. It reports
+// whether any row refers to an environment variable.
+func (g *Generator) writeSummaryRows(buf *bytes.Buffer, keyPath, label, access, env string, table map[string]any, item bool) bool {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sourced := false
+	for _, k := range keys {
+		value := table[k]
+		fieldKey := joinKey(keyPath, k)
+		fieldLabel := joinKey(label, k)
+		fieldEnv := env + "_" + strings.ToUpper(k)
+
+		fieldAccess := g.fieldName(fieldKey)
+		if access != "" {
+			fieldAccess = access + "." + fieldAccess
+		}
+		if nested, ok := value.(map[string]any); ok {
+			if g.mode == "getter" && access != "" && !item {
+				fieldAccess += "()"
+			}
+			sourced = g.writeSummaryRows(buf, fieldKey, fieldLabel, fieldAccess, fieldEnv, nested, item) || sourced
+			continue
+		}
+		if g.mode == "getter" && !item {
+			fieldAccess += "()"
+		}
+
+		if items, ok := tables(value); ok {
+			for i, elem := range items {
+				index := fmt.Sprintf("[%d]", i)
+				sourced = g.writeSummaryRows(buf, fieldKey, fieldLabel+index, fieldAccess+index, fmt.Sprintf("%s_%d", fieldEnv, i), elem, true) || sourced
+			}
+			continue
+		}
+
+		goType := g.keyType(fieldKey, value)
+		verb, arg := "%v", fieldAccess
+		switch {
+		case g.isSecret(fieldKey):
+			verb, arg = Redacted, ""
+		case goType == "string":
+			verb = "%q"
+		case goType == "[]byte":
+			verb, arg = "%d bytes", "len("+fieldAccess+")"
+		case goType == "Asset":
+			verb, arg = "%d bytes", "len("+fieldAccess+".Content)"
+		}
+
+		if g.optional[fieldKey] && arg != "" {
+			verb, arg = "%s", fmt.Sprintf("summaryOptional(%s, %q)", arg, verb)
+		}
+
+		format := escapeVerbs(fieldLabel) + "\t" + verb
+		var args []string
+		if arg != "" {
+			args = append(args, arg)
+		}
+		switch {
+		case g.mode == "static":
+		case g.overridable(fieldKey, value, item):
+			format += "\t%s"
+			args = append(args, fmt.Sprintf("summarySource(%q)", fieldEnv))
+			sourced = true
+		default:
+			format += "\tdefault"
+		}
+		fmt.Fprintf(buf, "\tfmt.Fprintf(tw, %q", format+"\n")
+		for _, a := range args {
+			buf.WriteString(", " + a)
+		}
+		buf.WriteString(")\n")
+	}
+	return sourced
+}
+
+// overridable reports whether the generated code reads the value of key from
+// an environment variable at runtime, as getters and LoadOverrides do.
+func (g *Generator) overridable(key string, value any, item bool) bool {
+	if _, ok := value.(*mapTable); ok {
+		return false
+	}
+	_, typed := g.valueTypeOf(key)
+	goType, generic := g.genericType(key, value)
+	switch g.mode {
+	case "getter":
+		if item {
+			_, _, parsed := overrideParser(goType)
+			return generic && (goType == "string" || parsed)
+		}
+		return typed || goType == "[]byte" || !strings.HasPrefix(goType, "[]")
+	case "hybrid":
+		if g.types[key] == "asset" {
+			return !item
+		}
+		if item || !generic {
+			return false
+		}
+		elemType := strings.TrimPrefix(goType, "[]")
+		_, _, parsed := overrideParser(elemType)
+		return goType == "[]byte" || elemType == "string" || parsed
+	}
+	return false
+}
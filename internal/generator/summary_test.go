@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Summary(t *testing.T) {
+	data := []byte(`
+name = "svc"
+
+[server]
+addr = ":8080"
+timeout = "5s"
+api_key = "abc"
+dsn = "postgres://u:p@db/app" # cfgx:secret
+password_policy = "strict" # cfgx:secret=false
+max_tokens = 100
+credentials = "u:p"
+
+[[servers]]
+host = "a"
+`)
+
+	output, err := New(WithSummary(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func PrintSummary(w io.Writer) {\n\ttw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)\n\tfmt.Fprintln(tw, \"KEY\\tVALUE\")\n")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"name\\t%q\\n\", Name)")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.timeout\\t%v\\n\", Server.Timeout)")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.api_key\\t[redacted]\\n\")")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.dsn\\t[redacted]\\n\")")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.max_tokens\\t%v\\n\", Server.MaxTokens)")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.credentials\\t[redacted]\\n\")")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.password_policy\\t%q\\n\", Server.PasswordPolicy)")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"servers[0].host\\t%q\\n\", Servers[0].Host)")
+	require.NotContains(t, outputStr, "summarySource")
+
+	output, err = New(WithMode("getter"), WithSummary(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "fmt.Fprintln(tw, \"KEY\\tVALUE\\tSOURCE\")")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.addr\\t%q\\t%s\\n\", Server.Addr(), summarySource(\"CONFIG_SERVER_ADDR\"))")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"servers[0].host\\t%q\\t%s\\n\", Servers()[0].Host, summarySource(\"CONFIG_SERVERS_0_HOST\"))")
+	require.Contains(t, outputStr, "func summarySource(env string) string {")
+
+	output, err = New(WithMode("hybrid"), WithSummary(true)).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"server.addr\\t%q\\t%s\\n\", Server.Addr, summarySource(\"CONFIG_SERVER_ADDR\"))")
+	require.Contains(t, outputStr, "fmt.Fprintf(tw, \"servers[0].host\\t%q\\tdefault\\n\", Servers[0].Host)")
+}
+
+func TestGenerator_SummaryConflict(t *testing.T) {
+	_, err := New(WithSummary(true)).Generate([]byte("print_summary = true\n"))
+	require.ErrorContains(t, err, "print_summary: conflicts with the generated PrintSummary function")
+
+	_, err = New(WithSummary(true), WithNameOverrides(map[string]string{"report": "PrintSummary"})).Generate([]byte("report = true\n"))
+	require.ErrorContains(t, err, "report: conflicts with the generated PrintSummary function")
+}
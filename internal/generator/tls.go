@@ -0,0 +1,81 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// tlsKeyPairs are the conventional key names of an embedded certificate and
+// private key, checked in order.
+var tlsKeyPairs = [][2]string{
+	{"tls_cert", "tls_key"},
+	{"cert", "key"},
+}
+
+// writeTLSHelpers generates a TLSConfig method for every table holding a
+// certificate and private key as file: references:
+//
+//	[server]
+//	tls_cert = "file:certs/server.crt"
+//	tls_key = "file:certs/server.key"
+//
+// In getter mode the certificate and key getters already honor environment
+// variables naming files to load at runtime, so TLSConfig picks up rotated
+// or mounted credentials without regenerating.
+func (g *Generator) writeTLSHelpers(buf *bytes.Buffer, data map[string]any) error {
+	names := make([]string, 0, len(g.structKeys))
+	for name := range g.structKeys {
+		if strings.HasSuffix(name, "Config") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, typeName := range names {
+		key := g.structKeys[typeName]
+		table, ok := lookupTable(data, key)
+		if !ok {
+			continue
+		}
+
+		for _, pair := range tlsKeyPairs {
+			if !g.isFileRefValue(table[pair[0]]) || !g.isFileRefValue(table[pair[1]]) {
+				continue
+			}
+			if err := g.checkMethodConflicts(key, table, "tls_config"); err != nil {
+				return err
+			}
+			g.writeTLSConfig(buf, typeName, key, pair[0], pair[1])
+			break
+		}
+	}
+	return nil
+}
+
+// writeTLSConfig writes the TLSConfig method for one table.
+func (g *Generator) writeTLSConfig(buf *bytes.Buffer, typeName, key, certKey, keyKey string) {
+	g.extra["crypto/tls"] = true
+
+	fmt.Fprintf(buf, "\n// TLSConfig returns a tls.Config serving the certificate and key from\n")
+	fmt.Fprintf(buf, "// %s and %s.\n", joinKey(key, certKey), joinKey(key, keyKey))
+	if g.mode == "getter" {
+		fmt.Fprintf(buf, "// Set %s and %s to load them from other files at runtime.\n",
+			g.envVarName(typeName, certKey), g.envVarName(typeName, keyKey))
+	}
+	g.extra["fmt"] = true
+	fmt.Fprintf(buf, "func (c %s) TLSConfig() (*tls.Config, error) {\n", typeName)
+	fmt.Fprintf(buf, "\tcert, err := tls.X509KeyPair(%s, %s)\n", g.field("c", key, certKey), g.field("c", key, keyKey))
+	buf.WriteString("\tif err != nil {\n")
+	fmt.Fprintf(buf, "\t\treturn nil, fmt.Errorf(\"%s: %%w\", err)\n", key)
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn &tls.Config{Certificates: []tls.Certificate{cert}}, nil\n")
+	buf.WriteString("}\n")
+}
+
+// isFileRefValue reports whether v is a file: reference string.
+func (g *Generator) isFileRefValue(v any) bool {
+	s, ok := v.(string)
+	return ok && g.isFileReference(s)
+}
@@ -0,0 +1,39 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_TLSConfig(t *testing.T) {
+	data := []byte(`
+[server]
+addr = ":8443"
+tls_cert = "file:files/cert.txt"
+tls_key = "file:files/small.txt"
+
+[client]
+cert = "file:files/cert.txt"
+`)
+
+	output, err := New(WithInputDir("../../testdata")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\"crypto/tls\"")
+	require.Contains(t, outputStr, "func (c ServerConfig) TLSConfig() (*tls.Config, error) {")
+	require.Contains(t, outputStr, "tls.X509KeyPair(c.TlsCert, c.TlsKey)")
+	require.NotContains(t, outputStr, "func (c ClientConfig) TLSConfig()", "a certificate without a key is not a pair")
+
+	output, err = New(WithInputDir("../../testdata"), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "tls.X509KeyPair(c.TlsCert(), c.TlsKey())")
+	require.Contains(t, outputStr, "// Set CONFIG_SERVER_TLS_CERT and CONFIG_SERVER_TLS_KEY")
+}
+
+func TestGenerator_TLSConfigRequiresFileRefs(t *testing.T) {
+	output, err := New().Generate([]byte("[server]\ncert = \"inline\"\nkey = \"inline\"\n"))
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "TLSConfig")
+}
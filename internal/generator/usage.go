@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// writeUsageCount writes the statement counting a read of key at the start of
+// its getter, when usage tracking is enabled.
+func (g *Generator) writeUsageCount(buf *bytes.Buffer, key string) {
+	if !g.usage {
+		return
+	}
+	fmt.Fprintf(buf, "\tusageCounts[%d].Add(1)\n", len(g.usageKeys))
+	g.usageKeys = append(g.usageKeys, key)
+}
+
+// writeUsage writes the counters incremented by the getters and the Usage
+// function reporting them.
+func (g *Generator) writeUsage(buf *bytes.Buffer) error {
+	if !g.usage {
+		return nil
+	}
+	if g.mode != "getter" {
+		return fmt.Errorf("usage tracking requires getter mode")
+	}
+
+	g.extra["sync/atomic"] = true
+
+	buf.WriteString("// usageKeys lists the keys whose reads are counted, indexed like usageCounts.\n")
+	buf.WriteString("var usageKeys = [...]string{\n")
+	for _, key := range g.usageKeys {
+		fmt.Fprintf(buf, "\t%q,\n", key)
+	}
+	buf.WriteString("}\n\n")
+	buf.WriteString("var usageCounts [len(usageKeys)]atomic.Uint64\n\n")
+
+	buf.WriteString("// Usage returns how many times each config key has been read through its\n")
+	buf.WriteString("// getter since the program started, keyed by dotted TOML key path. Keys\n")
+	buf.WriteString("// that are never read report zero.\n")
+	buf.WriteString("func Usage() map[string]uint64 {\n")
+	buf.WriteString("\tusage := make(map[string]uint64, len(usageKeys))\n")
+	buf.WriteString("\tfor i, key := range usageKeys {\n")
+	buf.WriteString("\t\tusage[key] = usageCounts[i].Load()\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn usage\n")
+	buf.WriteString("}\n\n")
+	return nil
+}
@@ -0,0 +1,45 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_UsageTracking(t *testing.T) {
+	data := []byte(`
+name = "svc"
+
+[server]
+addr = ":8080"
+schedule = "*/5 * * * *" # cfgx:type=cron
+`)
+
+	output, err := New(WithMode("getter"), WithUsageTracking(true)).Generate(data)
+	require.NoError(t, err)
+
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\"sync/atomic\"")
+	require.Contains(t, outputStr, "func (serverConfig) Addr() string {\n\tusageCounts[0].Add(1)\n")
+	require.Contains(t, outputStr, "func Name() string {\n\tusageCounts[2].Add(1)\n")
+	require.Contains(t, outputStr, "var usageKeys = [...]string{\n\t\"server.addr\",\n\t\"server.schedule\",\n\t\"name\",\n}")
+	require.Contains(t, outputStr, "var usageCounts [len(usageKeys)]atomic.Uint64")
+	require.Contains(t, outputStr, "func Usage() map[string]uint64 {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "usageCounts", "tracking should be opt-in")
+}
+
+func TestGenerator_UsageTrackingErrors(t *testing.T) {
+	_, err := New(WithMode("static"), WithUsageTracking(true)).Generate([]byte("name = \"svc\"\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "usage tracking requires getter mode")
+
+	_, err = New(WithMode("getter"), WithUsageTracking(true)).Generate([]byte("usage = 1\n"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "conflicts with the generated Usage function")
+
+	_, err = New(WithMode("getter"), WithUsageTracking(true)).Generate([]byte("reads = 1 # cfgx:name=Usage\n"))
+	require.ErrorContains(t, err, "reads: conflicts with the generated Usage function")
+}
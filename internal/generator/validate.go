@@ -0,0 +1,125 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// check is a runtime check emitted into the generated Validate function.
+type check struct {
+	key  string // Dotted key path of the checked value
+	cond string // Go expression on v that is true when the value is valid
+	msg  string // Error message format, given v
+}
+
+// formats lists the cfgx:format validators: the snippet function checking a
+// value at runtime and the message reported when it fails.
+var formats = map[string]struct {
+	fn  string
+	msg string
+}{
+	"email":    {"validEmail", "invalid email %q"},
+	"hostname": {"validHostname", "invalid hostname %q"},
+	"port":     {"validPort", "invalid port %d"},
+	"uuid":     {"validUUID", "invalid UUID %q"},
+}
+
+// validateFormats checks values annotated with cfgx:format at generation time
+// and records a runtime check for each, since getter-mode values can be
+// overridden through the environment after generation:
+//
+//	[admin]
+//	email = "ops@example.com" # cfgx:format=email
+func (g *Generator) validateFormats(data map[string]any) error {
+	if g.src == nil {
+		return nil
+	}
+
+	for _, key := range g.annotated("format") {
+		name, _ := g.annotation(key, "format")
+		format, ok := formats[name]
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("unknown cfgx:format %q (known: email, hostname, port, uuid)", name)}
+		}
+
+		values := lookupValues(data, key)
+		if len(values) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:format must annotate a value")}
+		}
+		for _, v := range values {
+			if !snippets.ValidFormat(name, v) {
+				return &KeyError{Key: key, Err: fmt.Errorf(format.msg, v)}
+			}
+		}
+
+		g.addCheck(check{key: key, cond: format.fn + "(v)", msg: format.msg}, "format")
+	}
+	return nil
+}
+
+// addCheck records a runtime check, along with the snippet it calls, and
+// reports whether it was recorded. Checks on keys inside arrays of tables are
+// enforced at generation time only.
+func (g *Generator) addCheck(c check, snippet string) bool {
+	path := strings.Split(c.key, ".")
+	if len(path) > 1 {
+		if _, ok := lookupTable(g.data, strings.Join(path[:len(path)-1], ".")); !ok {
+			return false
+		}
+	}
+	g.checks = append(g.checks, c)
+	g.useSnippet(snippet)
+	return true
+}
+
+// writeValidate generates the Validate function from the recorded checks.
+func (g *Generator) writeValidate(buf *bytes.Buffer) error {
+	if len(g.checks) == 0 {
+		return nil
+	}
+
+	checks := append([]check(nil), g.checks...)
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].key < checks[j].key })
+
+	g.extra["errors"] = true
+	g.extra["fmt"] = true
+
+	buf.WriteString("\n// Validate checks the configuration against the constraints annotated in\n")
+	buf.WriteString("// the TOML source and returns all violations joined.")
+	decl := g.funcDecl("Validate() error {\n")
+	if g.mode == "loader" {
+		// Loaded values are not in the package variables
+		buf.WriteString(" Call it on the result of\n")
+		buf.WriteString("// Load or Resolve, or on Default for the values baked in.\n")
+		decl = "func (c *Config) Validate() error {\n"
+	} else {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(decl)
+	buf.WriteString("\tvar errs []error\n")
+	for _, c := range checks {
+		access := g.accessor(strings.Split(c.key, "."))
+		if g.mode == "loader" {
+			access = "c." + access
+		}
+		if g.optional[c.key] {
+			// Unset optional values are valid
+			fmt.Fprintf(buf, "\tif p := %s; p != nil {\n", access)
+			access = "*p"
+		}
+		fmt.Fprintf(buf, "\tif v := %s; !(%s) {\n", access, c.cond)
+		fmt.Fprintf(buf, "\t\terrs = append(errs, fmt.Errorf(%q, v))\n", c.key+": "+c.msg)
+		buf.WriteString("\t}\n")
+		if g.optional[c.key] {
+			buf.WriteString("\t}\n")
+		}
+	}
+	buf.WriteString("\treturn errors.Join(errs...)\n")
+	buf.WriteString("}\n")
+
+	return nil
+}
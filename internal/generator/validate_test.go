@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_FormatValidate(t *testing.T) {
+	data := []byte(`
+admin_id = "123e4567-e89b-12d3-a456-426614174000" # cfgx:format=uuid
+
+[admin]
+email = "ops@example.com" # cfgx:format=email
+
+[database]
+host = "db.internal" # cfgx:format=hostname
+port = 5432 # cfgx:format=port
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "func Validate() error {")
+	require.Contains(t, outputStr, "if v := Database.Port; !(validPort(v)) {")
+	require.Contains(t, outputStr, "errs = append(errs, fmt.Errorf(\"database.port: invalid port %d\", v))")
+	require.Contains(t, outputStr, "return errors.Join(errs...)")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "if v := Admin.Email(); !(validEmail(v)) {")
+	require.Contains(t, outputStr, "if v := AdminId(); !(validUUID(v)) {")
+}
+
+func TestGenerator_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "invalid email",
+			toml: "[admin]\nemail = \"ops\" # cfgx:format=email\n",
+			want: "admin.email: invalid email \"ops\"",
+		},
+		{
+			name: "port out of range",
+			toml: "[db]\nport = 0 # cfgx:format=port\n",
+			want: "db.port: invalid port 0",
+		},
+		{
+			name: "invalid in array of tables",
+			toml: "[[hosts]]\nname = \"ok.example.com\" # cfgx:format=hostname\n\n[[hosts]]\nname = \"bad_host\"\n",
+			want: "invalid hostname \"bad_host\"",
+		},
+		{
+			name: "unknown format",
+			toml: "[db]\nport = 1 # cfgx:format=ipv6\n",
+			want: "unknown cfgx:format \"ipv6\"",
+		},
+		{
+			name: "conflict with Validate",
+			toml: "validate = true\n\n[db]\nport = 1 # cfgx:format=port\n",
+			want: "conflicts with the generated Validate function",
+		},
+		{
+			name: "renamed to Validate",
+			toml: "check = true # cfgx:name=Validate\n\n[db]\nport = 1 # cfgx:format=port\n",
+			want: "check: conflicts with the generated Validate function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerator_FormatInArrayOfTablesHasNoRuntimeCheck(t *testing.T) {
+	output, err := New().Generate([]byte("[[hosts]]\nname = \"a.example.com\" # cfgx:format=hostname\n"))
+	require.NoError(t, err)
+	require.NotContains(t, string(output), "func Validate() error")
+}
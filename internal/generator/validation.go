@@ -2,76 +2,174 @@ package generator
 
 import (
 	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
 // validateFileReferences recursively validates all file: references in the data.
 // This ensures all referenced files exist and don't exceed size limits before generation.
 func (g *Generator) validateFileReferences(data map[string]any) error {
-	for _, v := range data {
-		if err := g.validateFileReferencesValue(v); err != nil {
-			return err
-		}
+	if errs := g.fileReferenceErrors(data); len(errs) > 0 {
+		return errs[0]
 	}
 	return nil
 }
 
-// validateFileReferencesValue validates file references in a single value.
-func (g *Generator) validateFileReferencesValue(v any) error {
+// FileReferenceErrors checks the file: references in data the way generation
+// does, honoring cfgx:type annotations and hints, and returns a KeyError for
+// every reference that is missing or too large rather than only the first.
+func (g *Generator) FileReferenceErrors(data map[string]any) []error {
+	g.src, g.data = g.source, data
+	g.types = make(map[string]string)
+	g.files = nil
+	keys, names := g.typeHinted()
+	for _, key := range keys {
+		g.types[key] = names[key]
+	}
+	return g.fileReferenceErrors(data)
+}
+
+// FileReference is a file: reference of a value and the contents of its file.
+type FileReference struct {
+	Key     string // Dotted key path of the value
+	Ref     string // The reference, without the file: prefix
+	Path    string // Path of the file, resolved relative to the input directory
+	Content []byte
+}
+
+// FileReferences returns the file: references in data, in key order, with
+// the contents of their files read the way generation does. A reference in
+// an array is listed once per element holding it. It fails on the first
+// reference that is missing or too large.
+func (g *Generator) FileReferences(data map[string]any) ([]FileReference, error) {
+	if errs := g.FileReferenceErrors(data); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	var refs []fileReference
+	g.collectFileReferencesIn(data, "", &refs)
+	files := make([]FileReference, 0, len(refs))
+	for _, ref := range refs {
+		content, err := g.loadFileContent(ref.path)
+		if err != nil {
+			return nil, &KeyError{Key: ref.key, Err: err}
+		}
+		path := normalizeRefPath(strings.TrimPrefix(ref.path, "file:"))
+		if g.fsys == nil {
+			path = g.refPath(ref.path)
+		}
+		files = append(files, FileReference{
+			Key:     ref.key,
+			Ref:     strings.TrimPrefix(ref.path, "file:"),
+			Path:    path,
+			Content: content,
+		})
+	}
+	return files, nil
+}
+
+// fileReferenceErrors loads the files of the file: references in data into
+// the content cache and returns a KeyError for every reference that failed,
+// in key order.
+func (g *Generator) fileReferenceErrors(data map[string]any) []error {
+	var refs []fileReference
+	g.collectFileReferencesIn(data, "", &refs)
+
+	paths := make([]string, len(refs))
+	for i, ref := range refs {
+		paths[i] = ref.path
+	}
+	g.loadFiles(paths)
+
+	var errs []error
+	for _, ref := range refs {
+		if _, err := g.loadFileContent(ref.path); err != nil {
+			errs = append(errs, &KeyError{Key: ref.key, Err: err})
+		}
+	}
+	return errs
+}
+
+// fileReference is a file: reference and the dotted key path of its value.
+type fileReference struct {
+	key  string
+	path string
+}
+
+// collectFileReferencesIn adds the file: references in a table whose dotted
+// key path is prefix to refs, in key order.
+func (g *Generator) collectFileReferencesIn(data map[string]any, prefix string, refs *[]fileReference) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		g.collectFileReferencesValue(data[k], joinKey(prefix, k), refs)
+	}
+}
+
+// collectFileReferencesValue adds the file: references in a single value to refs.
+func (g *Generator) collectFileReferencesValue(v any, key string, refs *[]fileReference) {
 	switch val := v.(type) {
 	case string:
-		if g.isFileReference(val) {
-			// Try to load the file to validate it exists and size is OK
-			_, err := g.loadFileContent(val)
-			if err != nil {
-				return err
-			}
+		if vt, typed := g.valueTypeOf(key); (!typed || vt.file) && g.isFileReference(val) {
+			*refs = append(*refs, fileReference{key: key, path: val})
 		}
 	case map[string]any:
-		return g.validateFileReferences(val)
+		g.collectFileReferencesIn(val, key, refs)
+	case *mapTable:
+		g.collectFileReferencesIn(val.entries, key, refs)
 	case []any:
 		for _, item := range val {
-			if err := g.validateFileReferencesValue(item); err != nil {
-				return err
-			}
+			g.collectFileReferencesValue(item, key, refs)
 		}
 	case []map[string]any:
 		for _, m := range val {
-			if err := g.validateFileReferences(m); err != nil {
-				return err
-			}
+			g.collectFileReferencesIn(m, key, refs)
 		}
 	}
-	return nil
 }
 
-// needsTimeImport checks if any value in the data map is a duration string,
+// needsTimeImport checks if any value in the data map is a datetime or a duration string,
 // recursively traversing nested maps and arrays to determine if the generated
 // code needs to import the "time" package.
 func (g *Generator) needsTimeImport(data map[string]any) bool {
-	for _, v := range data {
-		if g.needsTimeImportValue(v) {
+	return g.needsTimeImportIn(data, "")
+}
+
+// needsTimeImportIn checks a table whose dotted key path is prefix.
+func (g *Generator) needsTimeImportIn(data map[string]any, prefix string) bool {
+	for k, v := range data {
+		if g.needsTimeImportValue(v, joinKey(prefix, k)) {
 			return true
 		}
 	}
 	return false
 }
 
-func (g *Generator) needsTimeImportValue(v any) bool {
+func (g *Generator) needsTimeImportValue(v any, key string) bool {
 	switch val := v.(type) {
+	case time.Time:
+		return true
 	case string:
-		// Check if string is a valid duration
-		if g.isDurationString(val) {
+		// Check if string is a valid duration not typed otherwise
+		_, typed := g.valueTypeOf(key)
+		if !typed && g.isDurationString(val) {
 			return true
 		}
 	case map[string]any:
-		return g.needsTimeImport(val)
+		return g.needsTimeImportIn(val, key)
+	case *mapTable:
+		return g.needsTimeImportIn(val.entries, key)
 	case []any:
-		if slices.ContainsFunc(val, g.needsTimeImportValue) {
+		if slices.ContainsFunc(val, func(item any) bool { return g.needsTimeImportValue(item, key) }) {
 			return true
 		}
 	case []map[string]any:
-		if slices.ContainsFunc(val, g.needsTimeImport) {
+		if slices.ContainsFunc(val, func(item map[string]any) bool { return g.needsTimeImportIn(item, key) }) {
 			return true
 		}
 	}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -120,3 +121,26 @@ func TestGenerator_validateFileReferences(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerator_FileReferences(t *testing.T) {
+	data := map[string]any{
+		"tls": map[string]any{"cert": "file:files/cert.txt", "name": "x"},
+		"servers": []map[string]any{
+			{"content": "file:files/small.txt"},
+			{"content": "file:files/small.txt"},
+		},
+	}
+
+	refs, err := New(WithInputDir("../../testdata")).FileReferences(data)
+	require.NoError(t, err)
+	require.Len(t, refs, 3)
+	require.Equal(t, "servers.content", refs[0].Key)
+	require.Equal(t, "files/small.txt", refs[0].Ref)
+	require.Equal(t, filepath.Join("../../testdata", "files", "small.txt"), refs[0].Path)
+	require.NotEmpty(t, refs[0].Content)
+	require.Equal(t, "tls.cert", refs[2].Key)
+
+	data["tls"].(map[string]any)["key"] = "file:files/missing.txt"
+	_, err = New(WithInputDir("../../testdata")).FileReferences(data)
+	require.ErrorContains(t, err, "tls.key")
+}
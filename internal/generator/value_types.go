@@ -0,0 +1,658 @@
+package generator
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	_ "time/tzdata" // Time zones are checked the same on every machine
+
+	"github.com/BurntSushi/toml"
+	"github.com/gomantics/cfgx/internal/generator/snippets"
+)
+
+// valueType is a Go type selected for a key with a cfgx:type annotation:
+//
+//	[jobs]
+//	cleanup = "0 3 * * *" # cfgx:type=cron
+//
+// Where a third-party type exists, the annotation also picks the
+// implementation: "decimal" uses github.com/shopspring/decimal and
+// "language-tag" golang.org/x/text/language, which the project must then
+// depend on; "rat" and "locale" need only the standard library.
+//
+// A "string" value is kept as the literal string, for values such as product
+// SKUs ("1h") or prefixes ("file:") that would otherwise be inferred as a
+// time.Duration or a file reference.
+//
+// An integer type, such as "int", "uint16" or "int64", makes an integer that
+// Go type rather than the one integers are otherwise generated as, which
+// WithIntType sets. The value must fit the type.
+//
+// A "path" value expands ~, environment variables and XDG base directories
+// into a cleaned absolute path: at generation time in static mode, and at
+// runtime in getter mode, where it is resolved on the running machine.
+//
+// A "tz" value is the name of an IANA time zone, such as "America/New_York",
+// checked against the tz database at generation time. Its Location method
+// loads the *time.Location on first use.
+//
+// An "asset" value is a file: reference generated as an Asset, holding the
+// content of the file along with its media type, found from the extension of
+// the file or else sniffed from its content, for HTTP handlers serving it.
+//
+// An "encrypted" value is ciphertext written as "<cipher>:<key ID>:<base64>",
+// generated as an Encrypted that is only decrypted, by the Cipher registered
+// under its name, when its Decrypt method is called. Keys annotated
+// cfgx:encrypted are decrypted at generation time instead in static and
+// hybrid mode.
+type valueType struct {
+	// goType is the type of the generated field, variable or getter.
+	goType string
+
+	// check validates a TOML value at generation time.
+	check func(v any) error
+
+	// literal returns the Go expression for a value that passed check.
+	literal func(v any) string
+
+	// file reports whether values are file: references, whose literals
+	// writeAssetLiteral writes from the content of the file, in place of
+	// literal.
+	file bool
+
+	// getterLiteral, if set, replaces literal for getter-mode defaults.
+	getterLiteral func(v any) string
+
+	// parse is the getter-mode code converting the env var string v; it
+	// returns on success and falls through to the default otherwise.
+	parse string
+
+	// snippet names the snippets file declaring goType, if any.
+	snippet string
+
+	// imports lists the packages needed by literals and getter parsing,
+	// besides those of the snippet and strconv, which getters import when
+	// parse uses it.
+	imports []string
+
+	// generic reports whether values are otherwise handled like inferred
+	// values of goType, such as by environment overrides of struct fields.
+	generic bool
+}
+
+// valueTypes lists the types available to cfgx:type, by annotation value.
+var valueTypes = map[string]valueType{
+	"string": {
+		goType: "string",
+		check: func(v any) error {
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("expected a string")
+			}
+			return nil
+		},
+		literal: func(v any) string { return fmt.Sprintf("%q", v) },
+		parse:   "return v\n",
+		generic: true,
+	},
+	"int":    integerType("int"),
+	"int8":   integerType("int8"),
+	"int16":  integerType("int16"),
+	"int32":  integerType("int32"),
+	"int64":  integerType("int64"),
+	"uint":   integerType("uint"),
+	"uint8":  integerType("uint8"),
+	"uint16": integerType("uint16"),
+	"uint32": integerType("uint32"),
+	"uint64": integerType("uint64"),
+	"cron": {
+		goType: "CronSpec",
+		check: func(v any) error {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("expected a string")
+			}
+			if !snippets.CronSpec(s).IsValid() {
+				return fmt.Errorf("invalid cron spec %q", s)
+			}
+			return nil
+		},
+		literal: func(v any) string { return fmt.Sprintf("%q", v) },
+		parse:   "if s := CronSpec(v); s.IsValid() {\n\treturn s\n}\n",
+		snippet: "cron",
+	},
+	"percent": {
+		goType: "float64",
+		check: func(v any) error {
+			switch val := v.(type) {
+			case string:
+				if _, ok := snippets.ParsePercent(val); !ok {
+					return fmt.Errorf("invalid percentage %q (want 0%%–100%%)", val)
+				}
+			case float64:
+				if val < 0 || val > 1 {
+					return fmt.Errorf("ratio %g out of range (want 0–1)", val)
+				}
+			default:
+				return fmt.Errorf("expected a percentage string such as \"10%%\" or a ratio between 0 and 1")
+			}
+			return nil
+		},
+		literal: func(v any) string {
+			f, ok := v.(float64)
+			if !ok {
+				f, _ = snippets.ParsePercent(v.(string))
+			}
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		},
+		parse:   "if r, ok := parsePercent(v); ok {\n\treturn r\n}\n",
+		snippet: "percent",
+	},
+	"decimal": {
+		goType: "decimal.Decimal",
+		check: func(v any) error {
+			s, err := decimalText(v)
+			if err != nil {
+				return err
+			}
+			if !decimalPattern.MatchString(s) {
+				return fmt.Errorf("invalid decimal %q", s)
+			}
+			return nil
+		},
+		literal: func(v any) string {
+			s, _ := decimalText(v)
+			return fmt.Sprintf("decimal.RequireFromString(%q)", s)
+		},
+		parse:   "if d, err := decimal.NewFromString(v); err == nil {\n\treturn d\n}\n",
+		imports: []string{"github.com/shopspring/decimal"},
+	},
+	"rat": {
+		goType: "*big.Rat",
+		check: func(v any) error {
+			s, err := decimalText(v)
+			if err != nil {
+				return err
+			}
+			if _, ok := new(big.Rat).SetString(s); !ok {
+				return fmt.Errorf("invalid decimal or fraction %q", s)
+			}
+			return nil
+		},
+		literal: func(v any) string {
+			s, _ := decimalText(v)
+			return fmt.Sprintf("mustRat(%q)", s)
+		},
+		parse:   "if r, ok := new(big.Rat).SetString(v); ok {\n\treturn r\n}\n",
+		snippet: "rat",
+	},
+	"locale": {
+		goType:  "Locale",
+		check:   checkLocale,
+		literal: func(v any) string { return fmt.Sprintf("%q", v) },
+		parse:   "if l := Locale(v); l.IsValid() {\n\treturn l\n}\n",
+		snippet: "locale",
+	},
+	"path": {
+		goType: "string",
+		check: func(v any) error {
+			s, ok := v.(string)
+			if !ok || s == "" {
+				return fmt.Errorf("expected a non-empty path string")
+			}
+			if snippets.ExpandPath(s) == "" {
+				return fmt.Errorf("cannot expand path %q: unknown home directory", s)
+			}
+			return nil
+		},
+		literal:       func(v any) string { return fmt.Sprintf("%q", snippets.ExpandPath(v.(string))) },
+		getterLiteral: func(v any) string { return fmt.Sprintf("expandPath(%q)", v) },
+		parse:         "if p := expandPath(v); p != \"\" {\n\treturn p\n}\n",
+		snippet:       "path",
+	},
+	"tz": {
+		goType: "TimeZone",
+		check: func(v any) error {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("expected a time zone name")
+			}
+			if !snippets.TimeZone(s).IsValid() {
+				return fmt.Errorf("unknown time zone %q", s)
+			}
+			return nil
+		},
+		literal: func(v any) string { return fmt.Sprintf("%q", v) },
+		parse:   "if z := TimeZone(v); z.IsValid() {\n\treturn z\n}\n",
+		snippet: "tz",
+	},
+	"asset": {
+		goType: "Asset",
+		check: func(v any) error {
+			if s, ok := v.(string); !ok || !strings.HasPrefix(s, "file:") {
+				return fmt.Errorf("expected a file: reference")
+			}
+			return nil
+		},
+		file:    true,
+		parse:   "if data, err := os.ReadFile(v); err == nil {\n\treturn newAsset(v, data)\n}\n",
+		snippet: "asset",
+	},
+	"encrypted": {
+		goType: "Encrypted",
+		check: func(v any) error {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("expected an encrypted string")
+			}
+			return snippets.ParseEncrypted(s)
+		},
+		literal: func(v any) string { return fmt.Sprintf("%q", v) },
+		parse:   "if e := Encrypted(v); e.IsValid() {\n\treturn e\n}\n",
+		snippet: "encrypted",
+	},
+	"language-tag": {
+		goType: "language.Tag",
+		check:  checkLocale,
+		// language.Make never panics, even for well-formed but unregistered subtags
+		literal: func(v any) string { return fmt.Sprintf("language.Make(%q)", v) },
+		parse:   "if t, err := language.Parse(v); err == nil {\n\treturn t\n}\n",
+		imports: []string{"golang.org/x/text/language"},
+	},
+}
+
+// integerSizes gives the size in bits of the integer types, by Go type: 0
+// for int and uint, whose size is that of the platform.
+var integerSizes = map[string]int{
+	"int": 0, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": 0, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+}
+
+// isInteger reports whether goType is an integer type.
+func isInteger(goType string) bool {
+	_, ok := integerSizes[goType]
+	return ok
+}
+
+// integerType is the value type of integers generated as goType, such as
+// uint16 for ports or int where integers are otherwise generated as int64.
+// Values must fit the type, taking int and uint to be 64 bits.
+func integerType(goType string) valueType {
+	parse, parsed, _ := integerParser(goType, "v")
+	result := "i"
+	if parsed != goType {
+		result = goType + "(i)"
+	}
+	return valueType{
+		goType: goType,
+		check: func(v any) error {
+			n, ok := v.(int64)
+			if !ok {
+				return fmt.Errorf("expected an integer")
+			}
+			bits := cmp.Or(integerSizes[goType], 64)
+			if strings.HasPrefix(goType, "u") {
+				if n < 0 || bits < 64 && n >= 1<<bits {
+					return fmt.Errorf("%d overflows %s", n, goType)
+				}
+			} else if bits < 64 && (n < -1<<(bits-1) || n >= 1<<(bits-1)) {
+				return fmt.Errorf("%d overflows %s", n, goType)
+			}
+			return nil
+		},
+		literal: func(v any) string { return strconv.FormatInt(v.(int64), 10) },
+		parse:   "if i, err := " + parse + "; err == nil {\n\treturn " + result + "\n}\n",
+		generic: true,
+	}
+}
+
+// integerParser returns the strconv call parsing the string expression s as
+// an integer of goType, along with the Go type of its result, which must be
+// converted to goType if they differ. ok is false if goType is not an
+// integer type.
+func integerParser(goType, s string) (parse, parsed string, ok bool) {
+	bits, ok := integerSizes[goType]
+	switch {
+	case !ok:
+		return "", "", false
+	case goType == "int":
+		return fmt.Sprintf("strconv.Atoi(%s)", s), "int", true
+	case strings.HasPrefix(goType, "u"):
+		return fmt.Sprintf("strconv.ParseUint(%s, 10, %d)", s, bits), "uint64", true
+	default:
+		return fmt.Sprintf("strconv.ParseInt(%s, 10, %d)", s, bits), "int64", true
+	}
+}
+
+// checkLocale validates a BCP 47 language tag.
+func checkLocale(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("expected a string")
+	}
+	if !snippets.Locale(s).IsValid() {
+		return fmt.Errorf("invalid BCP 47 language tag %q", s)
+	}
+	return nil
+}
+
+// decimalPattern matches the decimal syntax accepted by shopspring/decimal.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+
+// decimalText returns the exact text of a decimal value. Floats are rejected
+// because the TOML decoder has already rounded them to binary.
+func decimalText(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case float64:
+		return "", fmt.Errorf("write decimal values as strings (e.g. \"%g\") to keep their precision", val)
+	}
+	return "", fmt.Errorf("expected a decimal string")
+}
+
+// WithTypeHints sets cfgx:type names by dotted key path, as read from a
+// sidecar file by ParseTypeHints. Annotations in the source take precedence.
+func WithTypeHints(hints map[string]string) Option {
+	return func(g *Generator) {
+		g.typeHints = hints
+	}
+}
+
+// ParseTypeHints parses a sidecar TOML file giving the cfgx:type of keys,
+// either as dotted keys or nested in tables:
+//
+//	"plans.starter" = "string"
+//
+//	[jobs]
+//	cleanup = "cron"
+func ParseTypeHints(data []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	hints := make(map[string]string)
+	var walk func(prefix string, table map[string]any) error
+	walk = func(prefix string, table map[string]any) error {
+		for k, v := range table {
+			key := joinKey(prefix, k)
+			switch val := v.(type) {
+			case map[string]any:
+				if err := walk(key, val); err != nil {
+					return err
+				}
+			case string:
+				if _, ok := valueTypes[val]; !ok {
+					return &KeyError{Key: key, Err: fmt.Errorf("unknown cfgx:type %q (known: %s)", val, strings.Join(valueTypeNames(), ", "))}
+				}
+				hints[key] = val
+			default:
+				return &KeyError{Key: key, Err: fmt.Errorf("expected a type name")}
+			}
+		}
+		return nil
+	}
+	if err := walk("", doc); err != nil {
+		return nil, err
+	}
+	return hints, nil
+}
+
+// typeHinted returns the keys given a type by annotation or sidecar hint that
+// are part of the data being generated, along with their type names.
+func (g *Generator) typeHinted() ([]string, map[string]string) {
+	names := make(map[string]string)
+	var keys []string
+	if g.src != nil {
+		for _, key := range g.annotated("type") {
+			names[key], _ = g.annotation(key, "type")
+			keys = append(keys, key)
+		}
+	}
+
+	hinted := make([]string, 0, len(g.typeHints))
+	for key := range g.typeHints {
+		top, _, _ := strings.Cut(key, ".")
+		if _, ok := g.data[top]; ok {
+			hinted = append(hinted, key)
+		}
+	}
+	sort.Strings(hinted)
+	for _, key := range hinted {
+		if _, ok := names[key]; !ok {
+			names[key] = g.typeHints[key]
+			keys = append(keys, key)
+		}
+	}
+	return keys, names
+}
+
+// validateTypes checks every value annotated with cfgx:type, or hinted in a
+// sidecar file, against its type and records the keys for code generation.
+func (g *Generator) validateTypes(data map[string]any) error {
+	g.types = make(map[string]string)
+
+	keys, names := g.typeHinted()
+	for _, key := range keys {
+		name := names[key]
+		vt, ok := valueTypes[name]
+		if !ok {
+			return &KeyError{Key: key, Err: fmt.Errorf("unknown cfgx:type %q (known: %s)", name, strings.Join(valueTypeNames(), ", "))}
+		}
+
+		values := lookupValues(data, key)
+		if len(values) == 0 {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:type must annotate a value")}
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case map[string]any, []map[string]any, []any:
+				return &KeyError{Key: key, Err: fmt.Errorf("cfgx:type=%s must annotate a single value", name)}
+			}
+			if err := vt.check(v); err != nil {
+				return &KeyError{Key: key, Err: err}
+			}
+		}
+
+		g.types[key] = name
+		g.useSnippet(vt.snippet)
+		if vt.file && g.mode != "static" {
+			// Files are read at runtime too
+			g.useSnippet("assetfile")
+		}
+		for _, imp := range vt.imports {
+			g.extra[imp] = true
+		}
+	}
+	return nil
+}
+
+// validatePathParents records a runtime check for each path annotated
+// cfgx:parent-exists, requiring the parent directory of the expanded path to
+// exist when Validate runs:
+//
+//	log_file = "~/.local/state/app/app.log" # cfgx:type=path parent-exists
+//
+// Nothing is checked at generation time, since the directory usually exists
+// on the deployment machine only.
+func (g *Generator) validatePathParents() error {
+	if g.src == nil {
+		return nil
+	}
+
+	for _, key := range g.annotated("parent-exists") {
+		if g.types[key] != "path" {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:parent-exists requires cfgx:type=path")}
+		}
+		if !g.addCheck(check{key: key, cond: "pathParentExists(v)", msg: "parent directory of %q does not exist"}, "path") {
+			return &KeyError{Key: key, Err: fmt.Errorf("cfgx:parent-exists is not supported in arrays of tables")}
+		}
+	}
+	return nil
+}
+
+// valueTypeOf returns the annotated type of key, if any.
+func (g *Generator) valueTypeOf(key string) (valueType, bool) {
+	name, ok := g.types[key]
+	if !ok {
+		return valueType{}, false
+	}
+	return valueTypes[name], true
+}
+
+// genericType returns the Go type of the value of key for code handling only
+// the inferred types, such as environment overrides of struct fields. Values
+// typed cfgx:type=string or an integer type are handled like inferred values;
+// ok is false for the other types and for optional values.
+func (g *Generator) genericType(key string, v any) (goType string, ok bool) {
+	if name, typed := g.types[key]; typed && !valueTypes[name].generic || g.optional[key] {
+		return "", false
+	}
+	return g.keyType(key, v), true
+}
+
+// keyType returns the Go type for the value of key.
+func (g *Generator) keyType(key string, v any) string {
+	if vt, ok := g.valueTypeOf(key); ok {
+		return vt.goType
+	}
+	return g.toGoType(v)
+}
+
+// writeKeyValue writes the Go literal for the value of key, or a pointer to
+// it if the key is optional.
+func (g *Generator) writeKeyValue(buf *bytes.Buffer, key string, v any, indent int) {
+	if g.optional[key] {
+		g.useSnippet("ptr")
+		fmt.Fprintf(buf, "ptr[%s](", g.keyType(key, v))
+		defer buf.WriteString(")")
+	}
+	if vt, ok := g.valueTypeOf(key); ok {
+		g.writeTypedValue(buf, vt, v, indent)
+		return
+	}
+	g.writeValueWithIndent(buf, v, indent)
+}
+
+// writeTypedValue writes the Go literal for v, a value of type vt.
+func (g *Generator) writeTypedValue(buf *bytes.Buffer, vt valueType, v any, indent int) {
+	if vt.file {
+		g.writeAssetLiteral(buf, v.(string), indent)
+		return
+	}
+	buf.WriteString(vt.literal(v))
+}
+
+// writeAssetLiteral writes the Asset literal of the file: reference ref,
+// whose content type is found at generation time.
+func (g *Generator) writeAssetLiteral(buf *bytes.Buffer, ref string, indent int) {
+	// File was already loaded by validateFileReferences
+	content, _ := g.loadFileContent(ref)
+	fmt.Fprintf(buf, "Asset{ContentType: %q, Content: ", snippets.AssetContentType(normalizeRefPath(strings.TrimPrefix(ref, "file:")), content))
+	g.writeValueWithIndent(buf, ref, indent)
+	buf.WriteString("}")
+}
+
+// writeTypedGetterBody writes a getter body for an annotated value type.
+func (g *Generator) writeTypedGetterBody(buf *bytes.Buffer, vt valueType, envVarName string, defaultValue any) {
+	g.extra["os"] = true
+	if strings.Contains(vt.parse, "strconv.") {
+		g.extra["strconv"] = true
+	}
+	fmt.Fprintf(buf, "\tif v := os.Getenv(%q); v != \"\" {\n", envVarName)
+	for _, line := range strings.Split(strings.TrimSuffix(vt.parse, "\n"), "\n") {
+		buf.WriteString("\t\t" + line + "\n")
+	}
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn ")
+	if vt.getterLiteral != nil {
+		buf.WriteString(vt.getterLiteral(defaultValue))
+	} else {
+		g.writeTypedValue(buf, vt, defaultValue, 1)
+	}
+	buf.WriteString("\n")
+}
+
+// useSnippet marks a snippet for emission and imports what it needs.
+func (g *Generator) useSnippet(name string) {
+	if name == "" || g.snippets[name] {
+		return
+	}
+	g.snippets[name] = true
+	for _, imp := range snippets.Imports(name) {
+		g.extra[imp] = true
+	}
+}
+
+// writeSnippets writes the snippets needed by annotated value types and checks.
+func (g *Generator) writeSnippets(buf *bytes.Buffer) {
+	names := make([]string, 0, len(g.snippets))
+	for name := range g.snippets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buf.WriteString("\n")
+		buf.WriteString(snippets.Source(name))
+	}
+}
+
+// valueTypeNames returns the known cfgx:type values, sorted.
+func valueTypeNames() []string {
+	names := make([]string, 0, len(valueTypes))
+	for name := range valueTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupValues returns the values at a dotted key path, descending into every
+// element of arrays of tables along the way.
+func lookupValues(data map[string]any, key string) []any {
+	head, rest, nested := strings.Cut(key, ".")
+	v, ok := data[head]
+	if !ok {
+		return nil
+	}
+	if !nested {
+		return []any{v}
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		return lookupValues(val, rest)
+	case []map[string]any:
+		var values []any
+		for _, item := range val {
+			values = append(values, lookupValues(item, rest)...)
+		}
+		return values
+	case []any:
+		var values []any
+		for _, item := range val {
+			if m, ok := item.(map[string]any); ok {
+				values = append(values, lookupValues(m, rest)...)
+			}
+		}
+		return values
+	case *mapTable:
+		if val.key == "" {
+			return nil
+		}
+		var values []any
+		for _, item := range val.entries {
+			values = append(values, lookupValues(item.(map[string]any), rest)...)
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_CronType(t *testing.T) {
+	data := []byte(`
+backup = "@daily" # cfgx:type=cron
+
+[jobs]
+cleanup = "0 3 * * *" # cfgx:type=cron
+name = "nightly"
+
+[[tasks]]
+schedule = "*/5 * * * *" # cfgx:type=cron
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Backup CronSpec = \"@daily\"")
+	require.Contains(t, outputStr, "Cleanup CronSpec")
+	require.Contains(t, outputStr, "Name    string")
+	require.Contains(t, outputStr, "Schedule CronSpec")
+	require.Contains(t, outputStr, "type CronSpec string")
+	require.Contains(t, outputStr, "func (s CronSpec) IsValid() bool {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (jobsConfig) Cleanup() CronSpec {")
+	require.Contains(t, outputStr, "if s := CronSpec(v); s.IsValid() {")
+	require.Contains(t, outputStr, "func Backup() CronSpec {")
+}
+
+func TestGenerator_PercentType(t *testing.T) {
+	data := []byte(`
+[tracing]
+sampling = "12.5%" # cfgx:type=percent
+error_budget = 0.01 # cfgx:type=percent
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Sampling    float64")
+	require.Contains(t, outputStr, "Sampling:    0.125,")
+	require.Contains(t, outputStr, "ErrorBudget: 0.01,")
+	require.Contains(t, outputStr, "func parsePercent(s string) (float64, bool) {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (tracingConfig) Sampling() float64 {")
+	require.Contains(t, outputStr, "if r, ok := parsePercent(v); ok {")
+	require.Contains(t, outputStr, "return 0.125")
+}
+
+func TestGenerator_DecimalTypes(t *testing.T) {
+	data := []byte(`
+[billing]
+price = "19.99" # cfgx:type=decimal
+tax = "1/3" # cfgx:type=rat
+fee = 2 # cfgx:type=rat
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "\"math/big\"\n\n\t\"github.com/shopspring/decimal\"")
+	require.Contains(t, outputStr, "Price decimal.Decimal")
+	require.Contains(t, outputStr, "Price: decimal.RequireFromString(\"19.99\"),")
+	require.Contains(t, outputStr, "Tax   *big.Rat")
+	require.Contains(t, outputStr, "Tax:   mustRat(\"1/3\"),")
+	require.Contains(t, outputStr, "Fee:   mustRat(\"2\"),")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (billingConfig) Price() decimal.Decimal {")
+	require.Contains(t, outputStr, "if d, err := decimal.NewFromString(v); err == nil {")
+}
+
+func TestGenerator_LocaleTypes(t *testing.T) {
+	data := []byte(`
+[i18n]
+default_locale = "en-US" # cfgx:type=locale
+fallback = "pt-BR" # cfgx:type=language-tag
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "DefaultLocale Locale")
+	require.Contains(t, outputStr, "Fallback      language.Tag")
+	require.Contains(t, outputStr, "Fallback:      language.Make(\"pt-BR\"),")
+	require.Contains(t, outputStr, "func (l Locale) IsValid() bool {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "if l := Locale(v); l.IsValid() {")
+}
+
+func TestGenerator_TimeZoneType(t *testing.T) {
+	data := []byte(`
+[app]
+timezone = "America/New_York" # cfgx:type=tz
+`)
+
+	output, err := New(WithMode("static")).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Timezone TimeZone")
+	require.Contains(t, outputStr, "Timezone: \"America/New_York\",")
+	require.Contains(t, outputStr, "func (z TimeZone) Location() (*time.Location, error) {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "if z := TimeZone(v); z.IsValid() {")
+
+	output, err = New(WithMode("loader")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "\"app.timezone\": func(v string) any {\n\t\tif z := TimeZone(v); z.IsValid() {")
+}
+
+func TestGenerator_AssetType(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "site.css"), []byte("a{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo"), []byte("\x89PNG\r\n\x1a\n"), 0644))
+
+	data := []byte(`
+[web]
+css = "file:site.css" # cfgx:type=asset
+logo = "file:logo" # cfgx:type=asset
+`)
+
+	output, err := New(WithInputDir(dir)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "type Asset struct {")
+	require.Contains(t, outputStr, "Css: Asset{ContentType: \"text/css; charset=utf-8\", Content: []byte{\n\t\t\t0x61, 0x7b, 0x7d,\n\t\t}},")
+	require.Contains(t, outputStr, "Logo: Asset{ContentType: \"image/png\", Content: []byte{")
+	require.NotContains(t, outputStr, "net/http")
+
+	output, err = New(WithInputDir(dir), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (webConfig) Css() Asset {")
+	require.Contains(t, outputStr, "if data, err := os.ReadFile(v); err == nil {\n\t\t\treturn newAsset(v, data)\n\t\t}")
+
+	output, err = New(WithInputDir(dir), WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Web.Css = newAsset(path, data)")
+
+	_, err = New(WithInputDir(dir)).Generate([]byte("[web]\ncss = \"site.css\" # cfgx:type=asset\n"))
+	require.EqualError(t, err, "web.css: expected a file: reference")
+
+	_, err = New(WithInputDir(dir)).Generate([]byte("[web]\ncss = \"file:missing.css\" # cfgx:type=asset\n"))
+	require.ErrorContains(t, err, "file not found")
+
+	_, err = New(WithInputDir(dir)).Generate([]byte("asset = \"file:site.css\" # cfgx:type=asset\n"))
+	require.EqualError(t, err, "asset: conflicts with the generated Asset type")
+
+	_, err = New(WithInputDir(dir), WithMode("getter")).Generate([]byte("[web]\ncss = \"file:site.css\" # cfgx:type=asset\n\n[asset]\nname = \"a\"\n"))
+	require.EqualError(t, err, "asset: conflicts with the generated Asset type")
+}
+
+func TestGenerator_StringType(t *testing.T) {
+	data := []byte(`
+sku = "1h" # cfgx:type=string
+
+[[plans]]
+name = "file:basic" # cfgx:type=string
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Sku string = \"1h\"")
+	require.Contains(t, outputStr, "Name: \"file:basic\",")
+	require.NotContains(t, outputStr, "\"time\"")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func Sku() string {\n\tif v := os.Getenv(\"CONFIG_SKU\"); v != \"\" {\n\t\treturn v\n\t}\n\treturn \"1h\"\n}")
+	require.Contains(t, outputStr, "if v := os.Getenv(\"CONFIG_PLANS_0_NAME\"); v != \"\" {")
+
+	output, err = New(WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "if v := os.Getenv(\"CONFIG_SKU\"); v != \"\" {\n\t\tSku = v\n\t}")
+}
+
+func TestGenerator_IntType(t *testing.T) {
+	data := []byte(`
+workers = 4 # cfgx:type=int
+ports = [80, 443]
+
+[limits]
+max_body = 1048576
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Workers int     = 4")
+	require.Contains(t, outputStr, "Ports   []int64 = []int64{80, 443}")
+	require.Contains(t, outputStr, "MaxBody int64")
+
+	output, err = New(WithIntType("int"), WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (limitsConfig) MaxBody() int {\n\tif v := os.Getenv(\"CONFIG_LIMITS_MAX_BODY\"); v != \"\" {\n\t\tif i, err := strconv.Atoi(v); err == nil {")
+	require.Contains(t, outputStr, "func Ports() []int {")
+
+	output, err = New(WithIntType("int"), WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "if x, err := strconv.Atoi(v); err != nil {")
+
+	_, err = New().Generate([]byte("workers = \"4\" # cfgx:type=int\n"))
+	require.EqualError(t, err, "workers: expected an integer")
+}
+
+func TestGenerator_SizedIntegerTypes(t *testing.T) {
+	data := []byte(`
+port = 8080 # cfgx:type=uint16
+offset = -5 # cfgx:type=int32
+
+[[workers]]
+slots = 4 # cfgx:type=uint8
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Offset  int32  = -5")
+	require.Contains(t, outputStr, "Port    uint16 = 8080")
+	require.Contains(t, outputStr, "Slots uint8")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func Port() uint16 {\n\tif v := os.Getenv(\"CONFIG_PORT\"); v != \"\" {\n\t\tif i, err := strconv.ParseUint(v, 10, 16); err == nil {\n\t\t\treturn uint16(i)\n")
+	require.Contains(t, outputStr, "if i, err := strconv.ParseUint(v, 10, 8); err == nil {\n\t\t\titems[0].Slots = uint8(i)\n")
+
+	output, err = New(WithMode("hybrid")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "if x, err := strconv.ParseInt(v, 10, 32); err != nil {")
+	require.Contains(t, string(output), "Offset = int32(x)")
+
+	tests := []struct {
+		data    string
+		wantErr string
+	}{
+		{"port = 70000 # cfgx:type=uint16\n", "port: 70000 overflows uint16"},
+		{"port = -1 # cfgx:type=uint\n", "port: -1 overflows uint"},
+		{"level = -129 # cfgx:type=int8\n", "level: -129 overflows int8"},
+		{"level = 1 # cfgx:type=uint8 min=-1\n", "level: invalid min: -1 overflows uint8"},
+		{"level = 1 # cfgx:type=int8 enum=1,300\n", "level: enum value 300 overflows int8"},
+	}
+	for _, tt := range tests {
+		_, err := New().Generate([]byte(tt.data))
+		require.EqualError(t, err, tt.wantErr)
+	}
+}
+
+func TestGenerator_TypeHints(t *testing.T) {
+	hints, err := ParseTypeHints([]byte(`
+"plans.id" = "string"
+
+[jobs]
+cleanup = "cron"
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"plans.id": "string", "jobs.cleanup": "cron"}, hints)
+
+	data := []byte(`
+[plans]
+id = "5m"
+
+[jobs]
+cleanup = "@daily"
+retry = "10s" # cfgx:type=string
+`)
+	hints["jobs.retry"] = "cron"
+	output, err := New(WithTypeHints(hints)).Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "Id string")
+	require.Contains(t, outputStr, "Cleanup CronSpec")
+	require.Contains(t, outputStr, "Retry   string", "annotations take precedence over hints")
+
+	_, err = ParseTypeHints([]byte("timeout = 5\n"))
+	require.ErrorContains(t, err, "timeout: expected a type name")
+	_, err = ParseTypeHints([]byte("timeout = \"interval\"\n"))
+	require.ErrorContains(t, err, "timeout: unknown cfgx:type \"interval\"")
+}
+
+func TestGenerator_ValueTypeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "invalid cron",
+			toml: "[jobs]\ncleanup = \"0 25 * * *\" # cfgx:type=cron\n",
+			want: "jobs.cleanup: invalid cron spec",
+		},
+		{
+			name: "invalid cron in array of tables",
+			toml: "[[tasks]]\nschedule = \"* * * * *\" # cfgx:type=cron\n\n[[tasks]]\nschedule = \"nope\"\n",
+			want: "invalid cron spec \"nope\"",
+		},
+		{
+			name: "percent out of range",
+			toml: "[tracing]\nsampling = \"120%\" # cfgx:type=percent\n",
+			want: "invalid percentage \"120%\"",
+		},
+		{
+			name: "ratio out of range",
+			toml: "[tracing]\nsampling = 5.0 # cfgx:type=percent\n",
+			want: "ratio 5 out of range",
+		},
+		{
+			name: "percent as integer",
+			toml: "[tracing]\nsampling = 10 # cfgx:type=percent\n",
+			want: "expected a percentage string",
+		},
+		{
+			name: "decimal as float",
+			toml: "[billing]\nprice = 19.99 # cfgx:type=decimal\n",
+			want: "write decimal values as strings",
+		},
+		{
+			name: "invalid decimal",
+			toml: "[billing]\nprice = \"19,99\" # cfgx:type=decimal\n",
+			want: "invalid decimal \"19,99\"",
+		},
+		{
+			name: "invalid locale",
+			toml: "[i18n]\ndefault_locale = \"en_US\" # cfgx:type=locale\n",
+			want: "invalid BCP 47 language tag \"en_US\"",
+		},
+		{
+			name: "unknown time zone",
+			toml: "[app]\ntimezone = \"Mars/Olympus\" # cfgx:type=tz\n",
+			want: "unknown time zone \"Mars/Olympus\"",
+		},
+		{
+			name: "unknown type",
+			toml: "[jobs]\ncleanup = \"x\" # cfgx:type=quartz\n",
+			want: "unknown cfgx:type \"quartz\"",
+		},
+		{
+			name: "table",
+			toml: "# cfgx:type=cron\n[jobs]\ncleanup = \"x\"\n",
+			want: "must annotate a single value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestGenerator_PathType(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	data := []byte(`
+[app]
+config_dir = "$XDG_CONFIG_HOME/app" # cfgx:type=path
+log_file = "~/logs//app.log" # cfgx:type=path parent-exists
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "ConfigDir: \""+filepath.Join(home, ".config", "app")+"\",")
+	require.Contains(t, outputStr, "LogFile:   \""+filepath.Join(home, "logs", "app.log")+"\",")
+	require.Contains(t, outputStr, "if v := App.LogFile; !(pathParentExists(v)) {")
+	require.Contains(t, outputStr, "func expandPath(s string) string {")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	outputStr = string(output)
+	require.Contains(t, outputStr, "func (appConfig) ConfigDir() string {\n\tif v := os.Getenv(\"CONFIG_APP_CONFIG_DIR\"); v != \"\" {\n\t\tif p := expandPath(v); p != \"\" {\n\t\t\treturn p\n\t\t}\n\t}\n\treturn expandPath(\"$XDG_CONFIG_HOME/app\")\n}")
+	require.Contains(t, outputStr, "if v := App.LogFile(); !(pathParentExists(v)) {")
+}
+
+func TestGenerator_PathTypeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		toml string
+		want string
+	}{
+		{
+			name: "not a string",
+			toml: "dir = 1 # cfgx:type=path\n",
+			want: "dir: expected a non-empty path string",
+		},
+		{
+			name: "parent-exists without path type",
+			toml: "dir = \"/tmp\" # cfgx:parent-exists\n",
+			want: "dir: cfgx:parent-exists requires cfgx:type=path",
+		},
+		{
+			name: "parent-exists in array of tables",
+			toml: "[[mounts]]\ndir = \"/tmp\" # cfgx:type=path parent-exists\n",
+			want: "mounts.dir: cfgx:parent-exists is not supported in arrays of tables",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New().Generate([]byte(tt.toml))
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
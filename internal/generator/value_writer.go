@@ -10,7 +10,8 @@ import (
 // toGoType converts a value to its Go type string representation. This function
 // inspects the runtime type of a value and returns the corresponding Go type as a string.
 //
-// For primitive types (string, int64, float64, bool), it returns the standard type name.
+// For primitive types (string, int64, float64, bool), it returns the standard type name,
+// with integers of the type set with WithIntType.
 // For slices, it recursively determines the element type. For maps and []map[string]any,
 // it returns placeholder strings ("struct", "[]struct") that will be replaced with actual
 // struct type names in context by the calling code.
@@ -26,14 +27,14 @@ func (g *Generator) toGoType(v any) string {
 			return "time.Duration"
 		}
 		return "string"
-	case int64:
-		return "int64"
-	case int:
-		return "int64"
+	case int64, int:
+		return g.intType
 	case float64:
 		return "float64"
 	case bool:
 		return "bool"
+	case time.Time:
+		return "time.Time"
 	case []any:
 		if len(val) > 0 {
 			elemType := g.toGoType(val[0])
@@ -46,11 +47,24 @@ func (g *Generator) toGoType(v any) string {
 	case map[string]any:
 		// This will be replaced with the actual struct type name in context
 		return "struct"
+	case *mapTable:
+		if val.ordered {
+			return "OrderedMap[" + val.elem + "]"
+		}
+		return "map[string]" + val.elem
 	default:
 		return "any"
 	}
 }
 
+// ElemType returns the Go type generated code declares the elements of arr
+// with, such as "time.Duration" for durations, or "any" if arr is empty.
+// Arrays cannot be given a type with cfgx:type, so their elements have the
+// type inferred from the first.
+func ElemType(arr []any) string {
+	return strings.TrimPrefix(New().toGoType(arr), "[]")
+}
+
 // writeValue writes a Go value literal to the buffer. This function handles the
 // serialization of various Go types into their source code representation.
 //
@@ -68,13 +82,17 @@ func (g *Generator) writeValueWithIndent(buf *bytes.Buffer, v any, indent int) {
 	case string:
 		// Check if this is a file reference
 		if g.isFileReference(val) {
-			// File was already validated in validateFileReferences, so this should not fail
+			// File was already loaded by validateFileReferences, so this should not fail
 			content, err := g.loadFileContent(val)
 			if err != nil {
 				// This should never happen if validation passed
 				fmt.Fprintf(buf, "[]byte{} /* unexpected error: %s */", err)
 				return
 			}
+			if g.shouldEmbed(len(content)) {
+				buf.WriteString(g.embedFile(val, content))
+				return
+			}
 			g.writeByteArrayLiteral(buf, content, indent)
 			return
 		}
@@ -92,8 +110,15 @@ func (g *Generator) writeValueWithIndent(buf *bytes.Buffer, v any, indent int) {
 		fmt.Fprintf(buf, "%g", val)
 	case bool:
 		fmt.Fprintf(buf, "%t", val)
+	case time.Time:
+		g.writeTimeLiteral(buf, val)
 	case []any:
 		g.writeArray(buf, val)
+	case *mapTable:
+		if err := g.writeMapLiteral(buf, val, indent); err != nil {
+			// Entries were already validated, so this should not fail
+			fmt.Fprintf(buf, "nil /* unexpected error: %s */", err)
+		}
 	default:
 		buf.WriteString("nil")
 	}
@@ -134,6 +159,24 @@ func (g *Generator) writeByteArrayLiteral(buf *bytes.Buffer, data []byte, indent
 	buf.WriteString("}")
 }
 
+// localZones are the names of the locations the TOML decoder gives local
+// datetimes, dates and times, which have no offset of their own.
+var localZones = map[string]bool{"datetime-local": true, "date-local": true, "time-local": true}
+
+// writeTimeLiteral writes a TOML datetime as a time.Date call. Offset
+// datetimes keep their offset; local datetimes, dates and times are in the
+// local time zone of the program, and times alone are on January 1 of year 0.
+func (g *Generator) writeTimeLiteral(buf *bytes.Buffer, t time.Time) {
+	loc := "time.UTC"
+	if _, offset := t.Zone(); localZones[t.Location().String()] {
+		loc = "time.Local"
+	} else if offset != 0 {
+		loc = fmt.Sprintf("time.FixedZone(%q, %d)", t.Format("-07:00"), offset)
+	}
+	fmt.Fprintf(buf, "time.Date(%d, time.%s, %d, %d, %d, %d, %d, %s)",
+		t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
 // writeDurationLiteral parses a duration string at generation time and writes
 // it as a duration literal in a human-readable format using time constants.
 // Complex durations like '2h30m' are decomposed into multiple time constants
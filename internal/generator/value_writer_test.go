@@ -2,6 +2,7 @@ package generator
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -115,6 +116,7 @@ func TestGenerator_toGoType(t *testing.T) {
 		{"int type", 42, "int64"},
 		{"float64 type", 3.14, "float64"},
 		{"bool type", true, "bool"},
+		{"datetime type", time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), "time.Time"},
 		{"string array", []any{"a", "b"}, "[]string"},
 		{"int array", []any{int64(1), int64(2)}, "[]int64"},
 		{"empty array", []any{}, "[]any"},
@@ -131,6 +133,32 @@ func TestGenerator_toGoType(t *testing.T) {
 	}
 }
 
+func TestGenerator_DatetimeTypes(t *testing.T) {
+	data := []byte(`
+released_at = 2024-01-01T00:00:00Z
+starts_at = 2024-06-01T09:30:00.5+02:00
+launch = 2024-03-04T05:06:07
+day = 2024-01-03
+at = 07:30:00
+holidays = [2024-12-25, 2024-12-26]
+`)
+
+	output, err := New().Generate(data)
+	require.NoError(t, err)
+	outputStr := string(output)
+	require.Contains(t, outputStr, "import \"time\"")
+	require.Contains(t, outputStr, "ReleasedAt time.Time   = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)")
+	require.Contains(t, outputStr, "StartsAt   time.Time   = time.Date(2024, time.June, 1, 9, 30, 0, 500000000, time.FixedZone(\"+02:00\", 7200))")
+	require.Contains(t, outputStr, "Launch     time.Time   = time.Date(2024, time.March, 4, 5, 6, 7, 0, time.Local)")
+	require.Contains(t, outputStr, "Day        time.Time   = time.Date(2024, time.January, 3, 0, 0, 0, 0, time.Local)")
+	require.Contains(t, outputStr, "At         time.Time   = time.Date(0, time.January, 1, 7, 30, 0, 0, time.Local)")
+	require.Contains(t, outputStr, "Holidays   []time.Time = []time.Time{time.Date(2024, time.December, 25, 0, 0, 0, 0, time.Local), ")
+
+	output, err = New(WithMode("getter")).Generate(data)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "\t\tif t, err := time.Parse(time.RFC3339, v); err == nil {\n\t\t\treturn t\n")
+}
+
 func TestGenerator_toGoType_Duration(t *testing.T) {
 	g := New()
 	got := g.toGoType("30s")
@@ -0,0 +1,28 @@
+package i18n
+
+// spanish is the built-in Spanish catalog.
+var spanish = map[string]string{
+	DiffHeader:         "Diferencias entre %s y %s:",
+	DiffNone:           "No se encontraron diferencias.",
+	DiffOnlyIn:         "solo en %s",
+	DiffOwner:          "responsable: %s",
+	DiffOverlayHeader:  "Superposición generada por cfgx: aplíquela sobre %s para obtener %s",
+	DiffOverlayRemoved: "Las siguientes claves solo existen en %s y no se pueden eliminar con una superposición:",
+	DiffOverlaySecrets: "Los siguientes secretos difieren y se omiten; use --show-secrets para incluirlos:",
+
+	LintRemovedIn:            "%s debía eliminarse en la versión %s (versión actual %s)",
+	LintExperimentalOverride: "%s es experimental pero se define en la superposición %s",
+	LintRedundantOverride:    "%s se define en la superposición %s con el valor que ya tiene en %s",
+	LintOrphanOverride:       "%s se define en la superposición %s pero no sobrescribe nada en %s ni en superposiciones anteriores",
+	LintDurationString:       "%s = %q parece una duración pero no es una duración válida de Go y se generará como cadena",
+	LintDurationInt:          "%s = %d es un entero sin unidad; use una cadena de duración como \"%ds\" para explicitar la unidad",
+	LintDurationTyped:        "%s = %q es una duración pero está anotada cfgx:type=string y se generará como cadena",
+	LintDuplicateValue:       "%s repite el valor de %s; considere mantenerlo en una sola clave",
+	LintSecretLiteral:        "%s es un secreto escrito como literal; léalo con una referencia file: o de una variable de entorno",
+	LintUnusedFile:           "%s está junto a archivos leídos con referencias file: pero ninguna clave lo referencia",
+	LintNameCollision:        "%s y %s se generan ambos como %s",
+	LintSummary:              "%d problema(s) encontrado(s)",
+
+	ValidateOK:      "%s es válido",
+	ValidateSummary: "%d problema(s) encontrado(s)",
+}
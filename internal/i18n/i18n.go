@@ -0,0 +1,206 @@
+// Package i18n provides the message catalog for user-facing CLI output such as
+// diff and lint reports.
+//
+// Messages are looked up by key in the catalog of the active locale, falling
+// back to the base language (e.g. "es" for "es-MX") and finally to English.
+// Additional catalogs can be registered programmatically or loaded from TOML
+// files of the form:
+//
+//	locale = "es"
+//
+//	[messages]
+//	"diff.none" = "No se encontraron diferencias."
+//
+// The full key set with the English text is available via Keys and English,
+// and printed by the "cfgx messages" command as a starting point for translators.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Message keys. Templates use fmt verbs; translations must keep the same
+// verbs in the same order.
+const (
+	DiffHeader         = "diff.header"          // file1, file2
+	DiffNone           = "diff.none"            //
+	DiffOnlyIn         = "diff.only_in"         // file
+	DiffOwner          = "diff.owner"           // owner
+	DiffOverlayHeader  = "diff.overlay_header"  // file1, file2
+	DiffOverlayRemoved = "diff.overlay_removed" // file1
+	DiffOverlaySecrets = "diff.overlay_secrets"
+
+	LintRemovedIn            = "lint.removed_in"            // key, version, current version
+	LintExperimentalOverride = "lint.experimental_override" // key, overlay file
+	LintRedundantOverride    = "lint.redundant_override"    // key, overlay file, file setting the same value
+	LintOrphanOverride       = "lint.orphan_override"       // key, overlay file, base file
+	LintDurationString       = "lint.duration_string"       // key, value
+	LintDurationInt          = "lint.duration_int"          // key, value
+	LintDurationTyped        = "lint.duration_typed"        // key, value
+	LintDuplicateValue       = "lint.duplicate_value"       // key, key with the same value
+	LintSecretLiteral        = "lint.secret_literal"        // key
+	LintUnusedFile           = "lint.unused_file"           // file
+	LintNameCollision        = "lint.name_collision"        // key, key with the same name, Go name
+	LintSummary              = "lint.summary"               // count
+
+	ValidateOK      = "validate.ok"      // file
+	ValidateSummary = "validate.summary" // count
+)
+
+// english is the built-in fallback catalog and defines the key set.
+var english = map[string]string{
+	DiffHeader:         "Differences between %s and %s:",
+	DiffNone:           "No differences found.",
+	DiffOnlyIn:         "only in %s",
+	DiffOwner:          "owner: %s",
+	DiffOverlayHeader:  "Overlay generated by cfgx: apply on top of %s to produce %s",
+	DiffOverlayRemoved: "The following keys exist only in %s and cannot be removed by an overlay:",
+	DiffOverlaySecrets: "The following secrets differ and are left out; pass --show-secrets to include them:",
+
+	LintRemovedIn:            "%s was scheduled for removal in %s (current version %s)",
+	LintExperimentalOverride: "%s is experimental but set in overlay %s",
+	LintRedundantOverride:    "%s is set in overlay %s to the value it already has from %s",
+	LintOrphanOverride:       "%s is set in overlay %s but overrides nothing in %s or earlier overlays",
+	LintDurationString:       "%s = %q looks like a duration but is not valid Go duration syntax and will be generated as a string",
+	LintDurationInt:          "%s = %d is a plain integer; use a duration string such as \"%ds\" to make the unit explicit",
+	LintDurationTyped:        "%s = %q is a duration but is annotated cfgx:type=string and will be generated as a string",
+	LintDuplicateValue:       "%s repeats the value of %s; consider keeping it in a single key",
+	LintSecretLiteral:        "%s is a secret written as a literal; read it with a file: reference or from an environment variable",
+	LintUnusedFile:           "%s is next to files read with file: references but no key references it",
+	LintNameCollision:        "%s and %s are both generated as %s",
+	LintSummary:              "%d issue(s) found",
+
+	ValidateOK:      "%s is valid",
+	ValidateSummary: "%d problem(s) found",
+}
+
+var (
+	mu       sync.RWMutex
+	locale   = "en"
+	catalogs = map[string]map[string]string{
+		"en": english,
+		"es": spanish,
+	}
+)
+
+// English returns the English template for key.
+func English(key string) string {
+	return english[key]
+}
+
+// Keys returns all message keys, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(english))
+	for k := range english {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Register adds or extends the catalog for a locale.
+func Register(tag string, messages map[string]string) {
+	tag = normalize(tag)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	catalog := catalogs[tag]
+	if catalog == nil {
+		catalog = make(map[string]string, len(messages))
+		catalogs[tag] = catalog
+	}
+	for k, v := range messages {
+		catalog[k] = v
+	}
+}
+
+// LoadFile registers a catalog from a TOML file.
+func LoadFile(path string) error {
+	var file struct {
+		Locale   string            `toml:"locale"`
+		Messages map[string]string `toml:"messages"`
+	}
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return fmt.Errorf("failed to load message catalog %s: %w", path, err)
+	}
+	if file.Locale == "" {
+		return fmt.Errorf("message catalog %s: missing locale", path)
+	}
+
+	for key := range file.Messages {
+		if _, ok := english[key]; !ok {
+			return fmt.Errorf("message catalog %s: unknown key %q", path, key)
+		}
+	}
+
+	Register(file.Locale, file.Messages)
+	return nil
+}
+
+// SetLocale sets the active locale. An empty tag selects the locale from the
+// environment (CFGX_LANG, LC_ALL, LC_MESSAGES, LANG), defaulting to English.
+func SetLocale(tag string) {
+	if tag == "" {
+		tag = FromEnv()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	locale = normalize(tag)
+}
+
+// FromEnv returns the locale configured in the environment, or "en".
+func FromEnv() string {
+	for _, name := range []string{"CFGX_LANG", "LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return "en"
+}
+
+// T returns the message for key in the active locale, formatted with args.
+func T(key string, args ...any) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	template, ok := lookup(locale, key)
+	if !ok {
+		template = key
+	}
+	if len(args) == 0 {
+		return template
+	}
+	return fmt.Sprintf(template, args...)
+}
+
+// lookup finds key for tag, falling back to its base language and English.
+func lookup(tag, key string) (string, bool) {
+	for {
+		if msg, ok := catalogs[tag][key]; ok {
+			return msg, true
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	msg, ok := english[key]
+	return msg, ok
+}
+
+// normalize converts POSIX locale names like "es_MX.UTF-8" to "es-mx".
+func normalize(tag string) string {
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(strings.ReplaceAll(tag, "_", "-"))
+}
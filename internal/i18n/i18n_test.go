@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestT_Fallback(t *testing.T) {
+	defer SetLocale("en")
+
+	SetLocale("es_MX.UTF-8")
+	require.Equal(t, "No se encontraron diferencias.", T(DiffNone), "es-mx should fall back to es")
+
+	SetLocale("fr-FR")
+	require.Equal(t, "No differences found.", T(DiffNone), "unknown locales should fall back to English")
+
+	require.Equal(t, "missing.key", T("missing.key"), "unknown keys should render as the key")
+}
+
+func TestLoadFile(t *testing.T) {
+	defer SetLocale("en")
+
+	path := filepath.Join(t.TempDir(), "de.toml")
+	err := os.WriteFile(path, []byte(`locale = "de"
+
+[messages]
+"lint.summary" = "%d Problem(e) gefunden"
+`), 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, LoadFile(path))
+
+	SetLocale("de-DE")
+	require.Equal(t, "3 Problem(e) gefunden", T(LintSummary, 3))
+	require.Equal(t, "No differences found.", T(DiffNone), "untranslated keys should fall back to English")
+}
+
+func TestLoadFile_UnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.toml")
+	err := os.WriteFile(path, []byte("locale = \"xx\"\n[messages]\n\"nope\" = \"x\"\n"), 0644)
+	require.NoError(t, err)
+
+	require.Error(t, LoadFile(path))
+}
+
+func TestCatalogs_Complete(t *testing.T) {
+	for key := range spanish {
+		_, ok := english[key]
+		require.True(t, ok, "spanish catalog has unknown key %q", key)
+	}
+	require.Len(t, spanish, len(english), "spanish catalog should translate every key")
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("CFGX_LANG", "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "C")
+	t.Setenv("LANG", "es_ES.UTF-8")
+	require.Equal(t, "es_ES.UTF-8", FromEnv())
+
+	t.Setenv("CFGX_LANG", "de")
+	require.Equal(t, "de", FromEnv())
+}
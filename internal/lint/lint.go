@@ -0,0 +1,598 @@
+// Package lint implements checks for suspicious configuration that is still
+// valid TOML and would generate fine, but is likely a mistake.
+package lint
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomantics/sx"
+
+	"github.com/gomantics/cfgx/internal/generator"
+	"github.com/gomantics/cfgx/internal/i18n"
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+// Severity is the severity of a lint issue.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Issue is a single problem reported by a rule.
+type Issue struct {
+	Rule     string           `json:"rule"`
+	Severity Severity         `json:"severity"`
+	File     string           `json:"file"`
+	Key      string           `json:"key"`
+	Pos      tomlsrc.Position `json:"pos"`
+	Message  string           `json:"message"`
+}
+
+// File is a parsed configuration file under lint.
+type File struct {
+	Path   string
+	Data   map[string]any
+	Source *tomlsrc.Source
+}
+
+// issue creates an issue located at key in the file.
+func (f *File) issue(rule string, severity Severity, key, message string) Issue {
+	pos, _ := f.Source.Position(key)
+	return Issue{
+		Rule:     rule,
+		Severity: severity,
+		File:     f.Path,
+		Key:      key,
+		Pos:      pos,
+		Message:  message,
+	}
+}
+
+// Context is what rules inspect.
+type Context struct {
+	// Base is the main configuration file.
+	Base *File
+
+	// Overlays are files layered on top of Base, in order (e.g. prod overrides).
+	Overlays []*File
+
+	// Version is the current version of the project, used by the removed-in
+	// rule. Empty disables version-based checks.
+	Version string
+
+	// Disable lists the names of the rules not to run.
+	Disable []string
+}
+
+// Rule is a named check.
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(ctx *Context) []Issue
+}
+
+// Rules lists all available rules.
+var Rules = []Rule{
+	{
+		Name:        "removed-in",
+		Description: "keys annotated cfgx:removed-in whose removal version has been reached",
+		Check:       checkRemovedIn,
+	},
+	{
+		Name:        "experimental-override",
+		Description: "overlays setting keys annotated cfgx:stability=experimental",
+		Check:       checkExperimentalOverride,
+	},
+	{
+		Name:        "redundant-override",
+		Description: "overlay keys set to the value they already have from the base file or earlier overlays",
+		Check:       checkRedundantOverride,
+	},
+	{
+		Name:        "orphan-override",
+		Description: "overlay keys that override nothing in the base file or earlier overlays, usually typos",
+		Check:       checkOrphanOverride,
+	},
+	{
+		Name:        "duration-type",
+		Description: "duration-like strings that will not generate as time.Duration, and integer _timeout/_interval keys",
+		Check:       checkDurationType,
+	},
+	{
+		Name:        "duplicate-value",
+		Description: "long strings repeated in different tables, which are easier to keep in sync as a single key",
+		Check:       checkDuplicateValue,
+	},
+	{
+		Name:        "secret-literal",
+		Description: "secrets written as literal values rather than file: references or environment variables",
+		Check:       checkSecretLiteral,
+	},
+	{
+		Name:        "unused-file",
+		Description: "files next to the targets of file: references that no key references",
+		Check:       checkUnusedFile,
+	},
+	{
+		Name:        "name-collision",
+		Description: "keys of a table whose Go names are the same, such as api_key and apiKey",
+		Check:       checkNameCollision,
+	},
+}
+
+// Lookup returns the rule with the given name.
+func Lookup(name string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Run runs all rules but those disabled in ctx and returns the issues sorted
+// by file and position. Issues at keys annotated cfgx:nolint, directly or
+// through their table, are dropped: a bare cfgx:nolint disables every rule,
+// and cfgx:nolint=rule1,rule2 the rules listed.
+func Run(ctx *Context) []Issue {
+	var issues []Issue
+	for _, rule := range Rules {
+		if slices.Contains(ctx.Disable, rule.Name) {
+			continue
+		}
+		for _, issue := range rule.Check(ctx) {
+			if !ctx.ignored(issue) {
+				issues = append(issues, issue)
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Pos.Line != b.Pos.Line {
+			return a.Pos.Line < b.Pos.Line
+		}
+		return a.Pos.Column < b.Pos.Column
+	})
+	return issues
+}
+
+// ignored reports whether issue is at a key annotated cfgx:nolint for its
+// rule.
+func (ctx *Context) ignored(issue Issue) bool {
+	if issue.Key == "" {
+		return false
+	}
+	for _, f := range append([]*File{ctx.Base}, ctx.Overlays...) {
+		if f.Path != issue.File {
+			continue
+		}
+		rules, ok := f.Source.Inherited(issue.Key, "nolint")
+		return ok && (rules == "" || slices.Contains(strings.Split(rules, ","), issue.Rule))
+	}
+	return false
+}
+
+// checkRemovedIn reports keys whose cfgx:removed-in version is at or below the
+// current project version.
+func checkRemovedIn(ctx *Context) []Issue {
+	if ctx.Version == "" {
+		return nil
+	}
+
+	var issues []Issue
+	for _, key := range ctx.Base.Source.Annotated("removed-in") {
+		removedIn, _ := ctx.Base.Source.Annotation(key, "removed-in")
+		if compareVersions(ctx.Version, removedIn) >= 0 {
+			issues = append(issues, ctx.Base.issue("removed-in", SeverityWarning, key,
+				i18n.T(i18n.LintRemovedIn, key, removedIn, ctx.Version)))
+		}
+	}
+	return issues
+}
+
+// checkExperimentalOverride reports overlay keys that are annotated as
+// experimental in the base file (directly or via their table).
+func checkExperimentalOverride(ctx *Context) []Issue {
+	var issues []Issue
+	for _, overlay := range ctx.Overlays {
+		for _, key := range leafKeys(overlay.Data, "") {
+			if stability, ok := ctx.Base.Source.Inherited(key, "stability"); ok && stability == "experimental" {
+				issues = append(issues, overlay.issue("experimental-override", SeverityWarning, key,
+					i18n.T(i18n.LintExperimentalOverride, key, overlay.Path)))
+			}
+		}
+	}
+	return issues
+}
+
+// checkRedundantOverride reports overlay keys whose value is the one in effect
+// before the overlay is applied, which makes them noise.
+func checkRedundantOverride(ctx *Context) []Issue {
+	var issues []Issue
+	walkOverrides(ctx, func(overlay *File, key string, previous any, from string) {
+		if previous != nil && reflect.DeepEqual(previous, lookup(overlay.Data, key)) {
+			issues = append(issues, overlay.issue("redundant-override", SeverityWarning, key,
+				i18n.T(i18n.LintRedundantOverride, key, overlay.Path, from)))
+		}
+	})
+	return issues
+}
+
+// checkOrphanOverride reports overlay keys that are defined neither in the
+// base file nor in an earlier overlay.
+func checkOrphanOverride(ctx *Context) []Issue {
+	var issues []Issue
+	walkOverrides(ctx, func(overlay *File, key string, previous any, _ string) {
+		if previous == nil {
+			issues = append(issues, overlay.issue("orphan-override", SeverityWarning, key,
+				i18n.T(i18n.LintOrphanOverride, key, overlay.Path, ctx.Base.Path)))
+		}
+	})
+	return issues
+}
+
+// walkOverrides calls fn for every key set by an overlay, in merge order, with
+// the value in effect before the overlay is applied (nil if there is none) and
+// the path of the file that set it.
+func walkOverrides(ctx *Context, fn func(overlay *File, key string, previous any, from string)) {
+	effective := make(map[string]any)
+	origin := make(map[string]string)
+	apply := func(f *File) {
+		for _, key := range leafKeys(f.Data, "") {
+			set(effective, key, lookup(f.Data, key))
+			origin[key] = f.Path
+		}
+	}
+
+	apply(ctx.Base)
+	for _, overlay := range ctx.Overlays {
+		for _, key := range leafKeys(overlay.Data, "") {
+			fn(overlay, key, lookup(effective, key), origin[key])
+		}
+		apply(overlay)
+	}
+}
+
+// durationLike matches strings that a human would read as a duration, including
+// spellings time.ParseDuration rejects ("30 s", "5min", "1d").
+var durationLike = regexp.MustCompile(`^\s*\d+(\.\d+)?\s*(ns|us|µs|ms|s|m|h|d|w|sec|secs|second|seconds|min|mins|minute|minutes|hr|hrs|hour|hours|day|days|week|weeks)\s*$`)
+
+// durationSuffixes are key suffixes that imply the value is a duration.
+var durationSuffixes = []string{"_timeout", "_interval"}
+
+// checkDurationType reports strings that look like durations but are not
+// generated as time.Duration, because their syntax is not Go's or they are
+// annotated cfgx:type=string, and integer values under duration-named keys,
+// whose unit is left to the reader's guess.
+func checkDurationType(ctx *Context) []Issue {
+	var issues []Issue
+	for _, f := range append([]*File{ctx.Base}, ctx.Overlays...) {
+		for _, key := range leafKeys(f.Data, "") {
+			switch v := lookup(f.Data, key).(type) {
+			case string:
+				_, err := time.ParseDuration(v)
+				var typeName string
+				if f.Source != nil {
+					typeName, _ = f.Source.Annotation(key, "type")
+				}
+				switch {
+				case err != nil && durationLike.MatchString(v):
+					issues = append(issues, f.issue("duration-type", SeverityWarning, key,
+						i18n.T(i18n.LintDurationString, key, v)))
+				case err == nil && typeName == "string":
+					issues = append(issues, f.issue("duration-type", SeverityWarning, key,
+						i18n.T(i18n.LintDurationTyped, key, v)))
+				}
+			case int64:
+				name := key[strings.LastIndex(key, ".")+1:]
+				for _, suffix := range durationSuffixes {
+					if strings.HasSuffix(name, suffix) || name == suffix[1:] {
+						issues = append(issues, f.issue("duration-type", SeverityWarning, key,
+							i18n.T(i18n.LintDurationInt, key, v, v)))
+						break
+					}
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// minDuplicateLen is the length of the shortest strings reported by the
+// duplicate-value rule: shorter ones, such as "info" or "tcp", repeat by
+// coincidence.
+const minDuplicateLen = 8
+
+// checkDuplicateValue reports strings set in a table that a key of another
+// table of the same file already has, such as a hostname repeated in several
+// connection strings, which must then be changed everywhere at once.
+func checkDuplicateValue(ctx *Context) []Issue {
+	var issues []Issue
+	for _, f := range append([]*File{ctx.Base}, ctx.Overlays...) {
+		first := make(map[string]string)
+		for _, key := range f.sourceOrder(leafKeys(f.Data, "")) {
+			v, ok := lookup(f.Data, key).(string)
+			if !ok || len(v) < minDuplicateLen {
+				continue
+			}
+			other, seen := first[v]
+			if !seen {
+				first[v] = key
+				continue
+			}
+			if parent(other) != parent(key) {
+				issues = append(issues, f.issue("duplicate-value", SeverityWarning, key,
+					i18n.T(i18n.LintDuplicateValue, key, other)))
+			}
+		}
+	}
+	return issues
+}
+
+// checkSecretLiteral reports non-empty secrets, recognized as the generator
+// does, whose value is written in the file rather than read from a file: or
+// ref: reference, expanded from an environment variable or encrypted with
+// cfgx:encrypted.
+func checkSecretLiteral(ctx *Context) []Issue {
+	var issues []Issue
+	for _, f := range append([]*File{ctx.Base}, ctx.Overlays...) {
+		for _, key := range leafKeys(f.Data, "") {
+			v, ok := lookup(f.Data, key).(string)
+			if !ok || v == "" || strings.HasPrefix(v, "file:") || strings.HasPrefix(v, "ref:") || strings.Contains(v, "$") {
+				continue
+			}
+			if f.Source != nil {
+				if _, ok := f.Source.Annotation(key, "encrypted"); ok {
+					continue
+				}
+			}
+			if generator.IsSecret(f.Source, key) {
+				issues = append(issues, f.issue("secret-literal", SeverityWarning, key,
+					i18n.T(i18n.LintSecretLiteral, key)))
+			}
+		}
+	}
+	return issues
+}
+
+// checkUnusedFile reports the files of the directories holding the targets
+// of file: references that no file: reference of any file targets, such as
+// a certificate left behind by a rotation. The directories of the files
+// under lint are skipped, since they hold other files too.
+func checkUnusedFile(ctx *Context) []Issue {
+	files := append([]*File{ctx.Base}, ctx.Overlays...)
+	referenced := make(map[string]bool)
+	skip := make(map[string]bool)
+	for _, f := range files {
+		dir := filepath.Dir(f.Path)
+		skip[filepath.Clean(dir)] = true
+		referenced[filepath.Clean(f.Path)] = true
+		for _, v := range stringValues(f.Data) {
+			target, ok := strings.CutPrefix(v, "file:")
+			if !ok {
+				continue
+			}
+			target = filepath.FromSlash(target)
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			referenced[filepath.Clean(target)] = true
+		}
+	}
+
+	dirs := make(map[string]bool)
+	for path := range referenced {
+		if dir := filepath.Dir(path); !skip[dir] {
+			dirs[dir] = true
+		}
+	}
+
+	var issues []Issue
+	for _, dir := range slices.Sorted(maps.Keys(dirs)) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") || referenced[path] {
+				continue
+			}
+			issues = append(issues, Issue{
+				Rule:     "unused-file",
+				Severity: SeverityWarning,
+				File:     path,
+				Message:  i18n.T(i18n.LintUnusedFile, path),
+			})
+		}
+	}
+	return issues
+}
+
+// checkNameCollision reports keys of a table whose Go names, derived as by
+// the generator, are the same as those of keys written before them, which
+// fails generation.
+func checkNameCollision(ctx *Context) []Issue {
+	var issues []Issue
+	for _, f := range append([]*File{ctx.Base}, ctx.Overlays...) {
+		var walk func(table map[string]any, prefix string)
+		walk = func(table map[string]any, prefix string) {
+			keys := make([]string, 0, len(table))
+			for k := range table {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+
+			names := make(map[string]string)
+			for _, key := range f.sourceOrder(keys) {
+				k := key[strings.LastIndex(key, ".")+1:]
+				name := sx.PascalCase(k)
+				if other, ok := names[name]; ok {
+					issues = append(issues, f.issue("name-collision", SeverityError, key,
+						i18n.T(i18n.LintNameCollision, key, other, name)))
+				} else {
+					names[name] = key
+				}
+				if nested, ok := table[k].(map[string]any); ok {
+					walk(nested, key)
+				}
+			}
+		}
+		walk(f.Data, "")
+	}
+	return issues
+}
+
+// sourceOrder returns keys sorted by where they are written in the file.
+func (f *File) sourceOrder(keys []string) []string {
+	sorted := slices.Clone(keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := f.Source.Position(sorted[i])
+		b, _ := f.Source.Position(sorted[j])
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return sorted
+}
+
+// parent returns the dotted path of the table holding key.
+func parent(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// stringValues returns all strings in v, including those in arrays and
+// arrays of tables.
+func stringValues(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case map[string]any:
+		var values []string
+		for _, elem := range v {
+			values = append(values, stringValues(elem)...)
+		}
+		return values
+	case []any:
+		var values []string
+		for _, elem := range v {
+			values = append(values, stringValues(elem)...)
+		}
+		return values
+	case []map[string]any:
+		var values []string
+		for _, elem := range v {
+			values = append(values, stringValues(elem)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// lookup returns the value at a dotted key path produced by leafKeys.
+func lookup(data map[string]any, key string) any {
+	var v any = data
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[part]
+	}
+	return v
+}
+
+// set stores v at a dotted key path produced by leafKeys, creating tables
+// along the way and replacing any other value in their place.
+func set(data map[string]any, key string, v any) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		m, ok := data[part].(map[string]any)
+		if !ok {
+			m = make(map[string]any)
+			data[part] = m
+		}
+		data = m
+	}
+	data[parts[len(parts)-1]] = v
+}
+
+// leafKeys returns the dotted paths of all non-table values in data.
+func leafKeys(data map[string]any, prefix string) []string {
+	var keys []string
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := v.(map[string]any); ok {
+			keys = append(keys, leafKeys(m, key)...)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compareVersions compares dotted numeric versions such as "1.2" or "v2.0.1".
+// Missing components count as zero and non-numeric suffixes are ignored.
+func compareVersions(a, b string) int {
+	pa := versionParts(a)
+	pb := versionParts(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+
+	var parts []int
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,239 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/gomantics/cfgx/internal/tomlsrc"
+)
+
+func parseFile(t *testing.T, path, src string) *File {
+	t.Helper()
+
+	var data map[string]any
+	require.NoError(t, toml.Unmarshal([]byte(src), &data))
+	return &File{Path: path, Data: data, Source: tomlsrc.Scan([]byte(src))}
+}
+
+func TestRun_RemovedIn(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[server]
+# cfgx:removed-in=2.0
+legacy_mode = true
+addr = ":8080"
+`)
+
+	issues := Run(&Context{Base: base, Version: "1.9.3"})
+	require.Empty(t, issues, "removal version not reached yet")
+
+	issues = Run(&Context{Base: base, Version: "v2.0.0"})
+	require.Len(t, issues, 1)
+	require.Equal(t, "removed-in", issues[0].Rule)
+	require.Equal(t, "server.legacy_mode", issues[0].Key)
+	require.Equal(t, tomlsrc.Position{Line: 4, Column: 1}, issues[0].Pos)
+}
+
+func TestRun_ExperimentalOverride(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+# cfgx:stability=experimental
+[beta]
+enabled = false
+
+[server]
+addr = ":8080"
+turbo = false # cfgx:stability=experimental
+`)
+	prod := parseFile(t, "prod.toml", `
+[beta]
+enabled = true
+
+[server]
+addr = ":443"
+turbo = true
+`)
+
+	issues := Run(&Context{Base: base, Overlays: []*File{prod}})
+	require.Len(t, issues, 2)
+	require.Equal(t, "beta.enabled", issues[0].Key)
+	require.Equal(t, "prod.toml", issues[0].File)
+	require.Equal(t, "server.turbo", issues[1].Key)
+}
+
+func TestRun_Overrides(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[server]
+addr = ":8080"
+tags = ["a", "b"]
+`)
+	staging := parseFile(t, "staging.toml", `
+[server]
+addr = ":8080"
+debug = true
+`)
+	prod := parseFile(t, "prod.toml", `
+[server]
+addr = ":443"
+adr = ":443"
+debug = true
+tags = ["a", "b"]
+`)
+
+	issues := Run(&Context{Base: base, Overlays: []*File{staging, prod}})
+	var got []string
+	for _, issue := range issues {
+		got = append(got, issue.File+" "+issue.Rule+" "+issue.Key)
+	}
+	require.Equal(t, []string{
+		"prod.toml orphan-override server.adr",
+		"prod.toml redundant-override server.debug",
+		"prod.toml redundant-override server.tags",
+		"staging.toml redundant-override server.addr",
+		"staging.toml orphan-override server.debug",
+	}, got)
+	require.Equal(t, "server.debug is set in overlay prod.toml to the value it already has from staging.toml", issues[1].Message)
+}
+
+func TestRun_DurationType(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[server]
+read_timeout = "30s"
+write_timeout = "30 s"
+retention = "7d"
+label = "5 apples"
+grace = "10s" # cfgx:type=string
+
+[worker]
+poll_interval = 15
+timeout = 60
+retries = 3
+`)
+
+	issues := Run(&Context{Base: base})
+	var keys []string
+	for _, issue := range issues {
+		require.Equal(t, "duration-type", issue.Rule)
+		keys = append(keys, issue.Key)
+	}
+	require.Equal(t, []string{"server.write_timeout", "server.retention", "server.grace", "worker.poll_interval", "worker.timeout"}, keys)
+}
+
+func TestRun_DuplicateValue(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[primary]
+host = "db.internal.example.com"
+user = "app"
+
+[replica]
+host = "db.internal.example.com"
+user = "app"
+
+[cache]
+url = "redis://cache:6379"
+fallback = "redis://cache:6379"
+`)
+
+	issues := Run(&Context{Base: base})
+	require.Len(t, issues, 1)
+	require.Equal(t, "duplicate-value", issues[0].Rule)
+	require.Equal(t, "replica.host", issues[0].Key)
+	require.Equal(t, "replica.host repeats the value of primary.host; consider keeping it in a single key", issues[0].Message)
+}
+
+func TestRun_SecretLiteral(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[db]
+password = "hunter2"
+api_token = "file:secrets/token"
+auth = "s3cr3t" # cfgx:secret
+signing_secret = "${SIGNING_SECRET}"
+secret_name = "prod-db" # cfgx:secret=false
+private_key = ""
+signing_key = "aes:APP_KEY:AAAA" # cfgx:encrypted
+`)
+
+	var keys []string
+	for _, issue := range Run(&Context{Base: base, Disable: []string{"unused-file"}}) {
+		require.Equal(t, "secret-literal", issue.Rule)
+		keys = append(keys, issue.Key)
+	}
+	require.Equal(t, []string{"db.password", "db.auth"}, keys)
+}
+
+func TestRun_UnusedFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "certs"), 0755))
+	for _, name := range []string{"certs/ca.pem", "certs/old.pem", "certs/.keep", "notes.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+	base := parseFile(t, filepath.Join(dir, "config.toml"), `
+[tls]
+ca = "file:certs/ca.pem"
+`)
+
+	issues := Run(&Context{Base: base})
+	require.Len(t, issues, 1)
+	require.Equal(t, "unused-file", issues[0].Rule)
+	require.Equal(t, filepath.Join(dir, "certs", "old.pem"), issues[0].File)
+}
+
+func TestRun_NameCollision(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[server]
+api_key = "a" # cfgx:nolint=secret-literal
+apiKey = "b" # cfgx:nolint=secret-literal
+`)
+
+	issues := Run(&Context{Base: base})
+	require.Len(t, issues, 1)
+	require.Equal(t, "name-collision", issues[0].Rule)
+	require.Equal(t, SeverityError, issues[0].Severity)
+	require.Equal(t, "server.apiKey and server.api_key are both generated as ApiKey", issues[0].Message)
+}
+
+func TestRun_Disable(t *testing.T) {
+	base := parseFile(t, "config.toml", `
+[worker]
+poll_interval = 15
+password = "hunter2"
+
+# cfgx:nolint
+[legacy]
+timeout = 60
+token = "t"
+`)
+
+	var rules []string
+	for _, issue := range Run(&Context{Base: base}) {
+		rules = append(rules, issue.Rule+" "+issue.Key)
+	}
+	require.Equal(t, []string{"duration-type worker.poll_interval", "secret-literal worker.password"}, rules)
+
+	issues := Run(&Context{Base: base, Disable: []string{"duration-type"}})
+	require.Len(t, issues, 1)
+	require.Equal(t, "secret-literal", issues[0].Rule)
+
+	_, ok := Lookup("secret-literal")
+	require.True(t, ok)
+	_, ok = Lookup("nope")
+	require.False(t, ok)
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0.0", 0},
+		{"v2.1", "2.0", 1},
+		{"1.9.9", "2", -1},
+		{"2.0.0-rc1", "2.0", 0},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.want, compareVersions(tt.a, tt.b), "compareVersions(%q, %q)", tt.a, tt.b)
+	}
+}
@@ -0,0 +1,326 @@
+// Package migrate detects keys renamed or moved between two versions of a
+// TOML configuration and generates Go code that rewrites files in the old
+// format to the new one.
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rename is a key, or a whole table, that moved to a new path.
+type Rename struct {
+	From string // Dotted path in the old format
+	To   string // Dotted path in the new format
+}
+
+// Plan is the set of changes between two versions of a configuration.
+type Plan struct {
+	Renames []Rename // Keys and tables moved, sorted by From
+	Removed []string // Old keys with no counterpart in the new format
+	Added   []string // New keys with no counterpart in the old format
+}
+
+// Detect compares the old and new versions of a configuration. A key
+// removed from old and a key added to new with an equal value are taken to
+// be the same key under a new path; when several added keys hold that
+// value, the one with the same last path element wins; if the match is still
+// ambiguous the key is reported as removed. Tables whose every key moved to
+// the same new table are reported as a single rename. Explicit renames are
+// taken as given and override detection for their keys.
+func Detect(old, new map[string]any, explicit []Rename) Plan {
+	oldLeaves := leaves(old, "", nil)
+	newLeaves := leaves(new, "", nil)
+
+	var removed, added []string
+	for key := range oldLeaves {
+		if _, ok := newLeaves[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	for key := range newLeaves {
+		if _, ok := oldLeaves[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(added)
+
+	to := make(map[string]string)
+	taken := make(map[string]bool)
+	for _, r := range explicit {
+		for from := range oldLeaves {
+			if suffix, ok := under(from, r.From); ok {
+				to[from] = join(r.To, suffix)
+				taken[join(r.To, suffix)] = true
+			}
+		}
+	}
+
+	// Keys that kept their name are matched first, so that they are not
+	// taken by a renamed key with the same value
+	for _, sameName := range []bool{true, false} {
+		for _, from := range removed {
+			if _, ok := to[from]; ok {
+				continue
+			}
+			var candidates []string
+			for _, key := range added {
+				if !taken[key] && reflect.DeepEqual(oldLeaves[from], newLeaves[key]) && (!sameName || last(key) == last(from)) {
+					candidates = append(candidates, key)
+				}
+			}
+			if len(candidates) == 1 {
+				to[from] = candidates[0]
+				taken[candidates[0]] = true
+			}
+		}
+	}
+
+	var plan Plan
+	for _, key := range removed {
+		if _, ok := to[key]; !ok {
+			plan.Removed = append(plan.Removed, key)
+		}
+	}
+	for _, key := range added {
+		if !taken[key] {
+			plan.Added = append(plan.Added, key)
+		}
+	}
+	plan.Renames = collapse(to, old, new)
+	return plan
+}
+
+// collapse turns the leaf renames in to into a list of renames, replacing
+// the renames of every key of a table that moved as a whole with one rename
+// of the table.
+func collapse(to map[string]string, old, new map[string]any) []Rename {
+	froms := make([]string, 0, len(to))
+	for from := range to {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	var renames []Rename
+	done := make(map[string]bool)
+	for _, from := range froms {
+		if done[from] {
+			continue
+		}
+		r := Rename{From: from, To: to[from]}
+		// Widen the rename while the parent tables moved as a whole
+		for {
+			fromTable, toTable := parent(r.From), parent(r.To)
+			if fromTable == "" || toTable == "" || last(r.From) != last(r.To) {
+				break
+			}
+			if _, ok := lookup(new, fromTable); ok {
+				break
+			}
+			if _, ok := lookup(old, toTable); ok {
+				break
+			}
+			table, _ := lookup(old, fromTable)
+			moved := true
+			for key := range leaves(table.(map[string]any), fromTable, nil) {
+				suffix, _ := under(key, fromTable)
+				if to[key] != join(toTable, suffix) {
+					moved = false
+					break
+				}
+			}
+			if !moved {
+				break
+			}
+			r = Rename{From: fromTable, To: toTable}
+		}
+		for _, key := range froms {
+			if _, ok := under(key, r.From); ok {
+				done[key] = true
+			}
+		}
+		renames = append(renames, r)
+	}
+	return renames
+}
+
+// leaves flattens data into its values keyed by dotted path. Arrays,
+// including arrays of tables, are values.
+func leaves(data map[string]any, prefix string, out map[string]any) map[string]any {
+	if out == nil {
+		out = make(map[string]any)
+	}
+	for key, v := range data {
+		if table, ok := v.(map[string]any); ok {
+			leaves(table, join(prefix, key), out)
+			continue
+		}
+		out[join(prefix, key)] = v
+	}
+	return out
+}
+
+// lookup returns the value at a dotted path of data.
+func lookup(data map[string]any, key string) (any, bool) {
+	var v any = data
+	for _, part := range strings.Split(key, ".") {
+		table, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = table[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// under reports whether key is table or inside it, returning the rest of
+// the path.
+func under(key, table string) (string, bool) {
+	if key == table {
+		return "", true
+	}
+	rest, ok := strings.CutPrefix(key, table+".")
+	return rest, ok
+}
+
+func join(prefix, key string) string {
+	switch {
+	case prefix == "":
+		return key
+	case key == "":
+		return prefix
+	}
+	return prefix + "." + key
+}
+
+func parent(key string) string {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+func last(key string) string {
+	return key[strings.LastIndex(key, ".")+1:]
+}
+
+// Options configure the generated migration code.
+type Options struct {
+	Package  string // Package name of the generated file
+	FuncName string // Name of the exported migration function
+	Source   string // Description of the compared files, for the header comment
+}
+
+// Generate returns Go source for a migration function that moves the keys
+// renamed in plan, in a map decoded from a file in the old format, to their
+// new paths. It is a skeleton: keys removed without a counterpart are listed
+// in TODO comments for the maintainer to handle.
+func Generate(plan Plan, opts Options) ([]byte, error) {
+	if !isIdent(opts.Package) {
+		return nil, fmt.Errorf("invalid package name %q", opts.Package)
+	}
+	if !isIdent(opts.FuncName) || !unicode.IsUpper(firstRune(opts.FuncName)) {
+		return nil, fmt.Errorf("invalid function name %q: must be an exported Go identifier", opts.FuncName)
+	}
+	prefix := string(unicode.ToLower(firstRune(opts.FuncName))) + opts.FuncName[utf8.RuneLen(firstRune(opts.FuncName)):]
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by cfgx migrate-gen")
+	if opts.Source != "" {
+		fmt.Fprintf(&buf, " from %s", opts.Source)
+	}
+	buf.WriteString(".\n// This is a starting point: review it, then edit it as needed.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", opts.Package)
+	buf.WriteString("import (\n\t\"fmt\"\n\t\"strings\"\n)\n\n")
+
+	fmt.Fprintf(&buf, "// %sRenames maps keys and tables of the old config format to their new path.\n", prefix)
+	fmt.Fprintf(&buf, "var %sRenames = []struct{ from, to string }{\n", prefix)
+	for _, r := range plan.Renames {
+		fmt.Fprintf(&buf, "\t{%q, %q},\n", r.From, r.To)
+	}
+	buf.WriteString("}\n\n")
+
+	fmt.Fprintf(&buf, "// %s rewrites data, decoded from a file in the old config format, to the\n", opts.FuncName)
+	buf.WriteString("// current format in place, so that old files keep loading during the\n")
+	buf.WriteString("// transition. It returns a deprecation warning for every old key found.\n")
+	if len(plan.Removed) > 0 {
+		buf.WriteString("//\n// TODO: these old keys have no counterpart in the new format; map them by\n")
+		buf.WriteString("// hand or drop them:\n//\n")
+		for _, key := range plan.Removed {
+			fmt.Fprintf(&buf, "//   - %s\n", key)
+		}
+	}
+	if len(plan.Added) > 0 {
+		buf.WriteString("//\n// These new keys have no counterpart in the old format and take their defaults:\n//\n")
+		for _, key := range plan.Added {
+			fmt.Fprintf(&buf, "//   - %s\n", key)
+		}
+	}
+	fmt.Fprintf(&buf, "func %s(data map[string]any) []string {\n", opts.FuncName)
+	buf.WriteString("\tvar warnings []string\n")
+	fmt.Fprintf(&buf, "\tfor _, r := range %sRenames {\n", prefix)
+	fmt.Fprintf(&buf, "\t\tv, ok := %sTake(data, r.from)\n", prefix)
+	buf.WriteString("\t\tif !ok {\n\t\t\tcontinue\n\t\t}\n")
+	fmt.Fprintf(&buf, "\t\tif !%sSet(data, r.to, v) {\n", prefix)
+	buf.WriteString("\t\t\twarnings = append(warnings, fmt.Sprintf(\"%s is deprecated and ignored because %s is set\", r.from, r.to))\n")
+	buf.WriteString("\t\t\tcontinue\n\t\t}\n")
+	buf.WriteString("\t\twarnings = append(warnings, fmt.Sprintf(\"%s is deprecated, use %s\", r.from, r.to))\n")
+	buf.WriteString("\t}\n")
+	buf.WriteString("\treturn warnings\n}\n\n")
+
+	fmt.Fprintf(&buf, "// %sTake removes and returns the value at a dotted path of data.\n", prefix)
+	fmt.Fprintf(&buf, "func %sTake(data map[string]any, key string) (any, bool) {\n", prefix)
+	buf.WriteString("\tpath := strings.Split(key, \".\")\n")
+	buf.WriteString("\tfor _, part := range path[:len(path)-1] {\n")
+	buf.WriteString("\t\ttable, ok := data[part].(map[string]any)\n")
+	buf.WriteString("\t\tif !ok {\n\t\t\treturn nil, false\n\t\t}\n")
+	buf.WriteString("\t\tdata = table\n\t}\n")
+	buf.WriteString("\tv, ok := data[path[len(path)-1]]\n")
+	buf.WriteString("\tdelete(data, path[len(path)-1])\n")
+	buf.WriteString("\treturn v, ok\n}\n\n")
+
+	fmt.Fprintf(&buf, "// %sSet sets the value at a dotted path of data, creating tables as\n", prefix)
+	buf.WriteString("// needed. It reports false, leaving data unchanged, if the path is already set.\n")
+	fmt.Fprintf(&buf, "func %sSet(data map[string]any, key string, v any) bool {\n", prefix)
+	buf.WriteString("\tpath := strings.Split(key, \".\")\n")
+	buf.WriteString("\tfor _, part := range path[:len(path)-1] {\n")
+	buf.WriteString("\t\tif _, ok := data[part]; !ok {\n\t\t\tdata[part] = make(map[string]any)\n\t\t}\n")
+	buf.WriteString("\t\ttable, ok := data[part].(map[string]any)\n")
+	buf.WriteString("\t\tif !ok {\n\t\t\treturn false\n\t\t}\n")
+	buf.WriteString("\t\tdata = table\n\t}\n")
+	buf.WriteString("\tif _, ok := data[path[len(path)-1]]; ok {\n\t\treturn false\n\t}\n")
+	buf.WriteString("\tdata[path[len(path)-1]] = v\n")
+	buf.WriteString("\treturn true\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return src, nil
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, src string) map[string]any {
+	t.Helper()
+	var data map[string]any
+	_, err := toml.Decode(src, &data)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDetect(t *testing.T) {
+	old := decode(t, `
+name = "svc"
+debug = true
+
+[server]
+addr = ":8080"
+timeout = "5s"
+
+[db]
+dsn = "postgres://db"
+pool = 10
+
+[cache]
+ttl = "1m"
+size = 10
+`)
+	new := decode(t, `
+name = "svc"
+
+[http]
+addr = ":8080"
+
+[server]
+read_timeout = "5s"
+
+[database]
+dsn = "postgres://db"
+pool = 10
+
+[cache]
+ttl = "1m"
+max_entries = 10
+retries = 3
+`)
+
+	plan := Detect(old, new, nil)
+	require.Equal(t, []Rename{
+		{From: "cache.size", To: "cache.max_entries"},
+		{From: "db", To: "database"},
+		{From: "server.addr", To: "http.addr"},
+		{From: "server.timeout", To: "server.read_timeout"},
+	}, plan.Renames)
+	require.Equal(t, []string{"debug"}, plan.Removed)
+	require.Equal(t, []string{"cache.retries"}, plan.Added)
+
+	// Explicit renames apply whatever the values
+	plan = Detect(old, new, []Rename{{From: "debug", To: "cache.retries"}})
+	require.Contains(t, plan.Renames, Rename{From: "debug", To: "cache.retries"})
+	require.Empty(t, plan.Removed)
+	require.Empty(t, plan.Added)
+}
+
+func TestDetect_Ambiguous(t *testing.T) {
+	old := decode(t, "a = 1\n")
+	new := decode(t, "b = 1\nc = 1\n")
+
+	plan := Detect(old, new, nil)
+	require.Empty(t, plan.Renames)
+	require.Equal(t, []string{"a"}, plan.Removed)
+	require.Equal(t, []string{"b", "c"}, plan.Added)
+
+	// The same last path element breaks the tie
+	old = decode(t, "[x]\na = 1\n")
+	new = decode(t, "[y]\na = 1\nb = 1\n")
+	plan = Detect(old, new, nil)
+	require.Equal(t, []Rename{{From: "x", To: "y"}}, plan.Renames[:1])
+}
+
+func TestGenerate(t *testing.T) {
+	plan := Plan{
+		Renames: []Rename{{From: "db", To: "database"}, {From: "server.addr", To: "http.addr"}},
+		Removed: []string{"debug"},
+		Added:   []string{"cache.retries"},
+	}
+
+	src, err := Generate(plan, Options{Package: "config", FuncName: "MigrateV1", Source: "v1.toml and v2.toml"})
+	require.NoError(t, err)
+	output := string(src)
+	require.Contains(t, output, "// Code generated by cfgx migrate-gen from v1.toml and v2.toml.\n")
+	require.Contains(t, output, "package config\n")
+	require.Contains(t, output, "var migrateV1Renames = []struct{ from, to string }{\n\t{\"db\", \"database\"},\n\t{\"server.addr\", \"http.addr\"},\n}")
+	require.Contains(t, output, "//   - debug\n")
+	require.Contains(t, output, "//   - cache.retries\n")
+	require.Contains(t, output, "func MigrateV1(data map[string]any) []string {")
+	require.Contains(t, output, "func migrateV1Take(data map[string]any, key string) (any, bool) {")
+	require.Contains(t, output, "func migrateV1Set(data map[string]any, key string, v any) bool {")
+
+	_, err = parser.ParseFile(token.NewFileSet(), "", src, 0)
+	require.NoError(t, err)
+
+	_, err = Generate(plan, Options{Package: "config", FuncName: "migrate"})
+	require.ErrorContains(t, err, `invalid function name "migrate"`)
+	_, err = Generate(plan, Options{Package: "my-config", FuncName: "Migrate"})
+	require.ErrorContains(t, err, `invalid package name "my-config"`)
+}
@@ -0,0 +1,975 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// This file implements the subset of CEL (https://cel.dev) that policies are
+// written in: literals, field selection and indexing, arithmetic, comparison,
+// logical and conditional operators, the "in" operator, the has() macro, the
+// all() and exists() list macros, size(), string methods and conversions.
+
+// node is a parsed expression.
+type node interface {
+	eval(env *scope) (any, error)
+}
+
+// scope holds the variables visible to an expression: the top-level keys of
+// the configuration and the variables bound by macros.
+type scope struct {
+	vars   map[string]any
+	parent *scope
+}
+
+func (s *scope) lookup(name string) (any, bool) {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+type (
+	literal struct{ v any }
+	ident   struct{ name string }
+	selectN struct {
+		x     node
+		field string
+	}
+	indexN struct{ x, index node }
+	listN  struct{ elems []node }
+	unary  struct {
+		op string
+		x  node
+	}
+	binary struct {
+		op   string
+		x, y node
+	}
+	cond struct{ c, t, f node }
+	call struct {
+		fn   string
+		recv node // nil for global functions
+		args []node
+	}
+	hasN  struct{ sel *selectN }
+	macro struct {
+		name  string
+		list  node
+		v     string
+		predN node
+	}
+)
+
+func (n literal) eval(*scope) (any, error) { return n.v, nil }
+
+func (n ident) eval(env *scope) (any, error) {
+	v, ok := env.lookup(n.name)
+	if !ok {
+		return nil, fmt.Errorf("undeclared reference to %q", n.name)
+	}
+	return v, nil
+}
+
+func (n selectN) eval(env *scope) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cannot select %q from %s", n.field, typeName(x))
+	}
+	v, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return v, nil
+}
+
+func (n indexN) eval(env *scope) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch x := x.(type) {
+	case map[string]any:
+		key, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index table with %s", typeName(i))
+		}
+		v, ok := x[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", key)
+		}
+		return v, nil
+	}
+	list, ok := asList(x)
+	if !ok {
+		return nil, fmt.Errorf("cannot index %s", typeName(x))
+	}
+	idx, ok := i.(int64)
+	if !ok {
+		return nil, fmt.Errorf("list index must be an int, got %s", typeName(i))
+	}
+	if idx < 0 || idx >= int64(len(list)) {
+		return nil, fmt.Errorf("index %d out of range [0, %d)", idx, len(list))
+	}
+	return list[idx], nil
+}
+
+func (n listN) eval(env *scope) (any, error) {
+	list := make([]any, len(n.elems))
+	for i, e := range n.elems {
+		v, err := e.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (n unary) eval(env *scope) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! needs a bool, got %s", typeName(x))
+		}
+		return !b, nil
+	default: // "-"
+		switch x := x.(type) {
+		case int64:
+			return -x, nil
+		case float64:
+			return -x, nil
+		case time.Duration:
+			return -x, nil
+		}
+		return nil, fmt.Errorf("operator - needs a number, got %s", typeName(x))
+	}
+}
+
+func (n binary) eval(env *scope) (any, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit
+	if n.op == "&&" || n.op == "||" {
+		bx, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %s", n.op, typeName(x))
+		}
+		if bx == (n.op == "||") {
+			return bx, nil
+		}
+		y, err := n.y.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		by, ok := y.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s needs bools, got %s", n.op, typeName(y))
+		}
+		return by, nil
+	}
+
+	y, err := n.y.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(x, y), nil
+	case "!=":
+		return !equal(x, y), nil
+	case "in":
+		if m, ok := y.(map[string]any); ok {
+			key, ok := x.(string)
+			_, found := m[key]
+			return ok && found, nil
+		}
+		list, ok := asList(y)
+		if !ok {
+			return nil, fmt.Errorf("operator in needs a list or table, got %s", typeName(y))
+		}
+		for _, e := range list {
+			if equal(x, e) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(x, y)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}
+	return arith(n.op, x, y)
+}
+
+func (n cond) eval(env *scope) (any, error) {
+	c, err := n.c.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := c.(bool)
+	if !ok {
+		return nil, fmt.Errorf("condition must be a bool, got %s", typeName(c))
+	}
+	if b {
+		return n.t.eval(env)
+	}
+	return n.f.eval(env)
+}
+
+func (n hasN) eval(env *scope) (any, error) {
+	x, err := n.sel.x.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := x.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("has() needs a table, got %s", typeName(x))
+	}
+	_, found := m[n.sel.field]
+	return found, nil
+}
+
+func (n macro) eval(env *scope) (any, error) {
+	x, err := n.list.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := asList(x)
+	if !ok {
+		return nil, fmt.Errorf("%s() needs a list, got %s", n.name, typeName(x))
+	}
+	for _, e := range list {
+		v, err := n.predN.eval(&scope{vars: map[string]any{n.v: e}, parent: env})
+		if err != nil {
+			return nil, err
+		}
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s() predicate must be a bool, got %s", n.name, typeName(v))
+		}
+		if n.name == "all" && !b {
+			return false, nil
+		}
+		if n.name == "exists" && b {
+			return true, nil
+		}
+	}
+	return n.name == "all", nil
+}
+
+func (n call) eval(env *scope) (any, error) {
+	var args []any
+	if n.recv != nil {
+		recv, err := n.recv.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, recv)
+	}
+	for _, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+
+	switch n.fn {
+	case "size":
+		switch x := args[0].(type) {
+		case string:
+			return int64(len([]rune(x))), nil
+		case map[string]any:
+			return int64(len(x)), nil
+		}
+		if list, ok := asList(args[0]); ok {
+			return int64(len(list)), nil
+		}
+		return nil, fmt.Errorf("size() not defined for %s", typeName(args[0]))
+	case "startsWith", "endsWith", "contains", "matches":
+		s, ok1 := args[0].(string)
+		t, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s() needs strings, got %s and %s", n.fn, typeName(args[0]), typeName(args[1]))
+		}
+		switch n.fn {
+		case "startsWith":
+			return strings.HasPrefix(s, t), nil
+		case "endsWith":
+			return strings.HasSuffix(s, t), nil
+		case "contains":
+			return strings.Contains(s, t), nil
+		}
+		re, err := regexp.Compile(t)
+		if err != nil {
+			return nil, fmt.Errorf("matches(): %w", err)
+		}
+		return re.MatchString(s), nil
+	case "int":
+		switch x := args[0].(type) {
+		case int64:
+			return x, nil
+		case float64:
+			return int64(x), nil
+		case string:
+			return strconv.ParseInt(x, 10, 64)
+		case time.Duration:
+			return int64(x), nil
+		}
+	case "double":
+		switch x := args[0].(type) {
+		case int64:
+			return float64(x), nil
+		case float64:
+			return x, nil
+		case string:
+			return strconv.ParseFloat(x, 64)
+		}
+	case "string":
+		switch x := args[0].(type) {
+		case string:
+			return x, nil
+		case int64, float64, bool, time.Duration:
+			return fmt.Sprint(x), nil
+		}
+	case "duration":
+		switch x := args[0].(type) {
+		case string:
+			return time.ParseDuration(x)
+		case time.Duration:
+			return x, nil
+		}
+	}
+	return nil, fmt.Errorf("%s() not defined for %s", n.fn, typeName(args[0]))
+}
+
+// functions lists the functions callable in policies with their number of
+// arguments, including the receiver of method calls such as s.startsWith(t).
+var functions = map[string]int{
+	"size":       1,
+	"startsWith": 2,
+	"endsWith":   2,
+	"contains":   2,
+	"matches":    2,
+	"int":        1,
+	"double":     1,
+	"string":     1,
+	"duration":   1,
+}
+
+// asList returns v as a list if it is one.
+func asList(v any) ([]any, bool) {
+	switch v := v.(type) {
+	case []any:
+		return v, true
+	case []map[string]any:
+		list := make([]any, len(v))
+		for i, e := range v {
+			list[i] = e
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// equal reports whether two values are equal, comparing ints and doubles by value.
+func equal(x, y any) bool {
+	if c, err := compare(x, y); err == nil {
+		return c == 0
+	}
+	if lx, ok := asList(x); ok {
+		ly, ok := asList(y)
+		if !ok || len(lx) != len(ly) {
+			return false
+		}
+		for i := range lx {
+			if !equal(lx[i], ly[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// compare orders two numbers, strings, durations or timestamps.
+func compare(x, y any) (int, error) {
+	switch x := x.(type) {
+	case int64:
+		switch y := y.(type) {
+		case int64:
+			return cmp(x, y), nil
+		case float64:
+			return cmp(float64(x), y), nil
+		}
+	case float64:
+		switch y := y.(type) {
+		case int64:
+			return cmp(x, float64(y)), nil
+		case float64:
+			return cmp(x, y), nil
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := y.(bool); ok {
+			if x == y {
+				return 0, nil
+			}
+			return 1, errors.New("bools are unordered")
+		}
+	case time.Duration:
+		if y, ok := y.(time.Duration); ok {
+			return cmp(x, y), nil
+		}
+	case time.Time:
+		if y, ok := y.(time.Time); ok {
+			return x.Compare(y), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(x), typeName(y))
+}
+
+func cmp[T int64 | float64 | time.Duration](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// arith applies an arithmetic operator.
+func arith(op string, x, y any) (any, error) {
+	switch x := x.(type) {
+	case int64:
+		switch y := y.(type) {
+		case int64:
+			switch op {
+			case "+":
+				return x + y, nil
+			case "-":
+				return x - y, nil
+			case "*":
+				return x * y, nil
+			case "/", "%":
+				if y == 0 {
+					return nil, errors.New("division by zero")
+				}
+				if op == "/" {
+					return x / y, nil
+				}
+				return x % y, nil
+			}
+		case float64:
+			return arith(op, float64(x), y)
+		}
+	case float64:
+		var fy float64
+		switch y := y.(type) {
+		case int64:
+			fy = float64(y)
+		case float64:
+			fy = y
+		default:
+			return nil, fmt.Errorf("operator %s not defined for %s and %s", op, typeName(x), typeName(y))
+		}
+		switch op {
+		case "+":
+			return x + fy, nil
+		case "-":
+			return x - fy, nil
+		case "*":
+			return x * fy, nil
+		case "/":
+			return x / fy, nil
+		case "%":
+			return math.Mod(x, fy), nil
+		}
+	case string:
+		if y, ok := y.(string); ok && op == "+" {
+			return x + y, nil
+		}
+	case time.Duration:
+		if y, ok := y.(time.Duration); ok && (op == "+" || op == "-") {
+			if op == "+" {
+				return x + y, nil
+			}
+			return x - y, nil
+		}
+	}
+	if lx, ok := asList(x); ok && op == "+" {
+		if ly, ok := asList(y); ok {
+			return append(append([]any{}, lx...), ly...), nil
+		}
+	}
+	return nil, fmt.Errorf("operator %s not defined for %s and %s", op, typeName(x), typeName(y))
+}
+
+// typeName names the CEL type of a value for error messages.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case int64:
+		return "int"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case time.Duration:
+		return "duration"
+	case time.Time:
+		return "timestamp"
+	case map[string]any:
+		return "table"
+	}
+	if _, ok := asList(v); ok {
+		return "list"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// compile parses a policy expression.
+func compile(src string) (node, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok != "" {
+		return nil, p.errorf("unexpected %q", p.tok)
+	}
+	return n, nil
+}
+
+// parser is a recursive-descent parser for policy expressions.
+type parser struct {
+	src  string
+	pos  int    // offset after the current token
+	tok  string // current token; "" at the end of the input
+	kind tokenKind
+	at   int // offset of the current token
+}
+
+type tokenKind int
+
+const (
+	tokOp tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+)
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("column %d: %s", p.at+1, fmt.Sprintf(format, args...))
+}
+
+// next advances to the next token.
+func (p *parser) next() error {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.at = p.pos
+	if p.pos == len(p.src) {
+		p.tok = ""
+		return nil
+	}
+
+	c := p.src[p.pos]
+	switch {
+	case c == '_' || unicode.IsLetter(rune(c)):
+		end := p.pos
+		for end < len(p.src) && (p.src[end] == '_' || unicode.IsLetter(rune(p.src[end])) || unicode.IsDigit(rune(p.src[end]))) {
+			end++
+		}
+		p.kind, p.tok, p.pos = tokIdent, p.src[p.pos:end], end
+	case unicode.IsDigit(rune(c)):
+		end := p.pos
+		for end < len(p.src) && (unicode.IsDigit(rune(p.src[end])) ||
+			p.src[end] == '.' && end+1 < len(p.src) && unicode.IsDigit(rune(p.src[end+1])) ||
+			p.src[end] == 'e' || p.src[end] == 'E' ||
+			(p.src[end] == '-' || p.src[end] == '+') && (p.src[end-1] == 'e' || p.src[end-1] == 'E')) {
+			end++
+		}
+		p.kind, p.tok, p.pos = tokNumber, p.src[p.pos:end], end
+	case c == '"' || c == '\'':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != c {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return p.errorf("unterminated string")
+		}
+		p.kind, p.tok, p.pos = tokString, p.src[p.pos:end+1], end+1
+	default:
+		p.kind = tokOp
+		for _, op := range []string{"&&", "||", "==", "!=", "<=", ">="} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.tok, p.pos = op, p.pos+2
+				return nil
+			}
+		}
+		if !strings.ContainsRune("!<>+-*/%?:.,()[]", rune(c)) {
+			return p.errorf("unexpected character %q", c)
+		}
+		p.tok, p.pos = string(c), p.pos+1
+	}
+	return nil
+}
+
+// expect consumes the operator tok or fails.
+func (p *parser) expect(tok string) error {
+	if p.kind != tokOp || p.tok != tok {
+		if p.tok == "" {
+			return p.errorf("expected %q, got end of expression", tok)
+		}
+		return p.errorf("expected %q, got %q", tok, p.tok)
+	}
+	return p.next()
+}
+
+// isOp reports whether the current token is one of the given operators.
+func (p *parser) isOp(ops ...string) bool {
+	if p.kind != tokOp && !(p.kind == tokIdent && p.tok == "in") {
+		return false
+	}
+	for _, op := range ops {
+		if p.tok == op {
+			return true
+		}
+	}
+	return false
+}
+
+// expr parses a conditional expression, the lowest precedence level.
+func (p *parser) expr() (node, error) {
+	c, err := p.binary(0)
+	if err != nil || !p.isOp("?") {
+		return c, err
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	t, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	f, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	return cond{c, t, f}, nil
+}
+
+// precedence lists binary operators from lowest to highest precedence.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// binary parses the binary operators at precedence level and above.
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(precedence[level]...) {
+		op := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = binary{op, x, y}
+	}
+	return x, nil
+}
+
+// unary parses prefix operators.
+func (p *parser) unary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unary{op, x}, nil
+	}
+	return p.member()
+}
+
+// member parses a primary expression followed by field selections, indexing
+// and method calls.
+func (p *parser) member() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOp("."):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.kind != tokIdent {
+				return nil, p.errorf("expected field name after '.'")
+			}
+			name := p.tok
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if !p.isOp("(") {
+				x = selectN{x, name}
+				continue
+			}
+			if x, err = p.method(x, name); err != nil {
+				return nil, err
+			}
+		case p.isOp("["):
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			index, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = indexN{x, index}
+		default:
+			return x, nil
+		}
+	}
+}
+
+// method parses the arguments of a method call on recv, including the all()
+// and exists() macros, whose first argument names the element variable.
+func (p *parser) method(recv node, name string) (node, error) {
+	if name == "all" || name == "exists" {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.kind != tokIdent {
+			return nil, p.errorf("%s() needs a variable name as its first argument", name)
+		}
+		v := p.tok
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		pred, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return macro{name, recv, v, pred}, nil
+	}
+
+	args, err := p.args()
+	if err != nil {
+		return nil, err
+	}
+	return p.call(name, recv, args)
+}
+
+// call checks a function call against the known functions.
+func (p *parser) call(name string, recv node, args []node) (node, error) {
+	arity, ok := functions[name]
+	if !ok {
+		return nil, p.errorf("unknown function %s()", name)
+	}
+	n := len(args)
+	if recv != nil {
+		n++
+	}
+	if n != arity {
+		return nil, p.errorf("%s() takes %d argument(s), got %d", name, arity, n)
+	}
+	return call{name, recv, args}, nil
+}
+
+// args parses a parenthesized, comma-separated argument list.
+func (p *parser) args() ([]node, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, p.next()
+}
+
+// primary parses literals, identifiers, global calls, lists and parentheses.
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch p.kind {
+	case tokNumber:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if i, err := strconv.ParseInt(tok, 10, 64); err == nil {
+			return literal{i}, nil
+		}
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", tok)
+		}
+		return literal{f}, nil
+	case tokString:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if tok[0] == '\'' {
+			tok = `"` + strings.ReplaceAll(strings.ReplaceAll(tok[1:len(tok)-1], `\'`, `'`), `"`, `\"`) + `"`
+		}
+		s, err := strconv.Unquote(tok)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", tok)
+		}
+		return literal{s}, nil
+	case tokIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		switch tok {
+		case "true", "false":
+			return literal{tok == "true"}, nil
+		case "null":
+			return literal{nil}, nil
+		}
+		if !p.isOp("(") {
+			return ident{tok}, nil
+		}
+		if tok == "has" {
+			args, err := p.args()
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != 1 {
+				return nil, p.errorf("has() takes 1 argument, got %d", len(args))
+			}
+			sel, ok := args[0].(selectN)
+			if !ok {
+				return nil, p.errorf("has() needs a field selection such as has(a.b)")
+			}
+			return hasN{&sel}, nil
+		}
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		return p.call(tok, nil, args)
+	}
+
+	switch tok {
+	case "(":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var elems []node
+		for !p.isOp("]") {
+			if len(elems) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, e)
+		}
+		return listN{elems}, p.next()
+	case "":
+		return nil, p.errorf("unexpected end of expression")
+	}
+	return nil, p.errorf("unexpected %q", tok)
+}
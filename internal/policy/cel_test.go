@@ -0,0 +1,138 @@
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+const celConfig = `
+env = "prod"
+replicas = 3
+ratio = 0.5
+hosts = ["a.example.com", "b.example.com"]
+
+[server]
+addr = "0.0.0.0:8080"
+timeout = "30s"
+
+[database.pool]
+max_size = 8
+
+[[listeners]]
+addr = "127.0.0.1:9000"
+
+[[listeners]]
+addr = "0.0.0.0:9001"
+`
+
+func TestCompile_Eval(t *testing.T) {
+	var data map[string]any
+	_, err := toml.Decode(celConfig, &data)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr string
+		want any
+	}{
+		{`env == "prod"`, true},
+		{`env != 'prod'`, false},
+		{`database.pool.max_size >= 10`, false},
+		{`env != "prod" || database.pool.max_size >= 10`, false},
+		{`replicas * 2 + 1`, int64(7)},
+		{`replicas / 2`, int64(1)},
+		{`replicas % 2 == 1 && ratio < 1`, true},
+		{`ratio * replicas`, 1.5},
+		{`-replicas`, int64(-3)},
+		{`!(replicas > 2)`, false},
+		{`replicas > 2 ? "many" : "few"`, "many"},
+		{`server.addr.startsWith("0.0.0.0")`, true},
+		{`server.addr.endsWith(":8080") && server.addr.contains(":")`, true},
+		{`server.addr.matches("^[0-9.]+:[0-9]+$")`, true},
+		{`size(hosts) == 2 && hosts.size() == 2 && size("héllo") == 5`, true},
+		{`hosts[1]`, "b.example.com"},
+		{`server["addr"]`, "0.0.0.0:8080"},
+		{`"a.example.com" in hosts`, true},
+		{`"pool" in database`, true},
+		{`env in ["dev", "staging"]`, false},
+		{`has(server.addr) && !has(server.tls)`, true},
+		{`listeners.all(l, !l.addr.startsWith("0.0.0.0"))`, false},
+		{`listeners.exists(l, l.addr.startsWith("127."))`, true},
+		{`hosts.all(h, h.endsWith(".example.com"))`, true},
+		{`duration(server.timeout) <= duration("1m")`, true},
+		{`duration(server.timeout) + duration("30s")`, time.Minute},
+		{`int("42") + int(2.9)`, int64(44)},
+		{`double(replicas) / 2`, 1.5},
+		{`"n=" + string(replicas)`, "n=3"},
+		{`[1, 2] + [3] == [1, 2, 3]`, true},
+		{`1 == 1.0 && 1e3 == 1000`, true},
+		{`null == null`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			prog, err := compile(tt.expr)
+			require.NoError(t, err)
+			got, err := prog.eval(&scope{vars: data})
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`replicas >`, "unexpected end of expression"},
+		{`(replicas`, `expected ")"`},
+		{`replicas $ 2`, "unexpected character"},
+		{`"open`, "unterminated string"},
+		{`nosuch(1)`, "unknown function nosuch()"},
+		{`size(1, 2)`, "size() takes 1 argument(s), got 2"},
+		{`has(replicas)`, "has() needs a field selection"},
+		{`hosts.all(1, true)`, "needs a variable name"},
+		{`replicas 2`, `unexpected "2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compile(tt.expr)
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	var data map[string]any
+	_, err := toml.Decode(celConfig, &data)
+	require.NoError(t, err)
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`nosuch == 1`, `undeclared reference to "nosuch"`},
+		{`server.tls`, "no such key: tls"},
+		{`replicas.x`, `cannot select "x" from int`},
+		{`hosts[5]`, "index 5 out of range"},
+		{`replicas / 0`, "division by zero"},
+		{`env < 1`, "cannot compare string and int"},
+		{`replicas && true`, "operator && needs bools"},
+		{`env - 1`, "operator - not defined for string and int"},
+		{`duration("soon")`, "invalid duration"},
+		{`hosts.all(h, h)`, "predicate must be a bool"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			prog, err := compile(tt.expr)
+			require.NoError(t, err)
+			_, err = prog.eval(&scope{vars: data})
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
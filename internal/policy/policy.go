@@ -0,0 +1,101 @@
+// Package policy evaluates organization-wide rules against the effective
+// configuration, such as "production database pools have at least 10
+// connections" or "nothing binds to 0.0.0.0 in production".
+//
+// A policy file is TOML with one [[rule]] table per rule:
+//
+//	[[rule]]
+//	name = "prod-db-pool"
+//	expr = 'env != "prod" || database.pool.max_size >= 10'
+//	message = "production database pools need at least 10 connections"
+//
+// Rules are written in a subset of CEL in which the top-level keys of the
+// configuration are variables. A rule is violated when its expression
+// evaluates to false.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Rule is a single named policy rule.
+type Rule struct {
+	Name    string `toml:"name"`
+	Expr    string `toml:"expr"`
+	Message string `toml:"message"`
+
+	prog node
+}
+
+// Policy is a set of rules loaded from one or more policy files.
+type Policy struct {
+	Rules []Rule `toml:"rule"`
+}
+
+// Parse parses a policy file named name and compiles its rules.
+func Parse(name string, data []byte) (*Policy, error) {
+	if strings.EqualFold(filepath.Ext(name), ".rego") {
+		return nil, fmt.Errorf("%s: Rego policies are not supported; write the rules as CEL expressions in a TOML policy file", name)
+	}
+
+	var p Policy
+	md, err := toml.Decode(string(data), &p)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown policy key %q", name, undecoded[0].String())
+	}
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("%s#%d", filepath.Base(name), i+1)
+		}
+		if rule.Expr == "" {
+			return nil, fmt.Errorf("%s: rule %s: expr is required", name, rule.Name)
+		}
+		if rule.prog, err = compile(rule.Expr); err != nil {
+			return nil, fmt.Errorf("%s: rule %s: %w", name, rule.Name, err)
+		}
+	}
+	return &p, nil
+}
+
+// Merge adds the rules of other to p.
+func (p *Policy) Merge(other *Policy) {
+	p.Rules = append(p.Rules, other.Rules...)
+}
+
+// Check evaluates every rule against the configuration data and returns an
+// error listing the rules that are violated or could not be evaluated.
+func (p *Policy) Check(data map[string]any) error {
+	if p == nil {
+		return nil
+	}
+
+	env := &scope{vars: data}
+	var errs []error
+	for _, rule := range p.Rules {
+		v, err := rule.prog.eval(env)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("policy %s: %w", rule.Name, err))
+			continue
+		}
+		ok, isBool := v.(bool)
+		switch {
+		case !isBool:
+			errs = append(errs, fmt.Errorf("policy %s: expression must evaluate to a bool, got %s", rule.Name, typeName(v)))
+		case !ok && rule.Message != "":
+			errs = append(errs, fmt.Errorf("policy %s violated: %s", rule.Name, rule.Message))
+		case !ok:
+			errs = append(errs, fmt.Errorf("policy %s violated: %s", rule.Name, rule.Expr))
+		}
+	}
+	return errors.Join(errs...)
+}
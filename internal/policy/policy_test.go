@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	p, err := Parse("org.toml", []byte(`
+[[rule]]
+name = "prod-db-pool"
+expr = 'env != "prod" || database.pool.max_size >= 10'
+message = "production database pools need at least 10 connections"
+
+[[rule]]
+name = "no-wildcard-binds"
+expr = 'env != "prod" || !server.addr.startsWith("0.0.0.0")'
+
+[[rule]]
+expr = 'has(server.addr)'
+`))
+	require.NoError(t, err)
+	require.Equal(t, "org.toml#3", p.Rules[2].Name)
+
+	var data map[string]any
+	_, err = toml.Decode(`
+env = "dev"
+
+[server]
+addr = "0.0.0.0:8080"
+
+[database.pool]
+max_size = 5
+`, &data)
+	require.NoError(t, err)
+	require.NoError(t, p.Check(data), "rules should hold outside prod")
+
+	data["env"] = "prod"
+	err = p.Check(data)
+	require.ErrorContains(t, err, "policy prod-db-pool violated: production database pools need at least 10 connections")
+	require.ErrorContains(t, err, `policy no-wildcard-binds violated: env != "prod" || !server.addr.startsWith("0.0.0.0")`)
+
+	delete(data, "database")
+	require.ErrorContains(t, p.Check(data), `policy prod-db-pool: undeclared reference to "database"`)
+
+	var nilPolicy *Policy
+	require.NoError(t, nilPolicy.Check(data))
+}
+
+func TestPolicy_Merge(t *testing.T) {
+	a, err := Parse("a.toml", []byte("[[rule]]\nexpr = 'x > 1'\n"))
+	require.NoError(t, err)
+	b, err := Parse("b.toml", []byte("[[rule]]\nexpr = 'x < 3'\n"))
+	require.NoError(t, err)
+	a.Merge(b)
+	require.Len(t, a.Rules, 2)
+
+	require.NoError(t, a.Check(map[string]any{"x": int64(2)}))
+	require.ErrorContains(t, a.Check(map[string]any{"x": int64(3)}), "policy b.toml#1 violated")
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want string
+	}{
+		{"rego", "policy.rego", "package cfgx", "Rego policies are not supported"},
+		{"invalid TOML", "p.toml", "[[rule]\n", "p.toml:"},
+		{"unknown key", "p.toml", "[[rule]]\nexpr = 'true'\nseverity = 'high'\n", `unknown policy key "rule.severity"`},
+		{"missing expr", "p.toml", "[[rule]]\nname = 'x'\n", "rule x: expr is required"},
+		{"invalid expr", "p.toml", "[[rule]]\nname = 'x'\nexpr = 'a >'\n", "rule x: column 4: unexpected end of expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.file, []byte(tt.data))
+			require.ErrorContains(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package present formats configuration values for people to read, in the
+// forms they are written in: durations such as "1m30s", byte sizes such as
+// "10MB" and file: references with the size and hash of the file they name.
+// The outputs of cfgx meant for reading share it, so that a value looks the
+// same in all of them.
+package present
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits are the units of byte sizes, largest first.
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TB", 1024 * 1024 * 1024 * 1024},
+	{"GB", 1024 * 1024 * 1024},
+	{"MB", 1024 * 1024},
+	{"KB", 1024},
+	{"B", 1},
+}
+
+// Size formats a byte count in the units cfgx accepts for sizes, such as
+// "512B" or "1.2KB".
+func Size(n int64) string {
+	for _, u := range sizeUnits {
+		if n >= u.size && u.size > 1 {
+			return fmt.Sprintf("%.1f%s", float64(n)/float64(u.size), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// ParseSize parses a byte size as Size formats it, a whole number of bytes
+// optionally followed by one of the units B, KB, MB, GB and TB, in any case.
+func ParseSize(s string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	size := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(upper, u.suffix) {
+			upper, size = strings.TrimSpace(strings.TrimSuffix(upper, u.suffix)), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * size, nil
+}
+
+// Duration formats d without the zero units time.Duration.String keeps, such
+// as "2h" rather than "2h0m0s".
+func Duration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Formatter formats values decoded from a config file.
+type Formatter struct {
+	// ReadFile reads the file named by a file: reference, resolving it the
+	// way the generator does. If nil, references are formatted as written.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Value formats v. Strings are quoted, except durations, which are shown as
+// written, and file: references, which are followed by the size and the
+// start of the SHA-256 hash of the file, such as
+// file:certs/ca.crt (1.2KB, sha256:9f86d081). Arrays are bracketed and tables
+// are shown as {...}.
+func (f Formatter) Value(v any) string {
+	switch val := v.(type) {
+	case string:
+		if ref, ok := strings.CutPrefix(val, "file:"); ok {
+			return f.fileRef(ref)
+		}
+		if isDuration(val) {
+			return val
+		}
+		return strconv.Quote(val)
+	case time.Duration:
+		return Duration(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		return Size(int64(len(val)))
+	case []any:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = f.Value(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case []map[string]any:
+		parts := make([]string, len(val))
+		for i := range val {
+			parts[i] = "{...}"
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		return "{...}"
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// fileRef formats the file: reference to name.
+func (f Formatter) fileRef(name string) string {
+	if f.ReadFile == nil {
+		return "file:" + name
+	}
+	data, err := f.ReadFile(name)
+	if err != nil {
+		return fmt.Sprintf("file:%s (unreadable)", name)
+	}
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("file:%s (%s, sha256:%s)", name, Size(int64(len(data))), hex.EncodeToString(sum[:])[:8])
+}
+
+// isDuration reports whether s is a duration string, which the generator
+// turns into a time.Duration.
+func isDuration(s string) bool {
+	_, err := time.ParseDuration(s)
+	return err == nil
+}
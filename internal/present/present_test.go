@@ -0,0 +1,71 @@
+package present
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSize(t *testing.T) {
+	require.Equal(t, "512B", Size(512))
+	require.Equal(t, "1.2KB", Size(1229))
+	require.Equal(t, "10.0MB", Size(10*1024*1024))
+	require.Equal(t, "2.0GB", Size(2*1024*1024*1024))
+}
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{"512": 512, "1B": 1, "64kb": 64 * 1024, " 10 MB ": 10 * 1024 * 1024, "2TB": 2 << 40} {
+		got, err := ParseSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, want, got, s)
+	}
+	for _, s := range []string{"", "lots", "-1KB", "1.5MB"} {
+		_, err := ParseSize(s)
+		require.EqualError(t, err, fmt.Sprintf("invalid size %q", s))
+	}
+}
+
+func TestDuration(t *testing.T) {
+	require.Equal(t, "2h", Duration(2*time.Hour))
+	require.Equal(t, "1h30m", Duration(90*time.Minute))
+	require.Equal(t, "5m", Duration(5*time.Minute))
+	require.Equal(t, "1h0m5s", Duration(time.Hour+5*time.Second))
+	require.Equal(t, "250ms", Duration(250*time.Millisecond))
+	require.Equal(t, "0s", Duration(0))
+}
+
+func TestFormatter_Value(t *testing.T) {
+	f := Formatter{ReadFile: func(name string) ([]byte, error) {
+		if name == "certs/ca.crt" {
+			return []byte("test"), nil
+		}
+		return nil, errors.New("not found")
+	}}
+
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{"hello", `"hello"`},
+		{"30s", "30s"},
+		{"1h30m", "1h30m"},
+		{"file:certs/ca.crt", "file:certs/ca.crt (4B, sha256:9f86d081)"},
+		{"file:missing.pem", "file:missing.pem (unreadable)"},
+		{int64(42), "42"},
+		{true, "true"},
+		{90 * time.Minute, "1h30m"},
+		{[]byte("PEM"), "3B"},
+		{[]any{"a", "10s", int64(1)}, `["a", 10s, 1]`},
+		{[]map[string]any{{"a": 1}, {"a": 2}}, "[{...}, {...}]"},
+		{map[string]any{"a": 1}, "{...}"},
+		{time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "2024-01-02T03:04:05Z"},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, f.Value(tt.value), "%v", tt.value)
+	}
+
+	require.Equal(t, "file:certs/ca.crt", Formatter{}.Value("file:certs/ca.crt"))
+}
@@ -0,0 +1,342 @@
+// Package record describes how a generated file was produced, so it can be
+// located and regenerated later without the original command line.
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Header is the first line of every file generated by cfgx.
+const Header = "// Code generated by cfgx. DO NOT EDIT."
+
+// prefix marks the record line written directly below Header.
+const prefix = "//cfgx:record "
+
+// Record holds the generation parameters of a generated file.
+type Record struct {
+	// Input is the input file path, relative to the generated file's directory
+	// and always slash-separated.
+	Input string
+
+	// Overlays are the input files deep-merged over Input in order, with
+	// paths of the same form as Input.
+	Overlays []string
+
+	// Append reports whether arrays in overlays were appended rather than
+	// replacing the arrays they override.
+	Append bool
+
+	// Duplicates is how repeated tables and keys in the input were merged
+	// ("last" or "error"), or empty if they were rejected.
+	Duplicates string
+
+	// Mode is the generation mode ("static", "getter", "hybrid" or "loader").
+	Mode string
+
+	// EnableEnv reports whether environment variable overrides were enabled.
+	EnableEnv bool
+
+	// MaxFileSize is the maximum size in bytes for file: references.
+	MaxFileSize int64
+
+	// CRLF reports whether the file was generated with CRLF line endings.
+	CRLF bool
+
+	// Part names the companion file this is, such as "redis" for the
+	// build-tagged config_redis.go next to config.go. Empty for the main file.
+	Part string
+
+	// Usage reports whether getters were generated with usage tracking.
+	Usage bool
+
+	// EnvAtInit reports whether getters were generated to read environment
+	// variables once, at package initialization.
+	EnvAtInit bool
+
+	// Summary reports whether a PrintSummary function was generated.
+	Summary bool
+
+	// EnvDocs reports whether the environment variables read were listed in
+	// a comment.
+	EnvDocs bool
+
+	// PreserveOrder reports whether fields were generated in source order.
+	PreserveOrder bool
+
+	// EmbedThreshold is the size in bytes above which file: references were
+	// embedded with go:embed, or zero if they were not.
+	EmbedThreshold int64
+
+	// StructTags lists the struct tag keys written on generated fields.
+	StructTags []string
+
+	// IntType is the Go type integers were generated as, if not int64.
+	IntType string
+
+	// Style is the style static mode was generated in, if not vars.
+	Style string
+
+	// Initialisms lists the initialisms written in upper case in generated
+	// identifiers, as given, with "default" standing for the default ones.
+	Initialisms []string
+
+	// Names maps dotted key paths to the Go names given to them with
+	// GenerateOptions.NameOverrides.
+	Names map[string]string
+
+	// EnvPrefix is the prefix of environment variable names, if not the
+	// default "CONFIG".
+	EnvPrefix string
+
+	// Precedence lists the runtime layers of loader mode, lowest first, if
+	// set explicitly.
+	Precedence []string
+
+	// Section is set on files generated for top-level tables annotated with
+	// cfgx:package. It names the package, which is also the directory below
+	// the main output file the section was written to.
+	Section string
+
+	// Dynamic reports whether the file contains values generated at generation
+	// time (uuid:, random: or now:), so regenerating it never reproduces it exactly.
+	Dynamic bool
+}
+
+// String formats the record as the comment line written into generated files.
+func (r Record) String() string {
+	s := fmt.Sprintf("%sin=%q mode=%s env=%t max-file-size=%d",
+		prefix, r.Input, r.Mode, r.EnableEnv, r.MaxFileSize)
+	for _, overlay := range r.Overlays {
+		s += fmt.Sprintf(" overlay=%q", overlay)
+	}
+	if r.Append {
+		s += " append=true"
+	}
+	if r.Duplicates != "" {
+		s += " duplicates=" + r.Duplicates
+	}
+	if r.CRLF {
+		s += " crlf=true"
+	}
+	if r.Part != "" {
+		s += " part=" + r.Part
+	}
+	if r.Usage {
+		s += " usage=true"
+	}
+	if r.EnvAtInit {
+		s += " env-at-init=true"
+	}
+	if r.Summary {
+		s += " summary=true"
+	}
+	if r.EnvDocs {
+		s += " env-docs=true"
+	}
+	if r.PreserveOrder {
+		s += " preserve-order=true"
+	}
+	if r.EmbedThreshold > 0 {
+		s += fmt.Sprintf(" embed-threshold=%d", r.EmbedThreshold)
+	}
+	if len(r.StructTags) > 0 {
+		s += " tags=" + strings.Join(r.StructTags, ",")
+	}
+	if r.IntType != "" {
+		s += " int-type=" + r.IntType
+	}
+	if r.Style != "" {
+		s += " style=" + r.Style
+	}
+	if len(r.Initialisms) > 0 {
+		s += " initialisms=" + strings.Join(r.Initialisms, ",")
+	}
+	for _, key := range slices.Sorted(maps.Keys(r.Names)) {
+		s += fmt.Sprintf(" name=%q", key+"="+r.Names[key])
+	}
+	if r.EnvPrefix != "" {
+		s += " env-prefix=" + r.EnvPrefix
+	}
+	if len(r.Precedence) > 0 {
+		s += " precedence=" + strings.Join(r.Precedence, ",")
+	}
+	if r.Section != "" {
+		s += " section=" + r.Section
+	}
+	if r.Dynamic {
+		s += " dynamic=true"
+	}
+	return s
+}
+
+// IsGenerated reports whether src starts with the cfgx generated-code header.
+func IsGenerated(src []byte) bool {
+	return bytes.HasPrefix(src, []byte(Header))
+}
+
+// Parse extracts the record from the header of a generated file.
+// It returns false if src is not a cfgx generated file or carries no record
+// (files generated before records were introduced, or via the in-memory API).
+func Parse(src []byte) (Record, bool, error) {
+	if !IsGenerated(src) {
+		return Record{}, false, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+
+		rec, err := parseFields(strings.TrimPrefix(line, prefix))
+		if err != nil {
+			return Record{}, false, fmt.Errorf("invalid cfgx record: %w", err)
+		}
+		return rec, true, nil
+	}
+
+	return Record{}, false, nil
+}
+
+// parseFields parses the key=value pairs of a record line.
+func parseFields(s string) (Record, error) {
+	var rec Record
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return Record{}, fmt.Errorf("expected key=value, got %q", s)
+		}
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid quoted value for %s: %w", key, err)
+			}
+			value, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		} else {
+			value, rest, _ = strings.Cut(rest, " ")
+		}
+		s = rest
+
+		switch key {
+		case "in":
+			rec.Input = value
+		case "overlay":
+			rec.Overlays = append(rec.Overlays, value)
+		case "append":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid append value %q", value)
+			}
+			rec.Append = b
+		case "duplicates":
+			rec.Duplicates = value
+		case "mode":
+			rec.Mode = value
+		case "env":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid env value %q", value)
+			}
+			rec.EnableEnv = b
+		case "max-file-size":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid max-file-size value %q", value)
+			}
+			rec.MaxFileSize = n
+		case "crlf":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid crlf value %q", value)
+			}
+			rec.CRLF = b
+		case "part":
+			rec.Part = value
+		case "usage":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid usage value %q", value)
+			}
+			rec.Usage = b
+		case "env-at-init":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid env-at-init value %q", value)
+			}
+			rec.EnvAtInit = b
+		case "summary":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid summary value %q", value)
+			}
+			rec.Summary = b
+		case "env-docs":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid env-docs value %q", value)
+			}
+			rec.EnvDocs = b
+		case "preserve-order":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid preserve-order value %q", value)
+			}
+			rec.PreserveOrder = b
+		case "embed-threshold":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid embed-threshold value %q", value)
+			}
+			rec.EmbedThreshold = n
+		case "tags":
+			rec.StructTags = strings.Split(value, ",")
+		case "int-type":
+			rec.IntType = value
+		case "style":
+			rec.Style = value
+		case "initialisms":
+			rec.Initialisms = strings.Split(value, ",")
+		case "name":
+			i := strings.LastIndex(value, "=")
+			if i < 0 {
+				return Record{}, fmt.Errorf("invalid name value %q", value)
+			}
+			if rec.Names == nil {
+				rec.Names = make(map[string]string)
+			}
+			rec.Names[value[:i]] = value[i+1:]
+		case "env-prefix":
+			rec.EnvPrefix = value
+		case "precedence":
+			rec.Precedence = strings.Split(value, ",")
+		case "section":
+			rec.Section = value
+		case "dynamic":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid dynamic value %q", value)
+			}
+			rec.Dynamic = b
+		}
+	}
+
+	if rec.Input == "" {
+		return Record{}, fmt.Errorf("missing input")
+	}
+
+	return rec, nil
+}
@@ -0,0 +1,226 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord_RoundTrip(t *testing.T) {
+	rec := Record{
+		Input:       "../config dir/config.toml",
+		Mode:        "getter",
+		EnableEnv:   true,
+		MaxFileSize: 1024,
+	}
+
+	src := []byte(Header + "\n" + rec.String() + "\n\npackage config\n")
+
+	got, ok, err := Parse(src)
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_CRLF(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", CRLF: true}
+
+	src := []byte(Header + "\r\n" + rec.String() + "\r\n\r\npackage config\r\n")
+
+	got, ok, err := Parse(src)
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Part(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "getter", EnableEnv: true, Part: "redis"}
+	require.Contains(t, rec.String(), " part=redis")
+
+	src := []byte(Header + "\n" + rec.String() + "\n\n//go:build cfgx_redis\n\npackage config\n")
+	got, ok, err := Parse(src)
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Dynamic(t *testing.T) {
+	rec := Record{Input: "fixtures.toml", Mode: "static", Dynamic: true}
+	require.Contains(t, rec.String(), " dynamic=true")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Section(t *testing.T) {
+	rec := Record{Input: "../../config.toml", Mode: "static", Section: "dbconfig"}
+	require.Contains(t, rec.String(), " section=dbconfig")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage dbconfig\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Usage(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "getter", Usage: true}
+	require.Contains(t, rec.String(), " usage=true")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_EnvAtInit(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "getter", EnvAtInit: true}
+	require.Contains(t, rec.String(), " env-at-init=true")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Overlays(t *testing.T) {
+	rec := Record{
+		Input:    "../config.toml",
+		Overlays: []string{"../overrides/prod.toml", "../local config.toml"},
+		Append:   true,
+		Mode:     "static",
+	}
+	require.Contains(t, rec.String(), ` overlay="../overrides/prod.toml" overlay="../local config.toml" append=true`)
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestParse_NoRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"not generated", "package config\n"},
+		{"generated without record", Header + "\n\npackage config\n"},
+		{"record after package clause", Header + "\n\npackage config\n//cfgx:record in=\"a.toml\"\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok, err := Parse([]byte(tt.src))
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	src := []byte(Header + "\n//cfgx:record mode=static env=maybe\n\npackage config\n")
+
+	_, _, err := Parse(src)
+	require.Error(t, err)
+}
+
+func TestRecord_Summary(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", Summary: true}
+	require.Contains(t, rec.String(), " summary=true")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_PreserveOrder(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", PreserveOrder: true}
+	require.Contains(t, rec.String(), " preserve-order=true")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Initialisms(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", Initialisms: []string{"default", "SKU"}}
+	require.Contains(t, rec.String(), " initialisms=default,SKU")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_IntType(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", IntType: "int"}
+	require.Contains(t, rec.String(), " int-type=int")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Style(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", Style: "compact"}
+	require.Contains(t, rec.String(), " style=compact")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Names(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", Names: map[string]string{"db.dsn": "DataSourceName", "app": "App"}}
+	require.Contains(t, rec.String(), ` name="app=App" name="db.dsn=DataSourceName"`)
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_EmbedThreshold(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", EmbedThreshold: 65536}
+	require.Contains(t, rec.String(), " embed-threshold=65536")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_Duplicates(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", Duplicates: "last"}
+	require.Contains(t, rec.String(), " duplicates=last")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_StructTags(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "static", StructTags: []string{"json", "yaml"}}
+	require.Contains(t, rec.String(), " tags=json,yaml")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
+
+func TestRecord_EnvPrefixAndPrecedence(t *testing.T) {
+	rec := Record{Input: "config.toml", Mode: "loader", EnvPrefix: "MYAPP", Precedence: []string{"file", "env", "flag"}}
+	require.Contains(t, rec.String(), " env-prefix=MYAPP precedence=file,env,flag")
+
+	got, ok, err := Parse([]byte(Header + "\n" + rec.String() + "\n\npackage config\n"))
+	require.NoError(t, err)
+	require.True(t, ok, "record should be found")
+	require.Equal(t, rec, got)
+}
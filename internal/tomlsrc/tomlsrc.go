@@ -0,0 +1,454 @@
+// Package tomlsrc scans raw TOML source for information the decoder discards,
+// such as the line and column each key was defined at and cfgx annotations
+// written in comments.
+//
+// Annotations are comments starting with "cfgx:" followed by space-separated
+// key=value pairs or bare flags. They apply to the key or table header on the
+// same line (trailing comment) or directly below (comment block without blank
+// lines in between):
+//
+//	# cfgx:owner=platform-team
+//	[database]
+//	port = 5432 # cfgx: min=1 max=65535
+//
+// The scanner is deliberately tolerant: it assumes the input has already been
+// (or will be) validated by a real TOML parser and only tracks enough syntax
+// to attribute keys to their tables and skip over multi-line values.
+package tomlsrc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Position is a 1-based line and column in a TOML source file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// Entry is a key or table header found in the source.
+type Entry struct {
+	// Key is the full dotted path of the key, including its table.
+	// Array-of-tables elements share the path of the array.
+	Key string
+
+	// Table reports whether the entry is a [table] or [[array]] header.
+	Table bool
+
+	// Pos is where the key (or header) starts.
+	Pos Position
+
+	// Annotations holds the cfgx annotations attached to the entry, if any.
+	// Bare flags have an empty value.
+	Annotations map[string]string
+}
+
+// Source holds the entries found in a TOML document, in source order.
+type Source struct {
+	Entries []Entry
+
+	index       map[string]int
+	annotations map[string]map[string]string
+}
+
+// Scan scans TOML source and returns the keys it defines.
+func Scan(src []byte) *Source {
+	s := &Source{
+		index:       make(map[string]int),
+		annotations: make(map[string]map[string]string),
+	}
+
+	var (
+		table   []string
+		pending valueState
+		doc     []string // comment lines directly above the current line
+	)
+
+	lines := strings.Split(strings.ReplaceAll(string(src), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lineNo := i + 1
+
+		// Continue a multi-line value from a previous line
+		if pending.open() {
+			pending.scan(line)
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		col := len(line) - len(trimmed) + 1
+
+		if trimmed == "" {
+			doc = nil
+			continue
+		}
+		if trimmed[0] == '#' {
+			doc = append(doc, trimmed[1:])
+			continue
+		}
+
+		comments := doc
+		doc = nil
+
+		if trimmed[0] == '[' {
+			inner := strings.TrimPrefix(trimmed, "[")
+			if strings.HasPrefix(inner, "[") {
+				inner = inner[1:]
+			}
+			parts, rest := parseKey(inner)
+			if len(parts) == 0 {
+				continue
+			}
+			if i := strings.IndexByte(rest, '#'); i >= 0 {
+				comments = append(comments, rest[i+1:])
+			}
+			table = parts
+			s.add(Entry{
+				Key:         strings.Join(parts, "."),
+				Table:       true,
+				Pos:         Position{Line: lineNo, Column: col},
+				Annotations: parseAnnotations(comments),
+			})
+			continue
+		}
+
+		parts, rest := parseKey(trimmed)
+		if len(parts) == 0 {
+			continue
+		}
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+
+		pending = valueState{}
+		if comment, ok := pending.scan(rest[1:]); ok {
+			comments = append(comments, comment)
+		}
+
+		path := append(append([]string{}, table...), parts...)
+		s.add(Entry{
+			Key:         strings.Join(path, "."),
+			Pos:         Position{Line: lineNo, Column: col},
+			Annotations: parseAnnotations(comments),
+		})
+	}
+
+	return s
+}
+
+// Fragment is a part of a TOML stream that parses as a document on its own.
+type Fragment struct {
+	Source []byte
+
+	// Line is the line of the stream that the first line of Source stands for.
+	Line int
+}
+
+// Split splits a stream of concatenated TOML documents into fragments that
+// each define every table and key at most once. A new fragment starts at a
+// [table] header or key already defined in the current one; one starting at a
+// key repeats the header of its table on the line above. Keys repeated inside
+// [[array]] elements are left for the parser to report.
+func Split(src []byte) []Fragment {
+	var (
+		fragments []Fragment
+		current   strings.Builder
+		first     = 1
+		table     []string
+		header    string // header line of the current [table], empty in [[arrays]]
+		defined   = make(map[string]bool)
+		pending   valueState
+	)
+	cut := func(line int) {
+		fragments = append(fragments, Fragment{Source: []byte(current.String()), Line: first})
+		current.Reset()
+		first = line
+		defined = make(map[string]bool)
+	}
+
+	for i, line := range strings.SplitAfter(string(src), "\n") {
+		lineNo := i + 1
+		if pending.open() {
+			pending.scan(line)
+			current.WriteString(line)
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case strings.TrimSpace(trimmed) == "" || trimmed[0] == '#':
+		case trimmed[0] == '[':
+			array := strings.HasPrefix(trimmed, "[[")
+			parts, _ := parseKey(strings.TrimPrefix(strings.TrimPrefix(trimmed, "["), "["))
+			if len(parts) == 0 {
+				break
+			}
+			key := strings.Join(parts, ".")
+			if array {
+				// Each element starts afresh
+				for k := range defined {
+					if strings.HasPrefix(k, key+".") {
+						delete(defined, k)
+					}
+				}
+				header = ""
+			} else {
+				if defined[key] {
+					cut(lineNo)
+				}
+				defined[key] = true
+				header = line
+			}
+			table = parts
+		default:
+			parts, rest := parseKey(trimmed)
+			rest = strings.TrimLeft(rest, " \t")
+			if len(parts) == 0 || !strings.HasPrefix(rest, "=") {
+				break
+			}
+			key := strings.Join(append(append([]string{}, table...), parts...), ".")
+			if defined[key] && (header != "" || len(table) == 0) {
+				cut(lineNo)
+				if header != "" {
+					current.WriteString(header)
+					first--
+					defined[strings.Join(table, ".")] = true
+				}
+			}
+			defined[key] = true
+			pending = valueState{}
+			pending.scan(rest[1:])
+		}
+		current.WriteString(line)
+	}
+
+	if current.Len() > 0 || len(fragments) == 0 {
+		cut(0)
+	}
+	return fragments
+}
+
+// Position returns where key was first defined.
+func (s *Source) Position(key string) (Position, bool) {
+	i, ok := s.index[key]
+	if !ok {
+		return Position{}, false
+	}
+	return s.Entries[i].Pos, true
+}
+
+// Annotations returns the cfgx annotations attached to key. Annotations from
+// repeated definitions of the same key (e.g. [[array]] headers) are merged.
+func (s *Source) Annotations(key string) map[string]string {
+	return s.annotations[key]
+}
+
+// Annotation returns the value of a single annotation on key.
+func (s *Source) Annotation(key, name string) (string, bool) {
+	v, ok := s.annotations[key][name]
+	return v, ok
+}
+
+// Inherited returns the value of the named annotation on key or, failing that,
+// on the closest enclosing table that carries it. This is how table-level
+// annotations such as owner apply to every key in the table.
+func (s *Source) Inherited(key, name string) (string, bool) {
+	for {
+		if v, ok := s.Annotation(key, name); ok {
+			return v, true
+		}
+		i := strings.LastIndexByte(key, '.')
+		if i < 0 {
+			return "", false
+		}
+		key = key[:i]
+	}
+}
+
+// Annotated returns the keys carrying the named annotation, in source order.
+func (s *Source) Annotated(name string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, e := range s.Entries {
+		if _, ok := e.Annotations[name]; ok && !seen[e.Key] {
+			seen[e.Key] = true
+			keys = append(keys, e.Key)
+		}
+	}
+	return keys
+}
+
+func (s *Source) add(e Entry) {
+	if _, exists := s.index[e.Key]; !exists {
+		s.index[e.Key] = len(s.Entries)
+	}
+	s.Entries = append(s.Entries, e)
+
+	if len(e.Annotations) == 0 {
+		return
+	}
+	if s.annotations[e.Key] == nil {
+		s.annotations[e.Key] = make(map[string]string)
+	}
+	for k, v := range e.Annotations {
+		s.annotations[e.Key][k] = v
+	}
+}
+
+// parseAnnotations extracts cfgx annotations from comment texts (without the
+// leading '#'). Values may be double-quoted to include spaces.
+func parseAnnotations(comments []string) map[string]string {
+	var annotations map[string]string
+
+	for _, comment := range comments {
+		comment = strings.TrimSpace(comment)
+		rest, ok := strings.CutPrefix(comment, "cfgx:")
+		if !ok {
+			continue
+		}
+
+		for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+			end := strings.IndexAny(rest, " \t=")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			rest = rest[end:]
+
+			var value string
+			if strings.HasPrefix(rest, "=") {
+				rest = rest[1:]
+				if quoted, err := strconv.QuotedPrefix(rest); err == nil {
+					value, _ = strconv.Unquote(quoted)
+					rest = rest[len(quoted):]
+				} else {
+					end := strings.IndexAny(rest, " \t")
+					if end < 0 {
+						end = len(rest)
+					}
+					value, rest = rest[:end], rest[end:]
+				}
+			}
+
+			if name == "" {
+				continue
+			}
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[name] = value
+		}
+	}
+
+	return annotations
+}
+
+// parseKey parses a dotted key (bare, "basic" or 'literal' parts) at the start of s
+// and returns its parts and the remaining text.
+func parseKey(s string) ([]string, string) {
+	var parts []string
+
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" {
+			return parts, s
+		}
+
+		var part string
+		switch s[0] {
+		case '"':
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, ""
+			}
+			part, s = s[1:end], s[end+1:]
+		case '\'':
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, ""
+			}
+			part, s = s[1:end+1], s[end+2:]
+		default:
+			end := 0
+			for end < len(s) && isBareKeyChar(s[end]) {
+				end++
+			}
+			if end == 0 {
+				return parts, s
+			}
+			part, s = s[:end], s[end:]
+		}
+		parts = append(parts, part)
+
+		s = strings.TrimLeft(s, " \t")
+		if !strings.HasPrefix(s, ".") {
+			return parts, s
+		}
+		s = s[1:]
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// valueState tracks whether a value continues onto following lines.
+type valueState struct {
+	depth        int  // nesting of [ ] and { }
+	multiBasic   bool // inside """ ... """
+	multiLiteral bool // inside ''' ... '''
+}
+
+func (v *valueState) open() bool {
+	return v.depth > 0 || v.multiBasic || v.multiLiteral
+}
+
+// scan consumes one line of a value, updating the continuation state.
+// It returns the text of a trailing comment, if the line has one.
+func (v *valueState) scan(line string) (string, bool) {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case v.multiBasic:
+			if line[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(line[i:], `"""`) {
+				v.multiBasic = false
+				i += 2
+			}
+		case v.multiLiteral:
+			if strings.HasPrefix(line[i:], "'''") {
+				v.multiLiteral = false
+				i += 2
+			}
+		case strings.HasPrefix(line[i:], `"""`):
+			v.multiBasic = true
+			i += 2
+		case strings.HasPrefix(line[i:], "'''"):
+			v.multiLiteral = true
+			i += 2
+		case line[i] == '"':
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case line[i] == '\'':
+			for i++; i < len(line) && line[i] != '\''; i++ {
+			}
+		case line[i] == '#':
+			return line[i+1:], true
+		case line[i] == '[' || line[i] == '{':
+			v.depth++
+		case line[i] == ']' || line[i] == '}':
+			v.depth--
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,137 @@
+package tomlsrc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan_Positions(t *testing.T) {
+	src := []byte(`name = "app"
+
+[server]
+  addr = ":8080" # trailing comment
+hosts = [
+  "a = 1",
+  "b",
+]
+description = """
+fake = "not a key"
+"""
+
+[database.pool]
+"max size" = 10
+
+[[endpoints]]
+path = "/api"
+`)
+
+	s := Scan(src)
+
+	tests := []struct {
+		key  string
+		want Position
+	}{
+		{"name", Position{Line: 1, Column: 1}},
+		{"server", Position{Line: 3, Column: 1}},
+		{"server.addr", Position{Line: 4, Column: 3}},
+		{"server.hosts", Position{Line: 5, Column: 1}},
+		{"server.description", Position{Line: 9, Column: 1}},
+		{"database.pool", Position{Line: 13, Column: 1}},
+		{"database.pool.max size", Position{Line: 14, Column: 1}},
+		{"endpoints", Position{Line: 16, Column: 1}},
+		{"endpoints.path", Position{Line: 17, Column: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := s.Position(tt.key)
+			require.True(t, ok, "key %q not found", tt.key)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := s.Position("server.fake")
+	require.False(t, ok, "keys inside multi-line strings must be ignored")
+	_, ok = s.Position("server.a")
+	require.False(t, ok, "keys inside arrays must be ignored")
+}
+
+func TestScan_CRLF(t *testing.T) {
+	s := Scan([]byte("[server]\r\naddr = \":8080\"\r\n"))
+
+	got, ok := s.Position("server.addr")
+	require.True(t, ok)
+	require.Equal(t, Position{Line: 2, Column: 1}, got)
+}
+
+func TestScan_Annotations(t *testing.T) {
+	src := []byte(`# General settings
+# cfgx:owner=platform-team
+[server]
+port = 8080 # cfgx: min=1 max=65535
+# cfgx:secret
+password = "hunter2"
+
+# cfgx:owner=ignored
+
+addr = ":8080"
+name = "x" # cfgx:pattern="^[a-z ]+$" required
+
+[[users]] # cfgx:key=email
+email = "a@example.com"
+`)
+
+	s := Scan(src)
+
+	require.Equal(t, map[string]string{"owner": "platform-team"}, s.Annotations("server"))
+	require.Equal(t, map[string]string{"min": "1", "max": "65535"}, s.Annotations("server.port"))
+	require.Equal(t, map[string]string{"secret": ""}, s.Annotations("server.password"))
+	require.Nil(t, s.Annotations("server.addr"), "annotation separated by a blank line must not apply")
+	require.Equal(t, map[string]string{"pattern": "^[a-z ]+$", "required": ""}, s.Annotations("server.name"))
+
+	key, ok := s.Annotation("users", "key")
+	require.True(t, ok)
+	require.Equal(t, "email", key)
+
+	require.Equal(t, []string{"server"}, s.Annotated("owner"))
+
+	owner, ok := s.Inherited("server.port", "owner")
+	require.True(t, ok, "table annotations should apply to their keys")
+	require.Equal(t, "platform-team", owner)
+	_, ok = s.Inherited("users.email", "owner")
+	require.False(t, ok)
+}
+
+func TestSplit(t *testing.T) {
+	src := `name = "a"
+
+[server]
+port = 80
+tags = [
+  "x",
+]
+
+[[workers]]
+id = 1
+
+[[workers]]
+id = 2
+
+[server]
+port = 81
+name = "b"
+host = "h"
+host = "i"
+`
+
+	fragments := Split([]byte(src))
+	require.Len(t, fragments, 3)
+	require.Equal(t, 1, fragments[0].Line)
+	require.Equal(t, "[server]\nport = 81\nname = \"b\"\nhost = \"h\"\n", string(fragments[1].Source))
+	require.Equal(t, 15, fragments[1].Line)
+	require.Equal(t, "[server]\nhost = \"i\"\n", string(fragments[2].Source))
+	require.Equal(t, 18, fragments[2].Line, "the repeated header stands for the line above the key")
+
+	require.Len(t, Split([]byte("a = 1\nb = \"\"\"\na = 2\n\"\"\"\n")), 1, "keys inside multi-line strings are not keys")
+}
@@ -0,0 +1,133 @@
+package cfgx
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gomantics/cfgx/internal/generator"
+)
+
+// inputFiles returns the input files of opts in merge order: InputFile
+// followed by the InputFiles after it, or InputFiles alone if InputFile is
+// empty or names its first entry.
+func inputFiles(opts *GenerateOptions) ([]string, error) {
+	if len(opts.InputFiles) == 0 {
+		return []string{opts.InputFile}, nil
+	}
+	if opts.InputFile != "" && opts.InputFile != opts.InputFiles[0] {
+		return nil, fmt.Errorf("input file %s must be the first of the input files", opts.InputFile)
+	}
+	return opts.InputFiles, nil
+}
+
+// mergeTables deep-merges src into dst: tables present in both are merged
+// key by key, and any other value in src replaces the one in dst. Arrays
+// present in both are concatenated instead when appendArrays is set.
+func mergeTables(dst, src map[string]any, appendArrays bool) {
+	for k, v := range src {
+		switch sv := v.(type) {
+		case map[string]any:
+			if dv, ok := dst[k].(map[string]any); ok {
+				mergeTables(dv, sv, appendArrays)
+				continue
+			}
+		case []any:
+			if dv, ok := dst[k].([]any); ok && appendArrays {
+				dst[k] = append(dv, sv...)
+				continue
+			}
+		case []map[string]any:
+			if dv, ok := dst[k].([]map[string]any); ok && appendArrays {
+				dst[k] = append(dv, sv...)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// mergeDocument merges doc, a document of a concatenated TOML stream, into
+// dst under the dotted key path prefix. Tables present in both are merged and
+// arrays of tables continue, as they would in a single document; any other
+// value replaces the one in dst or, if strict is set, is a conflict.
+func mergeDocument(dst, doc map[string]any, prefix string, strict bool) error {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := doc[k].(type) {
+		case map[string]any:
+			if dv, ok := dst[k].(map[string]any); ok {
+				if err := mergeDocument(dv, v, key, strict); err != nil {
+					return err
+				}
+				continue
+			}
+		case []map[string]any:
+			if dv, ok := dst[k].([]map[string]any); ok {
+				dst[k] = append(dv, v...)
+				continue
+			}
+		}
+		if _, ok := dst[k]; ok && strict {
+			return &generator.KeyError{Key: key, Err: fmt.Errorf("defined more than once in the concatenated documents")}
+		}
+		dst[k] = doc[k]
+	}
+	return nil
+}
+
+// rebaseFileRefs rewrites the relative file: references in data, which are
+// relative to dir, to be relative to baseDir instead, so the references of
+// an overlay resolve from the directory of the first input file.
+func rebaseFileRefs(data map[string]any, dir, baseDir string) error {
+	rel, err := filepath.Rel(baseDir, dir)
+	if err != nil {
+		return fmt.Errorf("cannot resolve file: references relative to %s: %w", baseDir, err)
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return nil
+	}
+	rebaseValue(data, rel)
+	return nil
+}
+
+// rebaseValue is rebaseFileRefs for a single value, returning the rewritten value.
+func rebaseValue(v any, rel string) any {
+	switch v := v.(type) {
+	case string:
+		ref, ok := strings.CutPrefix(v, "file:")
+		if !ok {
+			return v
+		}
+		ref = strings.ReplaceAll(ref, "\\", "/")
+		if path.IsAbs(ref) || filepath.IsAbs(ref) || len(ref) >= 3 && ref[1] == ':' && ref[2] == '/' {
+			return v
+		}
+		return "file:" + path.Join(rel, ref)
+	case map[string]any:
+		for k, e := range v {
+			v[k] = rebaseValue(e, rel)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = rebaseValue(e, rel)
+		}
+	case []map[string]any:
+		for _, e := range v {
+			rebaseValue(e, rel)
+		}
+	}
+	return v
+}
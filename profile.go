@@ -0,0 +1,83 @@
+package cfgx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Profiling configures runtime profiles collected around a generation run,
+// mirroring the -cpuprofile, -memprofile and -trace flags of go test.
+// Nil writers are skipped.
+//
+// CPU profiling and execution tracing are process-wide, so only one run may
+// collect them at a time.
+type Profiling struct {
+	// CPU receives a pprof CPU profile covering the whole run.
+	CPU io.Writer
+
+	// Mem receives a pprof heap profile taken after the run completes.
+	Mem io.Writer
+
+	// Trace receives a runtime execution trace covering the whole run.
+	// The generation phases (parse, analysis, emit) show up as trace regions.
+	Trace io.Writer
+}
+
+// start begins the configured profiles and returns a function that stops them.
+func (p *Profiling) start() (func() error, error) {
+	if p == nil {
+		return func() error { return nil }, nil
+	}
+
+	if p.CPU != nil {
+		if err := pprof.StartCPUProfile(p.CPU); err != nil {
+			return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+		}
+	}
+
+	if p.Trace != nil {
+		if err := trace.Start(p.Trace); err != nil {
+			if p.CPU != nil {
+				pprof.StopCPUProfile()
+			}
+			return nil, fmt.Errorf("failed to start trace: %w", err)
+		}
+	}
+
+	return func() error {
+		if p.CPU != nil {
+			pprof.StopCPUProfile()
+		}
+		if p.Trace != nil {
+			trace.Stop()
+		}
+
+		if p.Mem != nil {
+			runtime.GC() // get up-to-date statistics
+			if err := pprof.Lookup("heap").WriteTo(p.Mem, 0); err != nil {
+				return fmt.Errorf("failed to write memory profile: %w", err)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// withProfiling runs fn with the configured profiles active.
+func withProfiling[T any](p *Profiling, fn func() (T, error)) (T, error) {
+	var zero T
+
+	stop, err := p.start()
+	if err != nil {
+		return zero, err
+	}
+
+	result, err := fn()
+	if stopErr := stop(); stopErr != nil {
+		return zero, errors.Join(err, stopErr)
+	}
+	return result, err
+}
@@ -0,0 +1,61 @@
+package cfgx
+
+import (
+	"fmt"
+
+	"github.com/gomantics/cfgx/internal/generator"
+)
+
+// Schema returns a JSON Schema (draft 2020-12) for the input of opts, so
+// that editors and services not written in Go can validate config files
+// against the types cfgx infers: durations and file: references are strings
+// matching a pattern, datetimes are date-time strings, arrays of tables are
+// arrays of objects and cfgx:map tables are objects of any keys. Constraints
+// and formats annotated on keys are carried over, and current values become
+// defaults, except secrets. Only the input options are used; OutputFile may
+// be left empty.
+func Schema(opts *GenerateOptions) ([]byte, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("options cannot be nil")
+	}
+	inputs, err := inputFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+	o := *opts
+	o.InputFile = inputs[0]
+	opts = &o
+
+	source, err := readInput(opts, opts.InputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file %s: %w", opts.InputFile, err)
+	}
+	data, src, err := decodeInput(opts, opts.InputFile, source)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, err)
+	}
+
+	inputDir := inputDirOf(opts)
+	for _, file := range inputs[1:] {
+		if err := mergeInput(opts, data, file, inputDir); err != nil {
+			return nil, err
+		}
+	}
+	typeHints, err := loadTypeHints(opts, inputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	gen := generator.New(
+		generator.WithInputDir(inputDir),
+		generator.WithFS(opts.FS),
+		generator.WithSource(src),
+		generator.WithTypeHints(typeHints),
+		generator.WithContext(opts.Context),
+	)
+	schema, err := gen.Schema(data)
+	if err != nil {
+		return nil, locateError(opts.InputFile, source, fmt.Errorf("failed to generate schema: %w", err))
+	}
+	return schema, nil
+}
@@ -0,0 +1,88 @@
+package cfgx
+
+import (
+	"fmt"
+
+	"github.com/gomantics/cfgx/internal/envoverride"
+	"github.com/gomantics/cfgx/internal/generator"
+)
+
+// Validate checks the input of opts the way GenerateFromFile does, without
+// writing anything, and returns every problem found instead of only the
+// first: TOML syntax errors such as duplicate keys, missing or oversized
+// file: references, and environment variables (with EnableEnv) that do not
+// parse as the type of the value they override, unless EnvOverrideStrictness
+// is "warn". When none are found, the code is generated in memory to report
+// any remaining error, such as an invalid annotation or a policy violation.
+// Problems located in the input file are returned as *Error. OutputFile may
+// be left empty.
+func Validate(opts *GenerateOptions) []error {
+	o := *opts
+	if o.OutputFile == "" {
+		o.OutputFile = "config.go"
+		if o.PackageName == "" {
+			o.PackageName = "config"
+		}
+	}
+	opts = &o
+
+	inputs, err := inputFiles(opts)
+	if err != nil {
+		return []error{err}
+	}
+	opts.InputFile = inputs[0]
+
+	source, err := readInput(opts, opts.InputFile)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read input file %s: %w", opts.InputFile, err)}
+	}
+	data, src, err := decodeInput(opts, opts.InputFile, source)
+	if err != nil {
+		return []error{locateError(opts.InputFile, source, err)}
+	}
+
+	var errs []error
+	inputDir := inputDirOf(opts)
+	for _, file := range inputs[1:] {
+		if err := mergeInput(opts, data, file, inputDir); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	typeHints, err := loadTypeHints(opts, inputDir)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	maxFileSize := opts.MaxFileSize
+	if maxFileSize == 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	gen := generator.New(
+		generator.WithInputDir(inputDir),
+		generator.WithMaxFileSize(maxFileSize),
+		generator.WithFS(opts.FS),
+		generator.WithSource(src),
+		generator.WithTypeHints(typeHints),
+		generator.WithContext(opts.Context),
+	)
+	for _, err := range gen.FileReferenceErrors(data) {
+		errs = append(errs, locateError(opts.InputFile, source, err))
+	}
+	// With EnvOverrideStrictness "warn", generating logs them instead
+	if opts.EnableEnv && opts.EnvOverrideStrictness != "warn" {
+		for _, err := range envoverride.Check(data, envPrefixOf(opts)) {
+			errs = append(errs, locateError(opts.InputFile, source, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := generateFiles(opts); err != nil {
+		return []error{err}
+	}
+	return nil
+}
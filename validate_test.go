@@ -0,0 +1,55 @@
+package cfgx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputFile := filepath.Join(tmpDir, "config.toml")
+
+	tomlData := []byte(`[server]
+port = 80
+cert = "file:missing.pem"
+
+[db]
+debug = false
+dsn = "file:dsn.txt"
+`)
+	require.NoError(t, os.WriteFile(inputFile, tomlData, 0644))
+	t.Setenv("CONFIG_SERVER_PORT", "eighty")
+
+	problems := Validate(&GenerateOptions{InputFile: inputFile, EnableEnv: true})
+	require.Len(t, problems, 3)
+	require.ErrorContains(t, problems[0], "db.dsn: file not found")
+	require.ErrorContains(t, problems[1], "server.cert: file not found")
+	require.ErrorContains(t, problems[2], "invalid value for CONFIG_SERVER_PORT")
+
+	var cfgErr *Error
+	require.ErrorAs(t, problems[2], &cfgErr)
+	require.Equal(t, "server.port", cfgErr.Key)
+	require.Equal(t, 2, cfgErr.Line)
+
+	require.Len(t, Validate(&GenerateOptions{InputFile: inputFile}), 2, "env vars are only checked with EnableEnv")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "missing.pem"), []byte("cert"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "dsn.txt"), []byte("dsn"), 0644))
+	require.Empty(t, Validate(&GenerateOptions{InputFile: inputFile}))
+	_, err := os.Stat(filepath.Join(tmpDir, "config.go"))
+	require.ErrorIs(t, err, os.ErrNotExist, "nothing should be written")
+
+	// Generation errors are reported once the input itself checks out
+	require.NoError(t, os.WriteFile(inputFile, []byte("when = \"0 3 * *\" # cfgx:type=cron\n"), 0644))
+	problems = Validate(&GenerateOptions{InputFile: inputFile})
+	require.Len(t, problems, 1)
+	require.ErrorContains(t, problems[0], "invalid cron spec")
+
+	require.NoError(t, os.WriteFile(inputFile, []byte("a = 1\na = 2\n"), 0644))
+	problems = Validate(&GenerateOptions{InputFile: inputFile})
+	require.Len(t, problems, 1)
+	require.ErrorContains(t, problems[0], "has already been defined")
+}
@@ -0,0 +1,38 @@
+//go:build js && wasm
+
+package cfgx
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Run with:
+//
+//	GOOS=js GOARCH=wasm go test -exec "$(go env GOROOT)/lib/wasm/go_js_wasm_exec" -run Wasm .
+
+func TestWasm_Generate(t *testing.T) {
+	for _, mode := range []string{"static", "getter"} {
+		output, err := GenerateWithOptions([]byte("[server]\naddr = \":8080\"\ntimeout = \"5s\"\n"), "config", true, "", 0, mode)
+		require.NoError(t, err, mode)
+		require.Contains(t, string(output), "package config")
+	}
+}
+
+func TestWasm_GenerateBytesFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.toml":   {Data: []byte("[tls]\ncert = \"file:server.crt\"\n")},
+		"server.crt": {Data: []byte("CERT")},
+	}
+
+	output, err := GenerateBytes(&GenerateOptions{
+		InputFile:   "app.toml",
+		OutputFile:  "config.go",
+		PackageName: "config",
+		FS:          fsys,
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Cert []byte")
+}
@@ -0,0 +1,342 @@
+package cfgx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultDebounce is the debounce delay of Watch when WatchOptions.Debounce
+// is zero.
+const DefaultDebounce = 100 * time.Millisecond
+
+// ErrWatch is wrapped by the errors of Results that report a failure of the
+// file watcher rather than of a generation, such as a directory that could
+// not be watched. Watch carries on after them.
+var ErrWatch = errors.New("watch error")
+
+// WatchOptions configures Watch.
+type WatchOptions struct {
+	GenerateOptions
+
+	// Debounce is how long Watch waits after a change for more changes
+	// before regenerating, so that an editor saving several times in a row
+	// causes a single generation. Zero means DefaultDebounce.
+	Debounce time.Duration
+
+	// SkipInitial skips the generation Watch otherwise runs when it starts,
+	// for callers that only want to hear about changes.
+	SkipInitial bool
+}
+
+// Result reports what Watch did, to its callback.
+type Result struct {
+	// Initial is set for the generation run when Watch starts.
+	Initial bool
+
+	// Removed is set, without a generation, when an input file was removed.
+	// Watch then waits for it to be recreated.
+	Removed bool
+
+	// Err is why the generation failed, or a failure of the file watcher
+	// wrapping ErrWatch. It is nil when the outputs were written.
+	Err error
+}
+
+// Watch writes the outputs of opts as GenerateFromFile does, then again each
+// time the input files, the TypesFile next to them or the files referenced
+// with file: change, until ctx is done, when it returns nil. After each
+// generation, or removal of an input file, fn is called with the Result,
+// from a single goroutine: changes made while fn runs are regenerated once
+// it returns.
+//
+// Inputs are watched through their directories, so that editors saving by
+// rename, files removed and recreated, and atomic symlink swaps such as
+// Kubernetes ConfigMap updates are all observed. Files referenced with file:
+// are those of the last successful generation, and are only read again once
+// their size or modification time changes; outputs are only rewritten when
+// their content changes. Watch fails if an input file does not exist or an
+// output file is an input file.
+func Watch(ctx context.Context, opts *WatchOptions, fn func(Result)) error {
+	if opts == nil {
+		return fmt.Errorf("options cannot be nil")
+	}
+	gen := opts.GenerateOptions
+	if gen.FileCache == nil {
+		gen.FileCache = NewFileCache()
+	}
+	debounce := opts.Debounce
+	if debounce == 0 {
+		debounce = DefaultDebounce
+	}
+
+	inputs, err := inputFiles(&gen)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+	watched := &watchedDirs{watcher: watcher, count: make(map[string]int)}
+
+	var targets []*watchTarget
+	typesFiles := make(map[string]bool)
+	for _, input := range inputs {
+		// Writing the output into a watched input would regenerate forever.
+		if SamePath(input, gen.OutputFile) {
+			return fmt.Errorf("output file %s is the watched input file", gen.OutputFile)
+		}
+		abs, err := filepath.Abs(input)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		target := &watchTarget{path: abs, dirs: make(map[string]bool), watched: watched}
+		if _, err := target.sync(watcher); err != nil {
+			return err
+		}
+		if !target.exists() {
+			return fmt.Errorf("failed to watch %s: file not found", abs)
+		}
+		targets = append(targets, target)
+		// The type hints sidecar sits in the watched input directory
+		typesFiles[filepath.Join(filepath.Dir(abs), TypesFile)] = true
+	}
+	refs := &watchRefs{files: make(map[string]bool), dirs: make(map[string]bool), watched: watched}
+
+	generate := func(initial bool) {
+		err := GenerateFromFile(&gen)
+		if err == nil {
+			refs.files = make(map[string]bool)
+			for _, path := range gen.FileCache.ReferencedFiles() {
+				refs.files[path] = true
+			}
+			if err := refs.sync(); err != nil {
+				fn(Result{Err: fmt.Errorf("%w: %w", ErrWatch, err)})
+			}
+		}
+		fn(Result{Initial: initial, Err: err})
+	}
+	if !opts.SkipInitial {
+		generate(true)
+	}
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			changed := typesFiles[filepath.Clean(event.Name)] ||
+				(refs.matches(event.Name) && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename))
+			removed := false
+			for _, target := range targets {
+				// Re-resolve on every event: a ConfigMap update swaps a
+				// symlink in the parent directory rather than writing the
+				// file. Events for other files in the watched directories,
+				// including our own output, leave the target unchanged and
+				// are ignored.
+				resolved, err := target.sync(watcher)
+				if err != nil {
+					fn(Result{Err: fmt.Errorf("%w: %w", ErrWatch, err)})
+				}
+				if !target.exists() {
+					removed = removed || (target.matches(event.Name) && event.Has(fsnotify.Remove|fsnotify.Rename))
+					continue
+				}
+				changed = changed || resolved || (target.matches(event.Name) && event.Has(fsnotify.Write|fsnotify.Create))
+			}
+			if removed {
+				fn(Result{Removed: true})
+			}
+			if changed && !removed {
+				// Debounce: restart the timer on each change
+				timer.Reset(debounce)
+			}
+
+		case <-timer.C:
+			missing := false
+			for _, target := range targets {
+				missing = missing || !target.exists()
+			}
+			if !missing {
+				generate(false)
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fn(Result{Err: fmt.Errorf("%w: %w", ErrWatch, err)})
+		}
+	}
+}
+
+// watchedDirs counts the inputs and references watching each directory, so
+// that a directory is only unwatched once none of them needs it.
+type watchedDirs struct {
+	watcher *fsnotify.Watcher
+	count   map[string]int
+}
+
+// add watches dir for one more user.
+func (d *watchedDirs) add(dir string) error {
+	if d.count[dir] == 0 {
+		if err := d.watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	d.count[dir]++
+	return nil
+}
+
+// remove drops a user of dir, unwatching it after the last one.
+func (d *watchedDirs) remove(dir string) {
+	if d.count[dir]--; d.count[dir] <= 0 {
+		_ = d.watcher.Remove(dir)
+		delete(d.count, dir)
+	}
+}
+
+// watchTarget tracks an input file through symlinks. It watches the
+// directory holding the path as given, the directory holding the resolved
+// file, and the resolved file itself, so that writes through hard links,
+// editor rename-on-save and atomic symlink swaps (as done by Kubernetes
+// ConfigMap mounts) are all observed.
+type watchTarget struct {
+	path     string
+	resolved string
+	info     os.FileInfo
+	dirs     map[string]bool
+	file     string
+	watched  *watchedDirs
+}
+
+// sync re-resolves the target, adjusts the watched paths and reports
+// whether the file behind the path is different from the last sync.
+func (t *watchTarget) sync(watcher *fsnotify.Watcher) (bool, error) {
+	resolved, err := filepath.EvalSymlinks(t.path)
+	if err != nil {
+		resolved = ""
+	}
+
+	var info os.FileInfo
+	if resolved != "" {
+		if info, err = os.Stat(resolved); err != nil {
+			resolved = ""
+			info = nil
+		}
+	}
+
+	dirs := map[string]bool{filepath.Dir(t.path): true}
+	if resolved != "" {
+		dirs[filepath.Dir(resolved)] = true
+	}
+	for dir := range t.dirs {
+		if !dirs[dir] {
+			t.watched.remove(dir)
+			delete(t.dirs, dir)
+		}
+	}
+	var watchErr error
+	for dir := range dirs {
+		if t.dirs[dir] {
+			continue
+		}
+		if err := t.watched.add(dir); err != nil {
+			watchErr = err
+			continue
+		}
+		t.dirs[dir] = true
+	}
+
+	// The file watch is dropped by the OS when the file is removed, so
+	// it is re-added whenever the resolved file changes.
+	changed := resolved != t.resolved || !sameFile(info, t.info)
+	if changed || t.file != resolved {
+		if t.file != "" {
+			_ = watcher.Remove(t.file)
+			t.file = ""
+		}
+		if resolved != "" && watcher.Add(resolved) == nil {
+			t.file = resolved
+		}
+	}
+
+	t.resolved = resolved
+	t.info = info
+	return changed, watchErr
+}
+
+// exists reports whether the path resolved to a file on the last sync.
+func (t *watchTarget) exists() bool {
+	return t.resolved != ""
+}
+
+// matches reports whether an event path refers to the watched file.
+func (t *watchTarget) matches(name string) bool {
+	name = filepath.Clean(name)
+	return name == t.path || (t.resolved != "" && name == t.resolved)
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return os.SameFile(a, b) && a.Size() == b.Size() && a.ModTime().Equal(b.ModTime())
+}
+
+// watchRefs watches the files referenced with file: by the last successful
+// generation, through the directories holding them so that files replaced on
+// save are still observed.
+type watchRefs struct {
+	files   map[string]bool
+	dirs    map[string]bool
+	watched *watchedDirs
+}
+
+// sync adjusts the watched directories to those of the referenced files.
+func (r *watchRefs) sync() error {
+	dirs := make(map[string]bool)
+	for file := range r.files {
+		dirs[filepath.Dir(file)] = true
+	}
+	for dir := range r.dirs {
+		if !dirs[dir] {
+			r.watched.remove(dir)
+			delete(r.dirs, dir)
+		}
+	}
+	var watchErr error
+	for dir := range dirs {
+		if r.dirs[dir] {
+			continue
+		}
+		if err := r.watched.add(dir); err != nil {
+			watchErr = err
+			continue
+		}
+		r.dirs[dir] = true
+	}
+	return watchErr
+}
+
+// matches reports whether an event path refers to a referenced file.
+func (r *watchRefs) matches(name string) bool {
+	return r.files[filepath.Clean(name)]
+}
@@ -0,0 +1,92 @@
+//go:build !js
+
+package cfgx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "config.toml")
+	outputFile := filepath.Join(dir, "config", "config.go")
+	cert := filepath.Join(dir, "cert.pem")
+	require.NoError(t, os.WriteFile(cert, []byte("cert-1"), 0644))
+	require.NoError(t, os.WriteFile(inputFile, []byte("[tls]\ncert = \"file:cert.pem\"\nport = 1\n"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan Result, 16)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Watch(ctx, &WatchOptions{
+			GenerateOptions: GenerateOptions{InputFile: inputFile, OutputFile: outputFile, PackageName: "config"},
+			Debounce:        10 * time.Millisecond,
+		}, func(r Result) {
+			results <- r
+		})
+	}()
+
+	next := func() Result {
+		t.Helper()
+		select {
+		case r := <-results:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("no result from Watch")
+			return Result{}
+		}
+	}
+
+	r := next()
+	require.True(t, r.Initial)
+	require.NoError(t, r.Err)
+	require.FileExists(t, outputFile)
+
+	// Changes to the input regenerate
+	require.NoError(t, os.WriteFile(inputFile, []byte("[tls]\ncert = \"file:cert.pem\"\nport = 2\n"), 0644))
+	r = next()
+	require.False(t, r.Initial)
+	require.NoError(t, r.Err)
+	output, err := os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "Port: 2")
+
+	// So do changes to referenced files
+	require.NoError(t, os.WriteFile(cert, []byte("cert-22"), 0644))
+	r = next()
+	require.NoError(t, r.Err)
+	output, err = os.ReadFile(outputFile)
+	require.NoError(t, err)
+	require.Contains(t, string(output), "0x32, 0x32")
+
+	// Failures are reported and watching carries on
+	require.NoError(t, os.WriteFile(inputFile, []byte("port = \n"), 0644))
+	r = next()
+	require.Error(t, r.Err)
+	require.NotErrorIs(t, r.Err, ErrWatch)
+
+	cancel()
+	require.NoError(t, <-errc)
+}
+
+func TestWatch_Errors(t *testing.T) {
+	dir := t.TempDir()
+	inputFile := filepath.Join(dir, "config.toml")
+
+	err := Watch(context.Background(), &WatchOptions{
+		GenerateOptions: GenerateOptions{InputFile: inputFile, OutputFile: filepath.Join(dir, "config.go")},
+	}, func(Result) {})
+	require.ErrorContains(t, err, "file not found")
+
+	require.NoError(t, os.WriteFile(inputFile, []byte("port = 1\n"), 0644))
+	err = Watch(context.Background(), &WatchOptions{
+		GenerateOptions: GenerateOptions{InputFile: inputFile, OutputFile: inputFile},
+	}, func(Result) {})
+	require.ErrorContains(t, err, "is the watched input file")
+}